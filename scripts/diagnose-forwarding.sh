@@ -3,6 +3,13 @@ set -euo pipefail
 
 BASE_URL="${BASE_URL:-http://127.0.0.1:8080}"
 TARGET_URL="${TARGET_URL:-https://www.gstatic.com/generate_204}"
+# API token with at least operator scope (Settings > Security); not needed with BOXPILOT_AUTH=off.
+BOXPILOT_TOKEN="${BOXPILOT_TOKEN:-}"
+
+auth_args=()
+if [[ -n "${BOXPILOT_TOKEN}" ]]; then
+  auth_args=(-H "Authorization: Bearer ${BOXPILOT_TOKEN}")
+fi
 
 echo "[diag] BASE_URL=${BASE_URL}"
 echo "[diag] TARGET_URL=${TARGET_URL}"
@@ -19,7 +26,7 @@ warn() {
 
 check_get() {
   local path="$1"
-  if curl --noproxy '*' -fsS "${BASE_URL}${path}" >/tmp/boxpilot-diag.out 2>/tmp/boxpilot-diag.err; then
+  if curl --noproxy '*' -fsS ${auth_args[@]+"${auth_args[@]}"} "${BASE_URL}${path}" >/tmp/boxpilot-diag.out 2>/tmp/boxpilot-diag.err; then
     ok "GET ${path}"
     cat /tmp/boxpilot-diag.out
   else
@@ -32,7 +39,7 @@ check_get() {
 check_post() {
   local path="$1"
   local body="$2"
-  if curl --noproxy '*' -fsS -H 'Content-Type: application/json' -X POST -d "${body}" \
+  if curl --noproxy '*' -fsS ${auth_args[@]+"${auth_args[@]}"} -H 'Content-Type: application/json' -X POST -d "${body}" \
     "${BASE_URL}${path}" >/tmp/boxpilot-diag.out 2>/tmp/boxpilot-diag.err; then
     ok "POST ${path}"
     cat /tmp/boxpilot-diag.out
@@ -48,7 +55,7 @@ check_get "/api/v1/runtime/status"
 check_get "/api/v1/settings/forwarding/status"
 check_post "/api/v1/runtime/proxy/check" "{\"target_url\":\"${TARGET_URL}\"}"
 
-runtime_json="$(curl --noproxy '*' -fsS "${BASE_URL}/api/v1/runtime/status" || true)"
+runtime_json="$(curl --noproxy '*' -fsS ${auth_args[@]+"${auth_args[@]}"} "${BASE_URL}/api/v1/runtime/status" || true)"
 compact="$(printf '%s' "${runtime_json}" | tr -d '\n\r\t ')"
 http_port="$(printf '%s' "${compact}" | sed -n 's/.*"http":\([0-9]\+\).*/\1/p' | head -n1)"
 socks_port="$(printf '%s' "${compact}" | sed -n 's/.*"socks":\([0-9]\+\).*/\1/p' | head -n1)"
@@ -0,0 +1,62 @@
+package dto
+
+// AuthStatus tells the web UI whether to show setup, login or the app.
+// Principal is set when the request is authenticated.
+type AuthStatus struct {
+	Enabled       bool           `json:"enabled"`
+	SetupRequired bool           `json:"setup_required"`
+	Principal     *AuthPrincipal `json:"principal,omitempty"`
+}
+
+type AuthPrincipal struct {
+	Kind      string `json:"kind"`
+	Scope     string `json:"scope"`
+	Username  string `json:"username,omitempty"`
+	TokenName string `json:"token_name,omitempty"`
+}
+
+type AuthSetupRequest struct {
+	SetupToken string `json:"setup_token"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResult is returned by setup and login; the session itself is in the
+// HttpOnly cookie.
+type LoginResult struct {
+	Username  string `json:"username"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// APIToken is a long-lived token for scripts. Prefix is the start of the
+// token; the full token is only returned once, by create.
+type APIToken struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Scope      string  `json:"scope"`
+	Prefix     string  `json:"prefix"`
+	CreatedAt  string  `json:"created_at"`
+	ExpiresAt  *string `json:"expires_at,omitempty"`
+	LastUsedAt *string `json:"last_used_at,omitempty"`
+}
+
+type CreateAPITokenRequest struct {
+	Name          string `json:"name"`
+	Scope         string `json:"scope"`
+	ExpiresInDays int    `json:"expires_in_days"`
+}
+
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
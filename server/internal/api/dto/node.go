@@ -8,6 +8,7 @@ type Node struct {
 	Type              string  `json:"type"`
 	Enabled           bool    `json:"enabled"`
 	ForwardingEnabled bool    `json:"forwarding_enabled"`
+	NameLocked        bool    `json:"name_locked"`
 	Server            string  `json:"server,omitempty"`
 	ServerPort        int     `json:"server_port,omitempty"`
 	Network           string  `json:"network,omitempty"`
@@ -65,3 +66,18 @@ type ManualNodeCreateData struct {
 	Mode  string `json:"mode"`
 	Nodes []Node `json:"nodes"`
 }
+
+// NodeDuplicateCluster is one endpoint provided by several subscriptions; only
+// the canonical subscription's nodes are used in the runtime config.
+type NodeDuplicateCluster struct {
+	Fingerprint     string `json:"fingerprint"`
+	PreferredSubID  string `json:"preferred_sub_id,omitempty"`
+	CanonicalSubID  string `json:"canonical_sub_id"`
+	CanonicalNodeID string `json:"canonical_node_id"`
+	Nodes           []Node `json:"nodes"`
+}
+
+type PreferDuplicateRequest struct {
+	Fingerprint string `json:"fingerprint"`
+	SubID       string `json:"sub_id"`
+}
@@ -0,0 +1,87 @@
+package dto
+
+import "encoding/json"
+
+// NotificationChannel is an alert delivery target. Config depends on Type:
+// webhook {url, headers}, telegram {bot_token, chat_id, api_base},
+// smtp {host, port, username, password, from, to}, ntfy {server, topic, token, priority}.
+type NotificationChannel struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Enabled   bool            `json:"enabled"`
+	Config    json.RawMessage `json:"config"`
+	CreatedAt string          `json:"created_at"`
+	UpdatedAt string          `json:"updated_at"`
+}
+
+type CreateNotificationChannelRequest struct {
+	Name    string          `json:"name"`
+	Type    string          `json:"type"`
+	Enabled *bool           `json:"enabled"`
+	Config  json.RawMessage `json:"config"`
+}
+
+// UpdateNotificationChannelRequest replaces the fields that are set; a new
+// Type requires a matching Config.
+type UpdateNotificationChannelRequest struct {
+	ID      string          `json:"id"`
+	Name    *string         `json:"name"`
+	Type    *string         `json:"type"`
+	Enabled *bool           `json:"enabled"`
+	Config  json.RawMessage `json:"config"`
+}
+
+// TestNotificationChannelRequest sends a sample alert through a saved channel
+// (ID) or an unsaved Type + Config.
+type TestNotificationChannelRequest struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Config json.RawMessage `json:"config"`
+}
+
+// NotificationRule raises alerts of one kind. Threshold is a percentage for
+// quota_low / nodes_unhealthy, days for expiring and a failure count for
+// refresh_failed; 0 uses the default. Empty ChannelIDs notifies every enabled
+// channel.
+type NotificationRule struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	Enabled     bool     `json:"enabled"`
+	Threshold   int      `json:"threshold"`
+	CooldownSec int      `json:"cooldown_sec"`
+	ChannelIDs  []string `json:"channel_ids"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+type CreateNotificationRuleRequest struct {
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	Enabled     *bool    `json:"enabled"`
+	Threshold   int      `json:"threshold"`
+	CooldownSec *int     `json:"cooldown_sec"`
+	ChannelIDs  []string `json:"channel_ids"`
+}
+
+type UpdateNotificationRuleRequest struct {
+	ID          string    `json:"id"`
+	Name        *string   `json:"name"`
+	Kind        *string   `json:"kind"`
+	Enabled     *bool     `json:"enabled"`
+	Threshold   *int      `json:"threshold"`
+	CooldownSec *int      `json:"cooldown_sec"`
+	ChannelIDs  *[]string `json:"channel_ids"`
+}
+
+// NotificationAlert is an alert that is currently firing.
+type NotificationAlert struct {
+	RuleID      string  `json:"rule_id"`
+	Subject     string  `json:"subject"`
+	SubjectName string  `json:"subject_name,omitempty"`
+	Message     string  `json:"message"`
+	FirstSeenAt string  `json:"first_seen_at"`
+	LastSentAt  *string `json:"last_sent_at,omitempty"`
+	LastError   *string `json:"last_error,omitempty"`
+}
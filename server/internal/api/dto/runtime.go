@@ -98,22 +98,52 @@ type RuntimeConnectionsResponse struct {
 }
 
 type RuntimeConnectionsData struct {
-	ActiveCount int                 `json:"active_count"`
-	Items       []RuntimeConnection `json:"items"`
+	Source        string              `json:"source"`
+	Error         string              `json:"error,omitempty"`
+	ActiveCount   int                 `json:"active_count"`
+	Matched       int                 `json:"matched"`
+	UploadTotal   int64               `json:"upload_total"`
+	DownloadTotal int64               `json:"download_total"`
+	Items         []RuntimeConnection `json:"items"`
+}
+
+// RuntimeConnection is one live sing-box connection. Chain runs from the
+// outbound the route picked to the one that carried the traffic (Outbound);
+// NodeID and NodeName are set when Outbound is a BoxPilot node.
+type RuntimeConnection struct {
+	ID            string   `json:"id"`
+	Network       string   `json:"network"`
+	Inbound       string   `json:"inbound"`
+	Source        string   `json:"source"`
+	Destination   string   `json:"destination"`
+	Host          string   `json:"host,omitempty"`
+	DestinationIP string   `json:"destination_ip,omitempty"`
+	Process       string   `json:"process,omitempty"`
+	Rule          string   `json:"rule"`
+	RulePayload   string   `json:"rule_payload,omitempty"`
+	Outbound      string   `json:"outbound"`
+	Chain         []string `json:"chain"`
+	NodeID        string   `json:"node_id,omitempty"`
+	NodeName      string   `json:"node_name,omitempty"`
+	UploadBytes   int64    `json:"upload_bytes"`
+	DownloadBytes int64    `json:"download_bytes"`
+	StartedAt     string   `json:"started_at"`
+	DurationMs    int64    `json:"duration_ms"`
 }
 
-type RuntimeConnection struct {
-	ID          string  `json:"id"`
-	NodeID      string  `json:"node_id"`
-	NodeName    string  `json:"node_name"`
-	NodeType    string  `json:"node_type"`
-	Target      string  `json:"target"`
-	Status      string  `json:"status"`
-	LastTestAt  *string `json:"last_test_at,omitempty"`
-	LatencyMs   *int64  `json:"latency_ms,omitempty"`
-	Error       *string `json:"error,omitempty"`
-	Forwarding  bool    `json:"forwarding"`
-	LastUpdated string  `json:"last_updated"`
+// RuntimeConnectionCloseRequest closes the connection ID, or every connection
+// when All is set.
+type RuntimeConnectionCloseRequest struct {
+	ID  string `json:"id"`
+	All bool   `json:"all"`
+}
+
+type RuntimeConnectionCloseResponse struct {
+	Data RuntimeConnectionCloseData `json:"data"`
+}
+
+type RuntimeConnectionCloseData struct {
+	Closed int `json:"closed"`
 }
 
 type RuntimeLogsResponse struct {
@@ -121,16 +151,29 @@ type RuntimeLogsResponse struct {
 }
 
 type RuntimeLogsData struct {
-	Items []RuntimeLogItem `json:"items"`
+	Items     []RuntimeLogItem    `json:"items"`
+	LastSeq   int64               `json:"last_seq"`
+	Counts    map[string]int      `json:"counts"`
+	Collector RuntimeLogCollector `json:"collector"`
 }
 
 type RuntimeLogItem struct {
+	Seq       int64  `json:"seq"`
 	Timestamp string `json:"timestamp"`
 	Level     string `json:"level"`
 	Source    string `json:"source"`
 	Message   string `json:"message"`
 }
 
+// RuntimeLogCollector reports where sing-box logs are read from: clash_api,
+// file or disabled.
+type RuntimeLogCollector struct {
+	Source    string `json:"source"`
+	Target    string `json:"target,omitempty"`
+	Connected bool   `json:"connected"`
+	LastError string `json:"last_error,omitempty"`
+}
+
 type RuntimeGroupSummaryResponse struct {
 	Data RuntimeGroupSummaryData `json:"data"`
 }
@@ -140,18 +183,30 @@ type RuntimeGroupSummaryData struct {
 }
 
 type RuntimeGroupItem struct {
-	Tag                       string   `json:"tag"`
-	DisplayName               string   `json:"display_name,omitempty"`
-	Type                      string   `json:"type"`
-	Outbounds                 []string `json:"outbounds"`
-	Default                   string   `json:"default"`
-	AutoOutbound              *string  `json:"auto_outbound,omitempty"`
-	NodeCandidates            []string `json:"node_candidates,omitempty"`
-	AutoCandidates            []string `json:"auto_candidates,omitempty"`
-	RuntimeSelectedOutbound   *string  `json:"runtime_selected_outbound,omitempty"`
-	RuntimeEffectiveOutbound  *string  `json:"runtime_effective_outbound,omitempty"`
-	PersistedSelectedOutbound *string  `json:"persisted_selected_outbound,omitempty"`
-	PersistedUpdatedAt        *string  `json:"persisted_updated_at,omitempty"`
+	Tag                       string              `json:"tag"`
+	DisplayName               string              `json:"display_name,omitempty"`
+	Type                      string              `json:"type"`
+	Outbounds                 []string            `json:"outbounds"`
+	Default                   string              `json:"default"`
+	AutoOutbound              *string             `json:"auto_outbound,omitempty"`
+	NodeCandidates            []string            `json:"node_candidates,omitempty"`
+	AutoCandidates            []string            `json:"auto_candidates,omitempty"`
+	RuntimeSelectedOutbound   *string             `json:"runtime_selected_outbound,omitempty"`
+	RuntimeEffectiveOutbound  *string             `json:"runtime_effective_outbound,omitempty"`
+	PersistedSelectedOutbound *string             `json:"persisted_selected_outbound,omitempty"`
+	PersistedUpdatedAt        *string             `json:"persisted_updated_at,omitempty"`
+	Policy                    *RuntimeGroupPolicy `json:"policy,omitempty"`
+}
+
+// RuntimeGroupPolicy is the url-test / fallback / load-balance semantics a
+// business group inherited from its subscription.
+type RuntimeGroupPolicy struct {
+	Type        string `json:"type"`
+	URL         string `json:"url,omitempty"`
+	Interval    string `json:"interval,omitempty"`
+	Tolerance   int    `json:"tolerance,omitempty"`
+	Strategy    string `json:"strategy,omitempty"`
+	Unsupported string `json:"unsupported,omitempty"`
 }
 
 type RuntimeGroupSelectRequest struct {
@@ -0,0 +1,27 @@
+package dto
+
+// ShareToken grants a downstream client access to /sub/:token. Only the
+// start of the token is listed; the full token is returned once on create.
+type ShareToken struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Prefix     string  `json:"prefix"`
+	Enabled    bool    `json:"enabled"`
+	CreatedAt  string  `json:"created_at"`
+	LastUsedAt *string `json:"last_used_at,omitempty"`
+}
+
+// CreatedShareToken is returned once, when the token is created.
+type CreatedShareToken struct {
+	ShareToken
+	Token string `json:"token"`
+}
+
+type CreateShareTokenRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateShareTokenRequest struct {
+	ID      string `json:"id"`
+	Enabled *bool  `json:"enabled"`
+}
@@ -0,0 +1,42 @@
+package dto
+
+type TrafficStatsResponse struct {
+	Data TrafficStatsData `json:"data"`
+}
+
+// TrafficStatsData totals the traffic of one dimension (node, group or
+// inbound) over Range, read from Bucket rollups starting at From.
+type TrafficStatsData struct {
+	By            string              `json:"by"`
+	Range         string              `json:"range"`
+	Bucket        string              `json:"bucket"`
+	From          string              `json:"from"`
+	To            string              `json:"to"`
+	UploadBytes   int64               `json:"upload_bytes"`
+	DownloadBytes int64               `json:"download_bytes"`
+	Items         []TrafficStatsItem  `json:"items"`
+	Series        []TrafficStatsPoint `json:"series"`
+}
+
+// TrafficStatsItem is one key's total. For nodes, NodeID, Name and the
+// subscription fields are set when the tag belongs to a BoxPilot node.
+type TrafficStatsItem struct {
+	Key           string `json:"key"`
+	NodeID        string `json:"node_id,omitempty"`
+	Name          string `json:"name,omitempty"`
+	SubID         string `json:"sub_id,omitempty"`
+	SubName       string `json:"sub_name,omitempty"`
+	UploadBytes   int64  `json:"upload_bytes"`
+	DownloadBytes int64  `json:"download_bytes"`
+	TotalBytes    int64  `json:"total_bytes"`
+	Connections   int64  `json:"connections"`
+}
+
+// TrafficStatsPoint is the traffic of one period, for all keys or the key
+// given in the request.
+type TrafficStatsPoint struct {
+	PeriodStart   string `json:"period_start"`
+	UploadBytes   int64  `json:"upload_bytes"`
+	DownloadBytes int64  `json:"download_bytes"`
+	Connections   int64  `json:"connections"`
+}
@@ -1,42 +1,195 @@
 package dto
 
 type Subscription struct {
-	ID                 string   `json:"id"`
-	Name               string   `json:"name"`
-	URL                string   `json:"url"`
-	Type               string   `json:"type"`
-	Enabled            bool     `json:"enabled"`
-	AutoUpdateEnabled  bool     `json:"auto_update_enabled"`
-	RefreshIntervalSec int      `json:"refresh_interval_sec"`
-	Etag               string   `json:"etag,omitempty"`
-	LastModified       string   `json:"last_modified,omitempty"`
-	LastFetchAt        *string  `json:"last_fetch_at,omitempty"`
-	LastSuccessAt      *string  `json:"last_success_at,omitempty"`
-	LastError          *string  `json:"last_error,omitempty"`
-	UsedBytes          *int64   `json:"used_bytes,omitempty"`
-	TotalBytes         *int64   `json:"total_bytes,omitempty"`
-	RemainingBytes     *int64   `json:"remaining_bytes,omitempty"`
-	UsagePercent       *float64 `json:"usage_percent,omitempty"`
-	ExpireAt           *string  `json:"expire_at,omitempty"`
-	ProfileWebPage     *string  `json:"profile_web_page,omitempty"`
-	ProfileUpdateSec   *int     `json:"profile_update_interval_sec,omitempty"`
-	CreatedAt          string   `json:"created_at"`
-	UpdatedAt          string   `json:"updated_at"`
+	ID                     string   `json:"id"`
+	Name                   string   `json:"name"`
+	URL                    string   `json:"url"`
+	Type                   string   `json:"type"`
+	SourceType             string   `json:"source_type"`
+	Enabled                bool     `json:"enabled"`
+	AutoUpdateEnabled      bool     `json:"auto_update_enabled"`
+	RefreshIntervalSec     int      `json:"refresh_interval_sec"`
+	FollowProviderInterval bool     `json:"follow_provider_interval"`
+	Etag                   string   `json:"etag,omitempty"`
+	LastModified           string   `json:"last_modified,omitempty"`
+	LastFetchAt            *string  `json:"last_fetch_at,omitempty"`
+	LastSuccessAt          *string  `json:"last_success_at,omitempty"`
+	LastError              *string  `json:"last_error,omitempty"`
+	UsedBytes              *int64   `json:"used_bytes,omitempty"`
+	TotalBytes             *int64   `json:"total_bytes,omitempty"`
+	RemainingBytes         *int64   `json:"remaining_bytes,omitempty"`
+	UsagePercent           *float64 `json:"usage_percent,omitempty"`
+	ExpireAt               *string  `json:"expire_at,omitempty"`
+	ProfileWebPage         *string  `json:"profile_web_page,omitempty"`
+	ProfileUpdateSec       *int     `json:"profile_update_interval_sec,omitempty"`
+	Expired                bool     `json:"expired"`
+	Warnings               []string `json:"warnings,omitempty"`
+	CreatedAt              string   `json:"created_at"`
+	UpdatedAt              string   `json:"updated_at"`
+
+	ParseDiagnostics *SubscriptionParseDiagnostics `json:"parse_diagnostics,omitempty"`
+	FetchOptions     *SubscriptionFetchOptions     `json:"fetch_options,omitempty"`
+}
+
+// SubscriptionFetchOptions controls how the subscription URL is requested.
+type SubscriptionFetchOptions struct {
+	UserAgentPreset string            `json:"user_agent_preset"`
+	UserAgent       string            `json:"user_agent,omitempty"`
+	Headers         map[string]string `json:"headers"`
+	TimeoutSec      int               `json:"timeout_sec"`
+	MaxBodyBytes    int               `json:"max_body_bytes"`
+	FetchVia        string            `json:"fetch_via"`
+	FetchViaNodeTag string            `json:"fetch_via_node_tag,omitempty"`
+}
+
+// SubscriptionParseDiagnostics summarizes the latest parse of a subscription body.
+type SubscriptionParseDiagnostics struct {
+	Format       string                     `json:"format"`
+	ParsedCount  int                        `json:"parsed_count"`
+	SkippedCount int                        `json:"skipped_count"`
+	Skipped      []SubscriptionSkippedEntry `json:"skipped"`
+	UpdatedAt    string                     `json:"updated_at"`
+}
+
+type SubscriptionSkippedEntry struct {
+	Index    int    `json:"index"`
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Reason   string `json:"reason"`
 }
 
+// CreateSubscriptionRequest creates a url, file or inline subscription. For
+// file subscriptions URL is the path on the server; inline ones take Content.
+// FollowProviderInterval schedules refreshes by the provider's
+// profile-update-interval header when it sends one.
 type CreateSubscriptionRequest struct {
-	Name               string `json:"name"`
-	URL                string `json:"url"`
-	Type               string `json:"type"`
-	AutoUpdateEnabled  *bool  `json:"auto_update_enabled"`
-	RefreshIntervalSec int    `json:"refresh_interval_sec"`
+	Name                   string `json:"name"`
+	URL                    string `json:"url"`
+	Type                   string `json:"type"`
+	SourceType             string `json:"source_type"`
+	Content                string `json:"content"`
+	AutoUpdateEnabled      *bool  `json:"auto_update_enabled"`
+	RefreshIntervalSec     int    `json:"refresh_interval_sec"`
+	FollowProviderInterval *bool  `json:"follow_provider_interval"`
+
+	FetchOptions *SubscriptionFetchOptions `json:"fetch_options"`
 }
 
 type UpdateSubscriptionRequest struct {
-	ID                 string `json:"id"`
-	Name               string `json:"name"`
-	URL                string `json:"url"`
-	Enabled            *bool  `json:"enabled"`
-	AutoUpdateEnabled  *bool  `json:"auto_update_enabled"`
-	RefreshIntervalSec *int   `json:"refresh_interval_sec"`
+	ID                     string `json:"id"`
+	Name                   string `json:"name"`
+	URL                    string `json:"url"`
+	Enabled                *bool  `json:"enabled"`
+	AutoUpdateEnabled      *bool  `json:"auto_update_enabled"`
+	RefreshIntervalSec     *int   `json:"refresh_interval_sec"`
+	FollowProviderInterval *bool  `json:"follow_provider_interval"`
+	// Content replaces the body of an inline subscription.
+	Content *string `json:"content"`
+
+	FetchOptions *SubscriptionFetchOptions `json:"fetch_options"`
+}
+
+// SubscriptionInlineContent is the stored body of an inline subscription.
+type SubscriptionInlineContent struct {
+	SubID     string `json:"sub_id"`
+	Content   string `json:"content"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// SubscriptionPipeline filters and renames nodes at ingest time.
+type SubscriptionPipeline struct {
+	SubID         string                   `json:"sub_id"`
+	IncludeRegex  string                   `json:"include_regex"`
+	ExcludeRegex  string                   `json:"exclude_regex"`
+	TypeAllowlist []string                 `json:"type_allowlist"`
+	RenameRules   []SubscriptionRenameRule `json:"rename_rules"`
+	EmojiFlag     bool                     `json:"emoji_flag"`
+	NameTemplate  string                   `json:"name_template"`
+	UpdatedAt     string                   `json:"updated_at,omitempty"`
+}
+
+type SubscriptionRenameRule struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// PreviewSubscriptionPipelineRequest previews Pipeline, or the stored pipeline
+// when it is omitted, against the last fetched body.
+type PreviewSubscriptionPipelineRequest struct {
+	SubID    string                `json:"sub_id"`
+	Pipeline *SubscriptionPipeline `json:"pipeline"`
+}
+
+type SubscriptionPipelinePreview struct {
+	SubID   string                      `json:"sub_id"`
+	Format  string                      `json:"format"`
+	Kept    int                         `json:"kept"`
+	Dropped int                         `json:"dropped"`
+	Entries []SubscriptionPipelineEntry `json:"entries"`
+}
+
+type SubscriptionPipelineEntry struct {
+	SourceName string `json:"source_name"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Dropped    bool   `json:"dropped"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// SubscriptionRefreshHistoryEntry is one refresh attempt, newest first in lists.
+type SubscriptionRefreshHistoryEntry struct {
+	ID           string                `json:"id"`
+	SubID        string                `json:"sub_id"`
+	StartedAt    string                `json:"started_at"`
+	DurationMs   int64                 `json:"duration_ms"`
+	HTTPStatus   int                   `json:"http_status,omitempty"`
+	Bytes        int                   `json:"bytes"`
+	Format       string                `json:"format,omitempty"`
+	Success      bool                  `json:"success"`
+	NotModified  bool                  `json:"not_modified"`
+	ErrorCode    string                `json:"error_code,omitempty"`
+	Error        string                `json:"error,omitempty"`
+	NodesTotal   int                   `json:"nodes_total"`
+	NodesAdded   int                   `json:"nodes_added"`
+	NodesUpdated int                   `json:"nodes_updated"`
+	NodesRemoved int                   `json:"nodes_removed"`
+	Diff         *SubscriptionNodeDiff `json:"diff,omitempty"`
+}
+
+// SubscriptionNodeDiff lists the nodes a refresh added, removed or changed.
+type SubscriptionNodeDiff struct {
+	Added   []SubscriptionNodeDiffEntry `json:"added"`
+	Removed []SubscriptionNodeDiffEntry `json:"removed"`
+	Changed []SubscriptionNodeDiffEntry `json:"changed"`
+}
+
+type SubscriptionNodeDiffEntry struct {
+	Tag         string   `json:"tag"`
+	PreviousTag string   `json:"previous_tag,omitempty"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Server      string   `json:"server,omitempty"`
+	ServerPort  int      `json:"server_port,omitempty"`
+	Changes     []string `json:"changes,omitempty"`
+}
+
+// SubscriptionSchedule is the auto-refresh state of one subscription.
+// IntervalSource is "subscription" or "provider" (the provider's
+// profile-update-interval); NextRunAt is omitted for subscriptions the
+// scheduler does not refresh.
+type SubscriptionSchedule struct {
+	SubID          string  `json:"sub_id"`
+	Name           string  `json:"name"`
+	SourceType     string  `json:"source_type"`
+	Scheduled      bool    `json:"scheduled"`
+	Running        bool    `json:"running"`
+	Expired        bool    `json:"expired"`
+	IntervalSec    int     `json:"interval_sec"`
+	IntervalSource string  `json:"interval_source"`
+	JitterSec      int     `json:"jitter_sec"`
+	FailureStreak  int     `json:"failure_streak"`
+	BackoffSec     int     `json:"backoff_sec"`
+	LastFetchAt    *string `json:"last_fetch_at,omitempty"`
+	LastError      *string `json:"last_error,omitempty"`
+	NextRunAt      *string `json:"next_run_at,omitempty"`
 }
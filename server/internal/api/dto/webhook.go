@@ -0,0 +1,53 @@
+package dto
+
+import "encoding/json"
+
+// Webhook receives control-plane events as signed JSON POSTs. Empty Events
+// subscribes to every event type. Secret is the HMAC-SHA256 signing key.
+type Webhook struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret"`
+	Events    []string `json:"events"`
+	Enabled   bool     `json:"enabled"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// CreateWebhookRequest creates a target; an empty Secret is generated.
+type CreateWebhookRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Secret  string   `json:"secret"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// UpdateWebhookRequest replaces the fields that are set. Secret "" rotates
+// the secret to a new random one.
+type UpdateWebhookRequest struct {
+	ID      string    `json:"id"`
+	Name    *string   `json:"name"`
+	URL     *string   `json:"url"`
+	Secret  *string   `json:"secret"`
+	Events  *[]string `json:"events"`
+	Enabled *bool     `json:"enabled"`
+}
+
+// WebhookDelivery is one event sent to one target. Status is pending while
+// retries remain, then success or failed.
+type WebhookDelivery struct {
+	ID         string          `json:"id"`
+	WebhookID  string          `json:"webhook_id"`
+	EventID    string          `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload"`
+	Status     string          `json:"status"`
+	Attempts   int             `json:"attempts"`
+	HTTPStatus int             `json:"http_status"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+	CreatedAt  string          `json:"created_at"`
+	UpdatedAt  string          `json:"updated_at"`
+}
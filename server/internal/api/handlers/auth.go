@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Auth handles first-start setup, login sessions and API tokens.
+type Auth struct {
+	DB *sql.DB
+}
+
+// Status is public so the web UI can decide between setup, login and the app.
+func (h *Auth) Status(c *gin.Context) {
+	out := dto.AuthStatus{Enabled: service.AuthEnabled()}
+	if out.Enabled {
+		required, err := service.SetupRequired(h.DB)
+		if err != nil {
+			writeError(c, errorx.New(errorx.DBError, "load users"))
+			return
+		}
+		out.SetupRequired = required
+	}
+	if p := service.PrincipalFrom(c.Request.Context()); p != nil {
+		out.Principal = &dto.AuthPrincipal{Kind: p.Kind, Scope: p.Scope, Username: p.Username, TokenName: p.TokenName}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
+// Setup creates the first admin user with the setup token from the server
+// log and signs it in.
+func (h *Auth) Setup(c *gin.Context) {
+	var req dto.AuthSetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	user, appErr := service.SetupAdmin(c.Request.Context(), h.DB, c.ClientIP(), req.SetupToken, req.Username, req.Password)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	h.startSession(c, user)
+}
+
+func (h *Auth) Login(c *gin.Context) {
+	var req dto.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	user, appErr := service.Login(c.Request.Context(), h.DB, c.ClientIP(), req.Username, req.Password)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	h.startSession(c, user)
+}
+
+func (h *Auth) startSession(c *gin.Context, user *repo.AuthUserRow) {
+	token, expires, err := service.StartSession(h.DB, user, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "create session"))
+		return
+	}
+	setSessionCookie(c, token, expires)
+	c.JSON(http.StatusOK, gin.H{"data": dto.LoginResult{Username: user.Username, ExpiresAt: expires.UTC().Format(util.RFC3339)}})
+}
+
+// Logout ends the session in the cookie, if any, and clears the cookie.
+func (h *Auth) Logout(c *gin.Context) {
+	if token, err := c.Cookie(service.SessionCookieName); err == nil && token != "" {
+		if err := service.EndSession(h.DB, token); err != nil {
+			writeError(c, errorx.New(errorx.DBError, "delete session"))
+			return
+		}
+	}
+	setSessionCookie(c, "", time.Unix(0, 0))
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// ChangePassword keeps the current session and signs out the others.
+func (h *Auth) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	p := service.PrincipalFrom(c.Request.Context())
+	if appErr := service.ChangePassword(c.Request.Context(), h.DB, p, req.CurrentPassword, req.NewPassword); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *Auth) ListTokens(c *gin.Context) {
+	rows, err := repo.ListAPITokens(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list api tokens"))
+		return
+	}
+	data := make([]dto.APIToken, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, apiTokenRowToDTO(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// CreateToken returns the token in full; it cannot be read back later.
+func (h *Auth) CreateToken(c *gin.Context) {
+	var req dto.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	row, token, appErr := service.CreateAPIToken(c.Request.Context(), h.DB, req.Name, req.Scope, req.ExpiresInDays)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": dto.CreatedAPIToken{APIToken: apiTokenRowToDTO(row), Token: token}})
+}
+
+func (h *Auth) DeleteToken(c *gin.Context) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	if strings.TrimSpace(req.ID) == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	if appErr := service.DeleteAPIToken(c.Request.Context(), h.DB, req.ID); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// requireScope repeats the route-group scope check inside handlers whose
+// responses carry credentials, so they stay closed wherever they are mounted.
+func requireScope(c *gin.Context, scope string) *errorx.AppError {
+	p := service.PrincipalFrom(c.Request.Context())
+	if p == nil {
+		return errorx.New(errorx.AUTHUnauthorized, "sign in required")
+	}
+	if !service.ScopeAllows(p.Scope, scope) {
+		return errorx.New(errorx.AUTHForbidden, "insufficient scope").WithDetails(map[string]any{
+			"scope":    p.Scope,
+			"required": scope,
+		})
+	}
+	return nil
+}
+
+// setSessionCookie writes the session cookie; an empty token clears it. The
+// cookie is Secure when the request came over HTTPS, directly or through a
+// proxy that sets X-Forwarded-Proto.
+func setSessionCookie(c *gin.Context, token string, expires time.Time) {
+	cookie := &http.Cookie{
+		Name:     service.SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https"),
+		SameSite: http.SameSiteStrictMode,
+	}
+	if token == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+func apiTokenRowToDTO(r repo.APITokenRow) dto.APIToken {
+	out := dto.APIToken{
+		ID:        r.ID,
+		Name:      r.Name,
+		Scope:     r.Scope,
+		Prefix:    r.Prefix,
+		CreatedAt: r.CreatedAt,
+	}
+	if r.ExpiresAt.Valid {
+		out.ExpiresAt = &r.ExpiresAt.String
+	}
+	if r.LastUsedAt.Valid {
+		out.LastUsedAt = &r.LastUsedAt.String
+	}
+	return out
+}
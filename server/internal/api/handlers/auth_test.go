@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthStatusWhenDisabled(t *testing.T) {
+	t.Setenv("BOXPILOT_AUTH", "off")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(service.WithPrincipal(c.Request.Context(), service.AnonymousPrincipal()))
+	})
+	h := &Auth{}
+	r.GET("/api/v1/auth/status", h.Status)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/status", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data struct {
+			Enabled       bool `json:"enabled"`
+			SetupRequired bool `json:"setup_required"`
+			Principal     *struct {
+				Kind  string `json:"kind"`
+				Scope string `json:"scope"`
+			} `json:"principal"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Enabled || body.Data.SetupRequired || body.Data.Principal == nil ||
+		body.Data.Principal.Kind != service.PrincipalAnonymous || body.Data.Principal.Scope != service.ScopeAdmin {
+		t.Fatalf("unexpected status %+v", body.Data)
+	}
+}
+
+func TestSessionCookieAttributes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/login", func(c *gin.Context) { setSessionCookie(c, "secret", time.Now().Add(time.Hour)) })
+	r.GET("/logout", func(c *gin.Context) { setSessionCookie(c, "", time.Unix(0, 0)) })
+
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies", len(cookies))
+	}
+	c := cookies[0]
+	if c.Name != service.SessionCookieName || c.Value != "secret" || !c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("unexpected cookie %+v", c)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/logout", nil))
+	c = w.Result().Cookies()[0]
+	if c.Value != "" || c.MaxAge >= 0 || c.Secure {
+		t.Fatalf("logout cookie %+v", c)
+	}
+}
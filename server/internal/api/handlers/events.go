@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/api/middleware"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// Stream-only message types that are not bus events.
+const (
+	streamEventSubscribed = "subscribed"
+	streamEventHeartbeat  = "heartbeat"
+	streamEventError      = "error"
+)
+
+const (
+	eventStreamQueue         = 256
+	eventStreamHeartbeat     = 15 * time.Second
+	defaultTrafficIntervalMs = 1000
+	minTrafficIntervalMs     = 250
+	maxTrafficIntervalMs     = 60000
+)
+
+// Events pushes control-plane and progress events to dashboards, over
+// Server-Sent Events (Stream) or WebSocket (WebSocket).
+type Events struct{}
+
+type eventStreamOptions struct {
+	topics          service.EventTopicSet
+	trafficInterval time.Duration
+}
+
+// Stream serves GET /events as text/event-stream. Each message is named
+// after the event type and carries the event JSON as data.
+func (h *Events) Stream(c *gin.Context) {
+	opts, appErr := parseEventStreamOptions(c)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	w.Flush()
+	_ = pumpEvents(c.Request.Context(), opts, nil, func(e service.Event) error {
+		return writeSSEEvent(w, e)
+	})
+}
+
+// WebSocket serves GET /events/ws. Messages are event JSON objects; the
+// client may send {"topics": [...]} to replace its topics.
+func (h *Events) WebSocket(c *gin.Context) {
+	opts, appErr := parseEventStreamOptions(c)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	srv := websocket.Server{
+		Handshake: checkWebSocketOrigin,
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+			var mu sync.Mutex
+			send := func(e service.Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				return websocket.JSON.Send(ws, e)
+			}
+			updates := make(chan service.EventTopicSet, 1)
+			go readWebSocketTopics(ws, updates, send, cancel)
+			_ = pumpEvents(ctx, opts, updates, send)
+		},
+	}
+	srv.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkWebSocketOrigin rejects browser handshakes from other sites, which
+// would otherwise ride on the session cookie; CORS does not cover WebSocket.
+// Clients that send no Origin are not browsers and authenticate by token.
+func checkWebSocketOrigin(_ *websocket.Config, r *http.Request) error {
+	if origin := r.Header.Get("Origin"); origin != "" && !middleware.TrustedOrigin(origin, r.Host) {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+	return nil
+}
+
+func readWebSocketTopics(ws *websocket.Conn, updates chan service.EventTopicSet, send func(service.Event) error, cancel func()) {
+	defer cancel()
+	for {
+		var msg struct {
+			Topics []string `json:"topics"`
+		}
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				_ = send(streamError(errorx.New(errorx.REQValidationFailed, "invalid message")))
+				continue
+			}
+			return
+		}
+		topics, appErr := service.ParseEventTopics(msg.Topics)
+		if appErr != nil {
+			_ = send(streamError(appErr))
+			continue
+		}
+		select {
+		case updates <- topics:
+		default:
+			// Replace a pending update that the pump has not picked up yet.
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- topics
+		}
+	}
+}
+
+// pumpEvents sends the events of the subscribed topics, a heartbeat every
+// eventStreamHeartbeat and, with the traffic topic, a traffic sample every
+// trafficInterval, until ctx ends or send fails.
+func pumpEvents(ctx context.Context, opts eventStreamOptions, updates <-chan service.EventTopicSet, send func(service.Event) error) error {
+	events, unsubscribe := service.Events.Subscribe(eventStreamQueue)
+	defer unsubscribe()
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	topics := opts.topics
+	var traffic *time.Ticker
+	var trafficC <-chan time.Time
+	setTopics := func(next service.EventTopicSet) error {
+		topics = next
+		if traffic != nil {
+			traffic.Stop()
+			traffic, trafficC = nil, nil
+		}
+		if topics.Has(service.TopicTraffic) {
+			traffic = time.NewTicker(opts.trafficInterval)
+			trafficC = traffic.C
+		}
+		return send(streamSubscribed(topics))
+	}
+	defer func() {
+		if traffic != nil {
+			traffic.Stop()
+		}
+	}()
+	if err := setTopics(topics); err != nil {
+		return err
+	}
+	if topics.Has(service.TopicTraffic) {
+		if err := send(trafficEvent(sampleRuntimeTraffic(ctx))); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case next := <-updates:
+			if err := setTopics(next); err != nil {
+				return err
+			}
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if !topics.Matches(e.Type) {
+				continue
+			}
+			if err := send(e); err != nil {
+				return err
+			}
+		case <-trafficC:
+			if err := send(trafficEvent(sampleRuntimeTraffic(ctx))); err != nil {
+				return err
+			}
+		case now := <-heartbeat.C:
+			if err := send(service.Event{Type: streamEventHeartbeat, At: now.UTC()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func parseEventStreamOptions(c *gin.Context) (eventStreamOptions, *errorx.AppError) {
+	var names []string
+	for _, raw := range c.QueryArray("topics") {
+		names = append(names, strings.Split(raw, ",")...)
+	}
+	topics, appErr := service.ParseEventTopics(names)
+	if appErr != nil {
+		return eventStreamOptions{}, appErr
+	}
+	intervalMs := defaultTrafficIntervalMs
+	if raw := strings.TrimSpace(c.Query("traffic_interval_ms")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < minTrafficIntervalMs || n > maxTrafficIntervalMs {
+			return eventStreamOptions{}, errorx.New(errorx.REQInvalidField, "traffic_interval_ms out of range").WithDetails(map[string]any{
+				"traffic_interval_ms": raw,
+				"min":                 minTrafficIntervalMs,
+				"max":                 maxTrafficIntervalMs,
+			})
+		}
+		intervalMs = n
+	}
+	return eventStreamOptions{topics: topics, trafficInterval: time.Duration(intervalMs) * time.Millisecond}, nil
+}
+
+func writeSSEEvent(w gin.ResponseWriter, e service.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+	fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", e.Type, body)
+	if _, err := w.WriteString(b.String()); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+func streamSubscribed(topics service.EventTopicSet) service.Event {
+	names := make([]string, 0, len(topics))
+	for _, name := range service.EventTopics() {
+		if topics.Has(name) {
+			names = append(names, name)
+		}
+	}
+	return service.Event{Type: streamEventSubscribed, At: time.Now().UTC(), Data: map[string]any{"topics": names}}
+}
+
+func streamError(appErr *errorx.AppError) service.Event {
+	return service.Event{Type: streamEventError, At: time.Now().UTC(), Data: map[string]any{
+		"code":    appErr.Code,
+		"message": appErr.Message,
+		"details": appErr.Details,
+	}}
+}
+
+func trafficEvent(d dto.RuntimeTrafficData) service.Event {
+	return service.Event{Type: service.EventTrafficSample, At: time.Now().UTC(), Data: map[string]any{
+		"sampled_at":     d.SampledAt,
+		"source":         d.Source,
+		"rx_rate_bps":    d.RXRateBps,
+		"tx_rate_bps":    d.TXRateBps,
+		"rx_total_bytes": d.RXTotalBytes,
+		"tx_total_bytes": d.TXTotalBytes,
+	}}
+}
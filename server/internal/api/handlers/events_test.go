@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+func eventStreamServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := &Events{}
+	r.GET("/events", h.Stream)
+	r.GET("/events/ws", h.WebSocket)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// readSSEEvent returns the next named event and its data.
+func readSSEEvent(t *testing.T, r *bufio.Reader) (string, service.Event) {
+	t.Helper()
+	var name, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && name != "":
+			var e service.Event
+			if err := json.Unmarshal([]byte(data), &e); err != nil {
+				t.Fatalf("decode %q: %v", data, err)
+			}
+			return name, e
+		}
+	}
+}
+
+func TestEventStreamSSEFiltersTopics(t *testing.T) {
+	srv := eventStreamServer(t)
+	resp, err := http.Get(srv.URL + "/events?topics=reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	name, e := readSSEEvent(t, r)
+	if name != streamEventSubscribed || len(e.Data["topics"].([]any)) != 1 {
+		t.Fatalf("expected subscribed to reload, got %s %v", name, e.Data)
+	}
+
+	service.PublishEvent(service.EventNodesChanged, map[string]any{"source": "api"})
+	service.PublishEvent(service.EventReloadProgress, map[string]any{"reload_id": "r1", "stage": "check"})
+	name, e = readSSEEvent(t, r)
+	if name != service.EventReloadProgress || e.Data["stage"] != "check" || e.ID == "" {
+		t.Fatalf("expected the reload progress event only, got %s %+v", name, e)
+	}
+}
+
+func TestEventStreamRejectsBadQuery(t *testing.T) {
+	srv := eventStreamServer(t)
+	for _, q := range []string{"topics=logs", "traffic_interval_ms=10"} {
+		resp, err := http.Get(srv.URL + "/events?" + q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, resp.StatusCode)
+		}
+	}
+}
+
+func TestEventStreamWebSocketTopicUpdate(t *testing.T) {
+	srv := eventStreamServer(t)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/events/ws?topics=control"
+	ws, err := websocket.Dial(wsURL, "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	_ = ws.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var e service.Event
+	if err := websocket.JSON.Receive(ws, &e); err != nil || e.Type != streamEventSubscribed {
+		t.Fatalf("expected subscribed, got %+v %v", e, err)
+	}
+	if err := websocket.JSON.Send(ws, map[string]any{"topics": []string{"probe"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := websocket.JSON.Receive(ws, &e); err != nil || e.Type != streamEventSubscribed {
+		t.Fatalf("expected re-subscribed, got %+v %v", e, err)
+	}
+	service.PublishEvent(service.EventConfigApplied, nil)
+	service.PublishEvent(service.EventNodeProbe, map[string]any{"node_id": "n1"})
+	if err := websocket.JSON.Receive(ws, &e); err != nil || e.Type != service.EventNodeProbe || e.Data["node_id"] != "n1" {
+		t.Fatalf("expected the probe event only, got %+v %v", e, err)
+	}
+
+	if err := websocket.JSON.Send(ws, map[string]any{"topics": []string{"nope"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := websocket.JSON.Receive(ws, &e); err != nil || e.Type != streamEventError {
+		t.Fatalf("expected error message, got %+v %v", e, err)
+	}
+}
+
+func TestEventStreamWebSocketChecksOrigin(t *testing.T) {
+	t.Setenv("BOXPILOT_CORS_ORIGINS", "http://dev.example:5173")
+	srv := eventStreamServer(t)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/events/ws"
+	if ws, err := websocket.Dial(wsURL, "", "http://evil.example"); err == nil {
+		ws.Close()
+		t.Fatalf("expected a cross-site handshake to be rejected")
+	}
+	for _, origin := range []string{srv.URL, "http://dev.example:5173"} {
+		ws, err := websocket.Dial(wsURL, "", origin)
+		if err != nil {
+			t.Fatalf("origin %s: %v", origin, err)
+		}
+		ws.Close()
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"boxpilot/server/internal/export"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Export struct {
+	DB *sql.DB
+}
+
+var exportFileNames = map[string]string{
+	export.FormatClash:   "boxpilot-nodes.yaml",
+	export.FormatSingbox: "boxpilot-nodes.json",
+	export.FormatURI:     "boxpilot-nodes.txt",
+}
+
+// Nodes serves the managed node set as Clash YAML, sing-box JSON or a base64
+// URI list. Optional filters: sub_id, enabled and forwarding (0 or 1). The
+// output carries full node credentials, so it requires an admin principal.
+func (h *Export) Nodes(c *gin.Context) {
+	if appErr := requireScope(c, service.ScopeAdmin); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	format := c.Param("format")
+	if !export.ValidFormat(format) {
+		writeError(c, errorx.New(errorx.REQInvalidField, "unsupported export format").WithDetails(map[string]any{
+			"format":    format,
+			"supported": []string{export.FormatClash, export.FormatSingbox, export.FormatURI},
+		}))
+		return
+	}
+	enabled, appErr := optionalFlagQuery(c, "enabled")
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	forwarding, appErr := optionalFlagQuery(c, "forwarding")
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	rows, err := repo.ListNodes(h.DB, c.Query("sub_id"), enabled)
+	if err != nil {
+		writeError(c, errorx.New(errorx.NODEListFailed, "list nodes").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	nodes := make([]export.Node, 0, len(rows))
+	for _, r := range rows {
+		if forwarding != nil && r.ForwardingEnabled != *forwarding {
+			continue
+		}
+		nodes = append(nodes, export.Node{Tag: r.Tag, Type: r.Type, OutboundJSON: r.OutboundJSON})
+	}
+	res, err := export.Export(format, nodes)
+	if err != nil {
+		writeError(c, errorx.New(errorx.NODEInvalidOutbound, "export nodes").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+exportFileNames[format]+`"`)
+	c.Header("X-Export-Count", strconv.Itoa(res.Count))
+	c.Header("X-Export-Skipped", strconv.Itoa(len(res.Skipped)))
+	c.Data(http.StatusOK, res.ContentType, res.Body)
+}
+
+// optionalFlagQuery reads a 0/1 query filter; an absent value means no filter.
+func optionalFlagQuery(c *gin.Context, key string) (*int, *errorx.AppError) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || (v != 0 && v != 1) {
+		return nil, errorx.New(errorx.REQInvalidField, key+" must be 0 or 1").WithDetails(map[string]any{key: raw})
+	}
+	return &v, nil
+}
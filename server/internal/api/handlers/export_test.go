@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boxpilot/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportRequiresAdminPrincipal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name      string
+		principal *service.Principal
+		want      int
+	}{
+		{name: "anonymous request", want: http.StatusUnauthorized},
+		{name: "read-only token", principal: &service.Principal{Kind: service.PrincipalToken, Scope: service.ScopeReadOnly}, want: http.StatusForbidden},
+		{name: "operator token", principal: &service.Principal{Kind: service.PrincipalToken, Scope: service.ScopeOperator}, want: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tc.principal != nil {
+					c.Request = c.Request.WithContext(service.WithPrincipal(c.Request.Context(), tc.principal))
+				}
+			})
+			h := &Export{}
+			r.GET("/api/v1/export/:format", h.Nodes)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/export/clash", nil))
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package handlers
+
+import "boxpilot/server/internal/observability"
+
+var (
+	httpLog  = observability.Logger(observability.SubsystemHTTP)
+	probeLog = observability.Logger(observability.SubsystemProbe)
+)
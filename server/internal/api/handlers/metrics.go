@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"os"
+	"strings"
+
+	"boxpilot/server/internal/observability"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metrics serves /metrics in the Prometheus text format.
+type Metrics struct {
+	DB *sql.DB
+}
+
+// Serve refreshes the scrape-time metrics and writes every family. Requests
+// must carry BOXPILOT_METRICS_TOKEN or, while auth is enabled, an API token
+// with read-only scope as a bearer token; with neither configured the endpoint
+// is open.
+func (h *Metrics) Serve(c *gin.Context) {
+	if appErr := h.authorize(c); appErr != nil {
+		if appErr.Code == errorx.AUTHUnauthorized {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+		}
+		writeError(c, appErr)
+		return
+	}
+	if h.DB != nil {
+		// A partial scrape beats none; the event-driven metrics are still valid.
+		if err := service.CollectMetrics(c.Request.Context(), h.DB); err != nil {
+			httpLog.WarnContext(c.Request.Context(), "metrics collect failed", "error", err)
+		}
+	}
+	c.Header("Content-Type", metricsContentType)
+	c.Status(http.StatusOK)
+	if err := observability.Metrics.WriteText(c.Writer); err != nil {
+		httpLog.WarnContext(c.Request.Context(), "metrics write failed", "error", err)
+	}
+}
+
+func (h *Metrics) authorize(c *gin.Context) *errorx.AppError {
+	metricsToken := strings.TrimSpace(os.Getenv("BOXPILOT_METRICS_TOKEN"))
+	authEnabled := service.AuthEnabled()
+	if metricsToken == "" && !authEnabled {
+		return nil
+	}
+	got := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if got == "" {
+		return errorx.New(errorx.AUTHUnauthorized, "metrics token or api token required")
+	}
+	if metricsToken != "" && subtle.ConstantTimeCompare([]byte(got), []byte(metricsToken)) == 1 {
+		return nil
+	}
+	if authEnabled && h.DB != nil {
+		p, err := service.TokenPrincipal(h.DB, got)
+		if err != nil {
+			return errorx.New(errorx.DBError, "load api token")
+		}
+		if p != nil {
+			if !service.ScopeAllows(p.Scope, service.ScopeReadOnly) {
+				return errorx.New(errorx.AUTHForbidden, "insufficient scope").WithDetails(map[string]any{
+					"scope":    p.Scope,
+					"required": service.ScopeReadOnly,
+				})
+			}
+			return nil
+		}
+	}
+	return errorx.New(errorx.AUTHUnauthorized, "metrics token or api token required")
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"boxpilot/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsRequiresConfiguredToken(t *testing.T) {
+	t.Setenv("BOXPILOT_METRICS_TOKEN", "scrape-secret")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := &Metrics{}
+	r.GET("/metrics", h.Serve)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	service.RecordNodeProbe("ping", "ok", 120)
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected 401 with a challenge, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), `boxpilot_node_probe_latency_seconds_bucket{mode="ping",le="0.2"}`) {
+		t.Fatalf("probe histogram missing:\n%s", body)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Duplicates lists endpoints that more than one subscription provides, over all
+// stored nodes. The runtime config applies the same choice to the forwarding
+// candidates only, so a disabled canonical copy is replaced by the next source.
+func (h *Nodes) Duplicates(c *gin.Context) {
+	nodes, err := repo.ListNodes(h.DB, "", nil)
+	if err != nil {
+		writeError(c, errorx.New(errorx.NODEListFailed, "list nodes").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	prefs, err := repo.ListNodeDedupPreferences(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list node dedup preferences").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	clusters := service.FindDuplicateClusters(nodes, prefs)
+	data := make([]dto.NodeDuplicateCluster, 0, len(clusters))
+	for _, cl := range clusters {
+		item := dto.NodeDuplicateCluster{
+			Fingerprint:     cl.Fingerprint,
+			PreferredSubID:  cl.PreferredSubID,
+			CanonicalSubID:  cl.CanonicalSubID,
+			CanonicalNodeID: cl.CanonicalNodeID,
+			Nodes:           make([]dto.Node, 0, len(cl.Nodes)),
+		}
+		for _, n := range cl.Nodes {
+			item.Nodes = append(item.Nodes, nodeRowToDTO(n))
+		}
+		data = append(data, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *Nodes) PreferDuplicate(c *gin.Context) {
+	var req dto.PreferDuplicateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	if appErr := service.SetDuplicatePreference(h.DB, strings.TrimSpace(req.Fingerprint), strings.TrimSpace(req.SubID)); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	if err := service.ReloadIfForwardingRunning(c.Request.Context(), h.DB); err != nil {
+		if appErr, ok := err.(*errorx.AppError); ok {
+			writeError(c, appErr)
+			return
+		}
+		writeError(c, errorx.New(errorx.RTRestartFailed, "reload after dedup preference update failed").WithDetails(map[string]any{
+			"err": err.Error(),
+		}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
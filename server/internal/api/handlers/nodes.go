@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -104,6 +105,7 @@ func (h *Nodes) Update(c *gin.Context) {
 		}))
 		return
 	}
+	service.PublishEvent(service.EventNodesChanged, map[string]any{"source": "api", "node_ids": []string{req.ID}})
 	row, _ := repo.GetNode(h.DB, req.ID)
 	if row != nil {
 		c.JSON(http.StatusOK, gin.H{"data": nodeRowToDTO(*row)})
@@ -178,6 +180,11 @@ func (h *Nodes) CreateManual(c *gin.Context) {
 		}
 		created = append(created, nodeRowToDTO(row))
 	}
+	service.PublishEvent(service.EventNodesChanged, map[string]any{
+		"source":      "api",
+		"sub_id":      repo.ManualSubscriptionID,
+		"nodes_added": len(created),
+	})
 
 	c.JSON(http.StatusOK, dto.ManualNodeCreateResponse{
 		Data: dto.ManualNodeCreateData{
@@ -234,6 +241,13 @@ func (h *Nodes) BatchForwarding(c *gin.Context) {
 		}))
 		return
 	}
+	if updated > 0 {
+		service.PublishEvent(service.EventNodesChanged, map[string]any{
+			"source":        "api",
+			"node_ids":      req.NodeIDs,
+			"nodes_updated": updated,
+		})
+	}
 	c.JSON(http.StatusOK, gin.H{"data": gin.H{"updated": updated}})
 }
 
@@ -299,6 +313,8 @@ func (h *Nodes) Test(c *gin.Context) {
 		tasks = append(tasks, probeTask{index: idx, nodeID: nodeID, row: row})
 	}
 
+	ctx := c.Request.Context()
+	probeLog.InfoContext(ctx, "node probe started", "mode", req.Mode, "nodes", len(tasks), "concurrency", policy.NodeTestConcurrency)
 	// Probe network concurrently to speed up "test all" while keeping DB writes serialized.
 	probeOut := make([]probeResult, len(tasks))
 	if len(tasks) > 0 {
@@ -307,6 +323,8 @@ func (h *Nodes) Test(c *gin.Context) {
 			workers = 1
 		}
 		var wg sync.WaitGroup
+		var completed atomic.Int32
+		runID := util.NewID()
 		taskCh := make(chan probeTask)
 		resultCh := make(chan probeResult, len(tasks))
 		for i := 0; i < workers; i++ {
@@ -319,6 +337,17 @@ func (h *Nodes) Test(c *gin.Context) {
 					if latency >= 0 {
 						latencyPtr = &latency
 					}
+					service.RecordNodeProbe(req.Mode, status, latency)
+					probeLog.DebugContext(ctx, "node probed", "node_id", task.nodeID, "tag", task.row.Tag, "mode", req.Mode, "status", status, "latency_ms", latency, "error", errMsg)
+					service.PublishEvent(service.EventNodeProbe, map[string]any{
+						"run_id":     runID,
+						"node_id":    task.nodeID,
+						"status":     status,
+						"latency_ms": latencyPtr,
+						"error":      nullIfEmpty(errMsg),
+						"completed":  int(completed.Add(1)),
+						"total":      len(tasks),
+					})
 					resultCh <- probeResult{
 						index:      task.index,
 						nodeID:     task.nodeID,
@@ -520,6 +549,7 @@ func nodeRowToDTO(r repo.NodeRow) dto.Node {
 		Type:              r.Type,
 		Enabled:           r.Enabled == 1,
 		ForwardingEnabled: r.ForwardingEnabled == 1,
+		NameLocked:        r.NameLocked == 1,
 		CreatedAt:         r.CreatedAt,
 	}
 	meta := parseNodeMeta(r.OutboundJSON)
@@ -565,6 +595,15 @@ func parseNodeMeta(raw string) nodeMeta {
 	case int:
 		out.ServerPort = p
 	}
+	// WireGuard endpoints keep the remote address on the first peer.
+	if peers, ok := m["peers"].([]any); ok && len(peers) > 0 && out.Server == "" {
+		if peer, ok := peers[0].(map[string]any); ok {
+			out.Server, _ = peer["address"].(string)
+			if p, ok := peer["port"].(float64); ok {
+				out.ServerPort = int(p)
+			}
+		}
+	}
 	if t, ok := m["transport"].(map[string]any); ok {
 		if n, ok := t["type"].(string); ok {
 			out.Network = n
@@ -593,7 +632,8 @@ func probeNodePing(rawOutbound string, timeout time.Duration) (latencyMs int, st
 	return int(time.Since(start).Milliseconds()), "ok", ""
 }
 
-// probeNodeHysteria2UDP checks UDP reachability for Hysteria2 (QUIC) outbounds.
+// probeNodeHysteria2UDP checks UDP reachability for Hysteria2 (QUIC) outbounds and the
+// other UDP-only protocols (Hysteria, TUIC, WireGuard).
 // TCP dial to the same port often yields false negatives because the server listens on UDP only.
 func probeNodeHysteria2UDP(rawOutbound string, timeout time.Duration) (latencyMs int, status string, errMsg string) {
 	meta := parseNodeMeta(rawOutbound)
@@ -669,7 +709,8 @@ func probeNode(rawOutbound, nodeType, mode string, timeout time.Duration) (laten
 	if mode == "http" && nodeType == "http" {
 		return probeNodeHTTP(rawOutbound, timeout)
 	}
-	if nodeType == "hysteria2" {
+	switch nodeType {
+	case "hysteria2", "hysteria", "tuic", "wireguard":
 		return probeNodeHysteria2UDP(rawOutbound, timeout)
 	}
 	return probeNodePing(rawOutbound, timeout)
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Notifications manages alert channels and rules and reports firing alerts.
+type Notifications struct {
+	DB *sql.DB
+}
+
+func (h *Notifications) ListChannels(c *gin.Context) {
+	rows, err := repo.ListNotificationChannels(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list notification channels"))
+		return
+	}
+	data := make([]dto.NotificationChannel, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, notificationChannelRowToDTO(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *Notifications) CreateChannel(c *gin.Context) {
+	var req dto.CreateNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	typ, config, appErr := service.NormalizeNotifierConfig(req.Type, req.Config)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = typ
+	}
+	enabled := 1
+	if req.Enabled != nil && !*req.Enabled {
+		enabled = 0
+	}
+	now := util.NowRFC3339()
+	row := repo.NotificationChannelRow{
+		ID:         util.NewID(),
+		Name:       name,
+		Type:       typ,
+		Enabled:    enabled,
+		ConfigJSON: string(config),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := repo.CreateNotificationChannel(h.DB, row); err != nil {
+		writeError(c, errorx.New(errorx.DBError, "create notification channel"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": notificationChannelRowToDTO(row)})
+}
+
+func (h *Notifications) UpdateChannel(c *gin.Context) {
+	var req dto.UpdateNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	if req.ID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	row, err := repo.GetNotificationChannel(h.DB, req.ID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "load notification channel"))
+		return
+	}
+	if row == nil {
+		writeError(c, errorx.New(errorx.NOTIFYChannelNotFound, "notification channel not found").WithDetails(map[string]any{"id": req.ID}))
+		return
+	}
+	if req.Name != nil {
+		if name := strings.TrimSpace(*req.Name); name != "" {
+			row.Name = name
+		}
+	}
+	if req.Enabled != nil {
+		row.Enabled = 0
+		if *req.Enabled {
+			row.Enabled = 1
+		}
+	}
+	typ := row.Type
+	if req.Type != nil {
+		typ = *req.Type
+	}
+	config := []byte(row.ConfigJSON)
+	if len(req.Config) > 0 {
+		config = req.Config
+	}
+	normalizedType, normalized, appErr := service.NormalizeNotifierConfig(typ, config)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	row.Type = normalizedType
+	row.ConfigJSON = string(normalized)
+	row.UpdatedAt = util.NowRFC3339()
+	if _, err := repo.UpdateNotificationChannel(h.DB, *row); err != nil {
+		writeError(c, errorx.New(errorx.DBError, "update notification channel"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": notificationChannelRowToDTO(*row)})
+}
+
+func (h *Notifications) DeleteChannel(c *gin.Context) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	ok, err := repo.DeleteNotificationChannel(h.DB, req.ID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "delete notification channel"))
+		return
+	}
+	if !ok {
+		writeError(c, errorx.New(errorx.NOTIFYChannelNotFound, "notification channel not found").WithDetails(map[string]any{"id": req.ID}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// TestChannel sends a sample alert through a saved channel or an unsaved
+// type + config, so settings can be checked before saving them.
+func (h *Notifications) TestChannel(c *gin.Context) {
+	var req dto.TestNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	typ, config := req.Type, []byte(req.Config)
+	if req.ID != "" {
+		row, err := repo.GetNotificationChannel(h.DB, req.ID)
+		if err != nil {
+			writeError(c, errorx.New(errorx.DBError, "load notification channel"))
+			return
+		}
+		if row == nil {
+			writeError(c, errorx.New(errorx.NOTIFYChannelNotFound, "notification channel not found").WithDetails(map[string]any{"id": req.ID}))
+			return
+		}
+		typ, config = row.Type, []byte(row.ConfigJSON)
+	} else if typ == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id or type required"))
+		return
+	}
+	if appErr := service.SendTestNotification(c.Request.Context(), typ, config); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *Notifications) ListRules(c *gin.Context) {
+	rows, err := repo.ListNotificationRules(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list notification rules"))
+		return
+	}
+	data := make([]dto.NotificationRule, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, notificationRuleRowToDTO(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *Notifications) CreateRule(c *gin.Context) {
+	var req dto.CreateNotificationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	rule := service.NotificationRule{
+		ID:          util.NewID(),
+		Name:        req.Name,
+		Kind:        req.Kind,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		Threshold:   req.Threshold,
+		CooldownSec: service.DefaultAlertCooldownSec,
+		ChannelIDs:  req.ChannelIDs,
+	}
+	if req.CooldownSec != nil {
+		rule.CooldownSec = *req.CooldownSec
+	}
+	rule, appErr := h.normalizeRule(rule)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	row := service.NotificationRuleToRow(rule)
+	row.CreatedAt = util.NowRFC3339()
+	row.UpdatedAt = row.CreatedAt
+	if err := repo.CreateNotificationRule(h.DB, row); err != nil {
+		writeError(c, errorx.New(errorx.DBError, "create notification rule"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": notificationRuleRowToDTO(row)})
+}
+
+func (h *Notifications) UpdateRule(c *gin.Context) {
+	var req dto.UpdateNotificationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	if req.ID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	existing, err := repo.GetNotificationRule(h.DB, req.ID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "load notification rule"))
+		return
+	}
+	if existing == nil {
+		writeError(c, errorx.New(errorx.NOTIFYRuleNotFound, "notification rule not found").WithDetails(map[string]any{"id": req.ID}))
+		return
+	}
+	rule := service.NotificationRuleFromRow(*existing)
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.Kind != nil {
+		rule.Kind = *req.Kind
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Threshold != nil {
+		rule.Threshold = *req.Threshold
+	}
+	if req.CooldownSec != nil {
+		rule.CooldownSec = *req.CooldownSec
+	}
+	if req.ChannelIDs != nil {
+		rule.ChannelIDs = *req.ChannelIDs
+	}
+	rule, appErr := h.normalizeRule(rule)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	row := service.NotificationRuleToRow(rule)
+	row.CreatedAt = existing.CreatedAt
+	row.UpdatedAt = util.NowRFC3339()
+	if _, err := repo.UpdateNotificationRule(h.DB, row); err != nil {
+		writeError(c, errorx.New(errorx.DBError, "update notification rule"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": notificationRuleRowToDTO(row)})
+}
+
+func (h *Notifications) DeleteRule(c *gin.Context) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	ok, err := repo.DeleteNotificationRule(h.DB, req.ID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "delete notification rule"))
+		return
+	}
+	if !ok {
+		writeError(c, errorx.New(errorx.NOTIFYRuleNotFound, "notification rule not found").WithDetails(map[string]any{"id": req.ID}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// Alerts lists the alerts that are currently firing.
+func (h *Notifications) Alerts(c *gin.Context) {
+	rows, err := repo.ListNotificationAlertStates(h.DB, c.Query("rule_id"))
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list notification alerts"))
+		return
+	}
+	data := make([]dto.NotificationAlert, 0, len(rows))
+	for _, r := range rows {
+		d := dto.NotificationAlert{
+			RuleID:      r.RuleID,
+			Subject:     r.Subject,
+			SubjectName: r.SubjectName,
+			Message:     r.Message,
+			FirstSeenAt: r.FirstSeenAt,
+		}
+		if r.LastSentAt.Valid {
+			d.LastSentAt = &r.LastSentAt.String
+		}
+		if r.LastError.Valid {
+			d.LastError = &r.LastError.String
+		}
+		data = append(data, d)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// normalizeRule validates the rule and checks that its channels exist.
+func (h *Notifications) normalizeRule(rule service.NotificationRule) (service.NotificationRule, *errorx.AppError) {
+	rule, appErr := service.NormalizeNotificationRule(rule)
+	if appErr != nil {
+		return rule, appErr
+	}
+	for _, id := range rule.ChannelIDs {
+		row, err := repo.GetNotificationChannel(h.DB, id)
+		if err != nil {
+			return rule, errorx.New(errorx.DBError, "load notification channel")
+		}
+		if row == nil {
+			return rule, errorx.New(errorx.NOTIFYChannelNotFound, "notification channel not found").WithDetails(map[string]any{"id": id})
+		}
+	}
+	return rule, nil
+}
+
+func notificationChannelRowToDTO(r repo.NotificationChannelRow) dto.NotificationChannel {
+	config := json.RawMessage(r.ConfigJSON)
+	if !json.Valid(config) {
+		config = json.RawMessage("{}")
+	}
+	return dto.NotificationChannel{
+		ID:        r.ID,
+		Name:      r.Name,
+		Type:      r.Type,
+		Enabled:   r.Enabled == 1,
+		Config:    config,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+func notificationRuleRowToDTO(r repo.NotificationRuleRow) dto.NotificationRule {
+	rule := service.NotificationRuleFromRow(r)
+	ids := rule.ChannelIDs
+	if ids == nil {
+		ids = []string{}
+	}
+	return dto.NotificationRule{
+		ID:          r.ID,
+		Name:        r.Name,
+		Kind:        r.Kind,
+		Enabled:     r.Enabled == 1,
+		Threshold:   r.Threshold,
+		CooldownSec: r.CooldownSec,
+		ChannelIDs:  ids,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"math"
 	"net"
 	"net/http"
@@ -128,8 +127,14 @@ func (h *Runtime) Status(c *gin.Context) {
 }
 
 func (h *Runtime) Traffic(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.RuntimeTrafficResponse{Data: sampleRuntimeTraffic(c.Request.Context())})
+}
+
+// sampleRuntimeTraffic reads the current rates from the Clash API and keeps
+// the running totals in trafficSnapshot up to date.
+func sampleRuntimeTraffic(ctx context.Context) dto.RuntimeTrafficData {
 	now := time.Now().UTC()
-	sample, err := fetchProxyTraffic(c.Request.Context())
+	sample, err := fetchProxyTraffic(ctx)
 	source := sample.source
 	if source == "" {
 		source = "singbox_clash_api_unavailable"
@@ -164,209 +169,14 @@ func (h *Runtime) Traffic(c *gin.Context) {
 	}
 	trafficSnapshot.mu.Unlock()
 
-	c.JSON(http.StatusOK, dto.RuntimeTrafficResponse{
-		Data: dto.RuntimeTrafficData{
-			SampledAt:    now.Format(time.RFC3339),
-			Source:       source,
-			RXRateBps:    rxRate,
-			TXRateBps:    txRate,
-			RXTotalBytes: clampUint64ToInt64(rxTotal),
-			TXTotalBytes: clampUint64ToInt64(txTotal),
-		},
-	})
-}
-
-func (h *Runtime) Connections(c *gin.Context) {
-	nodes, err := repo.ListEnabledForwardingNodes(h.DB)
-	if err != nil {
-		writeError(c, errorx.New(errorx.DBError, "list runtime connections"))
-		return
-	}
-
-	items := make([]dto.RuntimeConnection, 0, len(nodes))
-	for _, n := range nodes {
-		target := parseTargetFromOutbound(n.OutboundJSON)
-		status := "selected"
-		if n.LastTestStatus.Valid && n.LastTestStatus.String != "" {
-			status = strings.ToLower(n.LastTestStatus.String)
-		}
-
-		var lastTestAt *string
-		if n.LastTestAt.Valid {
-			v := n.LastTestAt.String
-			lastTestAt = &v
-		}
-
-		var latency *int64
-		if n.LastLatencyMs.Valid {
-			v := n.LastLatencyMs.Int64
-			latency = &v
-		}
-
-		var errMsg *string
-		if n.LastTestError.Valid && n.LastTestError.String != "" {
-			v := n.LastTestError.String
-			errMsg = &v
-		}
-
-		lastUpdated := n.CreatedAt
-		if lastTestAt != nil {
-			lastUpdated = *lastTestAt
-		}
-
-		items = append(items, dto.RuntimeConnection{
-			ID:          n.ID,
-			NodeID:      n.ID,
-			NodeName:    fallbackNodeName(n.Name, n.Tag),
-			NodeType:    n.Type,
-			Target:      target,
-			Status:      status,
-			LastTestAt:  lastTestAt,
-			LatencyMs:   latency,
-			Error:       errMsg,
-			Forwarding:  n.ForwardingEnabled == 1,
-			LastUpdated: lastUpdated,
-		})
-	}
-
-	sort.SliceStable(items, func(i, j int) bool {
-		return items[i].LastUpdated > items[j].LastUpdated
-	})
-
-	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
-	if q != "" {
-		filtered := make([]dto.RuntimeConnection, 0, len(items))
-		for _, item := range items {
-			if strings.Contains(strings.ToLower(item.NodeName), q) ||
-				strings.Contains(strings.ToLower(item.NodeType), q) ||
-				strings.Contains(strings.ToLower(item.Target), q) ||
-				strings.Contains(strings.ToLower(item.Status), q) {
-				filtered = append(filtered, item)
-			}
-		}
-		items = filtered
-	}
-
-	c.JSON(http.StatusOK, dto.RuntimeConnectionsResponse{
-		Data: dto.RuntimeConnectionsData{
-			ActiveCount: len(items),
-			Items:       items,
-		},
-	})
-}
-
-func (h *Runtime) Logs(c *gin.Context) {
-	level := strings.ToLower(strings.TrimSpace(c.DefaultQuery("level", "all")))
-	keyword := strings.ToLower(strings.TrimSpace(c.Query("q")))
-	limit := parseLimit(c.DefaultQuery("limit", "80"), 80, 1, 500)
-
-	items := make([]dto.RuntimeLogItem, 0, 128)
-	now := time.Now().UTC().Format(time.RFC3339)
-
-	row, err := repo.GetRuntimeState(h.DB)
-	if err != nil {
-		writeError(c, errorx.New(errorx.DBError, "get runtime state"))
-		return
-	}
-	if row != nil {
-		if row.LastReloadAt.Valid {
-			items = append(items, dto.RuntimeLogItem{
-				Timestamp: row.LastReloadAt.String,
-				Level:     "info",
-				Source:    "runtime",
-				Message:   "runtime config applied",
-			})
-		}
-		if row.ForwardingRunning == 1 {
-			items = append(items, dto.RuntimeLogItem{
-				Timestamp: now,
-				Level:     "info",
-				Source:    "runtime",
-				Message:   "forwarding runtime running",
-			})
-		} else {
-			items = append(items, dto.RuntimeLogItem{
-				Timestamp: now,
-				Level:     "warn",
-				Source:    "runtime",
-				Message:   "forwarding runtime stopped",
-			})
-		}
-		if row.LastReloadError.Valid && strings.TrimSpace(row.LastReloadError.String) != "" {
-			items = append(items, dto.RuntimeLogItem{
-				Timestamp: fallbackTimestamp(row.LastReloadAt, now),
-				Level:     "error",
-				Source:    "runtime",
-				Message:   row.LastReloadError.String,
-			})
-		}
-	}
-
-	nodes, err := repo.ListEnabledForwardingNodes(h.DB)
-	if err != nil {
-		writeError(c, errorx.New(errorx.DBError, "list nodes for logs"))
-		return
-	}
-	for _, n := range nodes {
-		timestamp := n.CreatedAt
-		if n.LastTestAt.Valid {
-			timestamp = n.LastTestAt.String
-		}
-		name := fallbackNodeName(n.Name, n.Tag)
-		if n.LastTestError.Valid && strings.TrimSpace(n.LastTestError.String) != "" {
-			items = append(items, dto.RuntimeLogItem{
-				Timestamp: timestamp,
-				Level:     "error",
-				Source:    "probe",
-				Message:   name + " probe failed: " + n.LastTestError.String,
-			})
-			continue
-		}
-		if n.LastTestStatus.Valid && n.LastTestStatus.String != "" {
-			testLevel := "info"
-			status := strings.ToLower(n.LastTestStatus.String)
-			if status != "ok" {
-				testLevel = "warn"
-			}
-			msg := name + " probe status: " + strings.ToUpper(status)
-			if n.LastLatencyMs.Valid {
-				msg += " (" + strconv.FormatInt(n.LastLatencyMs.Int64, 10) + "ms)"
-			}
-			items = append(items, dto.RuntimeLogItem{
-				Timestamp: timestamp,
-				Level:     testLevel,
-				Source:    "probe",
-				Message:   msg,
-			})
-		}
-	}
-
-	sort.SliceStable(items, func(i, j int) bool {
-		return items[i].Timestamp > items[j].Timestamp
-	})
-
-	filtered := make([]dto.RuntimeLogItem, 0, len(items))
-	for _, item := range items {
-		if level != "all" && item.Level != level {
-			continue
-		}
-		if keyword != "" {
-			hay := strings.ToLower(item.Source + " " + item.Message)
-			if !strings.Contains(hay, keyword) {
-				continue
-			}
-		}
-		filtered = append(filtered, item)
-		if len(filtered) >= limit {
-			break
-		}
+	return dto.RuntimeTrafficData{
+		SampledAt:    now.Format(time.RFC3339),
+		Source:       source,
+		RXRateBps:    rxRate,
+		TXRateBps:    txRate,
+		RXTotalBytes: clampUint64ToInt64(rxTotal),
+		TXTotalBytes: clampUint64ToInt64(txTotal),
 	}
-
-	c.JSON(http.StatusOK, dto.RuntimeLogsResponse{
-		Data: dto.RuntimeLogsData{
-			Items: filtered,
-		},
-	})
 }
 
 func (h *Runtime) Plan(c *gin.Context) {
@@ -398,7 +208,7 @@ func (h *Runtime) Plan(c *gin.Context) {
 }
 
 func (h *Runtime) Groups(c *gin.Context) {
-	cfg, _, err := h.buildRuntimeConfig(false, false, false)
+	cfg, _, businessInfo, err := h.buildRuntimeConfigInfo(false, false, false)
 	if err != nil {
 		if appErr, ok := err.(*errorx.AppError); ok {
 			writeError(c, appErr)
@@ -422,6 +232,7 @@ func (h *Runtime) Groups(c *gin.Context) {
 		writeError(c, errorx.New(errorx.CFGJSONInvalid, "parse runtime groups"))
 		return
 	}
+	attachBusinessGroupInfo(groups, businessInfo)
 	c.JSON(http.StatusOK, dto.RuntimeGroupSummaryResponse{
 		Data: dto.RuntimeGroupSummaryData{
 			Items: groups,
@@ -509,6 +320,15 @@ func (h *Runtime) SelectGroup(c *gin.Context) {
 		writeError(c, errorx.New(errorx.RTRestartFailed, reloadErr.Error()).WithDetails(details))
 		return
 	}
+	previous := ""
+	if hadPrevSelection {
+		previous = prevSelection.SelectedOutbound
+	}
+	service.PublishEvent(service.EventGroupSelectionChanged, map[string]any{
+		"group_tag":         groupTag,
+		"selected_outbound": selected,
+		"previous_outbound": previous,
+	})
 	policy, policyErr := service.LoadForwardingPolicy(h.DB)
 	probeTimeout := autoProbeTimeoutMS
 	if policyErr == nil && policy.NodeTestTimeoutMs > 0 {
@@ -537,7 +357,6 @@ func (h *Runtime) SelectGroup(c *gin.Context) {
 }
 
 func (h *Runtime) Reload(c *gin.Context) {
-	log.Printf("[handlers.Runtime] Reload requested")
 	configPath := service.ResolveConfigPath()
 	v, hsh, out, err := service.Reload(c.Request.Context(), h.DB, configPath)
 	if err != nil {
@@ -648,15 +467,21 @@ func runtimeProxyRowsToInbounds(httpRow, socksRow repo.ProxySettingsRow) (genera
 }
 
 func (h *Runtime) buildRuntimeConfig(includeDisabledNodes bool, applyForwardingPolicy bool, requireForwardingNodes bool) ([]byte, []string, error) {
+	cfg, tags, _, err := h.buildRuntimeConfigInfo(includeDisabledNodes, applyForwardingPolicy, requireForwardingNodes)
+	return cfg, tags, err
+}
+
+// buildRuntimeConfigInfo also returns the rendered business selectors keyed by tag.
+func (h *Runtime) buildRuntimeConfigInfo(includeDisabledNodes bool, applyForwardingPolicy bool, requireForwardingNodes bool) ([]byte, []string, map[string]generator.BusinessGroupInfo, error) {
 	settings, err := repo.GetProxySettings(h.DB)
 	if err != nil {
-		return nil, nil, errorx.New(errorx.DBError, "get proxy settings")
+		return nil, nil, nil, errorx.New(errorx.DBError, "get proxy settings")
 	}
 	httpProxy, socksProxy := runtimeProxyRowsToInbounds(settings["http"], settings["socks"])
 
 	row, err := repo.GetRuntimeState(h.DB)
 	if err != nil {
-		return nil, nil, errorx.New(errorx.DBError, "get runtime state")
+		return nil, nil, nil, errorx.New(errorx.DBError, "get runtime state")
 	}
 	forwardingRunning := row != nil && row.ForwardingRunning == 1
 	if !forwardingRunning {
@@ -671,23 +496,29 @@ func (h *Runtime) buildRuntimeConfig(includeDisabledNodes bool, applyForwardingP
 		nodes, err = repo.ListEnabledForwardingNodes(h.DB)
 	}
 	if err != nil {
-		return nil, nil, errorx.New(errorx.DBError, "list nodes for runtime config")
+		return nil, nil, nil, errorx.New(errorx.DBError, "list nodes for runtime config")
 	}
 	policy, policyErr := service.LoadForwardingPolicy(h.DB)
 	if policyErr != nil {
-		return nil, nil, errorx.New(errorx.DBError, "get forwarding policy")
+		return nil, nil, nil, errorx.New(errorx.DBError, "get forwarding policy")
 	}
 	if !includeDisabledNodes && applyForwardingPolicy {
 		nodes = service.FilterForwardingNodes(nodes, policy)
 	}
+	var replaced map[string]string
+	if !includeDisabledNodes {
+		if nodes, replaced, err = service.CanonicalForwardingNodes(h.DB, nodes); err != nil {
+			return nil, nil, nil, errorx.New(errorx.DBError, "list node dedup preferences")
+		}
+	}
 
 	if requireForwardingNodes && forwardingRunning && (httpProxy.Enabled || socksProxy.Enabled) && len(nodes) == 0 {
-		return nil, nil, errorx.New(errorx.CFGNoEnabledNodes, "no forwarding nodes enabled")
+		return nil, nil, nil, errorx.New(errorx.CFGNoEnabledNodes, "no forwarding nodes enabled")
 	}
 
 	routing, _, err := service.LoadRoutingSettings(h.DB)
 	if err != nil {
-		return nil, nil, errorx.New(errorx.DBError, "get routing settings")
+		return nil, nil, nil, errorx.New(errorx.DBError, "get routing settings")
 	}
 
 	outbounds := make([]generator.NodeOutbound, 0, len(nodes))
@@ -702,19 +533,19 @@ func (h *Runtime) buildRuntimeConfig(includeDisabledNodes bool, applyForwardingP
 
 	ruleSetRows, err := repo.ListEnabledSubscriptionRuleSets(h.DB)
 	if err != nil {
-		return nil, nil, errorx.New(errorx.DBError, "list subscription rule sets")
+		return nil, nil, nil, errorx.New(errorx.DBError, "list subscription rule sets")
 	}
 	ruleRows, err := repo.ListEnabledSubscriptionRules(h.DB)
 	if err != nil {
-		return nil, nil, errorx.New(errorx.DBError, "list subscription rules")
+		return nil, nil, nil, errorx.New(errorx.DBError, "list subscription rules")
 	}
 	groupMemberRows, err := repo.ListEnabledSubscriptionGroupMembers(h.DB)
 	if err != nil {
-		return nil, nil, errorx.New(errorx.DBError, "list subscription group members")
+		return nil, nil, nil, errorx.New(errorx.DBError, "list subscription group members")
 	}
 	selectionRows, err := repo.ListRuntimeGroupSelections(h.DB)
 	if err != nil {
-		return nil, nil, errorx.New(errorx.DBError, "list runtime group selections")
+		return nil, nil, nil, errorx.New(errorx.DBError, "list runtime group selections")
 	}
 
 	extras := generator.RoutingExtras{
@@ -751,15 +582,54 @@ func (h *Runtime) buildRuntimeConfig(includeDisabledNodes bool, applyForwardingP
 		}
 		extras.BusinessNodePools[target] = append(extras.BusinessNodePools[target], tag)
 	}
+	for target, pool := range extras.BusinessNodePools {
+		extras.BusinessNodePools[target] = service.RemapNodeTags(pool, replaced)
+	}
 	for _, s := range selectionRows {
-		extras.GroupSelections[s.GroupTag] = s.SelectedOutbound
+		selected := s.SelectedOutbound
+		if canonical, ok := replaced[selected]; ok {
+			selected = canonical
+		}
+		extras.GroupSelections[s.GroupTag] = selected
+	}
+	businessGroupRows, err := repo.ListEnabledSubscriptionBusinessGroups(h.DB)
+	if err != nil {
+		return nil, nil, nil, errorx.New(errorx.DBError, "list subscription business groups")
+	}
+	extras.BusinessGroupPolicies = service.BusinessGroupPolicies(businessGroupRows)
+	extras.FetchProxy, err = service.LoadFetchProxy(h.DB, tags)
+	if err != nil {
+		return nil, nil, nil, errorx.New(errorx.DBError, "list subscription fetch options")
 	}
 
-	cfg, err := generator.BuildConfigWithRuntime(httpProxy, socksProxy, routing, outbounds, extras)
+	cfg, businessInfo, err := generator.BuildConfigWithRuntimeInfo(httpProxy, socksProxy, routing, outbounds, extras)
 	if err != nil {
-		return nil, nil, errorx.New(errorx.CFGBuildFailed, "build runtime config")
+		return nil, nil, nil, errorx.New(errorx.CFGBuildFailed, "build runtime config")
+	}
+	return cfg, tags, businessInfo, nil
+}
+
+// attachBusinessGroupInfo labels business selectors with their subscription target
+// and, for url-test / fallback / load-balance sources, the original group policy.
+func attachBusinessGroupInfo(groups []dto.RuntimeGroupItem, info map[string]generator.BusinessGroupInfo) {
+	for i := range groups {
+		biz, ok := info[groups[i].Tag]
+		if !ok {
+			continue
+		}
+		groups[i].DisplayName = biz.Target
+		if biz.Policy.Type == "" {
+			continue
+		}
+		groups[i].Policy = &dto.RuntimeGroupPolicy{
+			Type:        biz.Policy.Type,
+			URL:         biz.Policy.URL,
+			Interval:    biz.Policy.Interval,
+			Tolerance:   biz.Policy.Tolerance,
+			Strategy:    biz.Policy.Strategy,
+			Unsupported: biz.Unsupported,
+		}
 	}
-	return cfg, tags, nil
 }
 
 func parseSelectorGroups(cfg []byte, persisted map[string]repo.RuntimeGroupSelectionRow, clashState *clashProxyState) ([]dto.RuntimeGroupItem, error) {
@@ -1297,17 +1167,7 @@ func summarizeAutoProbeError(err error) string {
 }
 
 func resolveClashAPIBaseURL() (string, bool) {
-	controller := strings.TrimSpace(os.Getenv("SINGBOX_CLASH_API_ADDR"))
-	if controller == "" {
-		controller = "127.0.0.1:9090"
-	}
-	if strings.EqualFold(controller, "off") {
-		return "", false
-	}
-	if !strings.HasPrefix(controller, "http://") && !strings.HasPrefix(controller, "https://") {
-		controller = "http://" + controller
-	}
-	return strings.TrimRight(controller, "/"), true
+	return service.ResolveClashAPIBaseURL()
 }
 
 func pickInt64FromMap(payload map[string]any, keys ...string) (int64, bool) {
@@ -1380,38 +1240,6 @@ func clampUint64ToInt64(v uint64) int64 {
 	return int64(v)
 }
 
-func parseTargetFromOutbound(raw string) string {
-	var out map[string]any
-	if err := json.Unmarshal([]byte(raw), &out); err != nil {
-		return "-"
-	}
-	server, _ := out["server"].(string)
-	port := extractPort(out["server_port"])
-	if strings.TrimSpace(server) == "" {
-		return "-"
-	}
-	if port > 0 {
-		return server + ":" + strconv.Itoa(port)
-	}
-	return server
-}
-
-func extractPort(v any) int {
-	switch value := v.(type) {
-	case float64:
-		return int(value)
-	case int:
-		return value
-	case int64:
-		return int(value)
-	case string:
-		p, _ := strconv.Atoi(value)
-		return p
-	default:
-		return 0
-	}
-}
-
 func fallbackNodeName(name, tag string) string {
 	if strings.TrimSpace(name) != "" {
 		return name
@@ -1419,13 +1247,6 @@ func fallbackNodeName(name, tag string) string {
 	return tag
 }
 
-func fallbackTimestamp(ts sql.NullString, fallback string) string {
-	if ts.Valid && ts.String != "" {
-		return ts.String
-	}
-	return fallback
-}
-
 func parseLimit(raw string, defVal, minVal, maxVal int) int {
 	n, err := strconv.Atoi(raw)
 	if err != nil {
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Connections lists live sing-box connections from the Clash API, newest
+// first, filtered by outbound, group, rule and free-text q. When the Clash API
+// is off or unreachable the list is empty and source says why.
+func (h *Runtime) Connections(c *gin.Context) {
+	filter := service.ClashConnectionFilter{
+		Outbound: c.Query("outbound"),
+		Group:    c.Query("group"),
+		Rule:     c.Query("rule"),
+		Query:    c.Query("q"),
+	}
+	data := dto.RuntimeConnectionsData{Source: "singbox_clash_api", Items: []dto.RuntimeConnection{}}
+	snapshot, err := service.FetchClashConnections(c.Request.Context())
+	if err != nil {
+		data.Source = "singbox_clash_api_unavailable"
+		if errors.Is(err, service.ErrClashAPIDisabled) {
+			data.Source = "singbox_clash_api_disabled"
+		} else {
+			data.Error = err.Error()
+		}
+		c.JSON(http.StatusOK, dto.RuntimeConnectionsResponse{Data: data})
+		return
+	}
+
+	nodes, err := repo.ListEnabledForwardingNodes(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list nodes for connections"))
+		return
+	}
+	nodeByTag := make(map[string]repo.NodeRow, len(nodes))
+	for _, n := range nodes {
+		nodeByTag[n.Tag] = n
+	}
+
+	now := time.Now()
+	data.ActiveCount = len(snapshot.Connections)
+	data.UploadTotal = snapshot.UploadTotal
+	data.DownloadTotal = snapshot.DownloadTotal
+	for _, conn := range snapshot.Connections {
+		if !filter.Matches(conn) {
+			continue
+		}
+		item := runtimeConnectionItem(conn, now)
+		if n, ok := nodeByTag[item.Outbound]; ok {
+			item.NodeID = n.ID
+			item.NodeName = fallbackNodeName(n.Name, n.Tag)
+		}
+		data.Items = append(data.Items, item)
+	}
+	sort.SliceStable(data.Items, func(i, j int) bool {
+		return data.Items[i].StartedAt > data.Items[j].StartedAt
+	})
+	data.Matched = len(data.Items)
+	c.JSON(http.StatusOK, dto.RuntimeConnectionsResponse{Data: data})
+}
+
+// CloseConnection closes one live connection by id, or all of them.
+func (h *Runtime) CloseConnection(c *gin.Context) {
+	var req dto.RuntimeConnectionCloseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	req.ID = strings.TrimSpace(req.ID)
+	if req.ID == "" && !req.All {
+		writeError(c, errorx.New(errorx.REQMissingField, "id or all is required"))
+		return
+	}
+
+	snapshot, err := service.FetchClashConnections(c.Request.Context())
+	if err != nil {
+		writeError(c, clashAPIError(err))
+		return
+	}
+	closed := len(snapshot.Connections)
+	if !req.All {
+		closed = 0
+		for _, conn := range snapshot.Connections {
+			if conn.ID == req.ID {
+				closed = 1
+				break
+			}
+		}
+		if closed == 0 {
+			writeError(c, errorx.New(errorx.RTConnectionNotFound, "connection not found").WithDetails(map[string]any{"id": req.ID}))
+			return
+		}
+	} else {
+		req.ID = ""
+	}
+	if err := service.CloseClashConnection(c.Request.Context(), req.ID); err != nil {
+		writeError(c, clashAPIError(err))
+		return
+	}
+	c.JSON(http.StatusOK, dto.RuntimeConnectionCloseResponse{Data: dto.RuntimeConnectionCloseData{Closed: closed}})
+}
+
+func clashAPIError(err error) *errorx.AppError {
+	if errors.Is(err, service.ErrClashAPIDisabled) {
+		return errorx.New(errorx.RTClashAPIUnavailable, "clash api is disabled").WithDetails(map[string]any{
+			"env": "SINGBOX_CLASH_API_ADDR",
+		})
+	}
+	return errorx.New(errorx.RTClashAPIUnavailable, "clash api request failed").WithDetails(map[string]any{
+		"error": err.Error(),
+	})
+}
+
+func runtimeConnectionItem(conn service.ClashConnection, now time.Time) dto.RuntimeConnection {
+	dest := conn.Host
+	if dest == "" {
+		dest = conn.DestinationIP
+	}
+	item := dto.RuntimeConnection{
+		ID:            conn.ID,
+		Network:       conn.Network,
+		Inbound:       conn.Inbound,
+		Source:        net.JoinHostPort(conn.SourceIP, conn.SourcePort),
+		Destination:   net.JoinHostPort(dest, conn.DestinationPort),
+		Host:          conn.Host,
+		DestinationIP: conn.DestinationIP,
+		Process:       conn.Process,
+		Rule:          conn.Rule,
+		RulePayload:   conn.RulePayload,
+		Outbound:      conn.Outbound(),
+		Chain:         conn.Chain,
+		UploadBytes:   conn.Upload,
+		DownloadBytes: conn.Download,
+	}
+	if item.Chain == nil {
+		item.Chain = []string{}
+	}
+	if !conn.Start.IsZero() {
+		item.StartedAt = conn.Start.UTC().Format(time.RFC3339)
+		item.DurationMs = now.Sub(conn.Start).Milliseconds()
+	}
+	return item
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeClashController serves /connections with two connections and records
+// DELETE requests.
+func fakeClashController(t *testing.T) *[]string {
+	t.Helper()
+	var mu sync.Mutex
+	deleted := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/connections":
+			_, _ = w.Write([]byte(`{"uploadTotal": 10, "downloadTotal": 20, "connections": [
+			  {"id": "c1", "metadata": {"network": "tcp", "type": "mixed/mixed-in", "sourceIP": "10.0.0.2", "sourcePort": "5000",
+			    "destinationIP": "1.1.1.1", "destinationPort": "443", "host": "one.one.one.one"},
+			   "upload": 1, "download": 2, "start": "2024-05-01T10:00:00Z", "chains": ["hk-1", "proxy"], "rule": "final"}]}`))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/connections"):
+			mu.Lock()
+			deleted = append(deleted, r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("SINGBOX_CLASH_API_ADDR", srv.URL)
+	return &deleted
+}
+
+func postCloseConnection(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/runtime/connections/close", (&Runtime{}).CloseConnection)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/runtime/connections/close", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCloseConnection(t *testing.T) {
+	deleted := fakeClashController(t)
+
+	w := postCloseConnection(t, `{"id": "c1"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"closed":1`) {
+		t.Fatalf("unexpected close response %d %s", w.Code, w.Body.String())
+	}
+	w = postCloseConnection(t, `{"id": "missing"}`)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "RT_CONNECTION_NOT_FOUND") {
+		t.Fatalf("expected not found, got %d %s", w.Code, w.Body.String())
+	}
+	w = postCloseConnection(t, `{}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected missing field, got %d %s", w.Code, w.Body.String())
+	}
+	w = postCloseConnection(t, `{"all": true}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"closed":1`) {
+		t.Fatalf("unexpected close-all response %d %s", w.Code, w.Body.String())
+	}
+	if len(*deleted) != 2 || (*deleted)[0] != "/connections/c1" || (*deleted)[1] != "/connections" {
+		t.Fatalf("unexpected deletes: %v", *deleted)
+	}
+}
+
+func TestCloseConnectionClashAPIDisabled(t *testing.T) {
+	t.Setenv("SINGBOX_CLASH_API_ADDR", "off")
+	w := postCloseConnection(t, `{"id": "c1"}`)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "RT_CLASH_API_UNAVAILABLE") {
+		t.Fatalf("expected unavailable, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestConnectionsReportsDisabledClashAPI(t *testing.T) {
+	t.Setenv("SINGBOX_CLASH_API_ADDR", "off")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/runtime/connections", (&Runtime{}).Connections)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runtime/connections", nil))
+	var body dto.RuntimeConnectionsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || body.Data.Source != "singbox_clash_api_disabled" || body.Data.Items == nil {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRuntimeConnectionItem(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	item := runtimeConnectionItem(service.ClashConnection{
+		ID: "c1", Network: "udp", Inbound: "socks-in", SourceIP: "fd00::2", SourcePort: "5000",
+		DestinationIP: "223.5.5.5", DestinationPort: "53", Rule: "final", Chain: []string{"proxy", "hk-1"},
+		Upload: 3, Download: 4, Start: start,
+	}, start.Add(1500*time.Millisecond))
+	if item.Source != "[fd00::2]:5000" || item.Destination != "223.5.5.5:53" || item.Outbound != "hk-1" {
+		t.Fatalf("unexpected addresses: %+v", item)
+	}
+	if item.StartedAt != "2024-05-01T10:00:00Z" || item.DurationMs != 1500 || item.UploadBytes != 3 || item.DownloadBytes != 4 {
+		t.Fatalf("unexpected counters: %+v", item)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	runtimeLogStreamQueue = 512
+	runtimeLogStreamEvent = "log"
+)
+
+// Logs returns buffered sing-box log lines, newest first, filtered by level
+// (all by default), keyword q and limit.
+func (h *Runtime) Logs(c *gin.Context) {
+	q := parseRuntimeLogQuery(c)
+	q.Limit = parseLimit(c.DefaultQuery("limit", "80"), 80, 1, 500)
+	entries := service.RuntimeLogs.Query(q)
+
+	items := make([]dto.RuntimeLogItem, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, runtimeLogItem(e))
+	}
+	c.JSON(http.StatusOK, dto.RuntimeLogsResponse{
+		Data: dto.RuntimeLogsData{
+			Items:     items,
+			LastSeq:   service.RuntimeLogs.LastSeq(),
+			Counts:    service.RuntimeLogs.Counts(),
+			Collector: runtimeLogCollector(service.CurrentRuntimeLogStatus()),
+		},
+	})
+}
+
+// LogsStream serves GET /runtime/logs/stream as text/event-stream. It first
+// replays the newest tail lines, or the lines after Last-Event-ID / since when
+// resuming, oldest first, then sends new lines as "log" events whose id is the
+// line's seq. Lines dropped for a slow client show up as gaps in seq.
+func (h *Runtime) LogsStream(c *gin.Context) {
+	q := parseRuntimeLogQuery(c)
+	since := int64(0)
+	raw := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if raw == "" {
+		raw = strings.TrimSpace(c.Query("since"))
+	}
+	if raw != "" {
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil && n > 0 {
+			since = n
+		}
+	}
+
+	entries, unsubscribe := service.RuntimeLogs.Subscribe(runtimeLogStreamQueue)
+	defer unsubscribe()
+
+	var backlog []service.RuntimeLogEntry
+	// A seq beyond the newest line comes from before a restart; replay instead.
+	if since > 0 && since <= service.RuntimeLogs.LastSeq() {
+		q.AfterSeq = since
+		q.Limit = 500
+		backlog = service.RuntimeLogs.Query(q)
+	} else {
+		q.Limit = parseLimit(c.DefaultQuery("tail", "50"), 50, 0, 500)
+		if q.Limit > 0 {
+			backlog = service.RuntimeLogs.Query(q)
+		}
+	}
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	w.Flush()
+
+	last := since
+	for i := len(backlog) - 1; i >= 0; i-- {
+		if err := writeSSELog(w, backlog[i]); err != nil {
+			return
+		}
+		last = backlog[i].Seq
+	}
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-entries:
+			if !ok {
+				return
+			}
+			// Lines appended while the backlog was read arrive here too.
+			if e.Seq <= last || !q.Matches(e) {
+				continue
+			}
+			if err := writeSSELog(w, e); err != nil {
+				return
+			}
+			last = e.Seq
+		case now := <-heartbeat.C:
+			if err := writeSSEEvent(w, service.Event{Type: streamEventHeartbeat, At: now.UTC()}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func parseRuntimeLogQuery(c *gin.Context) service.RuntimeLogQuery {
+	return service.RuntimeLogQuery{
+		Level:   strings.ToLower(strings.TrimSpace(c.DefaultQuery("level", "all"))),
+		Keyword: strings.TrimSpace(c.Query("q")),
+	}
+}
+
+func writeSSELog(w gin.ResponseWriter, e service.RuntimeLogEntry) error {
+	body, err := json.Marshal(runtimeLogItem(e))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, runtimeLogStreamEvent, body); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+func runtimeLogItem(e service.RuntimeLogEntry) dto.RuntimeLogItem {
+	return dto.RuntimeLogItem{
+		Seq:       e.Seq,
+		Timestamp: e.Timestamp,
+		Level:     e.Level,
+		Source:    e.Source,
+		Message:   e.Message,
+	}
+}
+
+func runtimeLogCollector(s service.RuntimeLogStatus) dto.RuntimeLogCollector {
+	return dto.RuntimeLogCollector{
+		Source:    s.Source,
+		Target:    s.Target,
+		Connected: s.Connected,
+		LastError: s.LastError,
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func runtimeLogServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := &Runtime{}
+	r.GET("/runtime/logs", h.Logs)
+	r.GET("/runtime/logs/stream", h.LogsStream)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// readSSELog returns the next "log" event, skipping other events.
+func readSSELog(t *testing.T, r *bufio.Reader) (string, dto.RuntimeLogItem) {
+	t.Helper()
+	var id, name, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && name == runtimeLogStreamEvent:
+			var item dto.RuntimeLogItem
+			if err := json.Unmarshal([]byte(data), &item); err != nil {
+				t.Fatalf("decode %q: %v", data, err)
+			}
+			return id, item
+		case line == "":
+			id, name, data = "", "", ""
+		}
+	}
+}
+
+func TestRuntimeLogsServesBufferedLines(t *testing.T) {
+	srv := runtimeLogServer(t)
+	service.RuntimeLogs.Append(service.RuntimeLogEntry{Level: "info", Message: "logs-buffer-test: inbound connection"})
+	service.RuntimeLogs.Append(service.RuntimeLogEntry{Level: "warning", Message: "logs-buffer-test: dial slow"})
+	last := service.RuntimeLogs.Append(service.RuntimeLogEntry{Level: "error", Message: "logs-buffer-test: dial failed"})
+
+	resp, err := http.Get(srv.URL + "/runtime/logs?level=all&q=LOGS-BUFFER-TEST&limit=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body dto.RuntimeLogsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	items := body.Data.Items
+	if len(items) != 2 || items[0].Seq != last.Seq || items[0].Level != "error" || items[1].Level != "warn" {
+		t.Fatalf("expected the two newest matching lines, got %+v", items)
+	}
+	if body.Data.LastSeq < last.Seq || body.Data.Counts["error"] < 1 || body.Data.Collector.Source == "" {
+		t.Fatalf("missing buffer metadata: %+v", body.Data)
+	}
+
+	resp, err = http.Get(srv.URL + "/runtime/logs?level=warning&q=logs-buffer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body = dto.RuntimeLogsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data.Items) != 1 || body.Data.Items[0].Message != "logs-buffer-test: dial slow" {
+		t.Fatalf("expected the warn line only, got %+v", body.Data.Items)
+	}
+}
+
+func TestRuntimeLogsStreamReplaysAndFollows(t *testing.T) {
+	srv := runtimeLogServer(t)
+	first := service.RuntimeLogs.Append(service.RuntimeLogEntry{Level: "error", Message: "logs-stream-test: one"})
+	service.RuntimeLogs.Append(service.RuntimeLogEntry{Level: "info", Message: "logs-stream-test: two"})
+
+	resp, err := http.Get(srv.URL + "/runtime/logs/stream?level=error&q=logs-stream-test&tail=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	id, item := readSSELog(t, r)
+	if id != strconv.FormatInt(first.Seq, 10) || item.Message != "logs-stream-test: one" {
+		t.Fatalf("expected the backlog line, got %s %+v", id, item)
+	}
+
+	service.RuntimeLogs.Append(service.RuntimeLogEntry{Level: "info", Message: "logs-stream-test: filtered"})
+	live := service.RuntimeLogs.Append(service.RuntimeLogEntry{Level: "fatal", Message: "logs-stream-test: three"})
+	id, item = readSSELog(t, r)
+	if id != strconv.FormatInt(live.Seq, 10) || item.Level != "error" || item.Message != "logs-stream-test: three" {
+		t.Fatalf("expected the live error line, got %s %+v", id, item)
+	}
+}
+
+func TestRuntimeLogsStreamResumesAfterLastEventID(t *testing.T) {
+	srv := runtimeLogServer(t)
+	seen := service.RuntimeLogs.Append(service.RuntimeLogEntry{Message: "logs-resume-test: seen"})
+	missed := service.RuntimeLogs.Append(service.RuntimeLogEntry{Message: "logs-resume-test: missed"})
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/runtime/logs/stream?q=logs-resume-test", nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(seen.Seq, 10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	id, item := readSSELog(t, bufio.NewReader(resp.Body))
+	if id != strconv.FormatInt(missed.Seq, 10) || item.Message != "logs-resume-test: missed" {
+		t.Fatalf("expected only the missed line, got %s %+v", id, item)
+	}
+}
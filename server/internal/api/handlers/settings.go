@@ -408,6 +408,13 @@ func (h *Settings) setForwardingRunningAndReload(c *gin.Context, running bool) e
 		_ = repo.SetForwardingRunning(h.DB, prev)
 		return err
 	}
+	if prev != next {
+		event := service.EventForwardingStopped
+		if running {
+			event = service.EventForwardingStarted
+		}
+		service.PublishEvent(event, nil)
+	}
 	return nil
 }
 
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/export"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Share serves BoxPilot's curated forwarding node set as a subscription for
+// downstream clients and manages the tokens that protect it.
+type Share struct {
+	DB *sql.DB
+}
+
+const shareProfileName = "BoxPilot"
+
+// Subscription handles GET /sub/:token. The format comes from ?format= (or the
+// ?target= alias used by subscription converters), falling back to User-Agent.
+func (h *Share) Subscription(c *gin.Context) {
+	row, err := service.ShareTokenByToken(h.DB, c.Param("token"))
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "load share token"))
+		return
+	}
+	if row == nil || row.Enabled != 1 {
+		writeError(c, errorx.New(errorx.SHARETokenNotFound, "share token not found"))
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(c.Query("format")))
+	if format == "" {
+		format = strings.ToLower(strings.TrimSpace(c.Query("target")))
+	}
+	if format == "" {
+		format = export.FormatForUserAgent(c.GetHeader("User-Agent"))
+	}
+	if !export.ValidFormat(format) {
+		writeError(c, errorx.New(errorx.REQInvalidField, "unsupported export format").WithDetails(map[string]any{
+			"format":    format,
+			"supported": []string{export.FormatClash, export.FormatSingbox, export.FormatURI},
+		}))
+		return
+	}
+	share, err := service.BuildShareSubscription(h.DB, format)
+	if err != nil {
+		writeError(c, errorx.New(errorx.SHAREExportFailed, "build shared subscription").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	_ = repo.TouchShareToken(h.DB, row.ID, util.NowRFC3339())
+
+	if share.Userinfo != "" {
+		c.Header("subscription-userinfo", share.Userinfo)
+	}
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(shareProfileName))
+	c.Header("X-Export-Count", strconv.Itoa(share.Result.Count))
+	c.Header("X-Export-Skipped", strconv.Itoa(len(share.Result.Skipped)))
+	c.Data(http.StatusOK, share.Result.ContentType, share.Result.Body)
+}
+
+func (h *Share) ListTokens(c *gin.Context) {
+	rows, err := repo.ListShareTokens(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list share tokens"))
+		return
+	}
+	data := make([]dto.ShareToken, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, shareTokenRowToDTO(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// CreateToken returns the token in full; it cannot be read back later.
+func (h *Share) CreateToken(c *gin.Context) {
+	var req dto.CreateShareTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "name required"))
+		return
+	}
+	row, token, err := service.CreateShareToken(h.DB, name)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "create share token"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": dto.CreatedShareToken{ShareToken: shareTokenRowToDTO(row), Token: token}})
+}
+
+func (h *Share) UpdateToken(c *gin.Context) {
+	var req dto.UpdateShareTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	if req.ID == "" || req.Enabled == nil {
+		writeError(c, errorx.New(errorx.REQMissingField, "id and enabled required"))
+		return
+	}
+	enabled := 0
+	if *req.Enabled {
+		enabled = 1
+	}
+	ok, err := repo.UpdateShareTokenEnabled(h.DB, req.ID, enabled)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "update share token"))
+		return
+	}
+	if !ok {
+		writeError(c, errorx.New(errorx.SHARETokenNotFound, "share token not found").WithDetails(map[string]any{"id": req.ID}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *Share) DeleteToken(c *gin.Context) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	ok, err := repo.DeleteShareToken(h.DB, req.ID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "delete share token"))
+		return
+	}
+	if !ok {
+		writeError(c, errorx.New(errorx.SHARETokenNotFound, "share token not found").WithDetails(map[string]any{"id": req.ID}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func shareTokenRowToDTO(r repo.ShareTokenRow) dto.ShareToken {
+	out := dto.ShareToken{
+		ID:        r.ID,
+		Name:      r.Name,
+		Prefix:    r.Prefix,
+		Enabled:   r.Enabled == 1,
+		CreatedAt: r.CreatedAt,
+	}
+	if r.LastUsedAt.Valid {
+		out.LastUsedAt = &r.LastUsedAt.String
+	}
+	return out
+}
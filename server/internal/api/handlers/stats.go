@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Stats struct {
+	DB *sql.DB
+}
+
+// Traffic serves GET /stats/traffic?by=node|group|inbound&range=24h&key=.
+// Ranges up to 48h read hourly rollups, longer ones daily rollups; key limits
+// the series to one key.
+func (h *Stats) Traffic(c *gin.Context) {
+	by := strings.ToLower(strings.TrimSpace(c.DefaultQuery("by", service.TrafficDimensionNode)))
+	if !containsString(service.TrafficDimensions(), by) {
+		writeError(c, errorx.New(errorx.REQInvalidField, "unsupported traffic dimension").WithDetails(map[string]any{
+			"by":        by,
+			"supported": service.TrafficDimensions(),
+		}))
+		return
+	}
+	rangeRaw := strings.ToLower(strings.TrimSpace(c.DefaultQuery("range", "24h")))
+	span, bucket, appErr := service.ParseTrafficRange(rangeRaw)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	now := time.Now().UTC()
+	from := service.TrafficPeriodStart(bucket, now.Add(-span))
+
+	rows, err := repo.SumTrafficRollups(h.DB, bucket, by, from)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "sum traffic rollups"))
+		return
+	}
+	series, err := repo.ListTrafficRollupSeries(h.DB, bucket, by, strings.TrimSpace(c.Query("key")), from)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list traffic series"))
+		return
+	}
+
+	data := dto.TrafficStatsData{
+		By:     by,
+		Range:  rangeRaw,
+		Bucket: bucket,
+		From:   from,
+		To:     now.Format(time.RFC3339),
+		Items:  make([]dto.TrafficStatsItem, 0, len(rows)),
+		Series: make([]dto.TrafficStatsPoint, 0, len(series)),
+	}
+	for _, r := range rows {
+		data.Items = append(data.Items, dto.TrafficStatsItem{
+			Key:           r.Key,
+			UploadBytes:   r.UploadBytes,
+			DownloadBytes: r.DownloadBytes,
+			TotalBytes:    r.UploadBytes + r.DownloadBytes,
+			Connections:   r.Connections,
+		})
+		data.UploadBytes += r.UploadBytes
+		data.DownloadBytes += r.DownloadBytes
+	}
+	for _, r := range series {
+		data.Series = append(data.Series, dto.TrafficStatsPoint{
+			PeriodStart:   r.PeriodStart,
+			UploadBytes:   r.UploadBytes,
+			DownloadBytes: r.DownloadBytes,
+			Connections:   r.Connections,
+		})
+	}
+	if by == service.TrafficDimensionNode {
+		if err := h.attachNodes(data.Items); err != nil {
+			writeError(c, errorx.New(errorx.DBError, "list nodes for traffic stats"))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, dto.TrafficStatsResponse{Data: data})
+}
+
+// attachNodes names node keys after the BoxPilot node and subscription with
+// that outbound tag.
+func (h *Stats) attachNodes(items []dto.TrafficStatsItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	nodes, err := repo.ListNodes(h.DB, "", nil)
+	if err != nil {
+		return err
+	}
+	subs, err := repo.ListSubscriptions(h.DB, false)
+	if err != nil {
+		return err
+	}
+	subNames := make(map[string]string, len(subs))
+	for _, s := range subs {
+		subNames[s.ID] = s.Name
+	}
+	byTag := make(map[string]repo.NodeRow, len(nodes))
+	for _, n := range nodes {
+		byTag[n.Tag] = n
+	}
+	for i := range items {
+		n, ok := byTag[items[i].Key]
+		if !ok {
+			continue
+		}
+		items[i].NodeID = n.ID
+		items[i].Name = fallbackNodeName(n.Name, n.Tag)
+		items[i].SubID = n.SubID
+		items[i].SubName = subNames[n.SubID]
+	}
+	return nil
+}
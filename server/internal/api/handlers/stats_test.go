@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrafficStatsRejectsBadQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/stats/traffic", (&Stats{}).Traffic)
+	for _, query := range []string{"by=provider", "by=node&range=7w", "range=400d"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats/traffic?"+query, nil))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "REQ_INVALID_FIELD") {
+			t.Fatalf("%s: expected invalid field, got %d %s", query, w.Code, w.Body.String())
+		}
+	}
+}
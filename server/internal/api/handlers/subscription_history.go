@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultRefreshHistoryLimit = 20
+	maxRefreshHistoryLimit     = 500
+)
+
+// History lists the refresh attempts of one subscription, newest first.
+func (h *Subscriptions) History(c *gin.Context) {
+	subID := strings.TrimSpace(c.Param("id"))
+	if appErr := h.ensureSubscription(subID); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	limit := defaultRefreshHistoryLimit
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(c, errorx.New(errorx.REQInvalidField, "limit must be a positive integer").WithDetails(map[string]any{"limit": raw}))
+			return
+		}
+		if n > maxRefreshHistoryLimit {
+			n = maxRefreshHistoryLimit
+		}
+		limit = n
+	}
+	rows, err := repo.ListSubscriptionRefreshHistory(h.DB, subID, limit)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list subscription refresh history").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	data := make([]dto.SubscriptionRefreshHistoryEntry, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, refreshHistoryToDTO(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func refreshHistoryToDTO(r repo.SubscriptionRefreshHistoryRow) dto.SubscriptionRefreshHistoryEntry {
+	d := dto.SubscriptionRefreshHistoryEntry{
+		ID:           r.ID,
+		SubID:        r.SubID,
+		StartedAt:    r.StartedAt,
+		DurationMs:   r.DurationMs,
+		HTTPStatus:   r.HTTPStatus,
+		Bytes:        r.Bytes,
+		Format:       r.Format,
+		Success:      r.Success == 1,
+		NotModified:  r.NotModified == 1,
+		ErrorCode:    r.ErrorCode,
+		Error:        r.Error,
+		NodesTotal:   r.NodesTotal,
+		NodesAdded:   r.NodesAdded,
+		NodesUpdated: r.NodesUpdated,
+		NodesRemoved: r.NodesRemoved,
+	}
+	var diff dto.SubscriptionNodeDiff
+	if err := json.Unmarshal([]byte(r.DiffJSON), &diff); err == nil && diff.Added != nil {
+		d.Diff = &diff
+	}
+	return d
+}
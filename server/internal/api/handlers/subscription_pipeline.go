@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Subscriptions) GetPipeline(c *gin.Context) {
+	subID := strings.TrimSpace(c.Query("sub_id"))
+	if subID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "sub_id required"))
+		return
+	}
+	if appErr := h.ensureSubscription(subID); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	p, err := service.LoadSubscriptionPipeline(h.DB, subID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "load subscription pipeline").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": pipelineToDTO(subID, p)})
+}
+
+func (h *Subscriptions) UpdatePipeline(c *gin.Context) {
+	var req dto.SubscriptionPipeline
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	if req.SubID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "sub_id required"))
+		return
+	}
+	if appErr := h.ensureSubscription(req.SubID); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	p, appErr := service.NormalizeSubscriptionPipeline(pipelineFromDTO(req))
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	p, err := service.SaveSubscriptionPipeline(h.DB, req.SubID, p)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "save subscription pipeline").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": pipelineToDTO(req.SubID, p)})
+}
+
+// PreviewPipeline is a dry run: it applies the pipeline to the last fetched body
+// and reports every node's outcome without touching stored nodes.
+func (h *Subscriptions) PreviewPipeline(c *gin.Context) {
+	var req dto.PreviewSubscriptionPipelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	if req.SubID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "sub_id required"))
+		return
+	}
+	if appErr := h.ensureSubscription(req.SubID); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	var p service.SubscriptionPipeline
+	if req.Pipeline != nil {
+		var appErr *errorx.AppError
+		if p, appErr = service.NormalizeSubscriptionPipeline(pipelineFromDTO(*req.Pipeline)); appErr != nil {
+			writeError(c, appErr)
+			return
+		}
+	} else {
+		var err error
+		if p, err = service.LoadSubscriptionPipeline(h.DB, req.SubID); err != nil {
+			writeError(c, errorx.New(errorx.DBError, "load subscription pipeline").WithDetails(map[string]any{"err": err.Error()}))
+			return
+		}
+	}
+	preview, appErr := service.PreviewSubscriptionPipeline(service.ResolveConfigPath(), req.SubID, p)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	entries := make([]dto.SubscriptionPipelineEntry, 0, len(preview.Entries))
+	for _, e := range preview.Entries {
+		entries = append(entries, dto.SubscriptionPipelineEntry{
+			SourceName: e.SourceName,
+			Name:       e.Name,
+			Type:       e.Type,
+			Dropped:    e.Dropped,
+			Reason:     e.Reason,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": dto.SubscriptionPipelinePreview{
+		SubID:   req.SubID,
+		Format:  preview.Format,
+		Kept:    preview.Kept,
+		Dropped: preview.Dropped,
+		Entries: entries,
+	}})
+}
+
+func (h *Subscriptions) ensureSubscription(subID string) *errorx.AppError {
+	if err := repo.EnsureSubscriptionExists(h.DB, subID); err != nil {
+		if appErr, ok := err.(*errorx.AppError); ok {
+			return appErr
+		}
+		return errorx.New(errorx.DBError, err.Error())
+	}
+	return nil
+}
+
+func pipelineFromDTO(d dto.SubscriptionPipeline) service.SubscriptionPipeline {
+	rules := make([]service.RenameRule, 0, len(d.RenameRules))
+	for _, r := range d.RenameRules {
+		rules = append(rules, service.RenameRule{Pattern: r.Pattern, Replace: r.Replace})
+	}
+	return service.SubscriptionPipeline{
+		IncludeRegex:  d.IncludeRegex,
+		ExcludeRegex:  d.ExcludeRegex,
+		TypeAllowlist: d.TypeAllowlist,
+		RenameRules:   rules,
+		EmojiFlag:     d.EmojiFlag,
+		NameTemplate:  d.NameTemplate,
+	}
+}
+
+func pipelineToDTO(subID string, p service.SubscriptionPipeline) dto.SubscriptionPipeline {
+	rules := make([]dto.SubscriptionRenameRule, 0, len(p.RenameRules))
+	for _, r := range p.RenameRules {
+		rules = append(rules, dto.SubscriptionRenameRule{Pattern: r.Pattern, Replace: r.Replace})
+	}
+	types := p.TypeAllowlist
+	if types == nil {
+		types = []string{}
+	}
+	return dto.SubscriptionPipeline{
+		SubID:         subID,
+		IncludeRegex:  p.IncludeRegex,
+		ExcludeRegex:  p.ExcludeRegex,
+		TypeAllowlist: types,
+		RenameRules:   rules,
+		EmojiFlag:     p.EmojiFlag,
+		NameTemplate:  p.NameTemplate,
+		UpdatedAt:     p.UpdatedAt,
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Schedule reports when each subscription is next auto-refreshed and how many
+// refreshes in a row have failed.
+func (h *Subscriptions) Schedule(c *gin.Context) {
+	list, err := service.ListSubscriptionSchedules(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list subscriptions").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	data := make([]dto.SubscriptionSchedule, 0, len(list))
+	for _, s := range list {
+		d := dto.SubscriptionSchedule{
+			SubID:          s.SubID,
+			Name:           s.Name,
+			SourceType:     s.SourceType,
+			Scheduled:      s.Scheduled,
+			Running:        s.Running,
+			Expired:        s.Expired,
+			IntervalSec:    s.IntervalSec,
+			IntervalSource: s.IntervalSource,
+			JitterSec:      s.JitterSec,
+			FailureStreak:  s.FailureStreak,
+			BackoffSec:     s.BackoffSec,
+		}
+		if s.LastFetchAt != "" {
+			v := s.LastFetchAt
+			d.LastFetchAt = &v
+		}
+		if s.LastError != "" {
+			v := s.LastError
+			d.LastError = &v
+		}
+		if s.NextRunAt != nil {
+			// Never-fetched subscriptions are due now rather than at the zero time.
+			next := *s.NextRunAt
+			if next.IsZero() {
+				next = time.Now().UTC()
+			}
+			v := next.UTC().Format(time.RFC3339)
+			d.NextRunAt = &v
+		}
+		data = append(data, d)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"boxpilot/server/internal/api/dto"
@@ -18,15 +22,41 @@ type Subscriptions struct {
 	DB *sql.DB
 }
 
+// List returns every subscription. Principals below admin scope get the
+// location of URL subscriptions redacted (see redactSubscription), since
+// providers put the account token in it.
 func (h *Subscriptions) List(c *gin.Context) {
+	redact := requireScope(c, service.ScopeAdmin) != nil
 	list, err := repo.ListSubscriptions(h.DB, false)
 	if err != nil {
 		writeError(c, errorx.New(errorx.DBError, "list subscriptions").WithDetails(map[string]any{"err": err.Error()}))
 		return
 	}
+	diagnostics, err := repo.ListSubscriptionParseDiagnostics(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list subscription parse diagnostics").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	fetchOptions, err := repo.ListSubscriptionFetchOptions(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list subscription fetch options").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
 	data := make([]dto.Subscription, 0, len(list))
 	for _, r := range list {
-		data = append(data, subRowToDTO(r))
+		d := subRowToDTO(r)
+		if diag, ok := diagnostics[r.ID]; ok {
+			d.ParseDiagnostics = parseDiagnosticsToDTO(diag)
+		}
+		opts := service.DefaultFetchOptions()
+		if row, ok := fetchOptions[r.ID]; ok {
+			opts = service.FetchOptionsFromRow(row)
+		}
+		d.FetchOptions = fetchOptionsToDTO(opts)
+		if redact {
+			redactSubscription(&d)
+		}
+		data = append(data, d)
 	}
 	c.JSON(http.StatusOK, gin.H{"data": data})
 }
@@ -37,10 +67,16 @@ func (h *Subscriptions) Create(c *gin.Context) {
 		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
 		return
 	}
-	if req.URL == "" {
-		writeError(c, errorx.New(errorx.REQMissingField, "url required").WithDetails(map[string]any{"field": "url"}))
+	sourceType, location, appErr := service.NormalizeSubscriptionSource(req.SourceType, req.URL)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	if sourceType == service.SubscriptionSourceInline && req.Content == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "content required").WithDetails(map[string]any{"field": "content"}))
 		return
 	}
+	req.URL = location
 	if req.Type == "" {
 		req.Type = "singbox"
 	}
@@ -51,23 +87,50 @@ func (h *Subscriptions) Create(c *gin.Context) {
 	if req.AutoUpdateEnabled != nil && *req.AutoUpdateEnabled {
 		autoUpdateEnabled = 1
 	}
+	followProvider := 0
+	if req.FollowProviderInterval != nil && *req.FollowProviderInterval {
+		followProvider = 1
+	}
 	if req.Name == "" {
 		req.Name = req.URL
+		if sourceType == service.SubscriptionSourceInline {
+			req.Name = "Inline"
+		}
+	}
+	opts, appErr := fetchOptionsFromDTO(req.FetchOptions)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
 	}
 	id := util.NewID()
-	if err := repo.CreateSubscription(h.DB, id, req.Name, req.URL, req.Type, 1, autoUpdateEnabled, req.RefreshIntervalSec); err != nil {
+	if err := repo.CreateSubscription(h.DB, id, req.Name, req.URL, req.Type, sourceType, 1, autoUpdateEnabled, req.RefreshIntervalSec, followProvider); err != nil {
 		writeError(c, errorx.New(errorx.DBError, "create subscription"))
 		return
 	}
+	if sourceType == service.SubscriptionSourceInline {
+		if appErr := service.SaveInlineSubscriptionContent(h.DB, id, req.Content); appErr != nil {
+			_, _ = repo.DeleteSubscription(h.DB, id)
+			writeError(c, appErr)
+			return
+		}
+	}
+	if req.FetchOptions != nil {
+		if err := service.SaveFetchOptions(h.DB, id, opts); err != nil {
+			writeError(c, errorx.New(errorx.DBError, "save subscription fetch options").WithDetails(map[string]any{"err": err.Error()}))
+			return
+		}
+	}
 	row, _ := repo.GetSubscription(h.DB, id)
 	if row != nil {
-		c.JSON(http.StatusOK, gin.H{"data": subRowToDTO(*row)})
+		d := subRowToDTO(*row)
+		d.FetchOptions = fetchOptionsToDTO(opts)
+		c.JSON(http.StatusOK, gin.H{"data": d})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": dto.Subscription{
-		ID: id, Name: req.Name, URL: req.URL, Type: req.Type, Enabled: true,
+		ID: id, Name: req.Name, URL: req.URL, Type: req.Type, SourceType: sourceType, Enabled: true,
 		AutoUpdateEnabled: autoUpdateEnabled == 1, RefreshIntervalSec: req.RefreshIntervalSec,
-		CreatedAt: util.NowRFC3339(), UpdatedAt: util.NowRFC3339(),
+		FollowProviderInterval: followProvider == 1, CreatedAt: util.NowRFC3339(), UpdatedAt: util.NowRFC3339(),
 	}})
 }
 
@@ -97,8 +160,17 @@ func (h *Subscriptions) Update(c *gin.Context) {
 	if req.Name != "" {
 		name = &req.Name
 	}
-	if req.URL != "" {
-		subURL = &req.URL
+	if req.URL != "" && before.SourceType != service.SubscriptionSourceInline {
+		_, location, appErr := service.NormalizeSubscriptionSource(before.SourceType, req.URL)
+		if appErr != nil {
+			writeError(c, appErr)
+			return
+		}
+		subURL = &location
+	}
+	if req.Content != nil && before.SourceType != service.SubscriptionSourceInline {
+		writeError(c, errorx.New(errorx.REQInvalidField, "content only applies to inline subscriptions").WithDetails(map[string]any{"field": "content"}))
+		return
 	}
 	if req.Enabled != nil {
 		v := 0
@@ -114,6 +186,14 @@ func (h *Subscriptions) Update(c *gin.Context) {
 		}
 		autoUpdateEnabled = &v
 	}
+	var followProvider *int
+	if req.FollowProviderInterval != nil {
+		v := 0
+		if *req.FollowProviderInterval {
+			v = 1
+		}
+		followProvider = &v
+	}
 	var refresh *int
 	if req.RefreshIntervalSec != nil && *req.RefreshIntervalSec > 0 {
 		if *req.RefreshIntervalSec < 60 {
@@ -122,21 +202,79 @@ func (h *Subscriptions) Update(c *gin.Context) {
 		}
 		refresh = req.RefreshIntervalSec
 	}
-	if err := repo.UpdateSubscription(h.DB, req.ID, name, subURL, enabled, autoUpdateEnabled, refresh); err != nil {
+	var opts service.FetchOptions
+	if req.FetchOptions != nil {
+		var appErr *errorx.AppError
+		if opts, appErr = fetchOptionsFromDTO(req.FetchOptions); appErr != nil {
+			writeError(c, appErr)
+			return
+		}
+	}
+	if err := repo.UpdateSubscription(h.DB, req.ID, name, subURL, enabled, autoUpdateEnabled, refresh, followProvider); err != nil {
 		writeError(c, errorx.New(errorx.DBError, "update subscription"))
 		return
 	}
+	// The fetch-in inbound carries one user per node-routed subscription, so a
+	// change of the fetch node needs the running config to be rebuilt.
+	fetchNodeChanged := false
+	if req.FetchOptions != nil {
+		prev, err := service.LoadFetchOptions(h.DB, req.ID)
+		if err != nil {
+			writeError(c, errorx.New(errorx.DBError, "load subscription fetch options").WithDetails(map[string]any{"err": err.Error()}))
+			return
+		}
+		if err := service.SaveFetchOptions(h.DB, req.ID, opts); err != nil {
+			writeError(c, errorx.New(errorx.DBError, "save subscription fetch options").WithDetails(map[string]any{"err": err.Error()}))
+			return
+		}
+		fetchNodeChanged = (prev.FetchVia == service.FetchViaNode || opts.FetchVia == service.FetchViaNode) &&
+			(prev.FetchVia != opts.FetchVia || prev.FetchViaNodeTag != opts.FetchViaNodeTag)
+	}
+	if fetchNodeChanged {
+		if err := service.ReloadIfForwardingRunning(c.Request.Context(), h.DB); err != nil {
+			if appErr, ok := err.(*errorx.AppError); ok {
+				writeError(c, appErr)
+				return
+			}
+			writeError(c, errorx.New(errorx.RTRestartFailed, "reload after fetch options update failed").WithDetails(map[string]any{
+				"id":  req.ID,
+				"err": err.Error(),
+			}))
+			return
+		}
+	}
 
 	urlChanged := subURL != nil && *subURL != before.URL
-	if urlChanged {
-		if _, _, _, err := service.RefreshSubscription(h.DB, req.ID); err != nil {
-			oldURL := before.URL
-			_ = repo.UpdateSubscription(h.DB, req.ID, nil, &oldURL, nil, nil, nil)
+	contentChanged := false
+	var oldContent string
+	var hadContent bool
+	if req.Content != nil {
+		if oldContent, _, hadContent, err = repo.GetSubscriptionInlineContent(h.DB, req.ID); err != nil {
+			writeError(c, errorx.New(errorx.DBError, "load inline subscription content").WithDetails(map[string]any{"err": err.Error()}))
+			return
+		}
+		if !hadContent || oldContent != *req.Content {
+			if appErr := service.SaveInlineSubscriptionContent(h.DB, req.ID, *req.Content); appErr != nil {
+				writeError(c, appErr)
+				return
+			}
+			contentChanged = true
+		}
+	}
+	if urlChanged || contentChanged {
+		if _, err := service.RefreshSubscription(c.Request.Context(), h.DB, req.ID); err != nil {
+			if urlChanged {
+				oldURL := before.URL
+				_ = repo.UpdateSubscription(h.DB, req.ID, nil, &oldURL, nil, nil, nil, nil)
+			}
+			if contentChanged && hadContent {
+				_ = service.SaveInlineSubscriptionContent(h.DB, req.ID, oldContent)
+			}
 			if appErr, ok := err.(*errorx.AppError); ok {
 				writeError(c, appErr)
 				return
 			}
-			writeError(c, errorx.New(errorx.SUBFetchFailed, "refresh after url update failed").WithDetails(map[string]any{
+			writeError(c, errorx.New(errorx.SUBFetchFailed, "refresh after source update failed").WithDetails(map[string]any{
 				"id":  req.ID,
 				"err": err.Error(),
 			}))
@@ -147,7 +285,7 @@ func (h *Subscriptions) Update(c *gin.Context) {
 				writeError(c, appErr)
 				return
 			}
-			writeError(c, errorx.New(errorx.RTRestartFailed, "reload after subscription url update failed").WithDetails(map[string]any{
+			writeError(c, errorx.New(errorx.RTRestartFailed, "reload after subscription source update failed").WithDetails(map[string]any{
 				"id":  req.ID,
 				"err": err.Error(),
 			}))
@@ -157,7 +295,14 @@ func (h *Subscriptions) Update(c *gin.Context) {
 
 	row, _ := repo.GetSubscription(h.DB, req.ID)
 	if row != nil {
-		c.JSON(http.StatusOK, gin.H{"data": subRowToDTO(*row)})
+		d := subRowToDTO(*row)
+		if diag, _ := repo.GetSubscriptionParseDiagnostics(h.DB, req.ID); diag != nil {
+			d.ParseDiagnostics = parseDiagnosticsToDTO(*diag)
+		}
+		if opts, err := service.LoadFetchOptions(h.DB, req.ID); err == nil {
+			d.FetchOptions = fetchOptionsToDTO(opts)
+		}
+		c.JSON(http.StatusOK, gin.H{"data": d})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": nil})
@@ -180,6 +325,7 @@ func (h *Subscriptions) Delete(c *gin.Context) {
 		writeError(c, errorx.New(errorx.SUBNotFound, "subscription not found").WithDetails(map[string]any{"id": req.ID}))
 		return
 	}
+	_ = os.RemoveAll(service.SubscriptionProviderDir(service.ResolveConfigPath(), req.ID))
 	if err := service.ReloadIfForwardingRunning(c.Request.Context(), h.DB); err != nil {
 		if appErr, ok := err.(*errorx.AppError); ok {
 			writeError(c, appErr)
@@ -191,6 +337,7 @@ func (h *Subscriptions) Delete(c *gin.Context) {
 		}))
 		return
 	}
+	service.PublishEvent(service.EventNodesChanged, map[string]any{"source": "api", "sub_id": req.ID, "subscription_deleted": true})
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
@@ -210,9 +357,17 @@ func (h *Subscriptions) Refresh(c *gin.Context) {
 		writeError(c, errorx.New(errorx.DBError, err.Error()))
 		return
 	}
-	notModified, total, enabled, err := service.RefreshSubscription(h.DB, req.ID)
+	res, err := service.RefreshSubscription(c.Request.Context(), h.DB, req.ID)
 	if err != nil {
 		if appErr, ok := err.(*errorx.AppError); ok {
+			if appErr.Code == errorx.SUBEmptyOutbounds {
+				if diag, _ := repo.GetSubscriptionParseDiagnostics(h.DB, req.ID); diag != nil {
+					if appErr.Details == nil {
+						appErr.Details = map[string]any{}
+					}
+					appErr.Details["parse_diagnostics"] = parseDiagnosticsToDTO(*diag)
+				}
+			}
 			writeError(c, appErr)
 			return
 		}
@@ -222,7 +377,7 @@ func (h *Subscriptions) Refresh(c *gin.Context) {
 		}))
 		return
 	}
-	if !notModified {
+	if !res.NotModified {
 		if err := service.ReloadIfForwardingRunning(c.Request.Context(), h.DB); err != nil {
 			if appErr, ok := err.(*errorx.AppError); ok {
 				writeError(c, appErr)
@@ -235,17 +390,138 @@ func (h *Subscriptions) Refresh(c *gin.Context) {
 			return
 		}
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"sub_id": req.ID, "not_modified": notModified, "nodes_total": total, "nodes_enabled": enabled,
+	resp := gin.H{
+		"sub_id": req.ID, "not_modified": res.NotModified, "nodes_total": res.NodesTotal, "nodes_enabled": res.NodesEnabled,
+		"nodes_added": res.Added, "nodes_updated": res.Updated, "nodes_removed": res.Removed,
 		"fetched_at": util.NowRFC3339(),
+	}
+	if diag, _ := repo.GetSubscriptionParseDiagnostics(h.DB, req.ID); diag != nil {
+		resp["parse_diagnostics"] = parseDiagnosticsToDTO(*diag)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Content returns the stored body of an inline subscription.
+func (h *Subscriptions) Content(c *gin.Context) {
+	subID := c.Query("sub_id")
+	if subID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "sub_id required"))
+		return
+	}
+	row, err := repo.GetSubscription(h.DB, subID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "get subscription").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	if row == nil {
+		writeError(c, errorx.New(errorx.SUBNotFound, "subscription not found").WithDetails(map[string]any{"id": subID}))
+		return
+	}
+	if row.SourceType != service.SubscriptionSourceInline {
+		writeError(c, errorx.New(errorx.REQUnsupportedOperation, "subscription is not inline").WithDetails(map[string]any{"source_type": row.SourceType}))
+		return
+	}
+	content, updatedAt, _, err := repo.GetSubscriptionInlineContent(h.DB, subID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "load inline subscription content").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": dto.SubscriptionInlineContent{SubID: subID, Content: content, UpdatedAt: updatedAt}})
+}
+
+func parseDiagnosticsToDTO(r repo.SubscriptionParseDiagnosticsRow) *dto.SubscriptionParseDiagnostics {
+	d := &dto.SubscriptionParseDiagnostics{
+		Format:       r.Format,
+		ParsedCount:  r.ParsedCount,
+		SkippedCount: r.SkippedCount,
+		Skipped:      []dto.SubscriptionSkippedEntry{},
+		UpdatedAt:    r.UpdatedAt,
+	}
+	if r.SkippedJSON != "" {
+		_ = json.Unmarshal([]byte(r.SkippedJSON), &d.Skipped)
+	}
+	return d
+}
+
+// fetchOptionsFromDTO normalizes request fetch options; nil yields the defaults.
+func fetchOptionsFromDTO(d *dto.SubscriptionFetchOptions) (service.FetchOptions, *errorx.AppError) {
+	if d == nil {
+		return service.DefaultFetchOptions(), nil
+	}
+	return service.NormalizeFetchOptions(service.FetchOptions{
+		UserAgentPreset: d.UserAgentPreset,
+		UserAgent:       d.UserAgent,
+		Headers:         d.Headers,
+		TimeoutSec:      d.TimeoutSec,
+		MaxBodyBytes:    d.MaxBodyBytes,
+		FetchVia:        d.FetchVia,
+		FetchViaNodeTag: d.FetchViaNodeTag,
 	})
 }
 
+// redactedValue replaces secrets hidden from principals below admin scope.
+const redactedValue = "redacted"
+
+// redactSubscription hides the parts of a URL subscription that can carry the
+// provider token: userinfo, path and query of the URL (also where it shows up
+// in the name or last error) and custom fetch header values.
+func redactSubscription(d *dto.Subscription) {
+	if d.SourceType != service.SubscriptionSourceURL {
+		return
+	}
+	redacted := redactSubscriptionURL(d.URL)
+	if d.Name == d.URL {
+		d.Name = redacted
+	}
+	if d.LastError != nil {
+		msg := strings.ReplaceAll(*d.LastError, d.URL, redacted)
+		d.LastError = &msg
+	}
+	d.URL = redacted
+	if d.FetchOptions != nil && len(d.FetchOptions.Headers) > 0 {
+		headers := make(map[string]string, len(d.FetchOptions.Headers))
+		for k := range d.FetchOptions.Headers {
+			headers[k] = redactedValue
+		}
+		d.FetchOptions.Headers = headers
+	}
+}
+
+// redactSubscriptionURL keeps only the scheme and host.
+func redactSubscriptionURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redactedValue
+	}
+	out := u.Scheme + "://" + u.Host
+	if u.User != nil || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		out += "/" + redactedValue
+	}
+	return out
+}
+
+func fetchOptionsToDTO(o service.FetchOptions) *dto.SubscriptionFetchOptions {
+	headers := o.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	return &dto.SubscriptionFetchOptions{
+		UserAgentPreset: o.UserAgentPreset,
+		UserAgent:       o.UserAgent,
+		Headers:         headers,
+		TimeoutSec:      o.TimeoutSec,
+		MaxBodyBytes:    o.MaxBodyBytes,
+		FetchVia:        o.FetchVia,
+		FetchViaNodeTag: o.FetchViaNodeTag,
+	}
+}
+
 func subRowToDTO(r repo.SubscriptionRow) dto.Subscription {
 	d := dto.Subscription{
-		ID: r.ID, Name: r.Name, URL: r.URL, Type: r.Type, Enabled: r.Enabled == 1,
-		AutoUpdateEnabled:  r.AutoUpdateEnabled == 1,
-		RefreshIntervalSec: r.RefreshIntervalSec, Etag: r.Etag, LastModified: r.LastModified,
+		ID: r.ID, Name: r.Name, URL: r.URL, Type: r.Type, SourceType: r.SourceType, Enabled: r.Enabled == 1,
+		AutoUpdateEnabled:      r.AutoUpdateEnabled == 1,
+		FollowProviderInterval: r.FollowProviderInterval == 1,
+		RefreshIntervalSec:     r.RefreshIntervalSec, Etag: r.Etag, LastModified: r.LastModified,
 		CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt,
 	}
 	if r.LastFetchAt.Valid {
@@ -302,5 +578,8 @@ func subRowToDTO(r repo.SubscriptionRow) dto.Subscription {
 		n := int(r.SubProfileInterval.Int64)
 		d.ProfileUpdateSec = &n
 	}
+	usage := service.EvaluateSubscriptionUsage(r, time.Now())
+	d.Expired = usage.Expired
+	d.Warnings = usage.Warnings()
 	return d
 }
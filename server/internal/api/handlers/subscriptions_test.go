@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store"
+	"boxpilot/server/internal/store/repo"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListSubscriptionsRedactsURLsBelowAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sdb, err := store.Open(filepath.Join(t.TempDir(), "boxpilot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sdb.Close() })
+	db := sdb.DB
+	const subURL = "https://sub.example.com/api/v1/client/subscribe?token=s3cret"
+	if err := repo.CreateSubscription(db, "sub-1", subURL, subURL, "auto", service.SubscriptionSourceURL, 1, 0, 3600, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetSubscriptionFetchResult(db, "sub-1", "", "", `Get "`+subURL+`": timeout`, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpsertSubscriptionFetchOptions(db, repo.SubscriptionFetchOptionsRow{
+		SubID: "sub-1", UserAgentPreset: "default", HeadersJSON: `{"Authorization":"Bearer s3cret"}`,
+		TimeoutSec: 30, MaxBodyBytes: 1 << 20, FetchVia: "direct", UpdatedAt: "t0",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(scope string) (string, dto.Subscription) {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			p := &service.Principal{Kind: service.PrincipalToken, Scope: scope}
+			c.Request = c.Request.WithContext(service.WithPrincipal(c.Request.Context(), p))
+		})
+		h := &Subscriptions{DB: db}
+		r.GET("/api/v1/subscriptions", h.List)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/subscriptions", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", scope, w.Code, w.Body.String())
+		}
+		var body struct {
+			Data []dto.Subscription `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Data) != 1 {
+			t.Fatalf("%s: decode %s: %v", scope, w.Body.String(), err)
+		}
+		return w.Body.String(), body.Data[0]
+	}
+
+	for _, scope := range []string{service.ScopeReadOnly, service.ScopeOperator} {
+		raw, sub := list(scope)
+		if strings.Contains(raw, "s3cret") {
+			t.Fatalf("%s: response leaks the provider token: %s", scope, raw)
+		}
+		if sub.URL != "https://sub.example.com/redacted" || sub.Name != sub.URL {
+			t.Fatalf("%s: unexpected url %q name %q", scope, sub.URL, sub.Name)
+		}
+	}
+	if _, sub := list(service.ScopeAdmin); sub.URL != subURL || sub.FetchOptions.Headers["Authorization"] != "Bearer s3cret" {
+		t.Fatalf("admin should see the full subscription, got %+v", sub)
+	}
+}
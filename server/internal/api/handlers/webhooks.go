@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultWebhookDeliveryLimit = 50
+	maxWebhookDeliveryLimit     = 500
+)
+
+// Webhooks manages the targets that receive control-plane events and their
+// delivery log.
+type Webhooks struct {
+	DB *sql.DB
+}
+
+func (h *Webhooks) List(c *gin.Context) {
+	rows, err := repo.ListWebhookTargets(h.DB)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list webhooks"))
+		return
+	}
+	data := make([]dto.Webhook, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, webhookRowToDTO(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *Webhooks) Create(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	target, appErr := service.NormalizeWebhookTarget(service.WebhookTarget{
+		ID:      util.NewID(),
+		Name:    req.Name,
+		URL:     req.URL,
+		Secret:  req.Secret,
+		Events:  req.Events,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	})
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	row := service.WebhookTargetToRow(target)
+	row.CreatedAt = util.NowRFC3339()
+	row.UpdatedAt = row.CreatedAt
+	if err := repo.CreateWebhookTarget(h.DB, row); err != nil {
+		writeError(c, errorx.New(errorx.DBError, "create webhook"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": webhookRowToDTO(row)})
+}
+
+func (h *Webhooks) Update(c *gin.Context) {
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, errorx.New(errorx.REQValidationFailed, "invalid body"))
+		return
+	}
+	if req.ID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	existing, appErr := h.load(req.ID)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	target := service.WebhookTargetFromRow(*existing)
+	if req.Name != nil {
+		target.Name = *req.Name
+	}
+	if req.URL != nil {
+		target.URL = *req.URL
+	}
+	if req.Secret != nil {
+		target.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		target.Events = *req.Events
+	}
+	if req.Enabled != nil {
+		target.Enabled = *req.Enabled
+	}
+	target, appErr = service.NormalizeWebhookTarget(target)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	row := service.WebhookTargetToRow(target)
+	row.CreatedAt = existing.CreatedAt
+	row.UpdatedAt = util.NowRFC3339()
+	if _, err := repo.UpdateWebhookTarget(h.DB, row); err != nil {
+		writeError(c, errorx.New(errorx.DBError, "update webhook"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": webhookRowToDTO(row)})
+}
+
+func (h *Webhooks) Delete(c *gin.Context) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	ok, err := repo.DeleteWebhookTarget(h.DB, req.ID)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "delete webhook"))
+		return
+	}
+	if !ok {
+		writeError(c, errorx.New(errorx.WEBHOOKNotFound, "webhook not found").WithDetails(map[string]any{"id": req.ID}))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// Test sends a webhook.test event to a saved target, once and without
+// retries, and returns the logged delivery.
+func (h *Webhooks) Test(c *gin.Context) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" {
+		writeError(c, errorx.New(errorx.REQMissingField, "id required"))
+		return
+	}
+	row, appErr := h.load(req.ID)
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	delivery, appErr := service.SendTestWebhook(c.Request.Context(), h.DB, service.WebhookTargetFromRow(*row))
+	if appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": webhookDeliveryToDTO(delivery)})
+}
+
+// Deliveries lists the delivery log of one target, newest first.
+func (h *Webhooks) Deliveries(c *gin.Context) {
+	id := strings.TrimSpace(c.Param("id"))
+	if _, appErr := h.load(id); appErr != nil {
+		writeError(c, appErr)
+		return
+	}
+	limit := defaultWebhookDeliveryLimit
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(c, errorx.New(errorx.REQInvalidField, "limit must be a positive integer").WithDetails(map[string]any{"limit": raw}))
+			return
+		}
+		if n > maxWebhookDeliveryLimit {
+			n = maxWebhookDeliveryLimit
+		}
+		limit = n
+	}
+	rows, err := repo.ListWebhookDeliveries(h.DB, id, limit)
+	if err != nil {
+		writeError(c, errorx.New(errorx.DBError, "list webhook deliveries").WithDetails(map[string]any{"err": err.Error()}))
+		return
+	}
+	data := make([]dto.WebhookDelivery, 0, len(rows))
+	for _, r := range rows {
+		data = append(data, webhookDeliveryToDTO(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (h *Webhooks) load(id string) (*repo.WebhookTargetRow, *errorx.AppError) {
+	row, err := repo.GetWebhookTarget(h.DB, id)
+	if err != nil {
+		return nil, errorx.New(errorx.DBError, "load webhook")
+	}
+	if row == nil {
+		return nil, errorx.New(errorx.WEBHOOKNotFound, "webhook not found").WithDetails(map[string]any{"id": id})
+	}
+	return row, nil
+}
+
+func webhookRowToDTO(r repo.WebhookTargetRow) dto.Webhook {
+	target := service.WebhookTargetFromRow(r)
+	events := target.Events
+	if events == nil {
+		events = []string{}
+	}
+	return dto.Webhook{
+		ID:        r.ID,
+		Name:      r.Name,
+		URL:       r.URL,
+		Secret:    r.Secret,
+		Events:    events,
+		Enabled:   r.Enabled == 1,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+func webhookDeliveryToDTO(r repo.WebhookDeliveryRow) dto.WebhookDelivery {
+	payload := json.RawMessage(r.Payload)
+	if !json.Valid(payload) {
+		payload = json.RawMessage("null")
+	}
+	return dto.WebhookDelivery{
+		ID:         r.ID,
+		WebhookID:  r.TargetID,
+		EventID:    r.EventID,
+		EventType:  r.EventType,
+		Payload:    payload,
+		Status:     r.Status,
+		Attempts:   r.Attempts,
+		HTTPStatus: r.HTTPStatus,
+		Error:      r.Error,
+		DurationMs: r.DurationMs,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"boxpilot/server/internal/observability"
+
+	"github.com/gin-gonic/gin"
+)
+
+var httpLog = observability.Logger(observability.SubsystemHTTP)
+
+// quietPaths are polled by probes and scrapers; they log at debug.
+var quietPaths = map[string]bool{"/healthz": true, "/metrics": true}
+
+// AccessLog logs one line per request after it completes, with the request
+// id when RequestID runs first. 5xx responses log at error, 4xx at warn.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case quietPaths[c.Request.URL.Path]:
+			level = slog.LevelDebug
+		}
+		httpLog.Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
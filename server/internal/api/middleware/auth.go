@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+
+	"boxpilot/server/internal/api/dto"
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/util/errorx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate resolves the caller from an API token in the Authorization
+// header or from the session cookie, and puts the principal on the request
+// context. Requests without credentials pass through without one so public
+// routes work; RequireScope rejects them. With BOXPILOT_AUTH=off every
+// request is an anonymous admin.
+func Authenticate(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.AuthEnabled() {
+			setPrincipal(c, service.AnonymousPrincipal())
+			c.Next()
+			return
+		}
+		if token, ok := bearerToken(c.GetHeader("Authorization")); ok {
+			p, err := service.TokenPrincipal(db, token)
+			if err != nil {
+				abortWithError(c, errorx.New(errorx.DBError, "load api token"))
+				return
+			}
+			if p == nil {
+				c.Header("WWW-Authenticate", `Bearer realm="boxpilot"`)
+				abortWithError(c, errorx.New(errorx.AUTHUnauthorized, "invalid or expired api token"))
+				return
+			}
+			setPrincipal(c, p)
+			c.Next()
+			return
+		}
+		if cookie, err := c.Cookie(service.SessionCookieName); err == nil && cookie != "" {
+			p, err := service.SessionPrincipal(db, cookie)
+			if err != nil {
+				abortWithError(c, errorx.New(errorx.DBError, "load session"))
+				return
+			}
+			if p != nil {
+				// Cookies ride along on requests other sites trigger; only
+				// trust them from our own origin.
+				if origin := c.GetHeader("Origin"); origin != "" && !TrustedOrigin(origin, c.Request.Host) {
+					abortWithError(c, errorx.New(errorx.AUTHForbidden, "cross-origin request rejected").WithDetails(map[string]any{"origin": origin}))
+					return
+				}
+				setPrincipal(c, p)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose principal lacks scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := service.PrincipalFrom(c.Request.Context())
+		if p == nil {
+			abortWithError(c, errorx.New(errorx.AUTHUnauthorized, "sign in required"))
+			return
+		}
+		if !service.ScopeAllows(p.Scope, scope) {
+			abortWithError(c, errorx.New(errorx.AUTHForbidden, "insufficient scope").WithDetails(map[string]any{
+				"scope":    p.Scope,
+				"required": scope,
+			}))
+			return
+		}
+		c.Next()
+	}
+}
+
+func setPrincipal(c *gin.Context, p *service.Principal) {
+	c.Request = c.Request.WithContext(service.WithPrincipal(c.Request.Context(), p))
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, host)
+}
+
+func abortWithError(c *gin.Context, err *errorx.AppError) {
+	c.AbortWithStatusJSON(err.HTTPStatus(), dto.ErrorEnvelope{
+		Error: dto.ErrorObject{
+			Code:    err.Code,
+			Message: err.Message,
+			Details: err.Details,
+		},
+	})
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boxpilot/server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if scope := c.GetHeader("X-Test-Scope"); scope != "" {
+			setPrincipal(c, &service.Principal{Kind: service.PrincipalToken, Scope: scope})
+		}
+		c.Next()
+	})
+	r.GET("/read", RequireScope(service.ScopeReadOnly), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/reload", RequireScope(service.ScopeOperator), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/settings", RequireScope(service.ScopeAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		method, path, scope string
+		want                int
+	}{
+		{http.MethodGet, "/read", "", http.StatusUnauthorized},
+		{http.MethodGet, "/read", service.ScopeReadOnly, http.StatusOK},
+		{http.MethodPost, "/reload", service.ScopeReadOnly, http.StatusForbidden},
+		{http.MethodPost, "/reload", service.ScopeOperator, http.StatusOK},
+		{http.MethodPost, "/settings", service.ScopeOperator, http.StatusForbidden},
+		{http.MethodPost, "/settings", service.ScopeAdmin, http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.scope != "" {
+			req.Header.Set("X-Test-Scope", tc.scope)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s %s as %q: got %d, want %d", tc.method, tc.path, tc.scope, w.Code, tc.want)
+		}
+	}
+}
+
+func TestAuthenticateDisabledIsAdmin(t *testing.T) {
+	t.Setenv("BOXPILOT_AUTH", "off")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Authenticate(nil))
+	r.POST("/settings", RequireScope(service.ScopeAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/settings", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d with auth off", w.Code)
+	}
+}
+
+func TestCORSOnlyAllowsListedOrigins(t *testing.T) {
+	t.Setenv("BOXPILOT_CORS_ORIGINS", "http://localhost:5173, https://ops.example.com/")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORS())
+	r.GET("/api/v1/nodes", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for origin, allowed := range map[string]bool{
+		"http://localhost:5173":   true,
+		"https://ops.example.com": true,
+		"https://evil.example":    false,
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/nodes", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("preflight from %s: got %d", origin, w.Code)
+		}
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if allowed && (got != origin || w.Header().Get("Access-Control-Allow-Credentials") != "true") {
+			t.Errorf("%s: allow-origin %q, want it echoed with credentials", origin, got)
+		}
+		if !allowed && got != "" {
+			t.Errorf("%s: allow-origin %q, want none", origin, got)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bearer bpt_abc":  "bpt_abc",
+		"bearer  bpt_abc": "bpt_abc",
+		"Basic dXNlcg==":  "",
+		"Bearer ":         "",
+		"":                "",
+	} {
+		got, ok := bearerToken(header)
+		if got != want || ok != (want != "") {
+			t.Errorf("bearerToken(%q) = %q, %v", header, got, ok)
+		}
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	if !sameOrigin("http://box.local:8080", "box.local:8080") {
+		t.Fatal("same host rejected")
+	}
+	for _, origin := range []string{"http://evil.example", "null", "http://box.local"} {
+		if sameOrigin(origin, "box.local:8080") {
+			t.Errorf("%s accepted", origin)
+		}
+	}
+}
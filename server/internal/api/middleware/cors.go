@@ -1,19 +1,48 @@
 package middleware
 
 import (
+	"net/http"
+	"os"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 )
 
-// CORS sets permissive CORS headers (same-origin by default; adjust for dev if needed).
+// CORS answers cross-origin requests from the origins listed in
+// BOXPILOT_CORS_ORIGINS (comma-separated, e.g. "http://localhost:5173"),
+// with credentials so the session cookie works. Other origins get no CORS
+// headers; the web UI served by BoxPilot itself is same-origin.
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+RequestIDHeader)
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		origin := c.GetHeader("Origin")
+		if origin != "" && OriginAllowed(origin) {
+			c.Header("Vary", "Origin")
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+			c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+RequestIDHeader)
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
 		c.Next()
 	}
 }
+
+// OriginAllowed reports whether origin is listed in BOXPILOT_CORS_ORIGINS.
+func OriginAllowed(origin string) bool {
+	for _, allowed := range strings.Split(os.Getenv("BOXPILOT_CORS_ORIGINS"), ",") {
+		if allowed = strings.TrimRight(strings.TrimSpace(allowed), "/"); allowed != "" && strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustedOrigin reports whether a browser request from origin may use the
+// caller's credentials: it comes from the host it was sent to or from an
+// origin listed in BOXPILOT_CORS_ORIGINS.
+func TrustedOrigin(origin, host string) bool {
+	return sameOrigin(origin, host) || OriginAllowed(origin)
+}
@@ -1,8 +1,8 @@
 package middleware
 
 import (
-	"log"
 	"net/http"
+	"runtime/debug"
 
 	"boxpilot/server/internal/api/dto"
 	"boxpilot/server/internal/util/errorx"
@@ -15,7 +15,7 @@ func Recover() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("panic: %v", err)
+				httpLog.ErrorContext(c.Request.Context(), "panic", "error", err, "path", c.Request.URL.Path, "stack", string(debug.Stack()))
 				if appErr, ok := err.(*errorx.AppError); ok {
 					c.JSON(appErr.HTTPStatus(), dto.ErrorEnvelope{
 						Error: dto.ErrorObject{
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"boxpilot/server/internal/observability"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -8,7 +10,8 @@ import (
 const RequestIDKey = "request_id"
 const RequestIDHeader = "X-Request-ID"
 
-// RequestID attaches a request ID to the context and response header.
+// RequestID attaches a request ID to the gin context, the request context
+// (for service-layer logs) and the response header.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetHeader(RequestIDHeader)
@@ -16,6 +19,7 @@ func RequestID() gin.HandlerFunc {
 			id = uuid.New().String()
 		}
 		c.Set(RequestIDKey, id)
+		c.Request = c.Request.WithContext(observability.WithRequestID(c.Request.Context(), id))
 		c.Header(RequestIDHeader, id)
 		c.Next()
 	}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"database/sql"
+	"log/slog"
 	"net/http"
 	"os"
 	"path"
@@ -10,6 +11,7 @@ import (
 
 	"boxpilot/server/internal/api/handlers"
 	"boxpilot/server/internal/api/middleware"
+	"boxpilot/server/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,54 +19,128 @@ import (
 // Router returns the HTTP router.
 func Router(db *sql.DB) *gin.Engine {
 	r := gin.New()
-	r.Use(gin.Logger(), middleware.Recover(), middleware.RequestID(), middleware.CORS())
+	// Login rate limits key on the client IP, so only proxies listed in
+	// BOXPILOT_TRUSTED_PROXIES may set it through X-Forwarded-For.
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		slog.Warn("invalid BOXPILOT_TRUSTED_PROXIES", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+	r.Use(middleware.RequestID(), middleware.AccessLog(), middleware.Recover(), middleware.CORS())
 
 	sys := &handlers.System{}
 	r.GET("/healthz", sys.Healthz)
 
-	v1 := r.Group("/api/v1")
+	metrics := &handlers.Metrics{DB: db}
+	r.GET("/metrics", metrics.Serve)
+
+	share := &handlers.Share{DB: db}
+	r.GET("/sub/:token", share.Subscription)
+
+	v1 := r.Group("/api/v1", middleware.Authenticate(db))
 	{
+		auth := &handlers.Auth{DB: db}
+		v1.GET("/auth/status", auth.Status)
+		v1.POST("/auth/setup", auth.Setup)
+		v1.POST("/auth/login", auth.Login)
+		v1.POST("/auth/logout", auth.Logout)
+
+		// Everything else needs a session or an API token whose scope covers
+		// the route: read-only for reads, operator for runtime actions such
+		// as refresh, probe and reload, admin for config changes and secrets.
+		read := v1.Group("", middleware.RequireScope(service.ScopeReadOnly))
+		operate := v1.Group("", middleware.RequireScope(service.ScopeOperator))
+		admin := v1.Group("", middleware.RequireScope(service.ScopeAdmin))
+
+		admin.POST("/auth/password", auth.ChangePassword)
+		admin.GET("/auth/tokens", auth.ListTokens)
+		admin.POST("/auth/tokens/create", auth.CreateToken)
+		admin.POST("/auth/tokens/delete", auth.DeleteToken)
+
 		sub := &handlers.Subscriptions{DB: db}
-		v1.GET("/subscriptions", sub.List)
-		v1.POST("/subscriptions/create", sub.Create)
-		v1.POST("/subscriptions/update", sub.Update)
-		v1.POST("/subscriptions/delete", sub.Delete)
-		v1.POST("/subscriptions/refresh", sub.Refresh)
+		read.GET("/subscriptions", sub.List)
+		admin.POST("/subscriptions/create", sub.Create)
+		admin.POST("/subscriptions/update", sub.Update)
+		admin.POST("/subscriptions/delete", sub.Delete)
+		operate.POST("/subscriptions/refresh", sub.Refresh)
+		read.GET("/subscriptions/:id/history", sub.History)
+		admin.GET("/subscriptions/content", sub.Content)
+		read.GET("/subscriptions/schedule", sub.Schedule)
+		read.GET("/subscriptions/pipeline", sub.GetPipeline)
+		admin.POST("/subscriptions/pipeline/update", sub.UpdatePipeline)
+		admin.POST("/subscriptions/pipeline/preview", sub.PreviewPipeline)
 
 		node := &handlers.Nodes{DB: db}
-		v1.GET("/nodes", node.List)
-		v1.POST("/nodes/create-manual", node.CreateManual)
-		v1.POST("/nodes/update", node.Update)
-		v1.POST("/nodes/forwarding/batch", node.BatchForwarding)
-		v1.POST("/nodes/test", node.Test)
-		v1.GET("/nodes/forwarding", node.Forwarding)
-		v1.POST("/nodes/forwarding/update", node.UpdateForwarding)
-		v1.POST("/nodes/forwarding/restart", node.RestartForwarding)
+		read.GET("/nodes", node.List)
+		admin.POST("/nodes/create-manual", node.CreateManual)
+		admin.POST("/nodes/update", node.Update)
+		admin.POST("/nodes/forwarding/batch", node.BatchForwarding)
+		operate.POST("/nodes/test", node.Test)
+		read.GET("/nodes/duplicates", node.Duplicates)
+		admin.POST("/nodes/duplicates/prefer", node.PreferDuplicate)
+		read.GET("/nodes/forwarding", node.Forwarding)
+		admin.POST("/nodes/forwarding/update", node.UpdateForwarding)
+		operate.POST("/nodes/forwarding/restart", node.RestartForwarding)
+
+		exp := &handlers.Export{DB: db}
+		admin.GET("/export/:format", exp.Nodes)
+		admin.GET("/share/tokens", share.ListTokens)
+		admin.POST("/share/tokens/create", share.CreateToken)
+		admin.POST("/share/tokens/update", share.UpdateToken)
+		admin.POST("/share/tokens/delete", share.DeleteToken)
 
 		rt := &handlers.Runtime{DB: db}
-		v1.GET("/runtime/status", rt.Status)
-		v1.GET("/runtime/traffic", rt.Traffic)
-		v1.GET("/runtime/connections", rt.Connections)
-		v1.GET("/runtime/logs", rt.Logs)
-		v1.POST("/runtime/proxy/check", rt.ProxyCheck)
-		v1.POST("/runtime/plan", rt.Plan)
-		v1.POST("/runtime/reload", rt.Reload)
-		v1.GET("/runtime/groups", rt.Groups)
-		v1.POST("/runtime/groups/:tag/select", rt.SelectGroup)
+		read.GET("/runtime/status", rt.Status)
+		read.GET("/runtime/traffic", rt.Traffic)
+		read.GET("/runtime/connections", rt.Connections)
+		operate.POST("/runtime/connections/close", rt.CloseConnection)
+		read.GET("/runtime/logs", rt.Logs)
+		read.GET("/runtime/logs/stream", rt.LogsStream)
+		operate.POST("/runtime/proxy/check", rt.ProxyCheck)
+		operate.POST("/runtime/plan", rt.Plan)
+		operate.POST("/runtime/reload", rt.Reload)
+		read.GET("/runtime/groups", rt.Groups)
+		operate.POST("/runtime/groups/:tag/select", rt.SelectGroup)
+
+		stats := &handlers.Stats{DB: db}
+		read.GET("/stats/traffic", stats.Traffic)
+
+		events := &handlers.Events{}
+		read.GET("/events", events.Stream)
+		read.GET("/events/ws", events.WebSocket)
+
+		notify := &handlers.Notifications{DB: db}
+		admin.GET("/notifications/channels", notify.ListChannels)
+		admin.POST("/notifications/channels/create", notify.CreateChannel)
+		admin.POST("/notifications/channels/update", notify.UpdateChannel)
+		admin.POST("/notifications/channels/delete", notify.DeleteChannel)
+		admin.POST("/notifications/channels/test", notify.TestChannel)
+		read.GET("/notifications/rules", notify.ListRules)
+		admin.POST("/notifications/rules/create", notify.CreateRule)
+		admin.POST("/notifications/rules/update", notify.UpdateRule)
+		admin.POST("/notifications/rules/delete", notify.DeleteRule)
+		read.GET("/notifications/alerts", notify.Alerts)
+
+		hooks := &handlers.Webhooks{DB: db}
+		admin.GET("/webhooks", hooks.List)
+		admin.POST("/webhooks/create", hooks.Create)
+		admin.POST("/webhooks/update", hooks.Update)
+		admin.POST("/webhooks/delete", hooks.Delete)
+		admin.POST("/webhooks/test", hooks.Test)
+		admin.GET("/webhooks/:id/deliveries", hooks.Deliveries)
 
 		settings := &handlers.Settings{DB: db}
-		v1.GET("/settings/proxy", settings.GetProxySettings)
-		v1.POST("/settings/proxy/update", settings.UpdateProxySettings)
-		v1.POST("/settings/proxy/apply", settings.ApplyProxySettings)
-		v1.GET("/settings/routing", settings.GetRoutingSettings)
-		v1.POST("/settings/routing/update", settings.UpdateRoutingSettings)
-		v1.GET("/settings/routing/summary", settings.RoutingSummary)
-		v1.GET("/settings/forwarding/status", settings.ForwardingStatus)
-		v1.GET("/settings/forwarding/summary", settings.ForwardingSummary)
-		v1.GET("/settings/forwarding/policy", settings.GetForwardingPolicy)
-		v1.POST("/settings/forwarding/policy/update", settings.UpdateForwardingPolicy)
-		v1.POST("/settings/forwarding/start", settings.StartForwarding)
-		v1.POST("/settings/forwarding/stop", settings.StopForwarding)
+		admin.GET("/settings/proxy", settings.GetProxySettings)
+		admin.POST("/settings/proxy/update", settings.UpdateProxySettings)
+		operate.POST("/settings/proxy/apply", settings.ApplyProxySettings)
+		read.GET("/settings/routing", settings.GetRoutingSettings)
+		admin.POST("/settings/routing/update", settings.UpdateRoutingSettings)
+		read.GET("/settings/routing/summary", settings.RoutingSummary)
+		read.GET("/settings/forwarding/status", settings.ForwardingStatus)
+		read.GET("/settings/forwarding/summary", settings.ForwardingSummary)
+		read.GET("/settings/forwarding/policy", settings.GetForwardingPolicy)
+		admin.POST("/settings/forwarding/policy/update", settings.UpdateForwardingPolicy)
+		operate.POST("/settings/forwarding/start", settings.StartForwarding)
+		operate.POST("/settings/forwarding/stop", settings.StopForwarding)
 	}
 
 	// Static files when WEB_ROOT is set (e.g. production)
@@ -104,3 +180,13 @@ func Router(db *sql.DB) *gin.Engine {
 	}
 	return r
 }
+
+func trustedProxies() []string {
+	var out []string
+	for _, p := range strings.Split(os.Getenv("BOXPILOT_TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"boxpilot/server/internal/service"
+	"boxpilot/server/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsRequireAuthWhenAuthEnabled(t *testing.T) {
+	t.Setenv("BOXPILOT_AUTH", "")
+	t.Setenv("BOXPILOT_METRICS_TOKEN", "")
+	gin.SetMode(gin.TestMode)
+	sdb, err := store.Open(filepath.Join(t.TempDir(), "boxpilot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sdb.Close() })
+	r := Router(sdb.DB)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("unauthenticated scrape: status %d, want 401 with a challenge", w.Code)
+	}
+
+	_, token, appErr := service.CreateAPIToken(context.Background(), sdb.DB, "prometheus", service.ScopeReadOnly, 0)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("read-only api token: status %d: %s", w.Code, w.Body.String())
+	}
+}
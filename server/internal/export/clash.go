@@ -0,0 +1,363 @@
+package export
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// proxyFields is a Clash proxy mapping that keeps insertion order, so name,
+// type, server and port stay at the top of every exported entry.
+type proxyFields struct {
+	keys []string
+	vals map[string]any
+}
+
+func newProxyFields() *proxyFields {
+	return &proxyFields{vals: map[string]any{}}
+}
+
+func (p *proxyFields) set(key string, value any) {
+	if _, ok := p.vals[key]; !ok {
+		p.keys = append(p.keys, key)
+	}
+	p.vals[key] = value
+}
+
+// setString only records non-empty values.
+func (p *proxyFields) setString(key, value string) {
+	if value != "" {
+		p.set(key, value)
+	}
+}
+
+func (p *proxyFields) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range p.keys {
+		var value yaml.Node
+		if err := value.Encode(p.vals[key]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &value)
+	}
+	return node, nil
+}
+
+func exportClash(list []outbound) (Result, error) {
+	byTag := indexByTag(list)
+	// shadowtls outbounds only exist as the detour of a shadowsocks node and are
+	// folded back into its shadow-tls plugin.
+	folded := map[string]struct{}{}
+	for _, ob := range list {
+		if ob.typ != "shadowsocks" {
+			continue
+		}
+		if detour, ok := byTag[str(ob.m, "detour")]; ok && detour.typ == "shadowtls" {
+			folded[detour.tag] = struct{}{}
+		}
+	}
+
+	proxies := make([]*proxyFields, 0, len(list))
+	var skipped []Skipped
+	for _, ob := range list {
+		if _, ok := folded[ob.tag]; ok {
+			continue
+		}
+		p, reason := clashProxy(ob, byTag)
+		if p == nil {
+			skipped = append(skipped, Skipped{Tag: ob.tag, Type: ob.typ, Reason: reason})
+			continue
+		}
+		proxies = append(proxies, p)
+	}
+	body, err := yaml.Marshal(map[string]any{"proxies": proxies})
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Body: body, ContentType: "application/yaml; charset=utf-8", Count: len(proxies), Skipped: skipped}, nil
+}
+
+func clashProxy(ob outbound, byTag map[string]outbound) (*proxyFields, string) {
+	m := ob.m
+	p := newProxyFields()
+	p.set("name", ob.tag)
+	if ob.typ == "wireguard" {
+		if !clashWireGuard(p, m) {
+			return nil, "missing peer"
+		}
+		return p, ""
+	}
+	server := str(m, "server")
+	port := num(m, "server_port")
+	if server == "" || port <= 0 {
+		return nil, "missing server or port"
+	}
+	switch ob.typ {
+	case "shadowsocks":
+		p.set("type", "ss")
+	case "vmess", "vless", "trojan", "hysteria", "hysteria2", "tuic", "http", "anytls":
+		p.set("type", ob.typ)
+	case "socks":
+		p.set("type", "socks5")
+	default:
+		return nil, "unsupported protocol"
+	}
+	p.set("server", server)
+	p.set("port", port)
+	tls := readTLS(m)
+
+	switch ob.typ {
+	case "shadowsocks":
+		p.set("cipher", str(m, "method"))
+		p.set("password", str(m, "password"))
+		p.set("udp", true)
+		if detour, ok := byTag[str(m, "detour")]; ok && detour.typ == "shadowtls" {
+			clashShadowTLSPlugin(p, detour.m)
+			return p, ""
+		}
+		clashSSPlugin(p, str(m, "plugin"), str(m, "plugin_opts"))
+	case "vmess":
+		p.set("uuid", str(m, "uuid"))
+		p.set("alterId", num(m, "alter_id"))
+		p.set("cipher", orDefault(str(m, "security"), "auto"))
+		p.set("udp", true)
+		clashStreamTLS(p, tls, "servername")
+		clashTransport(p, readTransport(m))
+	case "vless":
+		p.set("uuid", str(m, "uuid"))
+		p.setString("flow", str(m, "flow"))
+		p.set("udp", true)
+		clashStreamTLS(p, tls, "servername")
+		clashTransport(p, readTransport(m))
+	case "trojan":
+		p.set("password", str(m, "password"))
+		p.set("udp", true)
+		clashStreamTLS(p, tls, "sni")
+		clashTransport(p, readTransport(m))
+	case "hysteria2":
+		p.set("password", str(m, "password"))
+		if up := num(m, "up_mbps"); up > 0 {
+			p.set("up", up)
+		}
+		if down := num(m, "down_mbps"); down > 0 {
+			p.set("down", down)
+		}
+		if obfs := obj(m, "obfs"); obfs != nil {
+			p.setString("obfs", str(obfs, "type"))
+			p.setString("obfs-password", str(obfs, "password"))
+		}
+		clashQUICTLS(p, tls)
+	case "hysteria":
+		p.setString("auth-str", str(m, "auth_str"))
+		clashBandwidth(p, m, "up")
+		clashBandwidth(p, m, "down")
+		p.setString("obfs", str(m, "obfs"))
+		clashQUICTLS(p, tls)
+	case "tuic":
+		p.set("uuid", str(m, "uuid"))
+		p.set("password", str(m, "password"))
+		p.setString("congestion-controller", str(m, "congestion_control"))
+		p.setString("udp-relay-mode", str(m, "udp_relay_mode"))
+		if flag(m, "zero_rtt_handshake") {
+			p.set("reduce-rtt", true)
+		}
+		if ms, ok := durationMillis(str(m, "heartbeat")); ok {
+			p.set("heartbeat-interval", ms)
+		}
+		clashQUICTLS(p, tls)
+	case "anytls":
+		p.set("password", str(m, "password"))
+		clashQUICTLS(p, tls)
+		p.setString("client-fingerprint", tls.fingerprint)
+	case "http", "socks":
+		p.setString("username", str(m, "username"))
+		p.setString("password", str(m, "password"))
+		if tls.enabled {
+			p.set("tls", true)
+			p.setString("sni", tls.serverName)
+			if tls.insecure {
+				p.set("skip-cert-verify", true)
+			}
+		}
+	}
+	if detour := str(m, "detour"); detour != "" {
+		p.set("dialer-proxy", detour)
+	}
+	return p, ""
+}
+
+func clashSSPlugin(p *proxyFields, plugin, rawOpts string) {
+	opts := splitPluginOpts(rawOpts)
+	switch plugin {
+	case "obfs-local":
+		p.set("plugin", "obfs")
+		pluginOpts := newProxyFields()
+		pluginOpts.setString("mode", opts["obfs"])
+		pluginOpts.setString("host", opts["obfs-host"])
+		p.set("plugin-opts", pluginOpts)
+	case "v2ray-plugin":
+		p.set("plugin", "v2ray-plugin")
+		pluginOpts := newProxyFields()
+		pluginOpts.set("mode", orDefault(opts["mode"], "websocket"))
+		if _, ok := opts["tls"]; ok {
+			pluginOpts.set("tls", true)
+		}
+		pluginOpts.setString("host", opts["host"])
+		pluginOpts.setString("path", opts["path"])
+		p.set("plugin-opts", pluginOpts)
+	}
+}
+
+func clashShadowTLSPlugin(p *proxyFields, detour map[string]any) {
+	tls := readTLS(detour)
+	p.set("plugin", "shadow-tls")
+	pluginOpts := newProxyFields()
+	pluginOpts.setString("host", tls.serverName)
+	pluginOpts.setString("password", str(detour, "password"))
+	pluginOpts.set("version", num(detour, "version"))
+	p.set("plugin-opts", pluginOpts)
+	if tls.insecure {
+		p.set("skip-cert-verify", true)
+	}
+}
+
+// clashStreamTLS writes the TLS fields of vmess / vless / trojan proxies; the
+// SNI key is "servername" for vmess and vless but "sni" for trojan.
+func clashStreamTLS(p *proxyFields, tls tlsOptions, sniKey string) {
+	if !tls.enabled {
+		return
+	}
+	p.set("tls", true)
+	p.setString(sniKey, tls.serverName)
+	if tls.insecure {
+		p.set("skip-cert-verify", true)
+	}
+	if len(tls.alpn) > 0 {
+		p.set("alpn", tls.alpn)
+	}
+	p.setString("client-fingerprint", tls.fingerprint)
+	if tls.realityKey != "" {
+		reality := newProxyFields()
+		reality.set("public-key", tls.realityKey)
+		reality.setString("short-id", tls.realitySID)
+		p.set("reality-opts", reality)
+	}
+}
+
+// clashQUICTLS writes the TLS fields of QUIC based proxies, which always use TLS.
+func clashQUICTLS(p *proxyFields, tls tlsOptions) {
+	p.setString("sni", tls.serverName)
+	if tls.insecure {
+		p.set("skip-cert-verify", true)
+	}
+	if len(tls.alpn) > 0 {
+		p.set("alpn", tls.alpn)
+	}
+}
+
+func clashTransport(p *proxyFields, t transportOptions) {
+	switch t.typ {
+	case "ws", "httpupgrade":
+		p.set("network", "ws")
+		wsOpts := newProxyFields()
+		wsOpts.set("path", orDefault(t.path, "/"))
+		if t.host != "" {
+			wsOpts.set("headers", map[string]string{"Host": t.host})
+		}
+		if t.typ == "httpupgrade" {
+			wsOpts.set("v2ray-http-upgrade", true)
+		}
+		p.set("ws-opts", wsOpts)
+	case "grpc":
+		p.set("network", "grpc")
+		grpcOpts := newProxyFields()
+		grpcOpts.setString("grpc-service-name", t.serviceName)
+		p.set("grpc-opts", grpcOpts)
+	case "http":
+		p.set("network", "h2")
+		h2Opts := newProxyFields()
+		h2Opts.set("path", orDefault(t.path, "/"))
+		if t.host != "" {
+			h2Opts.set("host", []string{t.host})
+		}
+		p.set("h2-opts", h2Opts)
+	}
+}
+
+func clashBandwidth(p *proxyFields, m map[string]any, key string) {
+	if mbps := num(m, key+"_mbps"); mbps > 0 {
+		p.set(key, mbps)
+		return
+	}
+	p.setString(key, str(m, key))
+}
+
+func clashWireGuard(p *proxyFields, m map[string]any) bool {
+	peers, _ := m["peers"].([]any)
+	if len(peers) == 0 {
+		return false
+	}
+	peer, _ := peers[0].(map[string]any)
+	if peer == nil || str(peer, "address") == "" || num(peer, "port") <= 0 {
+		return false
+	}
+	p.set("type", "wireguard")
+	p.set("server", str(peer, "address"))
+	p.set("port", num(peer, "port"))
+	for _, addr := range strList(m, "address") {
+		prefix, err := netip.ParsePrefix(addr)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Is4() {
+			p.setString("ip", prefix.Addr().String())
+		} else {
+			p.setString("ipv6", prefix.Addr().String())
+		}
+	}
+	p.set("private-key", str(m, "private_key"))
+	p.set("public-key", str(peer, "public_key"))
+	p.setString("pre-shared-key", str(peer, "pre_shared_key"))
+	if allowed := strList(peer, "allowed_ips"); len(allowed) > 0 {
+		p.set("allowed-ips", allowed)
+	}
+	if reserved, ok := peer["reserved"].([]any); ok && len(reserved) > 0 {
+		p.set("reserved", reserved)
+	}
+	if mtu := num(m, "mtu"); mtu > 0 {
+		p.set("mtu", mtu)
+	}
+	p.set("udp", true)
+	return true
+}
+
+// durationMillis converts a sing-box duration string such as "10s" or "500ms"
+// into whole milliseconds.
+func durationMillis(raw string) (int, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, false
+	}
+	unit := 1
+	switch {
+	case strings.HasSuffix(raw, "ms"):
+		raw = strings.TrimSuffix(raw, "ms")
+	case strings.HasSuffix(raw, "s"):
+		raw = strings.TrimSuffix(raw, "s")
+		unit = 1000
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n * unit, true
+}
+
+func orDefault(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,277 @@
+// Package export serialises stored sing-box node outbounds back into formats
+// other clients can import: Clash proxies, share-link URI lists and a
+// standalone sing-box outbound list. It is the reverse of package parser.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	FormatClash   = "clash"
+	FormatSingbox = "singbox"
+	FormatURI     = "uri"
+)
+
+// Node is one stored node: its tag and the sing-box outbound (or endpoint) JSON.
+type Node struct {
+	Tag          string
+	Type         string
+	OutboundJSON string
+}
+
+// Skipped describes a node that could not be represented in the target format.
+type Skipped struct {
+	Tag    string
+	Type   string
+	Reason string
+}
+
+type Result struct {
+	Body        []byte
+	ContentType string
+	Count       int
+	Skipped     []Skipped
+}
+
+// ValidFormat reports whether format is one of the supported export formats.
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatClash, FormatSingbox, FormatURI:
+		return true
+	}
+	return false
+}
+
+// FormatForUserAgent picks the export format a subscribing client expects from
+// its User-Agent. Clients that are not recognised get the base64 URI list,
+// which almost every client can import.
+func FormatForUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "sing-box"), strings.Contains(ua, "singbox"),
+		strings.HasPrefix(ua, "sfa"), strings.HasPrefix(ua, "sfi"), strings.HasPrefix(ua, "sfm"), strings.HasPrefix(ua, "sft"):
+		return FormatSingbox
+	case strings.Contains(ua, "clash"), strings.Contains(ua, "mihomo"), strings.Contains(ua, "stash"):
+		return FormatClash
+	default:
+		return FormatURI
+	}
+}
+
+// Export renders nodes in the requested format. Nodes whose protocol or options
+// have no equivalent in that format are reported in Result.Skipped.
+func Export(format string, nodes []Node) (Result, error) {
+	decoded, skipped := decodeNodes(nodes)
+	var (
+		res Result
+		err error
+	)
+	switch format {
+	case FormatClash:
+		res, err = exportClash(decoded)
+	case FormatSingbox:
+		res, err = exportSingbox(decoded)
+	case FormatURI:
+		res, err = exportURI(decoded)
+	default:
+		return Result{}, fmt.Errorf("unsupported export format %q", format)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	res.Skipped = append(skipped, res.Skipped...)
+	return res, nil
+}
+
+type outbound struct {
+	tag string
+	typ string
+	m   map[string]any
+}
+
+func decodeNodes(nodes []Node) ([]outbound, []Skipped) {
+	out := make([]outbound, 0, len(nodes))
+	var skipped []Skipped
+	for _, n := range nodes {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(n.OutboundJSON), &m); err != nil || m == nil {
+			skipped = append(skipped, Skipped{Tag: n.Tag, Type: n.Type, Reason: "invalid outbound json"})
+			continue
+		}
+		tag := strings.TrimSpace(n.Tag)
+		if tag == "" {
+			tag = str(m, "tag")
+		}
+		m["tag"] = tag
+		typ := str(m, "type")
+		if typ == "" {
+			typ = n.Type
+		}
+		out = append(out, outbound{tag: tag, typ: typ, m: m})
+	}
+	return out, skipped
+}
+
+// indexByTag maps tags to outbounds so detour chains (e.g. shadowsocks over
+// shadowtls) can be folded back into a single proxy.
+func indexByTag(list []outbound) map[string]outbound {
+	idx := make(map[string]outbound, len(list))
+	for _, ob := range list {
+		idx[ob.tag] = ob
+	}
+	return idx
+}
+
+func exportSingbox(list []outbound) (Result, error) {
+	outbounds := make([]any, 0, len(list))
+	endpoints := make([]any, 0)
+	for _, ob := range list {
+		if ob.typ == "wireguard" {
+			endpoints = append(endpoints, ob.m)
+			continue
+		}
+		outbounds = append(outbounds, ob.m)
+	}
+	doc := map[string]any{"outbounds": outbounds}
+	if len(endpoints) > 0 {
+		doc["endpoints"] = endpoints
+	}
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Body: body, ContentType: "application/json; charset=utf-8", Count: len(list)}, nil
+}
+
+func str(m map[string]any, key string) string {
+	switch v := m[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprintf("%v", v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func num(m map[string]any, key string) int {
+	switch v := m[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func flag(m map[string]any, key string) bool {
+	v, _ := m[key].(bool)
+	return v
+}
+
+func obj(m map[string]any, key string) map[string]any {
+	v, _ := m[key].(map[string]any)
+	return v
+}
+
+func strList(m map[string]any, key string) []string {
+	switch v := m[key].(type) {
+	case string:
+		if v = strings.TrimSpace(v); v != "" {
+			return []string{v}
+		}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// tlsOptions flattens the sing-box tls object into the fields every target
+// format needs.
+type tlsOptions struct {
+	enabled     bool
+	serverName  string
+	insecure    bool
+	alpn        []string
+	fingerprint string
+	realityKey  string
+	realitySID  string
+}
+
+func readTLS(m map[string]any) tlsOptions {
+	tls := obj(m, "tls")
+	if tls == nil {
+		return tlsOptions{}
+	}
+	opts := tlsOptions{
+		enabled:    flag(tls, "enabled"),
+		serverName: str(tls, "server_name"),
+		insecure:   flag(tls, "insecure"),
+		alpn:       strList(tls, "alpn"),
+	}
+	if utls := obj(tls, "utls"); utls != nil && flag(utls, "enabled") {
+		opts.fingerprint = str(utls, "fingerprint")
+	}
+	if reality := obj(tls, "reality"); reality != nil && flag(reality, "enabled") {
+		opts.realityKey = str(reality, "public_key")
+		opts.realitySID = str(reality, "short_id")
+	}
+	return opts
+}
+
+// transportOptions flattens the sing-box v2ray transport object.
+type transportOptions struct {
+	typ         string
+	path        string
+	host        string
+	serviceName string
+}
+
+func readTransport(m map[string]any) transportOptions {
+	t := obj(m, "transport")
+	if t == nil {
+		return transportOptions{}
+	}
+	opts := transportOptions{
+		typ:         str(t, "type"),
+		path:        str(t, "path"),
+		serviceName: str(t, "service_name"),
+	}
+	if headers := obj(t, "headers"); headers != nil {
+		opts.host = str(headers, "Host")
+	}
+	if opts.host == "" {
+		if hosts := strList(t, "host"); len(hosts) > 0 {
+			opts.host = hosts[0]
+		}
+	}
+	return opts
+}
+
+// splitPluginOpts parses "key=value;flag" SIP003 plugin options.
+func splitPluginOpts(raw string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, found := strings.Cut(part, "=")
+		if !found {
+			v = "true"
+		}
+		out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return out
+}
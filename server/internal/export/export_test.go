@@ -0,0 +1,167 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"boxpilot/server/internal/parser"
+)
+
+var testNodes = []Node{
+	{Tag: "vmess-ws", Type: "vmess", OutboundJSON: `{"type":"vmess","tag":"vmess-ws","server":"v.example.com","server_port":443,"uuid":"11111111-1111-1111-1111-111111111111","security":"auto","alter_id":0,"transport":{"type":"ws","path":"/ws","headers":{"Host":"cdn.example.com"}},"tls":{"enabled":true,"server_name":"v.example.com"}}`},
+	{Tag: "vless-reality", Type: "vless", OutboundJSON: `{"type":"vless","tag":"vless-reality","server":"1.2.3.4","server_port":443,"uuid":"22222222-2222-2222-2222-222222222222","flow":"xtls-rprx-vision","tls":{"enabled":true,"server_name":"www.microsoft.com","utls":{"enabled":true,"fingerprint":"chrome"},"reality":{"enabled":true,"public_key":"pbk","short_id":"abcd"}}}`},
+	{Tag: "ss", Type: "shadowsocks", OutboundJSON: `{"type":"shadowsocks","tag":"ss","server":"s.example.com","server_port":8388,"method":"aes-128-gcm","password":"p@ss"}`},
+	{Tag: "trojan grpc", Type: "trojan", OutboundJSON: `{"type":"trojan","tag":"trojan grpc","server":"t.example.com","server_port":443,"password":"secret","transport":{"type":"grpc","service_name":"svc"},"tls":{"enabled":true,"server_name":"t.example.com","insecure":true}}`},
+	{Tag: "hy2", Type: "hysteria2", OutboundJSON: `{"type":"hysteria2","tag":"hy2","server":"h.example.com","server_port":8443,"password":"pw","obfs":{"type":"salamander","password":"ob"},"tls":{"enabled":true,"server_name":"h.example.com"}}`},
+	{Tag: "stls-ss", Type: "shadowsocks", OutboundJSON: `{"type":"shadowsocks","tag":"stls-ss","server":"x.example.com","server_port":443,"method":"2022-blake3-aes-128-gcm","password":"key","detour":"stls-ss-shadowtls"}`},
+	{Tag: "stls-ss-shadowtls", Type: "shadowtls", OutboundJSON: `{"type":"shadowtls","tag":"stls-ss-shadowtls","server":"x.example.com","server_port":443,"version":3,"password":"stls","tls":{"enabled":true,"server_name":"www.apple.com"}}`},
+	{Tag: "wg", Type: "wireguard", OutboundJSON: `{"type":"wireguard","tag":"wg","address":["10.0.0.2/32"],"private_key":"priv","peers":[{"address":"w.example.com","port":51820,"public_key":"pub","allowed_ips":["0.0.0.0/0"]}]}`},
+	{Tag: "ssh", Type: "ssh", OutboundJSON: `{"type":"ssh","tag":"ssh","server":"ssh.example.com","server_port":22}`},
+}
+
+func outboundsByTag(t *testing.T, items []parser.OutboundItem) map[string]map[string]any {
+	t.Helper()
+	out := make(map[string]map[string]any, len(items))
+	for _, item := range items {
+		var m map[string]any
+		if err := json.Unmarshal(item.Raw, &m); err != nil {
+			t.Fatalf("unmarshal %s: %v", item.Tag, err)
+		}
+		out[item.Tag] = m
+	}
+	return out
+}
+
+func skippedTags(skipped []Skipped) map[string]string {
+	out := make(map[string]string, len(skipped))
+	for _, s := range skipped {
+		out[s.Tag] = s.Reason
+	}
+	return out
+}
+
+func TestExport_ClashRoundTrip(t *testing.T) {
+	res, err := Export(FormatClash, testNodes)
+	if err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	if !strings.HasPrefix(string(res.Body), "proxies:\n    - name: vmess-ws\n      type: vmess\n") {
+		t.Fatalf("unexpected clash layout:\n%s", res.Body)
+	}
+	if reason, ok := skippedTags(res.Skipped)["ssh"]; !ok || reason == "" {
+		t.Fatalf("expected ssh to be skipped, got %+v", res.Skipped)
+	}
+	if res.Count != 7 {
+		t.Fatalf("expected 7 proxies (shadowtls folded), got %d", res.Count)
+	}
+
+	parsed, err := parser.ParseSubscriptionBundle(res.Body)
+	if err != nil {
+		t.Fatalf("parse exported clash: %v", err)
+	}
+	got := outboundsByTag(t, parsed.Outbounds)
+	vmess := got["vmess-ws"]
+	if vmess == nil || vmess["transport"].(map[string]any)["path"] != "/ws" {
+		t.Fatalf("vmess transport lost: %+v", vmess)
+	}
+	if tls := got["trojan grpc"]["tls"].(map[string]any); tls["insecure"] != true || tls["server_name"] != "t.example.com" {
+		t.Fatalf("trojan tls lost: %+v", tls)
+	}
+	if got["stls-ss"]["detour"] != "stls-ss-shadowtls" {
+		t.Fatalf("expected shadow-tls plugin to round-trip as detour, got %+v", got["stls-ss"])
+	}
+	if stls := got["stls-ss-shadowtls"]; stls == nil || stls["password"] != "stls" {
+		t.Fatalf("expected shadowtls detour outbound, got %+v", stls)
+	}
+	if wg := got["wg"]; wg == nil || wg["private_key"] != "priv" {
+		t.Fatalf("wireguard lost: %+v", wg)
+	}
+}
+
+func TestExport_URIRoundTrip(t *testing.T) {
+	res, err := Export(FormatURI, testNodes)
+	if err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(res.Body))
+	if err != nil {
+		t.Fatalf("uri list is not base64: %v", err)
+	}
+	if !strings.Contains(string(decoded), "hy2://pw@h.example.com:8443?") {
+		t.Fatalf("missing hy2 link:\n%s", decoded)
+	}
+	skipped := skippedTags(res.Skipped)
+	for _, tag := range []string{"stls-ss", "stls-ss-shadowtls", "wg", "ssh"} {
+		if _, ok := skipped[tag]; !ok {
+			t.Fatalf("expected %s to be skipped, got %+v", tag, res.Skipped)
+		}
+	}
+
+	parsed, err := parser.ParseSubscriptionBundle(res.Body)
+	if err != nil {
+		t.Fatalf("parse exported uri list: %v", err)
+	}
+	got := outboundsByTag(t, parsed.Outbounds)
+	if len(got) != 5 {
+		t.Fatalf("expected 5 outbounds, got %d: %v", len(got), got)
+	}
+	if ss := got["ss"]; ss["password"] != "p@ss" || ss["method"] != "aes-128-gcm" {
+		t.Fatalf("ss credentials lost: %+v", ss)
+	}
+	reality := got["vless-reality"]["tls"].(map[string]any)["reality"].(map[string]any)
+	if reality["public_key"] != "pbk" || reality["short_id"] != "abcd" {
+		t.Fatalf("reality options lost: %+v", reality)
+	}
+	if got["trojan grpc"]["transport"].(map[string]any)["service_name"] != "svc" {
+		t.Fatalf("trojan grpc service lost: %+v", got["trojan grpc"])
+	}
+	if obfs := got["hy2"]["obfs"].(map[string]any); obfs["password"] != "ob" {
+		t.Fatalf("hy2 obfs lost: %+v", obfs)
+	}
+}
+
+func TestExport_SingboxSplitsEndpoints(t *testing.T) {
+	res, err := Export(FormatSingbox, testNodes)
+	if err != nil {
+		t.Fatalf("Export returned error: %v", err)
+	}
+	var doc struct {
+		Outbounds []map[string]any `json:"outbounds"`
+		Endpoints []map[string]any `json:"endpoints"`
+	}
+	if err := json.Unmarshal(res.Body, &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(doc.Outbounds) != 8 || len(doc.Endpoints) != 1 || doc.Endpoints[0]["tag"] != "wg" {
+		t.Fatalf("unexpected split: %d outbounds, endpoints %+v", len(doc.Outbounds), doc.Endpoints)
+	}
+	if len(res.Skipped) != 0 {
+		t.Fatalf("sing-box export should keep every node, skipped %+v", res.Skipped)
+	}
+}
+
+func TestExport_UnknownFormat(t *testing.T) {
+	if _, err := Export("v2ray", testNodes); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestFormatForUserAgent(t *testing.T) {
+	cases := map[string]string{
+		"ClashMetaForAndroid/2.10.1.Meta": FormatClash,
+		"clash.meta":                      FormatClash,
+		"mihomo/1.18.3":                   FormatClash,
+		"Stash/2.4.7 Clash/1.9.0":         FormatClash,
+		"SFA/1.9.3 (sing-box 1.9.3)":      FormatSingbox,
+		"sing-box 1.11.0":                 FormatSingbox,
+		"v2rayN/6.42":                     FormatURI,
+		"":                                FormatURI,
+	}
+	for ua, want := range cases {
+		if got := FormatForUserAgent(ua); got != want {
+			t.Fatalf("FormatForUserAgent(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// exportURI renders vmess / vless / ss / trojan / hy2 share links, one per line,
+// and base64-encodes the list the way subscription servers serve it.
+func exportURI(list []outbound) (Result, error) {
+	lines := make([]string, 0, len(list))
+	var skipped []Skipped
+	for _, ob := range list {
+		link, reason := shareLink(ob)
+		if link == "" {
+			skipped = append(skipped, Skipped{Tag: ob.tag, Type: ob.typ, Reason: reason})
+			continue
+		}
+		lines = append(lines, link)
+	}
+	body := []byte(base64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n"))))
+	return Result{Body: body, ContentType: "text/plain; charset=utf-8", Count: len(lines), Skipped: skipped}, nil
+}
+
+func shareLink(ob outbound) (string, string) {
+	m := ob.m
+	switch ob.typ {
+	case "vmess", "vless", "shadowsocks", "trojan", "hysteria2":
+	default:
+		return "", "no share link format for protocol"
+	}
+	if str(m, "detour") != "" {
+		return "", "detour chains cannot be expressed as a share link"
+	}
+	server := str(m, "server")
+	port := num(m, "server_port")
+	if server == "" || port <= 0 {
+		return "", "missing server or port"
+	}
+	hostPort := net.JoinHostPort(server, strconv.Itoa(port))
+	tls := readTLS(m)
+	switch ob.typ {
+	case "vmess":
+		return vmessLink(ob.tag, server, port, m, tls), ""
+	case "vless":
+		q := url.Values{}
+		q.Set("encryption", "none")
+		if flow := str(m, "flow"); flow != "" {
+			q.Set("flow", flow)
+		}
+		setTransportQuery(q, readTransport(m))
+		setTLSQuery(q, tls)
+		return userLink("vless", str(m, "uuid"), hostPort, q, ob.tag), ""
+	case "trojan":
+		q := url.Values{}
+		setTransportQuery(q, readTransport(m))
+		setTLSQuery(q, tls)
+		return userLink("trojan", str(m, "password"), hostPort, q, ob.tag), ""
+	case "hysteria2":
+		q := url.Values{}
+		if obfs := obj(m, "obfs"); obfs != nil && str(obfs, "type") != "" {
+			q.Set("obfs", str(obfs, "type"))
+			q.Set("obfs-password", str(obfs, "password"))
+		}
+		if up := num(m, "up_mbps"); up > 0 {
+			q.Set("up", strconv.Itoa(up))
+		}
+		if down := num(m, "down_mbps"); down > 0 {
+			q.Set("down", strconv.Itoa(down))
+		}
+		if tls.serverName != "" {
+			q.Set("sni", tls.serverName)
+		}
+		if tls.insecure {
+			q.Set("insecure", "1")
+		}
+		if len(tls.alpn) > 0 {
+			q.Set("alpn", strings.Join(tls.alpn, ","))
+		}
+		return userLink("hy2", str(m, "password"), hostPort, q, ob.tag), ""
+	default:
+		// SIP002: ss://base64url(method:password)@host:port/?plugin=...#tag
+		userInfo := base64.RawURLEncoding.EncodeToString([]byte(str(m, "method") + ":" + str(m, "password")))
+		link := "ss://" + userInfo + "@" + hostPort
+		if plugin := str(m, "plugin"); plugin != "" {
+			value := plugin
+			if opts := str(m, "plugin_opts"); opts != "" {
+				value += ";" + opts
+			}
+			link += "/?plugin=" + url.QueryEscape(value)
+		}
+		return link + "#" + url.PathEscape(ob.tag), ""
+	}
+}
+
+func userLink(scheme, user, hostPort string, q url.Values, tag string) string {
+	u := url.URL{
+		Scheme:   scheme,
+		User:     url.User(user),
+		Host:     hostPort,
+		RawQuery: q.Encode(),
+		Fragment: tag,
+	}
+	return u.String()
+}
+
+func setTransportQuery(q url.Values, t transportOptions) {
+	switch t.typ {
+	case "ws", "httpupgrade", "http":
+		q.Set("type", t.typ)
+		if t.path != "" {
+			q.Set("path", t.path)
+		}
+		if t.host != "" {
+			q.Set("host", t.host)
+		}
+	case "grpc":
+		q.Set("type", "grpc")
+		if t.serviceName != "" {
+			q.Set("serviceName", t.serviceName)
+		}
+	default:
+		q.Set("type", "tcp")
+	}
+}
+
+func setTLSQuery(q url.Values, tls tlsOptions) {
+	switch {
+	case tls.realityKey != "":
+		q.Set("security", "reality")
+		q.Set("pbk", tls.realityKey)
+		if tls.realitySID != "" {
+			q.Set("sid", tls.realitySID)
+		}
+	case tls.enabled:
+		q.Set("security", "tls")
+	default:
+		q.Set("security", "none")
+		return
+	}
+	if tls.serverName != "" {
+		q.Set("sni", tls.serverName)
+	}
+	if tls.fingerprint != "" {
+		q.Set("fp", tls.fingerprint)
+	}
+	if len(tls.alpn) > 0 {
+		q.Set("alpn", strings.Join(tls.alpn, ","))
+	}
+	if tls.insecure {
+		q.Set("allowInsecure", "1")
+	}
+}
+
+// vmessLink renders the v2rayN JSON form: vmess://base64(json).
+func vmessLink(tag, server string, port int, m map[string]any, tls tlsOptions) string {
+	t := readTransport(m)
+	doc := map[string]string{
+		"v":    "2",
+		"ps":   tag,
+		"add":  server,
+		"port": strconv.Itoa(port),
+		"id":   str(m, "uuid"),
+		"aid":  strconv.Itoa(num(m, "alter_id")),
+		"scy":  orDefault(str(m, "security"), "auto"),
+		"net":  "tcp",
+		"type": "none",
+	}
+	switch t.typ {
+	case "ws", "httpupgrade", "grpc":
+		doc["net"] = t.typ
+		doc["host"] = t.host
+		doc["path"] = t.path
+		if t.typ == "grpc" {
+			doc["path"] = t.serviceName
+		}
+	case "http":
+		doc["net"] = "h2"
+		doc["host"] = t.host
+		doc["path"] = t.path
+	}
+	if tls.enabled {
+		doc["tls"] = "tls"
+		doc["sni"] = tls.serverName
+		doc["fp"] = tls.fingerprint
+		doc["alpn"] = strings.Join(tls.alpn, ",")
+	}
+	raw, _ := json.Marshal(doc)
+	return "vmess://" + base64.StdEncoding.EncodeToString(raw)
+}
@@ -61,8 +61,87 @@ type RoutingExtras struct {
 	Rules             []RouteRule
 	GroupSelections   map[string]string
 	BusinessNodePools map[string][]string
-	AutoTestURL       string
-	AutoTestInterval  string
+	// BusinessGroupPolicies holds the source group semantics keyed by business target.
+	BusinessGroupPolicies map[string]BusinessGroupPolicy
+	AutoTestURL           string
+	AutoTestInterval      string
+	// FetchProxy exposes selected nodes on a loopback inbound so subscriptions
+	// can be fetched through them.
+	FetchProxy FetchProxy
+}
+
+// FetchProxy is a loopback-only mixed inbound; each user is routed to one node.
+type FetchProxy struct {
+	Port  int
+	Users []FetchProxyUser
+}
+
+type FetchProxyUser struct {
+	Username string
+	Password string
+	Outbound string
+}
+
+// BusinessGroupPolicy describes a subscription url-test / fallback / load-balance
+// group. url-test renders as a urltest the business selector defaults to.
+// fallback renders as a urltest over the members in their original order with
+// fallbackTolerance, so it settles on the first member that passes the check
+// and only moves when that member fails. sing-box has no load balancer, so
+// load-balance renders as a plain selector and BusinessGroupInfo.Unsupported
+// says why.
+type BusinessGroupPolicy struct {
+	Type      string
+	URL       string
+	Interval  string
+	Tolerance int
+	Strategy  string
+}
+
+// BusinessGroupInfo describes the selector rendered for one business target.
+type BusinessGroupInfo struct {
+	Target      string
+	AutoTag     string
+	Policy      BusinessGroupPolicy
+	Unsupported string
+}
+
+const fetchProxyInboundTag = "fetch-in"
+
+// buildFetchProxy renders the fetch-in inbound and the auth_user rules that pin
+// each user to its node. Users whose node is not part of the config are dropped.
+// The rules must precede the bypass rules so fetches never leak out directly.
+func buildFetchProxy(fp FetchProxy, nodeTags []string) (map[string]any, []map[string]any) {
+	if fp.Port <= 0 || len(fp.Users) == 0 {
+		return nil, nil
+	}
+	available := make(map[string]struct{}, len(nodeTags))
+	for _, tag := range nodeTags {
+		available[tag] = struct{}{}
+	}
+	users := make([]map[string]any, 0, len(fp.Users))
+	rules := make([]map[string]any, 0, len(fp.Users))
+	for _, u := range fp.Users {
+		if _, ok := available[u.Outbound]; !ok {
+			continue
+		}
+		users = append(users, map[string]any{"username": u.Username, "password": u.Password})
+		rules = append(rules, map[string]any{
+			"inbound":   []string{fetchProxyInboundTag},
+			"auth_user": []string{u.Username},
+			"outbound":  u.Outbound,
+		})
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	inbound := map[string]any{
+		"type":        "mixed",
+		"tag":         fetchProxyInboundTag,
+		"listen":      "127.0.0.1",
+		"listen_port": fp.Port,
+		"users":       users,
+	}
+	return inbound, rules
 }
 
 func DefaultRoutingSettings() RoutingSettings {
@@ -96,6 +175,13 @@ func BuildConfigWithNodes(httpProxy ProxyInbound, socksProxy ProxyInbound, routi
 }
 
 func BuildConfigWithRuntime(httpProxy ProxyInbound, socksProxy ProxyInbound, routing RoutingSettings, nodes []NodeOutbound, extras RoutingExtras) ([]byte, error) {
+	cfg, _, err := BuildConfigWithRuntimeInfo(httpProxy, socksProxy, routing, nodes, extras)
+	return cfg, err
+}
+
+// BuildConfigWithRuntimeInfo is BuildConfigWithRuntime that also reports the
+// business selectors it rendered, keyed by selector tag.
+func BuildConfigWithRuntimeInfo(httpProxy ProxyInbound, socksProxy ProxyInbound, routing RoutingSettings, nodes []NodeOutbound, extras RoutingExtras) ([]byte, map[string]BusinessGroupInfo, error) {
 	inbounds := []map[string]any{}
 	if httpProxy.Enabled {
 		inbounds = append(inbounds, buildInbound("http", "http-in", httpProxy))
@@ -107,7 +193,10 @@ func BuildConfigWithRuntime(httpProxy ProxyInbound, socksProxy ProxyInbound, rou
 		map[string]any{"type": "direct", "tag": "direct"},
 		map[string]any{"type": "block", "tag": "block"},
 	}
+	var endpoints []any
 	var tags []string
+	nodes = dropBrokenDetourChains(nodes)
+	detourTargets := collectDetourTargets(nodes)
 	for _, node := range nodes {
 		raw := strings.TrimSpace(node.RawJSON)
 		if !json.Valid([]byte(raw)) {
@@ -116,16 +205,25 @@ func BuildConfigWithRuntime(httpProxy ProxyInbound, socksProxy ProxyInbound, rou
 
 		// Inject optimizations into the node outbound JSON
 		var m map[string]any
+		typ := ""
 		if err := json.Unmarshal([]byte(raw), &m); err == nil {
-			typ := strings.ToLower(fmt.Sprintf("%v", m["type"]))
-			if typ == "vless" || typ == "vmess" || typ == "trojan" || typ == "shadowsocks" {
+			typ = strings.ToLower(fmt.Sprintf("%v", m["type"]))
+			_, chained := m["detour"]
+			if !chained && (typ == "vless" || typ == "vmess" || typ == "trojan" || typ == "shadowsocks") {
 				m["tcp_fast_open"] = true
 				m["domain_strategy"] = "ipv4_only"
 			}
+			var item json.RawMessage
 			if optimized, err := json.Marshal(m); err == nil {
-				outbounds = append(outbounds, json.RawMessage(optimized))
+				item = optimized
 			} else {
-				outbounds = append(outbounds, json.RawMessage(raw))
+				item = json.RawMessage(raw)
+			}
+			// WireGuard is an endpoint in sing-box 1.11+, not an outbound.
+			if typ == "wireguard" {
+				endpoints = append(endpoints, item)
+			} else {
+				outbounds = append(outbounds, item)
 			}
 		} else {
 			outbounds = append(outbounds, json.RawMessage(raw))
@@ -135,6 +233,11 @@ func BuildConfigWithRuntime(httpProxy ProxyInbound, socksProxy ProxyInbound, rou
 		if tag == "" {
 			tag = parseTagFromOutbound(raw)
 		}
+		// Detour-only outbounds (shadowtls under shadowsocks) are not usable
+		// on their own, so keep them out of selector/urltest pools.
+		if _, ok := detourTargets[tag]; ok {
+			continue
+		}
 		if tag != "" {
 			tags = append(tags, tag)
 		}
@@ -213,6 +316,10 @@ func BuildConfigWithRuntime(httpProxy ProxyInbound, socksProxy ProxyInbound, rou
 		"protocol": "dns",
 		"outbound": "direct",
 	})
+	if inbound, rules := buildFetchProxy(extras.FetchProxy, tags); inbound != nil {
+		inbounds = append(inbounds, inbound)
+		routeRules = append(routeRules, rules...)
+	}
 
 	if routing.BypassPrivateEnabled {
 		if len(routing.BypassDomains) > 0 {
@@ -267,12 +374,13 @@ func BuildConfigWithRuntime(httpProxy ProxyInbound, socksProxy ProxyInbound, rou
 	}
 	routeRuleSets = append(routeRuleSets, buildRouteRuleSets(subscriptionRuleSets)...)
 
-	targetMap := buildBusinessGroups(
+	targetMap, businessInfo := buildBusinessGroups(
 		&outbounds,
 		tags,
 		extras.Rules,
 		extras.GroupSelections,
 		extras.BusinessNodePools,
+		extras.BusinessGroupPolicies,
 		extras.AutoTestURL,
 		extras.AutoTestInterval,
 	)
@@ -342,12 +450,15 @@ func BuildConfigWithRuntime(httpProxy ProxyInbound, socksProxy ProxyInbound, rou
 		"route":     route,
 		"dns":       dns,
 	}
+	if len(endpoints) > 0 {
+		cfg["endpoints"] = endpoints
+	}
 	applyClashAPI(cfg)
 	b, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
-		return nil, errorx.New(errorx.CFGJSONInvalid, "marshal config")
+		return nil, nil, errorx.New(errorx.CFGJSONInvalid, "marshal config")
 	}
-	return b, nil
+	return b, businessInfo, nil
 }
 
 func buildRouteRuleSets(extras []RouteRuleSetRef) []map[string]any {
@@ -393,9 +504,10 @@ func buildBusinessGroups(
 	rules []RouteRule,
 	selections map[string]string,
 	businessNodePools map[string][]string,
+	policies map[string]BusinessGroupPolicy,
 	autoTestURL string,
 	autoTestInterval string,
-) map[string]string {
+) (map[string]string, map[string]BusinessGroupInfo) {
 	targets := map[string]struct{}{}
 	for _, r := range rules {
 		target := strings.TrimSpace(r.TargetOutbound)
@@ -405,8 +517,9 @@ func buildBusinessGroups(
 		targets[target] = struct{}{}
 	}
 	result := map[string]string{}
+	info := map[string]BusinessGroupInfo{}
 	if len(targets) == 0 {
-		return result
+		return result, info
 	}
 	used := map[string]struct{}{
 		"direct": {},
@@ -436,6 +549,8 @@ func buildBusinessGroups(
 		autoTag := resolveUniqueTag(selectorTag+"-auto", used)
 		used[autoTag] = struct{}{}
 		result[target] = selectorTag
+		policy := policies[target]
+		groupInfo := BusinessGroupInfo{Target: target, Policy: policy, Unsupported: unsupportedGroupPolicy(policy)}
 		poolMembers := businessNodePools[target]
 		autoMembers := filterExistingNodeTags(nodeTags, poolMembers)
 		manualMembers := filterExistingGroupMembers(nodeTags, poolMembers)
@@ -446,11 +561,12 @@ func buildBusinessGroups(
 				"type":      "urltest",
 				"tag":       autoTag,
 				"outbounds": autoMembers,
-				"url":       autoURL,
-				"interval":  autoInterval,
-				"tolerance": 120,
+				"url":       defaultString(strings.TrimSpace(policy.URL), autoURL),
+				"interval":  defaultString(strings.TrimSpace(policy.Interval), autoInterval),
+				"tolerance": businessGroupTolerance(policy),
 			})
 			selectorOutbounds = append([]string{autoTag}, selectorOutbounds...)
+			groupInfo.AutoTag = autoTag
 		}
 		seenMember := map[string]struct{}{}
 		for _, existing := range selectorOutbounds {
@@ -468,6 +584,9 @@ func buildBusinessGroups(
 			selectorOutbounds = append(selectorOutbounds, tag)
 		}
 		selectedDefault := "manual"
+		if policy.Type != "" && groupInfo.Unsupported == "" && groupInfo.AutoTag != "" {
+			selectedDefault = groupInfo.AutoTag
+		}
 		if selected, ok := selections[selectorTag]; ok && containsString(selectorOutbounds, selected) {
 			selectedDefault = selected
 		}
@@ -477,8 +596,37 @@ func buildBusinessGroups(
 			"outbounds": selectorOutbounds,
 			"default":   selectedDefault,
 		})
+		info[selectorTag] = groupInfo
+	}
+	return result, info
+}
+
+// fallbackTolerance is wider than any delay a URL test can report, so a
+// fallback urltest never leaves a healthy member for a faster one: it picks the
+// first member in order with a passing test. It stays well below 65535 because
+// sing-box adds it to a uint16 delay.
+const fallbackTolerance = 30000
+
+func businessGroupTolerance(policy BusinessGroupPolicy) int {
+	if policy.Type == "fallback" {
+		return fallbackTolerance
+	}
+	if policy.Tolerance > 0 {
+		return policy.Tolerance
 	}
-	return result
+	return 120
+}
+
+// unsupportedGroupPolicy explains why a policy cannot be rendered; the group
+// then falls back to a plain selector instead of pretending to be a urltest.
+func unsupportedGroupPolicy(policy BusinessGroupPolicy) string {
+	if policy.Type != "load-balance" {
+		return ""
+	}
+	if policy.Strategy != "" {
+		return "sing-box has no load balancer; load-balance (" + policy.Strategy + ") is rendered as a plain selector"
+	}
+	return "sing-box has no load balancer; load-balance is rendered as a plain selector"
 }
 
 func filterExistingNodeTags(availableNodeTags, preferredNodeTags []string) []string {
@@ -601,6 +749,55 @@ func defaultString(value, fallback string) string {
 	return value
 }
 
+// collectDetourTargets returns the tags that other node outbounds dial through.
+func collectDetourTargets(nodes []NodeOutbound) map[string]struct{} {
+	out := map[string]struct{}{}
+	for _, node := range nodes {
+		if detour := parseDetourFromOutbound(node.RawJSON); detour != "" {
+			out[detour] = struct{}{}
+		}
+	}
+	return out
+}
+
+// dropBrokenDetourChains removes nodes whose detour upstream is not part of the
+// node set (e.g. filtered out by the forwarding policy); sing-box refuses to
+// start with a dangling detour.
+func dropBrokenDetourChains(nodes []NodeOutbound) []NodeOutbound {
+	available := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		tag := strings.TrimSpace(node.Tag)
+		if tag == "" {
+			tag = parseTagFromOutbound(node.RawJSON)
+		}
+		available[tag] = struct{}{}
+	}
+	out := make([]NodeOutbound, 0, len(nodes))
+	for _, node := range nodes {
+		if detour := parseDetourFromOutbound(node.RawJSON); detour != "" {
+			if _, ok := available[detour]; !ok {
+				continue
+			}
+		}
+		out = append(out, node)
+	}
+	return out
+}
+
+func parseDetourFromOutbound(raw string) string {
+	if !strings.Contains(raw, `"detour"`) {
+		return ""
+	}
+	var m map[string]any
+	if json.Unmarshal([]byte(raw), &m) != nil {
+		return ""
+	}
+	if detour, ok := m["detour"].(string); ok {
+		return strings.TrimSpace(detour)
+	}
+	return ""
+}
+
 func parseTagFromOutbound(raw string) string {
 	var m map[string]any
 	if json.Unmarshal([]byte(raw), &m) != nil {
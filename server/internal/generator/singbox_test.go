@@ -169,6 +169,63 @@ func TestBuildConfigWithNodes_UsesProvidedTags(t *testing.T) {
 	}
 }
 
+func TestBuildConfigWithNodes_DetourChainsAndEndpoints(t *testing.T) {
+	cfg, err := BuildConfigWithNodes(
+		ProxyInbound{Type: "http", ListenAddress: "0.0.0.0", Port: 7890, Enabled: true},
+		ProxyInbound{Type: "socks", ListenAddress: "0.0.0.0", Port: 7891, Enabled: true},
+		RoutingSettings{BypassPrivateEnabled: false},
+		[]NodeOutbound{
+			{Tag: "ss", RawJSON: `{"type":"shadowsocks","tag":"ss","server":"a.example.com","server_port":443,"method":"aes-128-gcm","password":"p","detour":"ss-shadowtls"}`},
+			{Tag: "ss-shadowtls", RawJSON: `{"type":"shadowtls","tag":"ss-shadowtls","server":"a.example.com","server_port":443,"version":3,"password":"x","tls":{"enabled":true}}`},
+			{Tag: "orphan", RawJSON: `{"type":"shadowsocks","tag":"orphan","server":"b.example.com","server_port":443,"method":"aes-128-gcm","password":"p","detour":"missing"}`},
+			{Tag: "wg", RawJSON: `{"type":"wireguard","tag":"wg","address":["10.0.0.2/32"],"private_key":"k","peers":[{"address":"wg.example.com","port":51820,"public_key":"p"}]}`},
+		},
+	)
+	if err != nil {
+		t.Fatalf("BuildConfigWithNodes returned error: %v", err)
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal(cfg, &parsed); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	endpoints, _ := parsed["endpoints"].([]any)
+	if len(endpoints) != 1 || endpoints[0].(map[string]any)["tag"] != "wg" {
+		t.Fatalf("expected wireguard endpoint, got %v", parsed["endpoints"])
+	}
+	for _, item := range parsed["outbounds"].([]any) {
+		outbound := item.(map[string]any)
+		tag, _ := outbound["tag"].(string)
+		switch tag {
+		case "orphan", "wg":
+			t.Fatalf("unexpected outbound %q in outbounds", tag)
+		case "ss":
+			if _, ok := outbound["tcp_fast_open"]; ok {
+				t.Fatalf("chained outbound must not get dial options: %v", outbound)
+			}
+		case "manual":
+			members, _ := outbound["outbounds"].([]any)
+			for _, m := range members {
+				if m == "ss-shadowtls" || m == "orphan" {
+					t.Fatalf("unexpected manual member %v in %v", m, members)
+				}
+			}
+			if !strings.Contains(strings.Join(toStrings(members), ","), "wg") {
+				t.Fatalf("expected wireguard endpoint in manual selector, got %v", members)
+			}
+		}
+	}
+}
+
+func toStrings(items []any) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
 func TestBuildConfigWithRuntime_BusinessGroups(t *testing.T) {
 	cfg, err := BuildConfigWithRuntime(
 		ProxyInbound{Type: "http", ListenAddress: "0.0.0.0", Port: 7890, Enabled: true},
@@ -275,6 +332,129 @@ func TestBuildConfigWithRuntime_BusinessGroups(t *testing.T) {
 	}
 }
 
+func TestBuildConfigWithRuntimeInfo_BusinessGroupPolicies(t *testing.T) {
+	nodes := []NodeOutbound{
+		{Tag: "node-a", RawJSON: `{"type":"trojan","tag":"node-a","server":"example.com","server_port":443,"password":"p"}`},
+		{Tag: "node-b", RawJSON: `{"type":"trojan","tag":"node-b","server":"example.org","server_port":443,"password":"p"}`},
+	}
+	cfg, info, err := BuildConfigWithRuntimeInfo(
+		ProxyInbound{Type: "http", ListenAddress: "0.0.0.0", Port: 7890, Enabled: true},
+		ProxyInbound{Type: "socks", ListenAddress: "0.0.0.0", Port: 7891},
+		RoutingSettings{},
+		nodes,
+		RoutingExtras{
+			Rules: []RouteRule{
+				{MatcherType: "domain_suffix", MatcherValue: "openai.com", TargetOutbound: "OpenAI"},
+				{MatcherType: "domain_suffix", MatcherValue: "netflix.com", TargetOutbound: "Netflix"},
+				{MatcherType: "domain_suffix", MatcherValue: "github.com", TargetOutbound: "GitHub"},
+			},
+			BusinessNodePools: map[string][]string{
+				"OpenAI":  {"node-a", "node-b"},
+				"Netflix": {"node-a", "node-b"},
+				"GitHub":  {"node-a", "node-b"},
+			},
+			BusinessGroupPolicies: map[string]BusinessGroupPolicy{
+				"OpenAI":  {Type: "url-test", URL: "https://cp.example.com/204", Interval: "300s", Tolerance: 50},
+				"Netflix": {Type: "fallback"},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("BuildConfigWithRuntimeInfo returned error: %v", err)
+	}
+	var parsed struct {
+		Outbounds []map[string]any `json:"outbounds"`
+	}
+	if err := json.Unmarshal(cfg, &parsed); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	byTag := map[string]map[string]any{}
+	for _, outbound := range parsed.Outbounds {
+		tag, _ := outbound["tag"].(string)
+		byTag[tag] = outbound
+	}
+
+	openAI := byTag["biz-OpenAI-auto"]
+	if openAI["url"] != "https://cp.example.com/204" || openAI["interval"] != "300s" || openAI["tolerance"] != float64(50) {
+		t.Fatalf("expected url-test policy on auto group, got %+v", openAI)
+	}
+	if byTag["biz-OpenAI"]["default"] != "biz-OpenAI-auto" {
+		t.Fatalf("expected url-test selector to default to auto group, got %+v", byTag["biz-OpenAI"])
+	}
+	if byTag["biz-Netflix-auto"]["tolerance"] != float64(fallbackTolerance) || byTag["biz-Netflix"]["default"] != "biz-Netflix-auto" {
+		t.Fatalf("unexpected fallback rendering: %+v / %+v", byTag["biz-Netflix-auto"], byTag["biz-Netflix"])
+	}
+	if byTag["biz-GitHub"]["default"] != "manual" || byTag["biz-GitHub-auto"]["url"] != DefaultAutoTestURL {
+		t.Fatalf("expected plain selector defaults for GitHub, got %+v / %+v", byTag["biz-GitHub"], byTag["biz-GitHub-auto"])
+	}
+
+	if got := info["biz-OpenAI"]; got.Target != "OpenAI" || got.AutoTag != "biz-OpenAI-auto" || got.Policy.Type != "url-test" || got.Unsupported != "" {
+		t.Fatalf("unexpected business info for OpenAI: %+v", got)
+	}
+	if got := info["biz-GitHub"]; got.Target != "GitHub" || got.Policy.Type != "" {
+		t.Fatalf("unexpected business info for GitHub: %+v", got)
+	}
+}
+
+func buildSingleBusinessGroup(t *testing.T, pool []string, policy BusinessGroupPolicy) (map[string]map[string]any, BusinessGroupInfo) {
+	t.Helper()
+	nodes := []NodeOutbound{
+		{Tag: "node-a", RawJSON: `{"type":"trojan","tag":"node-a","server":"example.com","server_port":443,"password":"p"}`},
+		{Tag: "node-b", RawJSON: `{"type":"trojan","tag":"node-b","server":"example.org","server_port":443,"password":"p"}`},
+		{Tag: "node-c", RawJSON: `{"type":"trojan","tag":"node-c","server":"example.net","server_port":443,"password":"p"}`},
+	}
+	cfg, info, err := BuildConfigWithRuntimeInfo(
+		ProxyInbound{Type: "http", ListenAddress: "0.0.0.0", Port: 7890, Enabled: true},
+		ProxyInbound{Type: "socks", ListenAddress: "0.0.0.0", Port: 7891},
+		RoutingSettings{},
+		nodes,
+		RoutingExtras{
+			Rules:                 []RouteRule{{MatcherType: "domain_suffix", MatcherValue: "example.com", TargetOutbound: "Media"}},
+			BusinessNodePools:     map[string][]string{"Media": pool},
+			BusinessGroupPolicies: map[string]BusinessGroupPolicy{"Media": policy},
+		},
+	)
+	if err != nil {
+		t.Fatalf("BuildConfigWithRuntimeInfo returned error: %v", err)
+	}
+	var parsed struct {
+		Outbounds []map[string]any `json:"outbounds"`
+	}
+	if err := json.Unmarshal(cfg, &parsed); err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	byTag := map[string]map[string]any{}
+	for _, outbound := range parsed.Outbounds {
+		tag, _ := outbound["tag"].(string)
+		byTag[tag] = outbound
+	}
+	return byTag, info["biz-Media"]
+}
+
+func TestBuildConfigWithRuntimeInfo_FallbackKeepsPriorityOrder(t *testing.T) {
+	byTag, info := buildSingleBusinessGroup(t, []string{"node-c", "node-a", "node-b"}, BusinessGroupPolicy{Type: "fallback", Tolerance: 50})
+	auto := byTag["biz-Media-auto"]
+	if auto["type"] != "urltest" || auto["tolerance"] != float64(fallbackTolerance) {
+		t.Fatalf("expected fallback urltest with fallback tolerance, got %+v", auto)
+	}
+	if got := toStrings(auto["outbounds"].([]any)); strings.Join(got, ",") != "node-c,node-a,node-b" {
+		t.Fatalf("expected fallback members in priority order, got %v", got)
+	}
+	if byTag["biz-Media"]["default"] != "biz-Media-auto" || info.Unsupported != "" {
+		t.Fatalf("unexpected fallback selector: %+v / %+v", byTag["biz-Media"], info)
+	}
+}
+
+func TestBuildConfigWithRuntimeInfo_LoadBalanceIsReportedUnsupported(t *testing.T) {
+	byTag, info := buildSingleBusinessGroup(t, []string{"node-a", "node-b"}, BusinessGroupPolicy{Type: "load-balance", Strategy: "round-robin"})
+	if byTag["biz-Media"]["default"] != "manual" {
+		t.Fatalf("expected load-balance to render as a plain selector, got %+v", byTag["biz-Media"])
+	}
+	if info.Policy.Strategy != "round-robin" || !strings.Contains(info.Unsupported, "round-robin") {
+		t.Fatalf("expected load-balance reported as unsupported, got %+v", info)
+	}
+}
+
 func TestBuildConfigWithRuntime_BusinessGroupsWithoutPool(t *testing.T) {
 	cfg, err := BuildConfigWithRuntime(
 		ProxyInbound{Type: "http", ListenAddress: "0.0.0.0", Port: 7890, Enabled: true},
@@ -509,3 +689,53 @@ func TestBuildConfigWithRuntime_SkipsDuplicateCNRuleSetTags(t *testing.T) {
 		t.Fatalf("expected subscription geosite-ads rule_set preserved, got counts %#v", seen)
 	}
 }
+
+func TestBuildConfigWithRuntime_FetchProxyInbound(t *testing.T) {
+	cfg, err := BuildConfigWithRuntime(
+		ProxyInbound{Type: "http", ListenAddress: "0.0.0.0", Port: 7890, Enabled: true},
+		ProxyInbound{Type: "socks", ListenAddress: "0.0.0.0", Port: 7891, Enabled: true},
+		DefaultRoutingSettings(),
+		[]NodeOutbound{
+			{Tag: "n1", RawJSON: `{"type":"trojan","tag":"n1","server":"x.com","server_port":443,"password":"p"}`},
+		},
+		RoutingExtras{
+			FetchProxy: FetchProxy{Port: 17899, Users: []FetchProxyUser{
+				{Username: "fetch-n1", Password: "pw", Outbound: "n1"},
+				{Username: "fetch-gone", Password: "pw", Outbound: "gone"},
+			}},
+		},
+	)
+	if err != nil {
+		t.Fatalf("BuildConfigWithRuntime: %v", err)
+	}
+	var parsed struct {
+		Inbounds []map[string]any `json:"inbounds"`
+		Route    struct {
+			Rules []map[string]any `json:"rules"`
+		} `json:"route"`
+	}
+	if err := json.Unmarshal(cfg, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	var fetchIn map[string]any
+	for _, in := range parsed.Inbounds {
+		if in["tag"] == fetchProxyInboundTag {
+			fetchIn = in
+		}
+	}
+	if fetchIn == nil || fetchIn["listen"] != "127.0.0.1" || fetchIn["listen_port"] != float64(17899) {
+		t.Fatalf("expected loopback fetch inbound, got %+v", parsed.Inbounds)
+	}
+	if users, _ := fetchIn["users"].([]any); len(users) != 1 {
+		t.Fatalf("expected only users for config nodes, got %+v", fetchIn["users"])
+	}
+	var fetchRule map[string]any
+	for _, rule := range parsed.Route.Rules {
+		if users, ok := rule["auth_user"].([]any); ok && len(users) == 1 && users[0] == "fetch-n1" {
+			fetchRule = rule
+		}
+	}
+	if fetchRule == nil || fetchRule["outbound"] != "n1" {
+		t.Fatalf("expected auth_user rule routing to n1, got %+v", parsed.Route.Rules)
+	}
+}
@@ -1,12 +1,203 @@
 package observability
 
-import "log"
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
 
-// Logger wraps structured logging. For MVP use log.Printf.
-func Info(msg string, kvs ...any) {
-	log.Println("[INFO]", msg, kvs)
+// Subsystems with their own level in BOXPILOT_LOG_LEVEL. Logger accepts any
+// name; these are the ones the server uses.
+const (
+	SubsystemServer    = "server"
+	SubsystemHTTP      = "http"
+	SubsystemScheduler = "scheduler"
+	SubsystemRefresh   = "refresh"
+	SubsystemParser    = "parser"
+	SubsystemApply     = "apply"
+	SubsystemProbe     = "probe"
+	SubsystemAlerts    = "alerts"
+	SubsystemWebhooks  = "webhooks"
+	SubsystemTraffic   = "traffic"
+	SubsystemRuntime   = "runtime"
+	SubsystemAuth      = "auth"
+)
+
+const (
+	defaultLogFileMaxMB   = 10
+	defaultLogFileBackups = 5
+)
+
+// LogConfig selects the log output. Levels overrides Level per subsystem.
+type LogConfig struct {
+	Format  string // text or json
+	Level   slog.Level
+	Levels  map[string]slog.Level
+	File    string
+	MaxSize int64
+	Backups int
+}
+
+type logState struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+}
+
+var current atomic.Pointer[logState]
+
+func init() {
+	current.Store(&logState{handler: slog.NewTextHandler(os.Stderr, nil), level: slog.LevelInfo})
+}
+
+// LogConfigFromEnv reads BOXPILOT_LOG_FORMAT, BOXPILOT_LOG_LEVEL (a default
+// level followed by subsystem=level pairs, e.g. "info,scheduler=debug"),
+// BOXPILOT_LOG_FILE, BOXPILOT_LOG_FILE_MAX_MB and BOXPILOT_LOG_FILE_BACKUPS.
+func LogConfigFromEnv() (LogConfig, error) {
+	cfg := LogConfig{
+		Format:  strings.ToLower(strings.TrimSpace(os.Getenv("BOXPILOT_LOG_FORMAT"))),
+		Level:   slog.LevelInfo,
+		File:    strings.TrimSpace(os.Getenv("BOXPILOT_LOG_FILE")),
+		MaxSize: int64(envInt("BOXPILOT_LOG_FILE_MAX_MB", defaultLogFileMaxMB)) << 20,
+		Backups: envInt("BOXPILOT_LOG_FILE_BACKUPS", defaultLogFileBackups),
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = "text"
+	case "text", "json":
+	default:
+		return cfg, fmt.Errorf("BOXPILOT_LOG_FORMAT: unknown format %q", cfg.Format)
+	}
+	level, levels, err := ParseLogLevels(os.Getenv("BOXPILOT_LOG_LEVEL"))
+	if err != nil {
+		return cfg, fmt.Errorf("BOXPILOT_LOG_LEVEL: %w", err)
+	}
+	cfg.Level, cfg.Levels = level, levels
+	return cfg, nil
+}
+
+// ParseLogLevels parses "warn,scheduler=debug,probe=error". A bare level sets
+// the default, which is info when none is given.
+func ParseLogLevels(raw string) (slog.Level, map[string]slog.Level, error) {
+	def := slog.LevelInfo
+	levels := map[string]slog.Level{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, scoped := strings.Cut(part, "=")
+		if !scoped {
+			value = name
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return def, nil, fmt.Errorf("invalid level %q", value)
+		}
+		if scoped {
+			levels[strings.ToLower(strings.TrimSpace(name))] = level
+		} else {
+			def = level
+		}
+	}
+	return def, levels, nil
+}
+
+// SetupLogging installs cfg for every logger, including ones created earlier,
+// and routes the standard log package through it. The returned closer closes
+// the log file, if any.
+func SetupLogging(cfg LogConfig) (io.Closer, error) {
+	var out io.Writer = os.Stderr
+	var closer io.Closer = io.NopCloser(nil)
+	if cfg.File != "" {
+		f, err := OpenRotatingFile(cfg.File, cfg.MaxSize, cfg.Backups)
+		if err != nil {
+			return nil, err
+		}
+		out, closer = io.MultiWriter(os.Stderr, f), f
+	}
+	// Subsystem loggers filter by level themselves.
+	opts := &slog.HandlerOptions{Level: slog.Level(-8)}
+	var h slog.Handler = slog.NewTextHandler(out, opts)
+	if cfg.Format == "json" {
+		h = slog.NewJSONHandler(out, opts)
+	}
+	current.Store(&logState{handler: h, level: cfg.Level, levels: cfg.Levels})
+	slog.SetDefault(Logger(SubsystemServer))
+	return closer, nil
+}
+
+// Logger returns the logger of a subsystem. Records carry subsystem and, when
+// the context has one, request_id; use the *Context methods to pass it.
+func Logger(subsystem string) *slog.Logger {
+	return slog.New(&subsystemHandler{subsystem: subsystem})
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the request id for logs.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request id carried by ctx, or "".
+func RequestIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// subsystemHandler resolves the installed handler on every record, so loggers
+// held in package variables follow SetupLogging.
+type subsystemHandler struct {
+	subsystem string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *subsystemHandler) Enabled(_ context.Context, level slog.Level) bool {
+	s := current.Load()
+	min, ok := s.levels[h.subsystem]
+	if !ok {
+		min = s.level
+	}
+	return level >= min
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	inner := current.Load().handler.WithAttrs([]slog.Attr{slog.String("subsystem", h.subsystem)})
+	if id := RequestIDFrom(ctx); id != "" {
+		inner = inner.WithAttrs([]slog.Attr{slog.String("request_id", id)})
+	}
+	for _, op := range h.ops {
+		inner = op(inner)
+	}
+	return inner.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithGroup(name) })
+}
+
+func (h *subsystemHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &subsystemHandler{subsystem: h.subsystem, ops: append(ops, op)}
 }
 
-func Error(msg string, kvs ...any) {
-	log.Println("[ERROR]", msg, kvs)
+func envInt(name string, fallback int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name))); err == nil && n > 0 {
+		return n
+	}
+	return fallback
 }
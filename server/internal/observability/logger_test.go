@@ -0,0 +1,96 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevels(t *testing.T) {
+	def, levels, err := ParseLogLevels(" warn , scheduler=DEBUG,probe=error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def != slog.LevelWarn || levels["scheduler"] != slog.LevelDebug || levels["probe"] != slog.LevelError {
+		t.Fatalf("unexpected levels: %v %v", def, levels)
+	}
+	if def, _, _ := ParseLogLevels(""); def != slog.LevelInfo {
+		t.Fatalf("empty should default to info, got %v", def)
+	}
+	if _, _, err := ParseLogLevels("apply=loud"); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+}
+
+func TestSubsystemLoggerLevelsAndRequestID(t *testing.T) {
+	prev := current.Load()
+	t.Cleanup(func() { current.Store(prev) })
+	var buf bytes.Buffer
+	current.Store(&logState{
+		handler: slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.Level(-8)}),
+		level:   slog.LevelWarn,
+		levels:  map[string]slog.Level{SubsystemScheduler: slog.LevelDebug},
+	})
+
+	// Created before the state changed below; must follow it.
+	scheduler := Logger(SubsystemScheduler).With("tick", 3)
+	ctx := WithRequestID(context.Background(), "req-1")
+	Logger(SubsystemApply).InfoContext(ctx, "filtered by the default level")
+	scheduler.DebugContext(ctx, "scheduler tick", "due", 2)
+	Logger(SubsystemApply).Error("apply failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two records, got %q", buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["subsystem"] != "scheduler" || rec["request_id"] != "req-1" || rec["tick"] != float64(3) || rec["due"] != float64(2) || rec["level"] != "DEBUG" {
+		t.Fatalf("unexpected scheduler record: %v", rec)
+	}
+	rec = nil
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["subsystem"] != "apply" || rec["request_id"] != nil {
+		t.Fatalf("unexpected apply record: %v", rec)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "server.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Rotated before "three" and "four"; "five" still fits in ten bytes.
+	want := map[string]string{
+		path:        "four\nfive\n",
+		path + ".1": "three\n",
+		path + ".2": "one\ntwo\n",
+	}
+	for p, content := range want {
+		got, err := os.ReadFile(p)
+		if err != nil || string(got) != content {
+			t.Fatalf("%s: got %q (%v), want %q", p, got, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected at most two backups, stat err %v", err)
+	}
+}
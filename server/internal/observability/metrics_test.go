@@ -0,0 +1,54 @@
+package observability
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	refresh := r.NewCounterVec("test_refresh_total", "Refreshes by result.", "result")
+	up := r.NewGaugeVec("test_up", "Whether it is up.\nSecond line.")
+	latency := r.NewHistogramVec("test_latency_seconds", "Latency.", []float64{1, 0.1}, "mode")
+	r.NewGaugeVec("test_unused", "Never set.")
+
+	refresh.Inc("success")
+	refresh.Add(2, `fail "quoted"`)
+	refresh.Add(-5, "success")
+	up.Set(1)
+	latency.Observe(0.05, "http")
+	latency.Observe(0.5, "http")
+	latency.Observe(3, "http")
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{mode="http",le="0.1"} 1
+test_latency_seconds_bucket{mode="http",le="1"} 2
+test_latency_seconds_bucket{mode="http",le="+Inf"} 3
+test_latency_seconds_sum{mode="http"} 3.55
+test_latency_seconds_count{mode="http"} 3
+# HELP test_refresh_total Refreshes by result.
+# TYPE test_refresh_total counter
+test_refresh_total{result="fail \"quoted\""} 2
+test_refresh_total{result="success"} 1
+# HELP test_up Whether it is up.\nSecond line.
+# TYPE test_up gauge
+test_up 1
+`
+	if b.String() != want {
+		t.Fatalf("unexpected exposition:\n%s", b.String())
+	}
+
+	up.Reset()
+	b.Reset()
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "test_up") {
+		t.Fatalf("reset gauge still written:\n%s", b.String())
+	}
+}
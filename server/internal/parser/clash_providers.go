@@ -0,0 +1,267 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProxyProviderItem is a Clash http proxy-provider. Its proxies are fetched by the
+// refresh service and merged back with ApplyClashProxyProviders.
+type ProxyProviderItem struct {
+	Name          string
+	URL           string
+	IntervalSec   int
+	Filter        string
+	ExcludeFilter string
+}
+
+func clashRemoteProxyProviders(raw map[string]map[string]any) []ProxyProviderItem {
+	out := make([]ProxyProviderItem, 0, len(raw))
+	for _, name := range sortedProviderNames(raw) {
+		p := raw[name]
+		if strings.ToLower(toString(p["type"])) != "http" {
+			continue
+		}
+		u := strings.TrimSpace(toString(p["url"]))
+		if u == "" {
+			continue
+		}
+		out = append(out, ProxyProviderItem{
+			Name:          name,
+			URL:           u,
+			IntervalSec:   toInt(p["interval"]),
+			Filter:        toString(p["filter"]),
+			ExcludeFilter: toString(p["exclude-filter"]),
+		})
+	}
+	return out
+}
+
+// clashInlineProviders converts "type: inline" providers, whose proxies are
+// embedded in the config under payload.
+func clashInlineProviders(raw map[string]map[string]any) map[string][]OutboundItem {
+	out := map[string][]OutboundItem{}
+	for name, p := range raw {
+		if strings.ToLower(toString(p["type"])) != "inline" {
+			continue
+		}
+		list, _ := p["payload"].([]any)
+		items := make([]OutboundItem, 0, len(list))
+		for _, entry := range list {
+			item, err := clashProxyToOutbound(mapFromAny(entry))
+			if err != nil || item == nil {
+				continue
+			}
+			items = append(items, *item)
+		}
+		out[name] = filterProviderOutbounds(items, toString(p["filter"]), toString(p["exclude-filter"]))
+	}
+	return out
+}
+
+func clashInlineProviderOutbounds(raw map[string]map[string]any) []OutboundItem {
+	providers := clashInlineProviders(raw)
+	var out []OutboundItem
+	for _, name := range sortedProviderNames(raw) {
+		out = append(out, providers[name]...)
+	}
+	return out
+}
+
+func clashGroupProviderMembers(g clashProxyGroup, providers map[string][]OutboundItem) []string {
+	var members []string
+	for _, name := range g.Use {
+		for _, item := range filterProviderOutbounds(providers[strings.TrimSpace(name)], g.Filter, "") {
+			members = append(members, item.Tag)
+		}
+	}
+	return members
+}
+
+// filterProviderOutbounds applies Clash filter / exclude-filter regexes to tags.
+// Invalid expressions are ignored rather than dropping every proxy.
+func filterProviderOutbounds(items []OutboundItem, filter, exclude string) []OutboundItem {
+	include := compileProviderFilter(filter)
+	skip := compileProviderFilter(exclude)
+	if include == nil && skip == nil {
+		return items
+	}
+	out := make([]OutboundItem, 0, len(items))
+	for _, item := range items {
+		if include != nil && !include.MatchString(item.Tag) {
+			continue
+		}
+		if skip != nil && skip.MatchString(item.Tag) {
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func compileProviderFilter(expr string) *regexp.Regexp {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+func sortedProviderNames(raw map[string]map[string]any) []string {
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseProxyProviderPayload parses a fetched proxy-provider body. Providers may
+// serve Clash YAML, URI lists or their base64 variants.
+func ParseProxyProviderPayload(body []byte) ([]OutboundItem, error) {
+	parsed, err := ParseSubscriptionBundle(body)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Outbounds, nil
+}
+
+// ApplyClashProxyProviders merges fetched proxy-provider outbounds into a parsed
+// Clash subscription and re-resolves business groups that reference them via "use".
+// body is the original subscription payload; fetched is keyed by provider name.
+func ApplyClashProxyProviders(body []byte, parsed ParsedSubscription, fetched map[string][]OutboundItem) ParsedSubscription {
+	if len(parsed.ProxyProviders) == 0 {
+		return parsed
+	}
+	payload := bytes.TrimSpace(body)
+	if parsed.Format == "clash_base64" {
+		decoded, ok := decodeBase64Payload(payload)
+		if !ok {
+			return parsed
+		}
+		payload = decoded
+	}
+	filtered := make(map[string][]OutboundItem, len(parsed.ProxyProviders))
+	outbounds := append([]OutboundItem{}, parsed.Outbounds...)
+	for _, provider := range parsed.ProxyProviders {
+		items := filterProviderOutbounds(fetched[provider.Name], provider.Filter, provider.ExcludeFilter)
+		filtered[provider.Name] = items
+		outbounds = append(outbounds, items...)
+	}
+	parsed.Outbounds = outbounds
+	if routing := parseClashRoutingWithProviders(payload, filtered); routing != nil {
+		parsed.Rules = routing.Rules
+		parsed.RuleSets = routing.RuleSets
+		parsed.BusinessGroups = routing.BusinessGroups
+	}
+	return parsed
+}
+
+// ConvertClashRuleProvider converts a Clash rule-provider payload (yaml or text
+// format, domain / ipcidr / classical behavior) into a sing-box source rule set.
+// Binary mrs payloads are not supported.
+func ConvertClashRuleProvider(payload []byte, behavior, format string) ([]byte, error) {
+	entries, err := clashRuleProviderEntries(payload, format)
+	if err != nil {
+		return nil, err
+	}
+	domainRule := map[string][]any{}
+	portRule := map[string][]any{}
+	processRule := map[string][]any{}
+	add := func(rule map[string][]any, key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			rule[key] = append(rule[key], value)
+		}
+	}
+	for _, entry := range entries {
+		switch strings.ToLower(strings.TrimSpace(behavior)) {
+		case "domain":
+			switch {
+			case strings.HasPrefix(entry, "+."):
+				add(domainRule, "domain_suffix", strings.TrimPrefix(entry, "+."))
+			case strings.HasPrefix(entry, "*."):
+				add(domainRule, "domain_suffix", strings.TrimPrefix(entry, "*"))
+			case strings.HasPrefix(entry, "."):
+				add(domainRule, "domain_suffix", entry)
+			case strings.Contains(entry, "*"):
+				continue
+			default:
+				add(domainRule, "domain", entry)
+			}
+		case "ipcidr":
+			add(domainRule, "ip_cidr", normalizeMatcherValue("ip_cidr", entry))
+		case "classical", "":
+			parts := splitClashRuleLine(entry)
+			if len(parts) < 2 {
+				continue
+			}
+			if matcherType, ok := basicRuleMatcherType(parts[0]); ok {
+				add(domainRule, matcherType, normalizeMatcherValue(matcherType, parts[1]))
+				continue
+			}
+			switch strings.ToUpper(parts[0]) {
+			case "DOMAIN-REGEX":
+				add(domainRule, "domain_regex", parts[1])
+			case "PROCESS-NAME":
+				add(processRule, "process_name", parts[1])
+			case "DST-PORT":
+				if port, ok := toOptionalInt(parts[1]); ok {
+					portRule["port"] = append(portRule["port"], port)
+				} else if strings.Contains(parts[1], "-") {
+					add(portRule, "port_range", strings.ReplaceAll(parts[1], "-", ":"))
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule-provider behavior %q", behavior)
+		}
+	}
+	rules := make([]map[string][]any, 0, 3)
+	for _, rule := range []map[string][]any{domainRule, processRule, portRule} {
+		if len(rule) > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("rule-provider payload has no supported entries")
+	}
+	return json.Marshal(map[string]any{"version": 1, "rules": rules})
+}
+
+func clashRuleProviderEntries(payload []byte, format string) ([]string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "yaml", "":
+		var doc struct {
+			Payload []string `yaml:"payload"`
+		}
+		if err := yaml.Unmarshal(payload, &doc); err != nil {
+			return nil, fmt.Errorf("decode rule-provider yaml: %w", err)
+		}
+		return trimProviderEntries(doc.Payload), nil
+	case "text":
+		return trimProviderEntries(strings.Split(string(payload), "\n")), nil
+	default:
+		return nil, fmt.Errorf("unsupported rule-provider format %q", format)
+	}
+}
+
+func trimProviderEntries(lines []string) []string {
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, strings.Trim(line, `'"`))
+	}
+	return out
+}
@@ -0,0 +1,266 @@
+package parser
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Quantumult X uses "protocol=host:port, key=value, ..., tag=Name" server lines
+// under [server_local], "type=Name, member, ..." policies under [policy] and
+// lower-case "host-suffix, example.com, Policy" rules under [filter_local].
+
+var quanxServerLine = regexp.MustCompile(`(?i)^(shadowsocks|shadowsocksr|vmess|vless|trojan|http|socks5)\s*=`)
+
+func parseQuanXConf(payload []byte) ([]OutboundItem, []SkippedEntry, bool, error) {
+	doc := parseINIDocument(payload)
+	lines, ok := doc.quanxServerLines()
+	if !ok {
+		return nil, nil, false, nil
+	}
+	out := make([]OutboundItem, 0, len(lines))
+	var skipped []SkippedEntry
+	for idx, line := range lines {
+		typ, rest, _ := strings.Cut(line, "=")
+		typ = strings.ToLower(strings.TrimSpace(typ))
+		args := splitConfigArgs(rest)
+		_, kv := splitPositionalArgs(args)
+		name := kv["tag"]
+		item, err := quanxServerToOutbound(typ, args, kv)
+		if err != nil || item == nil {
+			reason := "conversion failed"
+			if err != nil {
+				reason = err.Error()
+			}
+			skipped = append(skipped, SkippedEntry{Index: idx, Name: name, Protocol: typ, Reason: reason})
+			continue
+		}
+		out = append(out, *item)
+	}
+	return out, skipped, true, nil
+}
+
+// quanxServerLines returns the [server_local] section, or the whole payload when
+// every line is a bare Quantumult X server line.
+func (d iniDocument) quanxServerLines() ([]string, bool) {
+	if d.headers {
+		lines, ok := d.sections["server_local"]
+		return lines, ok
+	}
+	lines := d.sections[""]
+	if len(lines) == 0 {
+		return nil, false
+	}
+	for _, line := range lines {
+		if !quanxServerLine.MatchString(line) || strings.Contains(line, "://") {
+			return nil, false
+		}
+	}
+	return lines, true
+}
+
+func quanxServerToOutbound(typ string, args []string, kv map[string]string) (*OutboundItem, error) {
+	if len(args) == 0 {
+		return nil, skipReason("missing server or port")
+	}
+	host, portRaw, err := net.SplitHostPort(args[0])
+	port, convErr := strconv.Atoi(portRaw)
+	if err != nil || convErr != nil || host == "" || port <= 0 {
+		return nil, skipReason("missing server or port")
+	}
+	tag := kv["tag"]
+	if tag == "" {
+		tag = net.JoinHostPort(host, portRaw)
+	}
+	out := map[string]any{
+		"tag":         tag,
+		"server":      host,
+		"server_port": port,
+	}
+	obfs := strings.ToLower(kv["obfs"])
+	switch typ {
+	case "shadowsocks":
+		if kv["method"] == "" || kv["password"] == "" {
+			return nil, skipReason("missing cipher or password")
+		}
+		out["type"] = "shadowsocks"
+		out["method"] = kv["method"]
+		out["password"] = kv["password"]
+		switch obfs {
+		case "http", "tls":
+			opts := "obfs=" + obfs
+			if h := kv["obfs-host"]; h != "" {
+				opts += ";obfs-host=" + h
+			}
+			out["plugin"] = "obfs-local"
+			out["plugin_opts"] = opts
+		case "ws", "wss":
+			opts := "mode=websocket"
+			if h := kv["obfs-host"]; h != "" {
+				opts += ";host=" + h
+			}
+			if p := kv["obfs-uri"]; p != "" {
+				opts += ";path=" + p
+			}
+			if obfs == "wss" {
+				opts += ";tls"
+			}
+			out["plugin"] = "v2ray-plugin"
+			out["plugin_opts"] = opts
+		}
+	case "vmess", "vless":
+		if kv["password"] == "" {
+			return nil, skipReason("missing uuid")
+		}
+		out["type"] = typ
+		out["uuid"] = kv["password"]
+		if typ == "vmess" {
+			out["security"] = orDefault(kv["method"], "auto")
+			out["alter_id"] = 0
+		} else if flow := kv["vless-flow"]; flow != "" {
+			out["flow"] = flow
+		}
+		attachQuanXObfs(out, kv, obfs)
+	case "trojan":
+		if kv["password"] == "" {
+			return nil, skipReason("missing password")
+		}
+		out["type"] = "trojan"
+		out["password"] = kv["password"]
+		attachQuanXObfs(out, kv, obfs)
+		ensureTLSEnabled(out)
+	case "http":
+		out["type"] = "http"
+		if kv["username"] != "" {
+			out["username"] = kv["username"]
+		}
+		if kv["password"] != "" {
+			out["password"] = kv["password"]
+		}
+		attachQuanXObfs(out, kv, obfs)
+	case "socks5":
+		out["type"] = "socks"
+		if kv["username"] != "" {
+			out["username"] = kv["username"]
+		}
+		if kv["password"] != "" {
+			out["password"] = kv["password"]
+		}
+	default:
+		return nil, skipReason("unsupported protocol")
+	}
+	return mapToItem(out), nil
+}
+
+// attachQuanXObfs maps the obfs/over-tls/tls-* options shared by vmess, vless,
+// trojan and http lines.
+func attachQuanXObfs(out map[string]any, kv map[string]string, obfs string) {
+	if obfs == "ws" || obfs == "wss" {
+		transport := map[string]any{
+			"type": "ws",
+			"path": orDefault(kv["obfs-uri"], "/"),
+		}
+		if h := kv["obfs-host"]; h != "" {
+			transport["headers"] = map[string]any{"Host": h}
+		}
+		out["transport"] = transport
+	}
+	enabled := obfs == "wss" || obfs == "over-tls" || toBool(kv["over-tls"])
+	if !enabled {
+		return
+	}
+	tls := map[string]any{"enabled": true}
+	if serverName := orDefault(kv["tls-host"], kv["obfs-host"]); serverName != "" {
+		tls["server_name"] = serverName
+	}
+	if strings.EqualFold(kv["tls-verification"], "false") {
+		tls["insecure"] = true
+	}
+	if pbk := kv["reality-base64-pubkey"]; pbk != "" {
+		reality := map[string]any{"enabled": true, "public_key": pbk}
+		if sid := kv["reality-hex-shortid"]; sid != "" {
+			reality["short_id"] = sid
+		}
+		tls["reality"] = reality
+	}
+	out["tls"] = tls
+}
+
+func parseQuanXRouting(payload []byte, outbounds []OutboundItem) *ParsedSubscription {
+	doc := parseINIDocument(payload)
+	result := &ParsedSubscription{
+		RuleSets:       []RuleSetItem{},
+		Rules:          []RoutingRuleItem{},
+		BusinessGroups: []BusinessGroupItem{},
+	}
+	ruleLines := doc.sections["filter_local"]
+	targetOrder := make([]string, 0, len(ruleLines))
+	targetSeen := map[string]struct{}{}
+	for idx, line := range ruleLines {
+		parts := splitClashRuleLine(line)
+		if len(parts) < 3 {
+			continue
+		}
+		target := strings.TrimSpace(parts[2])
+		if !isBusinessTargetTag(target) {
+			continue
+		}
+		if _, seen := targetSeen[target]; !seen {
+			targetSeen[target] = struct{}{}
+			targetOrder = append(targetOrder, target)
+		}
+		if item, ok := basicRuleItem(quanxRuleType(parts[0]), parts[1], target, "quanx", idx); ok {
+			result.Rules = append(result.Rules, item)
+		}
+	}
+
+	groupRefs := map[string][]string{}
+	policies := map[string]GroupPolicy{}
+	for _, line := range doc.sections["policy"] {
+		typ, rest, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		members, kv := splitPositionalArgs(splitConfigArgs(rest))
+		if len(members) < 2 {
+			continue
+		}
+		groupRefs[members[0]] = members[1:]
+		policies[members[0]] = newGroupPolicy(quanxPolicyType(typ), kv["server-check-url"], toInt(kv["check-interval"]), toInt(kv["tolerance"]), "")
+	}
+	nodeSet, alias := nodeSetFromOutbounds(outbounds, groupRefs)
+	result.BusinessGroups = buildBusinessGroupsForTargets(targetOrder, nodeSet, groupRefs, alias)
+	attachGroupPolicies(result.BusinessGroups, policies, alias)
+	return result
+}
+
+// quanxRuleType translates Quantumult X filter keywords to their Surge spelling.
+func quanxRuleType(raw string) string {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "host":
+		return "DOMAIN"
+	case "host-suffix":
+		return "DOMAIN-SUFFIX"
+	case "host-keyword":
+		return "DOMAIN-KEYWORD"
+	case "ip-cidr", "ip6-cidr":
+		return "IP-CIDR"
+	default:
+		return raw
+	}
+}
+
+// quanxPolicyType maps Quantumult X policy kinds to their Clash equivalents.
+func quanxPolicyType(raw string) string {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "url-latency-benchmark":
+		return "url-test"
+	case "available":
+		return "fallback"
+	case "round-robin":
+		return "load-balance"
+	default:
+		return raw
+	}
+}
@@ -0,0 +1,475 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Surge and Loon share the same INI layout:
+//
+//	[Proxy]
+//	HK = vmess, hk.example.com, 443, username=uuid, ws=true, tls=true
+//	[Proxy Group]
+//	OpenAI = select, HK, US
+//	[Rule]
+//	DOMAIN-SUFFIX,openai.com,OpenAI
+//
+// Loon differs mainly in proxy lines, where cipher/credentials are positional
+// (HK = vmess, hk.example.com, 443, auto, "uuid", transport=ws, over-tls=true).
+
+var (
+	iniSectionPattern   = regexp.MustCompile(`^\[([^\]]+)\]$`)
+	surgeProxyLine      = regexp.MustCompile(`^[^=,]+=\s*[A-Za-z0-9-]+\s*,`)
+	configArgKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// surgeBuiltinPolicies are policy names that are not proxies.
+var surgeBuiltinPolicies = map[string]bool{
+	"direct": true, "reject": true, "reject-tinygif": true, "reject-drop": true, "reject-no-drop": true,
+}
+
+type iniDocument struct {
+	sections map[string][]string
+	headers  bool
+}
+
+func parseINIDocument(payload []byte) iniDocument {
+	doc := iniDocument{sections: map[string][]string{}}
+	current := ""
+	for _, raw := range strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(string(payload)), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if m := iniSectionPattern.FindStringSubmatch(line); m != nil {
+			current = strings.ToLower(strings.TrimSpace(m[1]))
+			doc.headers = true
+			continue
+		}
+		doc.sections[current] = append(doc.sections[current], line)
+	}
+	return doc
+}
+
+// surgeProxyLines returns the proxy section of a Surge/Loon config, or the whole
+// payload when it is a bare proxy list where every line looks like "Name = type, ...".
+func (d iniDocument) surgeProxyLines() ([]string, bool) {
+	if d.headers {
+		lines, ok := d.sections["proxy"]
+		return lines, ok
+	}
+	lines := d.sections[""]
+	if len(lines) == 0 {
+		return nil, false
+	}
+	for _, line := range lines {
+		if !surgeProxyLine.MatchString(line) || strings.Contains(line, "://") {
+			return nil, false
+		}
+	}
+	return lines, true
+}
+
+func (d iniDocument) isLoon() bool {
+	for _, section := range []string{"remote proxy", "remote filter", "remote rule", "plugin"} {
+		if _, ok := d.sections[section]; ok {
+			return true
+		}
+	}
+	lines, _ := d.surgeProxyLines()
+	for _, line := range lines {
+		_, rest, _ := strings.Cut(line, "=")
+		args := splitConfigArgs(rest)
+		if len(args) == 0 {
+			continue
+		}
+		switch strings.ToLower(args[0]) {
+		case "shadowsocks", "shadowsocksr", "vless":
+			return true
+		}
+	}
+	return false
+}
+
+// surgeConfFormat reports whether an INI proxy config is Surge or Loon flavoured.
+func surgeConfFormat(payload []byte) string {
+	if parseINIDocument(payload).isLoon() {
+		return "loon_conf"
+	}
+	return "surge_conf"
+}
+
+func parseSurgeConf(payload []byte) ([]OutboundItem, []SkippedEntry, bool, error) {
+	doc := parseINIDocument(payload)
+	lines, ok := doc.surgeProxyLines()
+	if !ok {
+		return nil, nil, false, nil
+	}
+	out := make([]OutboundItem, 0, len(lines))
+	var skipped []SkippedEntry
+	for idx, line := range lines {
+		name, rest, found := strings.Cut(line, "=")
+		if !found {
+			skipped = append(skipped, SkippedEntry{Index: idx, Reason: "malformed proxy line"})
+			continue
+		}
+		name = strings.TrimSpace(name)
+		args := splitConfigArgs(rest)
+		if len(args) == 0 {
+			skipped = append(skipped, SkippedEntry{Index: idx, Name: name, Reason: "malformed proxy line"})
+			continue
+		}
+		typ := strings.ToLower(args[0])
+		if surgeBuiltinPolicies[typ] {
+			continue
+		}
+		item, err := surgeProxyToOutbound(name, typ, args[1:])
+		if err != nil || item == nil {
+			reason := "conversion failed"
+			if err != nil {
+				reason = err.Error()
+			}
+			skipped = append(skipped, SkippedEntry{Index: idx, Name: name, Protocol: typ, Reason: reason})
+			continue
+		}
+		out = append(out, *item)
+	}
+	return out, skipped, true, nil
+}
+
+func surgeProxyToOutbound(name, typ string, rawArgs []string) (*OutboundItem, error) {
+	positional, kv := splitPositionalArgs(rawArgs)
+	if len(positional) < 2 {
+		return nil, skipReason("missing server or port")
+	}
+	server := positional[0]
+	port, err := strconv.Atoi(positional[1])
+	if err != nil || server == "" || port <= 0 {
+		return nil, skipReason("missing server or port")
+	}
+	extra := positional[2:]
+	at := func(i int) string {
+		if i < len(extra) {
+			return extra[i]
+		}
+		return ""
+	}
+	out := map[string]any{
+		"tag":         name,
+		"server":      server,
+		"server_port": port,
+	}
+	switch typ {
+	case "ss", "shadowsocks":
+		method := orDefault(kv["encrypt-method"], at(0))
+		password := orDefault(kv["password"], at(1))
+		if method == "" || password == "" {
+			return nil, skipReason("missing cipher or password")
+		}
+		out["type"] = "shadowsocks"
+		out["method"] = method
+		out["password"] = password
+		if obfs := orDefault(kv["obfs"], kv["obfs-name"]); obfs == "http" || obfs == "tls" {
+			opts := "obfs=" + obfs
+			if host := kv["obfs-host"]; host != "" {
+				opts += ";obfs-host=" + host
+			}
+			out["plugin"] = "obfs-local"
+			out["plugin_opts"] = opts
+		}
+	case "vmess":
+		// Surge: username=uuid; Loon: positional cipher then uuid.
+		uuid := kv["username"]
+		security := "auto"
+		if uuid == "" {
+			security = orDefault(at(0), "auto")
+			uuid = at(1)
+		}
+		if uuid == "" {
+			return nil, skipReason("missing uuid")
+		}
+		out["type"] = "vmess"
+		out["uuid"] = uuid
+		out["security"] = security
+		out["alter_id"] = toInt(kv["alterid"])
+		attachSurgeTransport(out, kv)
+		attachSurgeTLS(out, kv, false)
+	case "vless":
+		uuid := orDefault(kv["username"], at(0))
+		if uuid == "" {
+			return nil, skipReason("missing uuid")
+		}
+		out["type"] = "vless"
+		out["uuid"] = uuid
+		if flow := kv["flow"]; flow != "" {
+			out["flow"] = flow
+		}
+		attachSurgeTransport(out, kv)
+		attachSurgeTLS(out, kv, false)
+	case "trojan":
+		password := orDefault(kv["password"], at(0))
+		if password == "" {
+			return nil, skipReason("missing password")
+		}
+		out["type"] = "trojan"
+		out["password"] = password
+		attachSurgeTransport(out, kv)
+		attachSurgeTLS(out, kv, true)
+	case "http", "https":
+		out["type"] = "http"
+		if username := orDefault(kv["username"], at(0)); username != "" {
+			out["username"] = username
+		}
+		if password := orDefault(kv["password"], at(1)); password != "" {
+			out["password"] = password
+		}
+		attachSurgeTLS(out, kv, typ == "https")
+	case "socks5":
+		out["type"] = "socks"
+		if username := orDefault(kv["username"], at(0)); username != "" {
+			out["username"] = username
+		}
+		if password := orDefault(kv["password"], at(1)); password != "" {
+			out["password"] = password
+		}
+	case "hysteria2":
+		password := orDefault(kv["password"], at(0))
+		if password == "" {
+			return nil, skipReason("missing password")
+		}
+		out["type"] = "hysteria2"
+		out["password"] = password
+		if down, ok := toOptionalInt(kv["download-bandwidth"]); ok && down > 0 {
+			out["down_mbps"] = down
+		}
+		attachSurgeTLS(out, kv, true)
+	case "tuic-v5":
+		uuid := kv["uuid"]
+		password := kv["password"]
+		if uuid == "" || password == "" {
+			return nil, skipReason("missing uuid or password")
+		}
+		out["type"] = "tuic"
+		out["uuid"] = uuid
+		out["password"] = password
+		attachSurgeTLS(out, kv, true)
+		attachALPN(out, splitCommaList(kv["alpn"]))
+	case "tuic":
+		return nil, skipReason("tuic v4 token auth is not supported")
+	case "socks5-tls":
+		return nil, skipReason("socks5 over tls is not supported")
+	default:
+		return nil, skipReason("unsupported protocol")
+	}
+	return mapToItem(out), nil
+}
+
+func attachSurgeTransport(out map[string]any, kv map[string]string) {
+	isWS := toBool(kv["ws"]) || strings.EqualFold(kv["transport"], "ws")
+	if !isWS {
+		return
+	}
+	transport := map[string]any{
+		"type": "ws",
+		"path": orDefault(orDefault(kv["ws-path"], kv["path"]), "/"),
+	}
+	headers := map[string]any{}
+	// Surge: ws-headers=Host:example.com|X-Key:value
+	for _, pair := range strings.Split(kv["ws-headers"], "|") {
+		k, v, ok := strings.Cut(pair, ":")
+		if ok && strings.TrimSpace(k) != "" {
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	if host := kv["host"]; host != "" {
+		headers["Host"] = host
+	}
+	if len(headers) > 0 {
+		transport["headers"] = headers
+	}
+	out["transport"] = transport
+}
+
+func attachSurgeTLS(out map[string]any, kv map[string]string, forced bool) {
+	enabled := forced || toBool(kv["tls"]) || toBool(kv["over-tls"])
+	serverName := orDefault(kv["sni"], kv["tls-name"])
+	insecure := toBool(kv["skip-cert-verify"])
+	if !enabled && serverName == "" && !insecure {
+		return
+	}
+	tls := map[string]any{"enabled": enabled}
+	if serverName != "" && !strings.EqualFold(serverName, "off") {
+		tls["server_name"] = serverName
+	}
+	if insecure {
+		tls["insecure"] = true
+	}
+	if pbk := kv["public-key"]; pbk != "" {
+		reality := map[string]any{"enabled": true, "public_key": pbk}
+		if sid := kv["short-id"]; sid != "" {
+			reality["short_id"] = sid
+		}
+		tls["reality"] = reality
+	}
+	out["tls"] = tls
+}
+
+func parseSurgeRouting(payload []byte, format string, outbounds []OutboundItem) *ParsedSubscription {
+	doc := parseINIDocument(payload)
+	sourceKind := strings.TrimSuffix(format, "_conf")
+	result := &ParsedSubscription{
+		RuleSets:       []RuleSetItem{},
+		Rules:          []RoutingRuleItem{},
+		BusinessGroups: []BusinessGroupItem{},
+	}
+	ruleLines := doc.sections["rule"]
+	targetOrder := make([]string, 0, len(ruleLines))
+	targetSeen := map[string]struct{}{}
+	for idx, line := range ruleLines {
+		parts := splitClashRuleLine(line)
+		if len(parts) < 3 {
+			continue
+		}
+		target := strings.TrimSpace(parts[2])
+		if !isBusinessTargetTag(target) {
+			continue
+		}
+		if _, seen := targetSeen[target]; !seen {
+			targetSeen[target] = struct{}{}
+			targetOrder = append(targetOrder, target)
+		}
+		if item, ok := basicRuleItem(parts[0], parts[1], target, sourceKind, idx); ok {
+			result.Rules = append(result.Rules, item)
+		}
+	}
+
+	groupRefs := map[string][]string{}
+	policies := map[string]GroupPolicy{}
+	for _, line := range doc.sections["proxy group"] {
+		name, rest, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		args := splitConfigArgs(rest)
+		if len(args) < 2 {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		members, kv := splitPositionalArgs(args[1:])
+		groupRefs[name] = members
+		policies[name] = newGroupPolicy(args[0], kv["url"], toInt(kv["interval"]), toInt(kv["tolerance"]), kv["algorithm"])
+	}
+	nodeSet, alias := nodeSetFromOutbounds(outbounds, groupRefs)
+	result.BusinessGroups = buildBusinessGroupsForTargets(targetOrder, nodeSet, groupRefs, alias)
+	attachGroupPolicies(result.BusinessGroups, policies, alias)
+	return result
+}
+
+// basicRuleItem maps the Clash/Surge/Loon DOMAIN*/IP-CIDR* rule types onto a routing rule.
+func basicRuleItem(ruleType, value, target, sourceKind string, order int) (RoutingRuleItem, bool) {
+	matcherType, ok := basicRuleMatcherType(ruleType)
+	if !ok {
+		return RoutingRuleItem{}, false
+	}
+	value = normalizeMatcherValue(matcherType, value)
+	if value == "" {
+		return RoutingRuleItem{}, false
+	}
+	return RoutingRuleItem{
+		Priority:       200,
+		RuleOrder:      order,
+		MatcherType:    matcherType,
+		MatcherValue:   value,
+		TargetOutbound: target,
+		SourceKind:     sourceKind,
+	}, true
+}
+
+func basicRuleMatcherType(ruleType string) (string, bool) {
+	switch strings.ToUpper(strings.TrimSpace(ruleType)) {
+	case "DOMAIN":
+		return "domain", true
+	case "DOMAIN-SUFFIX":
+		return "domain_suffix", true
+	case "DOMAIN-KEYWORD":
+		return "domain_keyword", true
+	case "IP-CIDR", "IP-CIDR6":
+		return "ip_cidr", true
+	default:
+		return "", false
+	}
+}
+
+func nodeSetFromOutbounds(outbounds []OutboundItem, groupRefs map[string][]string) (map[string]struct{}, map[string]string) {
+	nodeSet := map[string]struct{}{}
+	alias := map[string]string{}
+	for _, item := range outbounds {
+		tag := strings.TrimSpace(item.Tag)
+		if tag == "" {
+			continue
+		}
+		nodeSet[tag] = struct{}{}
+		if _, ok := alias[strings.ToLower(tag)]; !ok {
+			alias[strings.ToLower(tag)] = tag
+		}
+	}
+	for name := range groupRefs {
+		if _, ok := alias[strings.ToLower(name)]; !ok {
+			alias[strings.ToLower(name)] = name
+		}
+	}
+	return nodeSet, alias
+}
+
+// splitConfigArgs splits a comma separated argument list, keeping commas that
+// appear inside double quotes.
+func splitConfigArgs(raw string) []string {
+	var out []string
+	var b strings.Builder
+	inQuote := false
+	flush := func() {
+		if v := strings.TrimSpace(b.String()); v != "" {
+			out = append(out, v)
+		}
+		b.Reset()
+	}
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			b.WriteRune(r)
+		case r == ',' && !inQuote:
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return out
+}
+
+// splitPositionalArgs separates bare values from key=value options.
+// Quoted values are always positional so passwords may contain '='.
+func splitPositionalArgs(args []string) ([]string, map[string]string) {
+	positional := make([]string, 0, len(args))
+	kv := map[string]string{}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, `"`) {
+			if k, v, ok := strings.Cut(arg, "="); ok && configArgKeyPattern.MatchString(strings.TrimSpace(k)) {
+				kv[strings.ToLower(strings.TrimSpace(k))] = unquoteConfigValue(v)
+				continue
+			}
+		}
+		positional = append(positional, unquoteConfigValue(arg))
+	}
+	return positional, kv
+}
+
+func unquoteConfigValue(raw string) string {
+	v := strings.TrimSpace(raw)
+	if len(v) >= 2 && strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
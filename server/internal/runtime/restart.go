@@ -9,9 +9,12 @@ import (
 	"strings"
 	"time"
 
+	"boxpilot/server/internal/observability"
 	"boxpilot/server/internal/util/errorx"
 )
 
+var applyLog = observability.Logger(observability.SubsystemApply)
+
 const (
 	defaultCheckCmd = `sing-box check -c "$SINGBOX_CONFIG"`
 )
@@ -23,6 +26,7 @@ func Restart(ctx context.Context, configPath string) ([]byte, error) {
 		return nil, err
 	}
 	startedAt := time.Now()
+	applyLog.DebugContext(ctx, "restarting sing-box", "cmd", cmdline, "config", configPath)
 	cmd := exec.CommandContext(ctx, "sh", "-lc", cmdline)
 	cmd.Env = append(os.Environ(), "SINGBOX_CONFIG="+configPath)
 	out, err := cmd.CombinedOutput()
@@ -31,6 +35,7 @@ func Restart(ctx context.Context, configPath string) ([]byte, error) {
 		elapsedMs = 0
 	}
 	if err != nil {
+		applyLog.WarnContext(ctx, "sing-box restart failed", "cmd", cmdline, "elapsed_ms", elapsedMs, "error", err)
 		return out, errorx.New(errorx.RTRestartFailed, "process restart failed").WithDetails(map[string]any{
 			"cmd":        cmdline,
 			"config":     configPath,
@@ -38,6 +43,7 @@ func Restart(ctx context.Context, configPath string) ([]byte, error) {
 			"elapsed_ms": elapsedMs,
 		})
 	}
+	applyLog.DebugContext(ctx, "sing-box restarted", "elapsed_ms", elapsedMs)
 	return out, nil
 }
 
@@ -50,10 +56,12 @@ func Check(ctx context.Context, configPath string) ([]byte, error) {
 	if cmdline == "" {
 		cmdline = defaultCheckCmd
 	}
+	applyLog.DebugContext(ctx, "checking sing-box config", "cmd", cmdline, "config", configPath)
 	cmd := exec.CommandContext(ctx, "sh", "-lc", cmdline)
 	cmd.Env = append(os.Environ(), "SINGBOX_CONFIG="+configPath)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
+		applyLog.WarnContext(ctx, "sing-box config check failed", "cmd", cmdline, "error", err)
 		return out, errorx.New(errorx.CFGCheckFailed, "sing-box config preflight failed").WithDetails(map[string]any{
 			"cmd":    cmdline,
 			"config": configPath,
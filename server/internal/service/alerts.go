@@ -0,0 +1,483 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+)
+
+// Alert rule kinds. Threshold meaning per kind:
+//
+//	quota_low        remaining quota below this percent of the total
+//	expiring         expiry within this many days
+//	expired          unused
+//	refresh_failed   at least this many consecutive refresh failures
+//	reload_failed    unused; fires while runtime_state.last_reload_error is set
+//	nodes_unhealthy  at least this percent of a subscription's tested nodes failing
+const (
+	AlertKindQuotaLow       = UsageWarningQuotaLow
+	AlertKindExpiring       = "expiring"
+	AlertKindExpired        = UsageWarningExpired
+	AlertKindRefreshFailed  = "refresh_failed"
+	AlertKindReloadFailed   = "reload_failed"
+	AlertKindNodesUnhealthy = "nodes_unhealthy"
+	AlertKindTest           = "test"
+
+	// AlertSubjectRuntime is the subject of alerts about sing-box itself rather
+	// than one subscription.
+	AlertSubjectRuntime = "runtime"
+
+	// DefaultAlertCooldownSec is the cooldown of rules created without one.
+	DefaultAlertCooldownSec = 6 * 3600
+
+	defaultExpiringDays          = 3
+	defaultRefreshFailureStreak  = 3
+	defaultNodesUnhealthyPercent = 100
+)
+
+// alertThresholdDefaults is the threshold used when a rule leaves it at 0.
+var alertThresholdDefaults = map[string]int{
+	AlertKindQuotaLow:       defaultQuotaWarnPercent,
+	AlertKindExpiring:       defaultExpiringDays,
+	AlertKindExpired:        0,
+	AlertKindRefreshFailed:  defaultRefreshFailureStreak,
+	AlertKindReloadFailed:   0,
+	AlertKindNodesUnhealthy: defaultNodesUnhealthyPercent,
+}
+
+// Alert is one notification. Subject is the subscription id, or
+// AlertSubjectRuntime; webhooks receive it as JSON.
+type Alert struct {
+	RuleID      string         `json:"rule_id,omitempty"`
+	Kind        string         `json:"kind"`
+	Subject     string         `json:"subject"`
+	SubjectName string         `json:"subject_name,omitempty"`
+	Title       string         `json:"title"`
+	Message     string         `json:"message"`
+	Details     map[string]any `json:"details,omitempty"`
+	At          time.Time      `json:"at"`
+}
+
+// NotificationRule is a validated alert rule. An empty ChannelIDs notifies
+// every enabled channel. CooldownSec 0 notifies once per occurrence.
+type NotificationRule struct {
+	ID          string
+	Name        string
+	Kind        string
+	Enabled     bool
+	Threshold   int
+	CooldownSec int
+	ChannelIDs  []string
+}
+
+// AlertKinds lists the supported rule kinds.
+func AlertKinds() []string {
+	return []string{AlertKindQuotaLow, AlertKindExpiring, AlertKindExpired, AlertKindRefreshFailed, AlertKindReloadFailed, AlertKindNodesUnhealthy}
+}
+
+// NormalizeNotificationRule validates a rule before it is saved.
+func NormalizeNotificationRule(r NotificationRule) (NotificationRule, *errorx.AppError) {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Kind = strings.ToLower(strings.TrimSpace(r.Kind))
+	if _, ok := alertThresholdDefaults[r.Kind]; !ok {
+		return r, errorx.New(errorx.REQInvalidField, "unsupported alert kind").WithDetails(map[string]any{
+			"kind":      r.Kind,
+			"supported": AlertKinds(),
+		})
+	}
+	if r.Name == "" {
+		r.Name = r.Kind
+	}
+	if r.Threshold < 0 {
+		return r, errorx.New(errorx.REQInvalidField, "threshold must be >= 0")
+	}
+	if (r.Kind == AlertKindQuotaLow || r.Kind == AlertKindNodesUnhealthy) && r.Threshold > 100 {
+		return r, errorx.New(errorx.REQInvalidField, "threshold is a percentage for "+r.Kind).WithDetails(map[string]any{"threshold": r.Threshold})
+	}
+	if r.CooldownSec < 0 {
+		return r, errorx.New(errorx.REQInvalidField, "cooldown_sec must be >= 0")
+	}
+	ids := make([]string, 0, len(r.ChannelIDs))
+	seen := map[string]struct{}{}
+	for _, id := range r.ChannelIDs {
+		id = strings.TrimSpace(id)
+		if _, dup := seen[id]; id == "" || dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	r.ChannelIDs = ids
+	return r, nil
+}
+
+func NotificationRuleFromRow(row repo.NotificationRuleRow) NotificationRule {
+	r := NotificationRule{
+		ID:          row.ID,
+		Name:        row.Name,
+		Kind:        row.Kind,
+		Enabled:     row.Enabled == 1,
+		Threshold:   row.Threshold,
+		CooldownSec: row.CooldownSec,
+	}
+	_ = json.Unmarshal([]byte(row.ChannelIDsJSON), &r.ChannelIDs)
+	return r
+}
+
+// NotificationRuleToRow encodes a rule for storage; timestamps are left to the caller.
+func NotificationRuleToRow(r NotificationRule) repo.NotificationRuleRow {
+	ids := r.ChannelIDs
+	if ids == nil {
+		ids = []string{}
+	}
+	raw, _ := json.Marshal(ids)
+	enabled := 0
+	if r.Enabled {
+		enabled = 1
+	}
+	return repo.NotificationRuleRow{
+		ID:             r.ID,
+		Name:           r.Name,
+		Kind:           r.Kind,
+		Enabled:        enabled,
+		Threshold:      r.Threshold,
+		CooldownSec:    r.CooldownSec,
+		ChannelIDsJSON: string(raw),
+	}
+}
+
+func (r NotificationRule) threshold() int {
+	if r.Threshold > 0 {
+		return r.Threshold
+	}
+	return alertThresholdDefaults[r.Kind]
+}
+
+// alertInputs is the state the rules are evaluated against.
+type alertInputs struct {
+	subs    []repo.SubscriptionRow
+	nodes   []repo.NodeRow
+	runtime *repo.RuntimeStateRow
+}
+
+func loadAlertInputs(db *sql.DB) (alertInputs, error) {
+	var in alertInputs
+	var err error
+	if in.subs, err = repo.ListSubscriptions(db, false); err != nil {
+		return in, err
+	}
+	if in.nodes, err = repo.ListNodes(db, "", nil); err != nil {
+		return in, err
+	}
+	in.runtime, err = repo.GetRuntimeState(db)
+	return in, err
+}
+
+// evaluateAlertRule returns the alerts rule raises for the current state, one
+// per affected subject. Disabled subscriptions never raise alerts.
+func evaluateAlertRule(rule NotificationRule, in alertInputs, now time.Time) []Alert {
+	threshold := rule.threshold()
+	newAlert := func(s repo.SubscriptionRow, title, message string, details map[string]any) Alert {
+		return Alert{
+			RuleID:      rule.ID,
+			Kind:        rule.Kind,
+			Subject:     s.ID,
+			SubjectName: s.Name,
+			Title:       title,
+			Message:     message,
+			Details:     details,
+			At:          now,
+		}
+	}
+	var out []Alert
+	if rule.Kind == AlertKindReloadFailed {
+		if in.runtime != nil && in.runtime.LastReloadError.Valid && in.runtime.LastReloadError.String != "" {
+			out = append(out, Alert{
+				RuleID:  rule.ID,
+				Kind:    rule.Kind,
+				Subject: AlertSubjectRuntime,
+				Title:   "BoxPilot: sing-box reload failed",
+				Message: in.runtime.LastReloadError.String,
+				Details: map[string]any{"last_reload_at": in.runtime.LastReloadAt.String},
+				At:      now,
+			})
+		}
+		return out
+	}
+	var health map[string]*nodeHealthCount
+	if rule.Kind == AlertKindNodesUnhealthy {
+		health = countNodeHealth(in.nodes)
+	}
+	for _, s := range in.subs {
+		if s.Enabled != 1 || s.ID == repo.ManualSubscriptionID {
+			continue
+		}
+		switch rule.Kind {
+		case AlertKindQuotaLow:
+			u := EvaluateSubscriptionUsage(s, now)
+			if u.RemainingBytes == nil || *u.RemainingBytes*100 >= *u.TotalBytes*int64(threshold) {
+				continue
+			}
+			out = append(out, newAlert(s,
+				fmt.Sprintf("BoxPilot: %s quota low", s.Name),
+				fmt.Sprintf("Subscription %s has %s of %s left (below %d%%).", s.Name, formatBytes(*u.RemainingBytes), formatBytes(*u.TotalBytes), threshold),
+				map[string]any{"remaining_bytes": *u.RemainingBytes, "total_bytes": *u.TotalBytes, "threshold_percent": threshold},
+			))
+		case AlertKindExpiring, AlertKindExpired:
+			u := EvaluateSubscriptionUsage(s, now)
+			if u.ExpireAt == nil {
+				continue
+			}
+			expireAt := u.ExpireAt.Format(time.RFC3339)
+			if rule.Kind == AlertKindExpired {
+				if !u.Expired {
+					continue
+				}
+				out = append(out, newAlert(s,
+					fmt.Sprintf("BoxPilot: %s expired", s.Name),
+					fmt.Sprintf("Subscription %s expired at %s; auto refresh is stopped.", s.Name, expireAt),
+					map[string]any{"expire_at": expireAt},
+				))
+				continue
+			}
+			if u.Expired || u.ExpireAt.Sub(now) > time.Duration(threshold)*24*time.Hour {
+				continue
+			}
+			out = append(out, newAlert(s,
+				fmt.Sprintf("BoxPilot: %s expires soon", s.Name),
+				fmt.Sprintf("Subscription %s expires at %s.", s.Name, expireAt),
+				map[string]any{"expire_at": expireAt, "threshold_days": threshold},
+			))
+		case AlertKindRefreshFailed:
+			if s.FailureStreak < threshold {
+				continue
+			}
+			out = append(out, newAlert(s,
+				fmt.Sprintf("BoxPilot: %s refresh failing", s.Name),
+				fmt.Sprintf("Subscription %s failed to refresh %d times in a row: %s", s.Name, s.FailureStreak, s.LastError.String),
+				map[string]any{"failure_streak": s.FailureStreak, "last_error": s.LastError.String},
+			))
+		case AlertKindNodesUnhealthy:
+			h := health[s.ID]
+			if h == nil || h.tested == 0 || h.failing*100 < h.tested*threshold {
+				continue
+			}
+			out = append(out, newAlert(s,
+				fmt.Sprintf("BoxPilot: %s nodes unhealthy", s.Name),
+				fmt.Sprintf("%d of %d tested nodes of subscription %s are failing.", h.failing, h.tested, s.Name),
+				map[string]any{"failing": h.failing, "tested": h.tested, "threshold_percent": threshold},
+			))
+		}
+	}
+	return out
+}
+
+type nodeHealthCount struct {
+	tested  int
+	failing int
+}
+
+// countNodeHealth tallies the last probe result of enabled nodes per
+// subscription; untested nodes are ignored.
+func countNodeHealth(nodes []repo.NodeRow) map[string]*nodeHealthCount {
+	out := map[string]*nodeHealthCount{}
+	for _, n := range nodes {
+		if n.Enabled != 1 || !n.LastTestStatus.Valid {
+			continue
+		}
+		status := strings.ToLower(strings.TrimSpace(n.LastTestStatus.String))
+		if status == "" {
+			continue
+		}
+		c := out[n.SubID]
+		if c == nil {
+			c = &nodeHealthCount{}
+			out[n.SubID] = c
+		}
+		c.tested++
+		if status != "ok" {
+			c.failing++
+		}
+	}
+	return out
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// alertDue reports whether a firing alert is sent now: on first sight, after
+// a failed delivery, or once the cooldown has passed. Cooldown 0 sends once.
+func alertDue(state *repo.NotificationAlertStateRow, cooldownSec int, now time.Time) bool {
+	if state == nil || !state.LastSentAt.Valid {
+		return true
+	}
+	if cooldownSec <= 0 {
+		return false
+	}
+	last, err := time.Parse(time.RFC3339, state.LastSentAt.String)
+	if err != nil {
+		return true
+	}
+	return !now.Before(last.Add(time.Duration(cooldownSec) * time.Second))
+}
+
+// StartAlertMonitor evaluates the notification rules periodically.
+func StartAlertMonitor(ctx context.Context, db *sql.DB, tick time.Duration) {
+	if tick <= 0 {
+		tick = time.Minute
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := RunAlertChecks(ctx, db, time.Now().UTC()); err != nil {
+				alertsLog.ErrorContext(ctx, "alert check failed", "error", err)
+			}
+		}
+	}
+}
+
+// RunAlertChecks evaluates every enabled rule, notifies its channels about new
+// or cooled-down alerts and forgets alerts that resolved.
+func RunAlertChecks(ctx context.Context, db *sql.DB, now time.Time) error {
+	ruleRows, err := repo.ListNotificationRules(db)
+	if err != nil || len(ruleRows) == 0 {
+		return err
+	}
+	channels, err := repo.ListNotificationChannels(db)
+	if err != nil {
+		return err
+	}
+	in, err := loadAlertInputs(db)
+	if err != nil {
+		return err
+	}
+	for _, row := range ruleRows {
+		rule := NotificationRuleFromRow(row)
+		states, err := repo.ListNotificationAlertStates(db, rule.ID)
+		if err != nil {
+			return err
+		}
+		bySubject := make(map[string]*repo.NotificationAlertStateRow, len(states))
+		for i := range states {
+			bySubject[states[i].Subject] = &states[i]
+		}
+		var alerts []Alert
+		if rule.Enabled {
+			alerts = evaluateAlertRule(rule, in, now)
+		}
+		firing := make(map[string]struct{}, len(alerts))
+		for _, a := range alerts {
+			firing[a.Subject] = struct{}{}
+			state := bySubject[a.Subject]
+			if !alertDue(state, rule.CooldownSec, now) {
+				continue
+			}
+			next := repo.NotificationAlertStateRow{
+				RuleID:      rule.ID,
+				Subject:     a.Subject,
+				SubjectName: a.SubjectName,
+				Message:     a.Message,
+				FirstSeenAt: now.Format(time.RFC3339),
+			}
+			if state != nil {
+				next.FirstSeenAt = state.FirstSeenAt
+				next.LastSentAt = state.LastSentAt
+			}
+			sent, errs := deliverAlert(ctx, channels, rule.ChannelIDs, a)
+			if sent > 0 {
+				next.LastSentAt = sql.NullString{String: now.Format(time.RFC3339), Valid: true}
+			}
+			if len(errs) > 0 {
+				next.LastError = sql.NullString{String: strings.Join(errs, "; "), Valid: true}
+				alertsLog.WarnContext(ctx, "alert delivery failed", "rule", rule.Kind, "subject", a.Subject, "error", next.LastError.String)
+			}
+			if err := repo.UpsertNotificationAlertState(db, next); err != nil {
+				return err
+			}
+		}
+		for subject := range bySubject {
+			if _, ok := firing[subject]; !ok {
+				if err := repo.DeleteNotificationAlertState(db, rule.ID, subject); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// deliverAlert sends a to the listed channels (every enabled channel when none
+// are listed) and returns how many deliveries succeeded plus the failures.
+func deliverAlert(ctx context.Context, channels []repo.NotificationChannelRow, ids []string, a Alert) (int, []string) {
+	wanted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+	sent := 0
+	var errs []string
+	for _, ch := range channels {
+		if ch.Enabled != 1 {
+			continue
+		}
+		if _, ok := wanted[ch.ID]; len(ids) > 0 && !ok {
+			continue
+		}
+		n, appErr := NewNotifier(ch.Type, []byte(ch.ConfigJSON))
+		if appErr != nil {
+			errs = append(errs, ch.Name+": "+appErr.Message)
+			continue
+		}
+		if err := n.Send(ctx, a); err != nil {
+			errs = append(errs, ch.Name+": "+err.Error())
+			continue
+		}
+		sent++
+	}
+	if sent == 0 && len(errs) == 0 {
+		errs = append(errs, "no enabled notification channel")
+	}
+	sort.Strings(errs)
+	return sent, errs
+}
+
+// SendTestNotification delivers a sample alert through one channel config so
+// it can be verified before rules depend on it.
+func SendTestNotification(ctx context.Context, typ string, config []byte) *errorx.AppError {
+	n, appErr := NewNotifier(typ, config)
+	if appErr != nil {
+		return appErr
+	}
+	a := Alert{
+		Kind:    AlertKindTest,
+		Subject: AlertSubjectRuntime,
+		Title:   "BoxPilot test notification",
+		Message: "This channel is configured correctly.",
+		At:      time.Now().UTC(),
+	}
+	if err := n.Send(ctx, a); err != nil {
+		return errorx.New(errorx.NOTIFYSendFailed, "send test notification").WithDetails(map[string]any{"type": typ, "err": err.Error()})
+	}
+	return nil
+}
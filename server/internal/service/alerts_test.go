@@ -0,0 +1,114 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+)
+
+func TestEvaluateAlertRule(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	gib := int64(1 << 30)
+	in := alertInputs{
+		subs: []repo.SubscriptionRow{
+			{
+				ID: "low", Name: "Low", Enabled: 1,
+				SubUploadBytes:   sql.NullInt64{Int64: 0, Valid: true},
+				SubDownloadBytes: sql.NullInt64{Int64: 95 * gib, Valid: true},
+				SubTotalBytes:    sql.NullInt64{Int64: 100 * gib, Valid: true},
+				SubExpireUnix:    sql.NullInt64{Int64: now.Add(48 * time.Hour).Unix(), Valid: true},
+			},
+			{
+				ID: "gone", Name: "Gone", Enabled: 1, FailureStreak: 4,
+				SubExpireUnix: sql.NullInt64{Int64: now.Add(-time.Hour).Unix(), Valid: true},
+				LastError:     sql.NullString{String: "http 403", Valid: true},
+			},
+			{
+				ID: "off", Name: "Off", Enabled: 0, FailureStreak: 9,
+				SubExpireUnix: sql.NullInt64{Int64: now.Add(-time.Hour).Unix(), Valid: true},
+			},
+		},
+		nodes: []repo.NodeRow{
+			{SubID: "low", Enabled: 1, LastTestStatus: sql.NullString{String: "ok", Valid: true}},
+			{SubID: "gone", Enabled: 1, LastTestStatus: sql.NullString{String: "timeout", Valid: true}},
+			{SubID: "gone", Enabled: 1, LastTestStatus: sql.NullString{String: "error", Valid: true}},
+			{SubID: "gone", Enabled: 1},
+		},
+		runtime: &repo.RuntimeStateRow{LastReloadError: sql.NullString{String: "sing-box check failed", Valid: true}},
+	}
+	subjects := func(kind string, threshold int) []string {
+		var out []string
+		for _, a := range evaluateAlertRule(NotificationRule{ID: "r", Kind: kind, Threshold: threshold}, in, now) {
+			if a.Kind != kind || a.RuleID != "r" || a.Title == "" || a.Message == "" {
+				t.Fatalf("incomplete alert %+v", a)
+			}
+			out = append(out, a.Subject)
+		}
+		return out
+	}
+	cases := []struct {
+		kind      string
+		threshold int
+		want      []string
+	}{
+		{AlertKindQuotaLow, 0, []string{"low"}},
+		{AlertKindQuotaLow, 5, nil},
+		{AlertKindExpiring, 0, []string{"low"}},
+		{AlertKindExpiring, 1, nil},
+		{AlertKindExpired, 0, []string{"gone"}},
+		{AlertKindRefreshFailed, 0, []string{"gone"}},
+		{AlertKindRefreshFailed, 5, nil},
+		{AlertKindNodesUnhealthy, 0, []string{"gone"}},
+		{AlertKindReloadFailed, 0, []string{AlertSubjectRuntime}},
+	}
+	for _, tc := range cases {
+		got := subjects(tc.kind, tc.threshold)
+		if len(got) != len(tc.want) || (len(got) > 0 && got[0] != tc.want[0]) {
+			t.Fatalf("%s threshold=%d: want %v got %v", tc.kind, tc.threshold, tc.want, got)
+		}
+	}
+}
+
+func TestAlertDue(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sent := func(at time.Time) *repo.NotificationAlertStateRow {
+		return &repo.NotificationAlertStateRow{LastSentAt: sql.NullString{String: at.Format(time.RFC3339), Valid: true}}
+	}
+	if !alertDue(nil, 3600, now) {
+		t.Fatalf("new alert should be sent")
+	}
+	if !alertDue(&repo.NotificationAlertStateRow{}, 3600, now) {
+		t.Fatalf("undelivered alert should be retried")
+	}
+	if alertDue(sent(now.Add(-30*time.Minute)), 3600, now) {
+		t.Fatalf("alert inside the cooldown should be suppressed")
+	}
+	if !alertDue(sent(now.Add(-time.Hour)), 3600, now) {
+		t.Fatalf("alert should be re-sent after the cooldown")
+	}
+	if alertDue(sent(now.Add(-48*time.Hour)), 0, now) {
+		t.Fatalf("cooldown 0 sends once per occurrence")
+	}
+}
+
+func TestNormalizeNotificationRule(t *testing.T) {
+	r, appErr := NormalizeNotificationRule(NotificationRule{Kind: " Quota_Low ", ChannelIDs: []string{"a", " a", "", "b"}})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if r.Kind != AlertKindQuotaLow || r.Name != AlertKindQuotaLow || len(r.ChannelIDs) != 2 {
+		t.Fatalf("unexpected rule %+v", r)
+	}
+	for _, bad := range []NotificationRule{
+		{Kind: "disk_full"},
+		{Kind: AlertKindQuotaLow, Threshold: 150},
+		{Kind: AlertKindExpiring, CooldownSec: -1},
+	} {
+		if _, appErr := NormalizeNotificationRule(bad); appErr == nil || appErr.Code != errorx.REQInvalidField {
+			t.Fatalf("%+v: expected REQ_INVALID_FIELD, got %v", bad, appErr)
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// API token scopes, from least to most privileged. Browser sessions of the
+// local admin always carry ScopeAdmin.
+const (
+	ScopeReadOnly = "read-only"
+	ScopeOperator = "operator"
+	ScopeAdmin    = "admin"
+)
+
+// Principal kinds. PrincipalAnonymous is used for every request when
+// BOXPILOT_AUTH=off.
+const (
+	PrincipalSession   = "session"
+	PrincipalToken     = "token"
+	PrincipalAnonymous = "anonymous"
+)
+
+const (
+	// SessionCookieName holds the browser session token.
+	SessionCookieName = "boxpilot_session"
+	// APITokenPrefix starts every API token, so tokens are easy to spot in
+	// headers and secret scanners.
+	APITokenPrefix = "bpt_"
+
+	defaultAdminUsername    = "admin"
+	defaultSessionTTLHours  = 7 * 24
+	defaultLoginMaxFailures = 5
+	loginFailureWindow      = 15 * time.Minute
+	minPasswordLength       = 8
+	maxPasswordLength       = 72 // bcrypt ignores the rest
+	maxUsernameLength       = 64
+	maxAPITokenNameLength   = 64
+	maxAPITokenDays         = 3650
+	apiTokenPrefixLength    = len(APITokenPrefix) + 6
+	// apiTokenTouchInterval limits last_used_at writes to one per token per
+	// interval, so busy scripts do not write on every request.
+	apiTokenTouchInterval = time.Minute
+)
+
+var scopeRank = map[string]int{ScopeReadOnly: 1, ScopeOperator: 2, ScopeAdmin: 3}
+
+// ValidScope reports whether scope is one of the API token scopes.
+func ValidScope(scope string) bool {
+	return scopeRank[scope] > 0
+}
+
+// ScopeAllows reports whether a principal with scope have may call a route
+// that needs scope need.
+func ScopeAllows(have, need string) bool {
+	return ValidScope(have) && scopeRank[have] >= scopeRank[need]
+}
+
+// AuthEnabled reports whether /api/v1 requires a login. BOXPILOT_AUTH=off
+// turns it off for deployments that authenticate in a reverse proxy.
+func AuthEnabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("BOXPILOT_AUTH"))) {
+	case "off", "false", "0", "disabled":
+		return false
+	}
+	return true
+}
+
+// Principal is who a request acts as.
+type Principal struct {
+	Kind      string
+	Scope     string
+	UserID    string
+	Username  string
+	TokenID   string
+	TokenName string
+	// SessionHash identifies the current session, so a password change can
+	// keep it while revoking the others.
+	SessionHash string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal carried by ctx, or nil.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// AnonymousPrincipal is the principal of every request when auth is off.
+func AnonymousPrincipal() *Principal {
+	return &Principal{Kind: PrincipalAnonymous, Scope: ScopeAdmin}
+}
+
+func hashAuthToken(token string) string {
+	return util.SHA256Hex([]byte(token))
+}
+
+func newAuthSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func sessionTTL() time.Duration {
+	return time.Duration(positiveEnvInt("BOXPILOT_SESSION_TTL_HOURS", defaultSessionTTLHours)) * time.Hour
+}
+
+// setupToken guards the first-start setup endpoint. It is set by
+// BootstrapAuth while no user exists and cleared once one is created.
+var setupToken struct {
+	mu    sync.Mutex
+	value string
+}
+
+func currentSetupToken() string {
+	setupToken.mu.Lock()
+	defer setupToken.mu.Unlock()
+	return setupToken.value
+}
+
+func setSetupToken(v string) {
+	setupToken.mu.Lock()
+	setupToken.value = v
+	setupToken.mu.Unlock()
+}
+
+// BootstrapAuth prepares first-start setup. When no user exists it creates
+// one from BOXPILOT_ADMIN_USERNAME / BOXPILOT_ADMIN_PASSWORD if the password
+// is set; otherwise it arms the setup endpoint with BOXPILOT_SETUP_TOKEN, or
+// a random token that is logged once.
+func BootstrapAuth(ctx context.Context, db *sql.DB) error {
+	if !AuthEnabled() {
+		authLog.WarnContext(ctx, "authentication is disabled by BOXPILOT_AUTH; every API client has admin access")
+		return nil
+	}
+	required, err := SetupRequired(db)
+	if err != nil || !required {
+		return err
+	}
+	if password := os.Getenv("BOXPILOT_ADMIN_PASSWORD"); password != "" {
+		username := strings.TrimSpace(os.Getenv("BOXPILOT_ADMIN_USERNAME"))
+		if username == "" {
+			username = defaultAdminUsername
+		}
+		if _, appErr := createFirstAdmin(db, username, password); appErr != nil {
+			if appErr.Code == errorx.AUTHAlreadySetUp {
+				return nil
+			}
+			return fmt.Errorf("BOXPILOT_ADMIN_PASSWORD: %s", appErr.Message)
+		}
+		authLog.InfoContext(ctx, "created admin user from environment", "username", username)
+		return nil
+	}
+	if token := strings.TrimSpace(os.Getenv("BOXPILOT_SETUP_TOKEN")); token != "" {
+		setSetupToken(token)
+		authLog.WarnContext(ctx, "no admin user yet; finish setup in the web UI with BOXPILOT_SETUP_TOKEN")
+		return nil
+	}
+	token, err := newAuthSecret()
+	if err != nil {
+		return err
+	}
+	setSetupToken(token)
+	authLog.WarnContext(ctx, "no admin user yet; finish setup in the web UI with this setup token", "setup_token", token)
+	return nil
+}
+
+// SetupRequired reports whether no user exists yet.
+func SetupRequired(db *sql.DB) (bool, error) {
+	n, err := repo.CountAuthUsers(db)
+	return n == 0, err
+}
+
+// SetupAdmin creates the first user, after checking the setup token logged
+// by BootstrapAuth. Failed attempts count against the client's login limit.
+func SetupAdmin(ctx context.Context, db *sql.DB, clientIP, token, username, password string) (*repo.AuthUserRow, *errorx.AppError) {
+	now := time.Now()
+	if appErr := loginLimits.reserve(clientIP, now); appErr != nil {
+		return nil, appErr
+	}
+	expected := currentSetupToken()
+	if expected == "" || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(expected)) != 1 {
+		required, err := SetupRequired(db)
+		if err != nil {
+			loginLimits.refund(clientIP, now)
+			return nil, errorx.New(errorx.DBError, "load users")
+		}
+		if !required {
+			loginLimits.refund(clientIP, now)
+			return nil, errorx.New(errorx.AUTHAlreadySetUp, "admin user already exists")
+		}
+		return nil, errorx.New(errorx.AUTHUnauthorized, "invalid setup token")
+	}
+	user, appErr := createFirstAdmin(db, username, password)
+	if appErr != nil {
+		loginLimits.refund(clientIP, now)
+		return nil, appErr
+	}
+	setSetupToken("")
+	loginLimits.reset(clientIP)
+	authLog.InfoContext(ctx, "admin user created by setup", "username", user.Username, "client_ip", clientIP)
+	return user, nil
+}
+
+func createFirstAdmin(db *sql.DB, username, password string) (*repo.AuthUserRow, *errorx.AppError) {
+	username = strings.TrimSpace(username)
+	if appErr := validateUsername(username); appErr != nil {
+		return nil, appErr
+	}
+	hash, appErr := hashPassword(password)
+	if appErr != nil {
+		return nil, appErr
+	}
+	now := util.NowRFC3339()
+	user := repo.AuthUserRow{ID: util.NewID(), Username: username, PasswordHash: hash, CreatedAt: now, UpdatedAt: now}
+	created, err := repo.CreateFirstAuthUser(db, user)
+	if err != nil {
+		return nil, errorx.New(errorx.DBError, "create user")
+	}
+	if !created {
+		return nil, errorx.New(errorx.AUTHAlreadySetUp, "admin user already exists")
+	}
+	return &user, nil
+}
+
+func validateUsername(username string) *errorx.AppError {
+	if username == "" {
+		return errorx.New(errorx.REQMissingField, "username required")
+	}
+	if len(username) > maxUsernameLength || strings.IndexFunc(username, unicode.IsSpace) >= 0 {
+		return errorx.New(errorx.REQInvalidField, "username must be at most 64 characters without spaces")
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, *errorx.AppError) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", errorx.New(errorx.REQInvalidField, "password must be 8 to 72 bytes").WithDetails(map[string]any{
+			"min": minPasswordLength,
+			"max": maxPasswordLength,
+		})
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errorx.New(errorx.InternalError, "hash password")
+	}
+	return string(hash), nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// comparePassword checks password against hash. A nil hash is compared
+// against a throwaway hash so unknown usernames take as long as wrong
+// passwords.
+func comparePassword(hash []byte, password string) bool {
+	if hash == nil {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("boxpilot-unknown-user"), bcrypt.DefaultCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// Login checks a username and password. Clients are limited to
+// BOXPILOT_LOGIN_MAX_FAILURES failures per 15 minutes.
+func Login(ctx context.Context, db *sql.DB, clientIP, username, password string) (*repo.AuthUserRow, *errorx.AppError) {
+	now := time.Now()
+	if appErr := loginLimits.reserve(clientIP, now); appErr != nil {
+		return nil, appErr
+	}
+	required, err := SetupRequired(db)
+	if err != nil {
+		loginLimits.refund(clientIP, now)
+		return nil, errorx.New(errorx.DBError, "load users")
+	}
+	if required {
+		loginLimits.refund(clientIP, now)
+		return nil, errorx.New(errorx.AUTHSetupRequired, "no admin user yet; finish setup first")
+	}
+	user, err := repo.GetAuthUserByUsername(db, strings.TrimSpace(username))
+	if err != nil {
+		loginLimits.refund(clientIP, now)
+		return nil, errorx.New(errorx.DBError, "load user")
+	}
+	var hash []byte
+	if user != nil {
+		hash = []byte(user.PasswordHash)
+	}
+	if !comparePassword(hash, password) {
+		authLog.WarnContext(ctx, "login failed", "username", username, "client_ip", clientIP)
+		return nil, errorx.New(errorx.AUTHInvalidCredentials, "invalid username or password")
+	}
+	loginLimits.reset(clientIP)
+	return user, nil
+}
+
+// StartSession creates a session for user and returns the cookie value.
+func StartSession(db *sql.DB, user *repo.AuthUserRow, userAgent, clientIP string) (string, time.Time, error) {
+	token, err := newAuthSecret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now().UTC()
+	expires := now.Add(sessionTTL())
+	_ = repo.PruneAuthSessions(db, now.Format(util.RFC3339))
+	if len(userAgent) > 256 {
+		userAgent = userAgent[:256]
+	}
+	err = repo.CreateAuthSession(db, repo.AuthSessionRow{
+		TokenHash: hashAuthToken(token),
+		UserID:    user.ID,
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+		CreatedAt: now.Format(util.RFC3339),
+		ExpiresAt: expires.Format(util.RFC3339),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expires, nil
+}
+
+// SessionPrincipal resolves a session cookie. It returns nil for unknown or
+// expired sessions.
+func SessionPrincipal(db *sql.DB, token string) (*Principal, error) {
+	hash := hashAuthToken(token)
+	session, err := repo.GetAuthSession(db, hash)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	if expired(session.ExpiresAt, time.Now()) {
+		return nil, repo.DeleteAuthSession(db, hash)
+	}
+	user, err := repo.GetAuthUser(db, session.UserID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &Principal{
+		Kind:        PrincipalSession,
+		Scope:       ScopeAdmin,
+		UserID:      user.ID,
+		Username:    user.Username,
+		SessionHash: hash,
+	}, nil
+}
+
+// EndSession deletes the session behind a cookie value.
+func EndSession(db *sql.DB, token string) error {
+	return repo.DeleteAuthSession(db, hashAuthToken(token))
+}
+
+// ChangePassword replaces the password of the session's user and signs out
+// every other session of that user.
+func ChangePassword(ctx context.Context, db *sql.DB, p *Principal, current, next string) *errorx.AppError {
+	if p == nil || p.Kind != PrincipalSession {
+		return errorx.New(errorx.AUTHForbidden, "password can only be changed from a signed-in session")
+	}
+	user, err := repo.GetAuthUser(db, p.UserID)
+	if err != nil {
+		return errorx.New(errorx.DBError, "load user")
+	}
+	if user == nil || !comparePassword([]byte(user.PasswordHash), current) {
+		return errorx.New(errorx.AUTHInvalidCredentials, "current password is wrong")
+	}
+	hash, appErr := hashPassword(next)
+	if appErr != nil {
+		return appErr
+	}
+	if err := repo.UpdateAuthUserPassword(db, user.ID, hash, util.NowRFC3339()); err != nil {
+		return errorx.New(errorx.DBError, "update password")
+	}
+	if err := repo.DeleteAuthSessionsForUser(db, user.ID, p.SessionHash); err != nil {
+		return errorx.New(errorx.DBError, "revoke sessions")
+	}
+	authLog.InfoContext(ctx, "password changed", "username", user.Username)
+	return nil
+}
+
+// CreateAPIToken stores a new token and returns it; the token itself is only
+// available here. expiresInDays 0 means it never expires.
+func CreateAPIToken(ctx context.Context, db *sql.DB, name, scope string, expiresInDays int) (repo.APITokenRow, string, *errorx.AppError) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return repo.APITokenRow{}, "", errorx.New(errorx.REQMissingField, "name required")
+	}
+	if len(name) > maxAPITokenNameLength {
+		return repo.APITokenRow{}, "", errorx.New(errorx.REQInvalidField, "name must be at most 64 characters")
+	}
+	if !ValidScope(scope) {
+		return repo.APITokenRow{}, "", errorx.New(errorx.REQInvalidField, "invalid scope").WithDetails(map[string]any{
+			"scope":     scope,
+			"supported": []string{ScopeReadOnly, ScopeOperator, ScopeAdmin},
+		})
+	}
+	if expiresInDays < 0 || expiresInDays > maxAPITokenDays {
+		return repo.APITokenRow{}, "", errorx.New(errorx.REQInvalidField, "expires_in_days must be 0 to 3650")
+	}
+	secret, err := newAuthSecret()
+	if err != nil {
+		return repo.APITokenRow{}, "", errorx.New(errorx.InternalError, "generate token")
+	}
+	token := APITokenPrefix + secret
+	now := time.Now().UTC()
+	row := repo.APITokenRow{
+		ID:        util.NewID(),
+		Name:      name,
+		Scope:     scope,
+		TokenHash: hashAuthToken(token),
+		Prefix:    token[:apiTokenPrefixLength],
+		CreatedAt: now.Format(util.RFC3339),
+	}
+	if expiresInDays > 0 {
+		row.ExpiresAt = sql.NullString{String: now.AddDate(0, 0, expiresInDays).Format(util.RFC3339), Valid: true}
+	}
+	if err := repo.CreateAPIToken(db, row); err != nil {
+		return repo.APITokenRow{}, "", errorx.New(errorx.DBError, "create api token")
+	}
+	authLog.InfoContext(ctx, "api token created", "token_id", row.ID, "name", row.Name, "scope", row.Scope)
+	return row, token, nil
+}
+
+// DeleteAPIToken revokes a token.
+func DeleteAPIToken(ctx context.Context, db *sql.DB, id string) *errorx.AppError {
+	deleted, err := repo.DeleteAPIToken(db, id)
+	if err != nil {
+		return errorx.New(errorx.DBError, "delete api token")
+	}
+	if !deleted {
+		return errorx.New(errorx.AUTHTokenNotFound, "api token not found")
+	}
+	authLog.InfoContext(ctx, "api token revoked", "token_id", id)
+	return nil
+}
+
+// TokenPrincipal resolves an API token. It returns nil for unknown or
+// expired tokens.
+func TokenPrincipal(db *sql.DB, token string) (*Principal, error) {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return nil, nil
+	}
+	row, err := repo.GetAPITokenByHash(db, hashAuthToken(token))
+	if err != nil || row == nil {
+		return nil, err
+	}
+	now := time.Now()
+	if row.ExpiresAt.Valid && expired(row.ExpiresAt.String, now) {
+		return nil, nil
+	}
+	if !row.LastUsedAt.Valid || expired(row.LastUsedAt.String, now.Add(-apiTokenTouchInterval)) {
+		_ = repo.TouchAPIToken(db, row.ID, now.UTC().Format(util.RFC3339))
+	}
+	return &Principal{Kind: PrincipalToken, Scope: row.Scope, TokenID: row.ID, TokenName: row.Name}, nil
+}
+
+// expired reports whether the RFC3339 time at is not after now. Unparseable
+// times count as expired.
+func expired(at string, now time.Time) bool {
+	t, err := time.Parse(time.RFC3339, at)
+	return err != nil || !t.After(now)
+}
+
+var loginLimits = newLoginLimiter(positiveEnvInt("BOXPILOT_LOGIN_MAX_FAILURES", defaultLoginMaxFailures), loginFailureWindow)
+
+// loginLimiter counts failed logins per client in a sliding window.
+type loginLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	failures map[string][]time.Time
+}
+
+func newLoginLimiter(max int, window time.Duration) *loginLimiter {
+	return &loginLimiter{max: max, window: window, failures: map[string][]time.Time{}}
+}
+
+// reserve records an attempt for key as a failure before its credentials are
+// checked, so concurrent attempts cannot all pass the limit while bcrypt runs.
+// Callers refund the attempt when it ends for another reason than bad
+// credentials and reset key after a success.
+func (l *loginLimiter) reserve(key string, now time.Time) *errorx.AppError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent := l.recent(key, now)
+	if len(recent) >= l.max {
+		wait := recent[len(recent)-l.max].Add(l.window).Sub(now)
+		return errorx.New(errorx.AUTHRateLimited, "too many failed attempts; try again later").WithDetails(map[string]any{
+			"retry_after_sec": int(math.Ceil(wait.Seconds())),
+		})
+	}
+	l.failures[key] = append(recent, now)
+	if len(l.failures) > 10000 {
+		for k := range l.failures {
+			l.recent(k, now)
+		}
+	}
+	return nil
+}
+
+// refund drops the attempt reserved for key at the given time.
+func (l *loginLimiter) refund(key string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	all := l.failures[key]
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].Equal(at) {
+			all = append(all[:i:i], all[i+1:]...)
+			break
+		}
+	}
+	if len(all) == 0 {
+		delete(l.failures, key)
+		return
+	}
+	l.failures[key] = all
+}
+
+func (l *loginLimiter) reset(key string) {
+	l.mu.Lock()
+	delete(l.failures, key)
+	l.mu.Unlock()
+}
+
+// recent drops failures older than the window; callers hold mu.
+func (l *loginLimiter) recent(key string, now time.Time) []time.Time {
+	all := l.failures[key]
+	i := 0
+	for i < len(all) && now.Sub(all[i]) >= l.window {
+		i++
+	}
+	if i == len(all) {
+		delete(l.failures, key)
+		return nil
+	}
+	l.failures[key] = all[i:]
+	return all[i:]
+}
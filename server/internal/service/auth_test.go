@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/util/errorx"
+)
+
+func TestScopeAllows(t *testing.T) {
+	cases := []struct {
+		have, need string
+		want       bool
+	}{
+		{ScopeReadOnly, ScopeReadOnly, true},
+		{ScopeReadOnly, ScopeOperator, false},
+		{ScopeOperator, ScopeReadOnly, true},
+		{ScopeOperator, ScopeAdmin, false},
+		{ScopeAdmin, ScopeOperator, true},
+		{"", ScopeReadOnly, false},
+		{"root", ScopeReadOnly, false},
+	}
+	for _, tc := range cases {
+		if got := ScopeAllows(tc.have, tc.need); got != tc.want {
+			t.Errorf("ScopeAllows(%q, %q) = %v, want %v", tc.have, tc.need, got, tc.want)
+		}
+	}
+}
+
+func TestLoginLimiter(t *testing.T) {
+	l := newLoginLimiter(3, time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+	for i := 0; i < 3; i++ {
+		if appErr := l.reserve("1.2.3.4", now.Add(time.Duration(i)*time.Second)); appErr != nil {
+			t.Fatalf("attempt %d limited: %v", i, appErr)
+		}
+	}
+	appErr := l.reserve("1.2.3.4", now.Add(10*time.Second))
+	if appErr == nil || appErr.Code != errorx.AUTHRateLimited {
+		t.Fatalf("reserve after 3 failures = %v, want AUTH_RATE_LIMITED", appErr)
+	}
+	if got := appErr.Details["retry_after_sec"]; got != 50 {
+		t.Fatalf("retry_after_sec = %v, want 50", got)
+	}
+	if appErr := l.reserve("5.6.7.8", now); appErr != nil {
+		t.Fatalf("other client limited: %v", appErr)
+	}
+	// A refunded attempt frees its slot.
+	l.refund("1.2.3.4", now.Add(2*time.Second))
+	if appErr := l.reserve("1.2.3.4", now.Add(11*time.Second)); appErr != nil {
+		t.Fatalf("limited after a refund: %v", appErr)
+	}
+	// The oldest failure leaves the window, freeing one attempt.
+	if appErr := l.reserve("1.2.3.4", now.Add(time.Minute)); appErr != nil {
+		t.Fatalf("still limited after the window: %v", appErr)
+	}
+	l.reset("1.2.3.4")
+	for i := 0; i < 3; i++ {
+		if appErr := l.reserve("1.2.3.4", now.Add(time.Minute)); appErr != nil {
+			t.Fatalf("limited after reset: %v", appErr)
+		}
+	}
+}
+
+func TestLoginLimitHoldsUnderConcurrentAttempts(t *testing.T) {
+	db := openTestDB(t)
+	if _, appErr := createFirstAdmin(db, "admin", "correct horse"); appErr != nil {
+		t.Fatal(appErr)
+	}
+	prev := loginLimits
+	loginLimits = newLoginLimiter(3, time.Minute)
+	t.Cleanup(func() { loginLimits = prev })
+
+	const attempts = 20
+	codes := make(chan string, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, appErr := Login(context.Background(), db, "1.2.3.4", "admin", "wrong horse")
+			codes <- appErr.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	checked := 0
+	for code := range codes {
+		switch code {
+		case errorx.AUTHInvalidCredentials:
+			checked++
+		case errorx.AUTHRateLimited:
+		default:
+			t.Fatalf("unexpected error code %s", code)
+		}
+	}
+	if checked != 3 {
+		t.Fatalf("%d concurrent attempts reached the password check, want 3", checked)
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	if _, appErr := hashPassword("short"); appErr == nil || appErr.Code != errorx.REQInvalidField {
+		t.Fatalf("short password: %v", appErr)
+	}
+	hash, appErr := hashPassword("correct horse")
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if !comparePassword([]byte(hash), "correct horse") {
+		t.Fatal("password does not match its hash")
+	}
+	if comparePassword([]byte(hash), "wrong horse") {
+		t.Fatal("wrong password matches")
+	}
+	if comparePassword(nil, "correct horse") {
+		t.Fatal("nil hash matches")
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	for _, name := range []string{"", "two words", string(make([]byte, 65))} {
+		if validateUsername(name) == nil {
+			t.Errorf("validateUsername(%q) accepted", name)
+		}
+	}
+	if appErr := validateUsername("admin"); appErr != nil {
+		t.Fatal(appErr)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if expired("2026-01-02T03:04:06Z", now) {
+		t.Fatal("future time expired")
+	}
+	if !expired("2026-01-02T03:04:05Z", now) || !expired("not a time", now) {
+		t.Fatal("past or invalid time not expired")
+	}
+}
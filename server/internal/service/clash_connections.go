@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const clashAPITimeout = 3 * time.Second
+
+// ErrClashAPIDisabled is returned when SINGBOX_CLASH_API_ADDR is "off".
+var ErrClashAPIDisabled = errors.New("clash api disabled")
+
+// ClashConnection is one live connection tracked by the sing-box Clash API.
+// Chain runs from the outbound the route picked to the outbound that carried
+// the traffic, so the last element is the node and any before it are groups.
+type ClashConnection struct {
+	ID              string
+	Network         string
+	Inbound         string
+	SourceIP        string
+	SourcePort      string
+	DestinationIP   string
+	DestinationPort string
+	Host            string
+	Process         string
+	Rule            string
+	RulePayload     string
+	Chain           []string
+	Upload          int64
+	Download        int64
+	Start           time.Time
+}
+
+// Outbound returns the tag of the outbound that carried the connection.
+func (c ClashConnection) Outbound() string {
+	if len(c.Chain) == 0 {
+		return ""
+	}
+	return c.Chain[len(c.Chain)-1]
+}
+
+// Groups returns the group tags the connection passed through.
+func (c ClashConnection) Groups() []string {
+	if len(c.Chain) < 2 {
+		return nil
+	}
+	return c.Chain[:len(c.Chain)-1]
+}
+
+// ClashConnections is one /connections snapshot.
+type ClashConnections struct {
+	UploadTotal   int64
+	DownloadTotal int64
+	Connections   []ClashConnection
+}
+
+type clashConnectionsPayload struct {
+	UploadTotal   int64 `json:"uploadTotal"`
+	DownloadTotal int64 `json:"downloadTotal"`
+	Connections   []struct {
+		ID       string `json:"id"`
+		Metadata struct {
+			Network         string `json:"network"`
+			Type            string `json:"type"`
+			SourceIP        string `json:"sourceIP"`
+			SourcePort      string `json:"sourcePort"`
+			DestinationIP   string `json:"destinationIP"`
+			DestinationPort string `json:"destinationPort"`
+			Host            string `json:"host"`
+			ProcessPath     string `json:"processPath"`
+		} `json:"metadata"`
+		Upload      int64     `json:"upload"`
+		Download    int64     `json:"download"`
+		Start       time.Time `json:"start"`
+		Chains      []string  `json:"chains"`
+		Rule        string    `json:"rule"`
+		RulePayload string    `json:"rulePayload"`
+	} `json:"connections"`
+}
+
+// FetchClashConnections reads GET /connections from the Clash API.
+func FetchClashConnections(parent context.Context) (*ClashConnections, error) {
+	body, err := clashAPIRequest(parent, http.MethodGet, "/connections")
+	if err != nil {
+		return nil, err
+	}
+	var payload clashConnectionsPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("decode clash api /connections: %w", err)
+	}
+	out := &ClashConnections{
+		UploadTotal:   payload.UploadTotal,
+		DownloadTotal: payload.DownloadTotal,
+		Connections:   make([]ClashConnection, 0, len(payload.Connections)),
+	}
+	for _, p := range payload.Connections {
+		// The Clash API lists chains from the final outbound back to the
+		// first group; reverse them into routing order.
+		chain := make([]string, 0, len(p.Chains))
+		for i := len(p.Chains) - 1; i >= 0; i-- {
+			chain = append(chain, p.Chains[i])
+		}
+		inbound := p.Metadata.Type
+		if i := strings.Index(inbound, "/"); i >= 0 {
+			inbound = inbound[i+1:]
+		}
+		out.Connections = append(out.Connections, ClashConnection{
+			ID:              p.ID,
+			Network:         p.Metadata.Network,
+			Inbound:         inbound,
+			SourceIP:        p.Metadata.SourceIP,
+			SourcePort:      p.Metadata.SourcePort,
+			DestinationIP:   p.Metadata.DestinationIP,
+			DestinationPort: p.Metadata.DestinationPort,
+			Host:            p.Metadata.Host,
+			Process:         p.Metadata.ProcessPath,
+			Rule:            p.Rule,
+			RulePayload:     p.RulePayload,
+			Chain:           chain,
+			Upload:          p.Upload,
+			Download:        p.Download,
+			Start:           p.Start,
+		})
+	}
+	return out, nil
+}
+
+// CloseClashConnection closes one connection, or every connection when id is
+// empty.
+func CloseClashConnection(parent context.Context, id string) error {
+	path := "/connections"
+	if id != "" {
+		path += "/" + url.PathEscape(id)
+	}
+	_, err := clashAPIRequest(parent, http.MethodDelete, path)
+	return err
+}
+
+func clashAPIRequest(parent context.Context, method, path string) ([]byte, error) {
+	baseURL, enabled := ResolveClashAPIBaseURL()
+	if !enabled {
+		return nil, ErrClashAPIDisabled
+	}
+	ctx, cancel := context.WithTimeout(parent, clashAPITimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if secret := strings.TrimSpace(os.Getenv("SINGBOX_CLASH_API_SECRET")); secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("clash api %s %s status %d", method, path, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// ClashConnectionFilter selects connections. Outbound matches the final
+// outbound tag, Group any group in the chain and Rule a substring of the
+// matched rule; Query matches host, addresses, inbound, rule and chain. All
+// comparisons ignore case.
+type ClashConnectionFilter struct {
+	Outbound string
+	Group    string
+	Rule     string
+	Query    string
+}
+
+func (f ClashConnectionFilter) Matches(c ClashConnection) bool {
+	if v := strings.TrimSpace(f.Outbound); v != "" && !strings.EqualFold(c.Outbound(), v) {
+		return false
+	}
+	if v := strings.TrimSpace(f.Group); v != "" {
+		found := false
+		for _, g := range c.Groups() {
+			if strings.EqualFold(g, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if v := strings.ToLower(strings.TrimSpace(f.Rule)); v != "" && !strings.Contains(strings.ToLower(c.Rule+" "+c.RulePayload), v) {
+		return false
+	}
+	if v := strings.ToLower(strings.TrimSpace(f.Query)); v != "" {
+		hay := strings.ToLower(strings.Join([]string{
+			c.Host, c.DestinationIP, net.JoinHostPort(c.SourceIP, c.SourcePort), c.Inbound,
+			c.Network, c.Process, c.Rule, c.RulePayload, strings.Join(c.Chain, " "),
+		}, " "))
+		if !strings.Contains(hay, v) {
+			return false
+		}
+	}
+	return true
+}
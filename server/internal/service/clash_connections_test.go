@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const fakeClashConnections = `{
+  "downloadTotal": 2048, "uploadTotal": 1024,
+  "connections": [
+    {"id": "c1", "metadata": {"network": "tcp", "type": "mixed/mixed-in", "sourceIP": "192.168.1.20", "sourcePort": "51000",
+      "destinationIP": "142.250.1.1", "destinationPort": "443", "host": "www.google.com", "processPath": ""},
+     "upload": 100, "download": 900, "start": "2024-05-01T10:00:00Z", "chains": ["hk-1", "hk-auto", "proxy"],
+     "rule": "rule_set=geosite-google => route(proxy)", "rulePayload": ""},
+    {"id": "c2", "metadata": {"network": "udp", "type": "socks/socks-in", "sourceIP": "192.168.1.21", "sourcePort": "52000",
+      "destinationIP": "223.5.5.5", "destinationPort": "53", "host": ""},
+     "upload": 60, "download": 120, "start": "2024-05-01T10:00:05Z", "chains": ["direct"], "rule": "final"}
+  ]
+}`
+
+func TestFetchClashConnections(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/connections" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer s3cret" {
+			t.Errorf("missing bearer secret, got %q", got)
+		}
+		_, _ = w.Write([]byte(fakeClashConnections))
+	}))
+	defer srv.Close()
+	t.Setenv("SINGBOX_CLASH_API_ADDR", srv.URL)
+	t.Setenv("SINGBOX_CLASH_API_SECRET", "s3cret")
+
+	got, err := FetchClashConnections(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UploadTotal != 1024 || got.DownloadTotal != 2048 || len(got.Connections) != 2 {
+		t.Fatalf("unexpected snapshot: %+v", got)
+	}
+	c := got.Connections[0]
+	if c.Inbound != "mixed-in" || c.Host != "www.google.com" || c.Upload != 100 || c.Download != 900 || c.Start.IsZero() {
+		t.Fatalf("unexpected connection: %+v", c)
+	}
+	if c.Outbound() != "hk-1" || len(c.Groups()) != 2 || c.Groups()[0] != "proxy" || c.Groups()[1] != "hk-auto" {
+		t.Fatalf("chain not in routing order: %v", c.Chain)
+	}
+	if d := got.Connections[1]; d.Outbound() != "direct" || d.Groups() != nil {
+		t.Fatalf("unexpected direct chain: %v", d.Chain)
+	}
+}
+
+func TestClashConnectionFilter(t *testing.T) {
+	google := ClashConnection{
+		Host: "www.google.com", SourceIP: "192.168.1.20", SourcePort: "51000", Inbound: "mixed-in",
+		Rule: "rule_set=geosite-google => route(proxy)", Chain: []string{"proxy", "hk-auto", "hk-1"},
+	}
+	dns := ClashConnection{DestinationIP: "223.5.5.5", Inbound: "socks-in", Rule: "final", Chain: []string{"direct"}}
+	cases := []struct {
+		name   string
+		filter ClashConnectionFilter
+		google bool
+		dns    bool
+	}{
+		{"empty", ClashConnectionFilter{}, true, true},
+		{"outbound", ClashConnectionFilter{Outbound: "HK-1"}, true, false},
+		{"group is not outbound", ClashConnectionFilter{Outbound: "proxy"}, false, false},
+		{"group", ClashConnectionFilter{Group: "hk-auto"}, true, false},
+		{"node is not group", ClashConnectionFilter{Group: "direct"}, false, false},
+		{"rule", ClashConnectionFilter{Rule: "GEOSITE"}, true, false},
+		{"query source", ClashConnectionFilter{Query: "192.168.1.20:51000"}, true, false},
+		{"query address", ClashConnectionFilter{Query: "223.5"}, false, true},
+		{"combined", ClashConnectionFilter{Group: "proxy", Rule: "final"}, false, false},
+	}
+	for _, tc := range cases {
+		if got := tc.filter.Matches(google); got != tc.google {
+			t.Errorf("%s: google match = %v", tc.name, got)
+		}
+		if got := tc.filter.Matches(dns); got != tc.dns {
+			t.Errorf("%s: dns match = %v", tc.name, got)
+		}
+	}
+}
+
+func TestCloseClashConnection(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		paths = append(paths, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	t.Setenv("SINGBOX_CLASH_API_ADDR", srv.URL)
+
+	if err := CloseClashConnection(context.Background(), "a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CloseClashConnection(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[0] != "/connections/a%2Fb" || paths[1] != "/connections" {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+
+	t.Setenv("SINGBOX_CLASH_API_ADDR", "off")
+	if err := CloseClashConnection(context.Background(), "c1"); !errors.Is(err, ErrClashAPIDisabled) {
+		t.Fatalf("expected disabled error, got %v", err)
+	}
+}
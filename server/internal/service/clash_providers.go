@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"boxpilot/server/internal/parser"
+	"boxpilot/server/internal/util"
+)
+
+const (
+	providerMaxBytes     = 5 * 1024 * 1024
+	providerFetchTimeout = 30 * time.Second
+)
+
+// ProviderFetcher fetches a Clash provider payload. Injected for testing.
+type ProviderFetcher func(ctx context.Context, url string) ([]byte, error)
+
+// DefaultProviderFetcher is the production HTTP fetcher; payloads larger than
+// providerMaxBytes are rejected instead of being silently truncated.
+func DefaultProviderFetcher(ctx context.Context, url string) ([]byte, error) {
+	return fetchProvider(ctx, http.DefaultClient, url, nil)
+}
+
+func fetchProvider(ctx context.Context, client *http.Client, url string, decorate func(*http.Request)) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, providerFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if decorate != nil {
+		decorate(req)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, providerMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > providerMaxBytes {
+		return nil, fmt.Errorf("payload from %s exceeds %d bytes", url, providerMaxBytes)
+	}
+	return data, nil
+}
+
+// SubscriptionProviderDir is where provider payloads and converted rule sets of one
+// subscription are cached, next to the generated sing-box config.
+func SubscriptionProviderDir(configPath, subID string) string {
+	return filepath.Join(filepath.Dir(configPath), "providers", subID)
+}
+
+// ResolveClashProviders fetches the http proxy-providers and rule-providers of a
+// parsed Clash subscription. Provider proxies are merged into the outbounds and
+// rule-providers are rewritten to local sing-box source rule sets. Providers that
+// cannot be fetched (and have no cached copy) or converted are reported in Skipped.
+func ResolveClashProviders(ctx context.Context, configPath, subID string, body []byte, parsed parser.ParsedSubscription, fetch ProviderFetcher) parser.ParsedSubscription {
+	dir := SubscriptionProviderDir(configPath, subID)
+	parsed = resolveClashProxyProviders(ctx, dir, body, parsed, fetch)
+
+	ruleSets := make([]parser.RuleSetItem, 0, len(parsed.RuleSets))
+	for idx, rs := range parsed.RuleSets {
+		if rs.Behavior == "" {
+			ruleSets = append(ruleSets, rs)
+			continue
+		}
+		converted, err := convertClashRuleProvider(ctx, fetch, dir, rs)
+		if err != nil {
+			parsed.Skipped = append(parsed.Skipped, parser.SkippedEntry{
+				Index:    idx,
+				Name:     rs.Tag,
+				Protocol: "rule-provider",
+				Reason:   err.Error(),
+			})
+			continue
+		}
+		ruleSets = append(ruleSets, converted)
+	}
+	parsed.RuleSets = ruleSets
+	return parsed
+}
+
+// resolveClashProxyProviders merges the proxies of the http proxy-providers into
+// the outbounds. It leaves rule-providers alone and writes nothing but the
+// payload cache of providers it fetched.
+func resolveClashProxyProviders(ctx context.Context, dir string, body []byte, parsed parser.ParsedSubscription, fetch ProviderFetcher) parser.ParsedSubscription {
+	if len(parsed.ProxyProviders) == 0 {
+		return parsed
+	}
+	fetched := make(map[string][]parser.OutboundItem, len(parsed.ProxyProviders))
+	for idx, provider := range parsed.ProxyProviders {
+		maxAge := time.Duration(provider.IntervalSec) * time.Second
+		data, err := fetchProviderWithCache(ctx, fetch, provider.URL, dir, "proxy-"+providerFileKey(provider.Name), maxAge)
+		if err == nil {
+			var items []parser.OutboundItem
+			if items, err = parser.ParseProxyProviderPayload(data); err == nil {
+				fetched[provider.Name] = items
+				continue
+			}
+		}
+		parsed.Skipped = append(parsed.Skipped, parser.SkippedEntry{
+			Index:    idx,
+			Name:     provider.Name,
+			Protocol: "proxy-provider",
+			Reason:   err.Error(),
+		})
+	}
+	return parser.ApplyClashProxyProviders(body, parsed, fetched)
+}
+
+func convertClashRuleProvider(ctx context.Context, fetch ProviderFetcher, dir string, rs parser.RuleSetItem) (parser.RuleSetItem, error) {
+	if rs.URL == "" {
+		return parser.RuleSetItem{}, fmt.Errorf("local rule-provider path is not reachable")
+	}
+	key := "rule-" + providerFileKey(rs.Tag)
+	data, err := fetchProviderWithCache(ctx, fetch, rs.URL, dir, key, 0)
+	if err != nil {
+		return parser.RuleSetItem{}, err
+	}
+	source, err := parser.ConvertClashRuleProvider(data, rs.Behavior, rs.Format)
+	if err != nil {
+		return parser.RuleSetItem{}, err
+	}
+	if err := util.AtomicWrite(dir, key+".json", source); err != nil {
+		return parser.RuleSetItem{}, fmt.Errorf("write rule set: %w", err)
+	}
+	return parser.RuleSetItem{
+		Tag:        rs.Tag,
+		SourceType: "local",
+		Format:     "source",
+		Path:       filepath.Join(dir, key+".json"),
+	}, nil
+}
+
+// fetchProviderWithCache returns the cached payload while it is younger than
+// maxAge, otherwise fetches it again. A failed fetch falls back to the cache.
+func fetchProviderWithCache(ctx context.Context, fetch ProviderFetcher, url, dir, key string, maxAge time.Duration) ([]byte, error) {
+	cachePath := filepath.Join(dir, key+".cache")
+	if maxAge > 0 {
+		if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < maxAge {
+			if data, err := os.ReadFile(cachePath); err == nil {
+				return data, nil
+			}
+		}
+	}
+	data, fetchErr := fetch(ctx, url)
+	if fetchErr == nil {
+		_ = util.AtomicWrite(dir, key+".cache", data)
+		return data, nil
+	}
+	if cached, err := os.ReadFile(cachePath); err == nil {
+		return cached, nil
+	}
+	return nil, fmt.Errorf("fetch %s: %w", url, fetchErr)
+}
+
+func providerFileKey(name string) string {
+	return util.SHA256Hex([]byte(name))[:16]
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"boxpilot/server/internal/parser"
+)
+
+const clashProviderSubscription = `
+proxies:
+  - name: inline-hk
+    type: trojan
+    server: hk.example.com
+    port: 443
+    password: secret
+proxy-providers:
+  airport:
+    type: http
+    url: https://example.com/provider.yaml
+    interval: 3600
+    exclude-filter: "expire"
+proxy-groups:
+  - name: OpenAI
+    type: select
+    use:
+      - airport
+rules:
+  - RULE-SET,openai,OpenAI
+rule-providers:
+  openai:
+    type: http
+    behavior: classical
+    format: text
+    url: https://example.com/openai.list
+`
+
+func TestResolveClashProviders_MergesProxiesAndConvertsRuleSets(t *testing.T) {
+	tmp := t.TempDir()
+	configPath := filepath.Join(tmp, "sing-box.json")
+	fetch := func(_ context.Context, url string) ([]byte, error) {
+		switch url {
+		case "https://example.com/provider.yaml":
+			return []byte(`
+proxies:
+  - {name: us-1, type: trojan, server: us.example.com, port: 443, password: pw}
+  - {name: expire 2026-12-01, type: trojan, server: info.example.com, port: 443, password: pw}
+`), nil
+		case "https://example.com/openai.list":
+			return []byte("DOMAIN-SUFFIX,openai.com\nDST-PORT,443\n"), nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	parsed, err := parser.ParseSubscriptionBundle([]byte(clashProviderSubscription))
+	if err != nil {
+		t.Fatalf("ParseSubscriptionBundle: %v", err)
+	}
+	parsed = ResolveClashProviders(context.Background(), configPath, "sub-1", []byte(clashProviderSubscription), parsed, fetch)
+
+	if len(parsed.Outbounds) != 2 || parsed.Outbounds[1].Tag != "us-1" {
+		t.Fatalf("expected inline and provider outbounds, got %+v", parsed.Outbounds)
+	}
+	if len(parsed.BusinessGroups) != 1 || len(parsed.BusinessGroups[0].NodeTags) != 1 || parsed.BusinessGroups[0].NodeTags[0] != "us-1" {
+		t.Fatalf("expected provider proxies in business group, got %+v", parsed.BusinessGroups)
+	}
+	if len(parsed.Skipped) != 0 {
+		t.Fatalf("unexpected skipped entries: %+v", parsed.Skipped)
+	}
+	if len(parsed.RuleSets) != 1 {
+		t.Fatalf("expected one converted rule set, got %+v", parsed.RuleSets)
+	}
+	rs := parsed.RuleSets[0]
+	if rs.SourceType != "local" || rs.Format != "source" || rs.URL != "" {
+		t.Fatalf("expected local source rule set, got %+v", rs)
+	}
+	raw, err := os.ReadFile(rs.Path)
+	if err != nil {
+		t.Fatalf("read converted rule set: %v", err)
+	}
+	var doc struct {
+		Rules []map[string]any `json:"rules"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil || len(doc.Rules) != 2 {
+		t.Fatalf("unexpected converted rule set: %s", raw)
+	}
+
+	// A later refresh whose fetches fail keeps serving the cached payloads.
+	offline := func(context.Context, string) ([]byte, error) { return nil, os.ErrNotExist }
+	again, _ := parser.ParseSubscriptionBundle([]byte(clashProviderSubscription))
+	again.ProxyProviders[0].IntervalSec = 0
+	again = ResolveClashProviders(context.Background(), configPath, "sub-1", []byte(clashProviderSubscription), again, offline)
+	if len(again.Outbounds) != 2 || len(again.RuleSets) != 1 || len(again.Skipped) != 0 {
+		t.Fatalf("expected cached providers on fetch failure, got outbounds=%+v rulesets=%+v skipped=%+v", again.Outbounds, again.RuleSets, again.Skipped)
+	}
+}
+
+func TestResolveClashProviders_ReportsUnavailableProviders(t *testing.T) {
+	tmp := t.TempDir()
+	configPath := filepath.Join(tmp, "sing-box.json")
+	offline := func(context.Context, string) ([]byte, error) { return nil, os.ErrNotExist }
+
+	parsed, err := parser.ParseSubscriptionBundle([]byte(clashProviderSubscription))
+	if err != nil {
+		t.Fatalf("ParseSubscriptionBundle: %v", err)
+	}
+	parsed = ResolveClashProviders(context.Background(), configPath, "sub-1", []byte(clashProviderSubscription), parsed, offline)
+	if len(parsed.Outbounds) != 1 || len(parsed.RuleSets) != 0 {
+		t.Fatalf("expected only inline outbound and no rule sets, got %+v / %+v", parsed.Outbounds, parsed.RuleSets)
+	}
+	if len(parsed.Skipped) != 2 || parsed.Skipped[0].Protocol != "proxy-provider" || parsed.Skipped[1].Protocol != "rule-provider" {
+		t.Fatalf("expected provider failures in skipped entries, got %+v", parsed.Skipped)
+	}
+}
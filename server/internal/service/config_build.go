@@ -2,6 +2,7 @@ package service
 
 import (
 	"database/sql"
+	"strconv"
 	"strings"
 
 	"boxpilot/server/internal/generator"
@@ -24,6 +25,10 @@ func BuildConfigFromDB(db *sql.DB, httpProxy, socksProxy generator.ProxyInbound,
 		return nil, nil, "", err
 	}
 	nodes = FilterForwardingNodes(nodes, policy)
+	nodes, replaced, err := CanonicalForwardingNodes(db, nodes)
+	if err != nil {
+		return nil, nil, "", err
+	}
 	if forwardingRunning && (httpProxy.Enabled || socksProxy.Enabled) && len(nodes) == 0 {
 		return nil, nil, "", errorx.New(errorx.CFGNoEnabledNodes, "no forwarding nodes enabled")
 	}
@@ -82,12 +87,24 @@ func BuildConfigFromDB(db *sql.DB, httpProxy, socksProxy generator.ProxyInbound,
 		}
 		extras.BusinessNodePools[target] = append(extras.BusinessNodePools[target], tag)
 	}
+	for target, pool := range extras.BusinessNodePools {
+		extras.BusinessNodePools[target] = RemapNodeTags(pool, replaced)
+	}
+	businessGroupRows, err := repo.ListEnabledSubscriptionBusinessGroups(db)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	extras.BusinessGroupPolicies = BusinessGroupPolicies(businessGroupRows)
 	selectionRows, err := repo.ListRuntimeGroupSelections(db)
 	if err != nil {
 		return nil, nil, "", err
 	}
 	for _, s := range selectionRows {
-		extras.GroupSelections[s.GroupTag] = s.SelectedOutbound
+		extras.GroupSelections[s.GroupTag] = canonicalNodeTag(s.SelectedOutbound, replaced)
+	}
+	extras.FetchProxy, err = LoadFetchProxy(db, tags)
+	if err != nil {
+		return nil, nil, "", err
 	}
 	cfg, err := generator.BuildConfigWithRuntime(httpProxy, socksProxy, routing, outbounds, extras)
 	if err != nil {
@@ -97,6 +114,39 @@ func BuildConfigFromDB(db *sql.DB, httpProxy, socksProxy generator.ProxyInbound,
 	return cfg, tags, hash, nil
 }
 
+// canonicalNodeTag returns the tag of the canonical copy when tag belongs to a
+// node dropped by deduplication.
+func canonicalNodeTag(tag string, replaced map[string]string) string {
+	if canonical, ok := replaced[tag]; ok {
+		return canonical
+	}
+	return tag
+}
+
+// BusinessGroupPolicies maps stored business group semantics to generator
+// policies keyed by target. When several subscriptions define the same target,
+// the oldest subscription wins.
+func BusinessGroupPolicies(rows []repo.SubscriptionBusinessGroupRow) map[string]generator.BusinessGroupPolicy {
+	out := make(map[string]generator.BusinessGroupPolicy, len(rows))
+	for _, row := range rows {
+		target := strings.TrimSpace(row.TargetOutbound)
+		if _, ok := out[target]; ok || target == "" {
+			continue
+		}
+		policy := generator.BusinessGroupPolicy{
+			Type:      row.GroupType,
+			URL:       row.TestURL,
+			Tolerance: row.ToleranceMs,
+			Strategy:  row.Strategy,
+		}
+		if row.IntervalSec > 0 {
+			policy.Interval = strconv.Itoa(row.IntervalSec) + "s"
+		}
+		out[target] = policy
+	}
+	return out
+}
+
 func FilterForwardingNodes(nodes []repo.NodeRow, policy ForwardingPolicy) []repo.NodeRow {
 	if !policy.HealthyOnlyEnabled {
 		return nodes
@@ -2,8 +2,11 @@ package service
 
 import (
 	"database/sql"
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"boxpilot/server/internal/generator"
 	"boxpilot/server/internal/store/repo"
 )
 
@@ -92,3 +95,94 @@ func TestFilterForwardingNodes(t *testing.T) {
 		}
 	})
 }
+
+func TestBusinessGroupPolicies(t *testing.T) {
+	policies := BusinessGroupPolicies([]repo.SubscriptionBusinessGroupRow{
+		{SubID: "a", TargetOutbound: "OpenAI", GroupType: "url-test", TestURL: "https://cp.example.com", IntervalSec: 300, ToleranceMs: 50},
+		{SubID: "b", TargetOutbound: "OpenAI", GroupType: "fallback"},
+		{SubID: "b", TargetOutbound: "Streaming", GroupType: "load-balance", Strategy: "round-robin"},
+	})
+	if len(policies) != 2 {
+		t.Fatalf("expected 2 policies, got %+v", policies)
+	}
+	openAI := policies["OpenAI"]
+	if openAI.Type != "url-test" || openAI.Interval != "300s" || openAI.Tolerance != 50 || openAI.URL != "https://cp.example.com" {
+		t.Fatalf("expected first subscription to win for OpenAI, got %+v", openAI)
+	}
+	if streaming := policies["Streaming"]; streaming.Interval != "" || streaming.Strategy != "round-robin" {
+		t.Fatalf("unexpected streaming policy: %+v", streaming)
+	}
+}
+
+func TestBuildConfigFromDBRemapsDeduplicatedNodeTags(t *testing.T) {
+	db := openTestDB(t)
+	for _, id := range []string{"sub-a", "sub-b"} {
+		if err := repo.CreateSubscription(db, id, id, "https://example.com/"+id, "auto", SubscriptionSourceURL, 1, 0, 3600, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latency := 80
+	for _, n := range []repo.NodeRow{
+		{ID: "a-hk", SubID: "sub-a", Tag: "A-HK", Fingerprint: "fp-hk"},
+		{ID: "b-hk", SubID: "sub-b", Tag: "B-HK", Fingerprint: "fp-hk"},
+		{ID: "b-jp", SubID: "sub-b", Tag: "B-JP", Fingerprint: "fp-jp"},
+	} {
+		n.Name, n.Type, n.Enabled, n.ForwardingEnabled = n.Tag, "trojan", 1, 1
+		n.OutboundJSON = `{"type":"trojan","tag":"` + n.Tag + `","server":"` + n.ID + `.example","server_port":443,"password":"p"}`
+		if err := repo.CreateNode(db, n); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SetNodeProbeResult(db, n.ID, &latency, "ok", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only sub-b routes to Proxy, so its pool names its own copy of the shared
+	// endpoint, which dedup drops in favour of sub-a's.
+	err := repo.ReplaceSubscriptionRouting(db, "sub-b", nil,
+		[]repo.SubscriptionRuleRow{{ID: "r-1", SubID: "sub-b", SourceKind: "clash", MatcherType: "domain_suffix", MatcherValue: "example.org", TargetOutbound: "Proxy"}},
+		[]repo.SubscriptionGroupMemberRow{
+			{ID: "m-1", SubID: "sub-b", TargetOutbound: "Proxy", NodeTag: "B-HK"},
+			{ID: "m-2", SubID: "sub-b", TargetOutbound: "Proxy", NodeTag: "B-JP"},
+		}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpsertRuntimeGroupSelection(db, "biz-Proxy", "B-HK", "t0"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, tags, _, err := BuildConfigFromDB(db, generator.ProxyInbound{}, generator.ProxyInbound{}, generator.RoutingSettings{}, false)
+	if err != nil {
+		t.Fatalf("BuildConfigFromDB: %v", err)
+	}
+	if strings.Join(tags, ",") != "A-HK,B-JP" {
+		t.Fatalf("expected the shared endpoint once, got %v", tags)
+	}
+	var parsed struct {
+		Outbounds []struct {
+			Type      string   `json:"type"`
+			Tag       string   `json:"tag"`
+			Outbounds []string `json:"outbounds"`
+			Default   string   `json:"default"`
+		} `json:"outbounds"`
+	}
+	if err := json.Unmarshal(cfg, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, ob := range parsed.Outbounds {
+		if ob.Tag != "biz-Proxy" {
+			continue
+		}
+		found = true
+		if got := strings.Join(ob.Outbounds, ","); got != "biz-Proxy-auto,manual,A-HK,B-JP" {
+			t.Fatalf("expected the pool to use the canonical tag once, got %s", got)
+		}
+		if ob.Default != "A-HK" {
+			t.Fatalf("expected the stored selection to follow the canonical tag, got %q", ob.Default)
+		}
+	}
+	if !found {
+		t.Fatalf("biz-Proxy selector missing from %s", cfg)
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+)
+
+// Control-plane event types, also sent to webhooks. Data keys per type:
+//
+//	subscription.refreshed   sub_id, not_modified, nodes_total, nodes_added, nodes_updated, nodes_removed
+//	nodes.changed            source (refresh / api), sub_id or node_ids, counts where known,
+//	                         subscription_deleted when a subscription and its nodes were removed
+//	config.applied           reload_id, config_version, config_hash, nodes_included, duration_ms
+//	reload.failed            reload_id, code, error
+//	rollback.performed       reload_id, source, success
+//	group.selection_changed  group_tag, selected_outbound, previous_outbound
+//	forwarding.started       -
+//	forwarding.stopped       -
+const (
+	EventSubscriptionRefreshed = "subscription.refreshed"
+	EventNodesChanged          = "nodes.changed"
+	EventConfigApplied         = "config.applied"
+	EventReloadFailed          = "reload.failed"
+	EventRollbackPerformed     = "rollback.performed"
+	EventGroupSelectionChanged = "group.selection_changed"
+	EventForwardingStarted     = "forwarding.started"
+	EventForwardingStopped     = "forwarding.stopped"
+)
+
+// Progress event types. They are only streamed to dashboards (GET /events),
+// never sent to webhooks. Data keys per type:
+//
+//	reload.progress                reload_id, stage (build / check / write / restart / wait_ready / rollback)
+//	subscription.refresh_progress  sub_id, stage (fetch / parse / ingest)
+//	subscription.refresh_failed    sub_id, code, error
+//	node.probe                     run_id, node_id, status, latency_ms, error, completed, total
+//	traffic.sample                 sampled_at, source, rx/tx rate and totals; generated per stream
+const (
+	EventReloadProgress              = "reload.progress"
+	EventSubscriptionRefreshProgress = "subscription.refresh_progress"
+	EventSubscriptionRefreshFailed   = "subscription.refresh_failed"
+	EventNodeProbe                   = "node.probe"
+	EventTrafficSample               = "traffic.sample"
+)
+
+// Stream topics group event types for GET /events subscribers. An event type
+// can belong to several topics.
+const (
+	TopicControl = "control"
+	TopicReload  = "reload"
+	TopicRefresh = "refresh"
+	TopicProbe   = "probe"
+	TopicTraffic = "traffic"
+)
+
+const defaultEventQueue = 64
+
+var eventTopicTypes = map[string][]string{
+	TopicControl: EventTypes(),
+	TopicReload:  {EventReloadProgress, EventConfigApplied, EventReloadFailed, EventRollbackPerformed},
+	TopicRefresh: {EventSubscriptionRefreshProgress, EventSubscriptionRefreshed, EventSubscriptionRefreshFailed},
+	TopicProbe:   {EventNodeProbe},
+	TopicTraffic: {EventTrafficSample},
+}
+
+// EventTypes lists the events BoxPilot emits.
+func EventTypes() []string {
+	return []string{
+		EventSubscriptionRefreshed, EventNodesChanged, EventConfigApplied, EventReloadFailed,
+		EventRollbackPerformed, EventGroupSelectionChanged, EventForwardingStarted, EventForwardingStopped,
+	}
+}
+
+// EventTopics lists the stream topics.
+func EventTopics() []string {
+	return []string{TopicControl, TopicReload, TopicRefresh, TopicProbe, TopicTraffic}
+}
+
+// IsControlPlaneEvent reports whether typ is one of EventTypes.
+func IsControlPlaneEvent(typ string) bool {
+	for _, t := range EventTypes() {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// EventTopicSet is the set of topics one stream subscribed to.
+type EventTopicSet map[string]struct{}
+
+// ParseEventTopics validates topic names; none selects every topic.
+func ParseEventTopics(names []string) (EventTopicSet, *errorx.AppError) {
+	set := EventTopicSet{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := eventTopicTypes[name]; !ok {
+			return nil, errorx.New(errorx.REQInvalidField, "unsupported event topic").WithDetails(map[string]any{
+				"topic":     name,
+				"supported": EventTopics(),
+			})
+		}
+		set[name] = struct{}{}
+	}
+	if len(set) == 0 {
+		for _, name := range EventTopics() {
+			set[name] = struct{}{}
+		}
+	}
+	return set, nil
+}
+
+func (s EventTopicSet) Has(topic string) bool {
+	_, ok := s[topic]
+	return ok
+}
+
+// Matches reports whether an event of type typ belongs to a subscribed topic.
+func (s EventTopicSet) Matches(typ string) bool {
+	for topic := range s {
+		for _, t := range eventTopicTypes[topic] {
+			if t == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Event is one control-plane state change or progress report.
+type Event struct {
+	ID   string         `json:"id"`
+	Type string         `json:"type"`
+	At   time.Time      `json:"at"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// EventBus fans events out to in-process subscribers. Publishing never blocks:
+// a subscriber whose queue is full misses the event.
+type EventBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]chan Event
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[int]chan Event{}}
+}
+
+// Events is the process-wide bus the services publish to.
+var Events = NewEventBus()
+
+// Subscribe returns a channel of future events and a function that stops the
+// subscription and closes the channel.
+func (b *EventBus) Subscribe(queue int) (<-chan Event, func()) {
+	if queue <= 0 {
+		queue = defaultEventQueue
+	}
+	ch := make(chan Event, queue)
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+	b.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// PublishEvent stamps and publishes an event on the process-wide bus.
+func PublishEvent(typ string, data map[string]any) Event {
+	e := Event{ID: util.NewID(), Type: typ, At: time.Now().UTC(), Data: data}
+	Events.Publish(e)
+	return e
+}
@@ -0,0 +1,76 @@
+package service
+
+import "testing"
+
+func TestEventBusFansOutAndUnsubscribes(t *testing.T) {
+	bus := NewEventBus()
+	a, cancelA := bus.Subscribe(4)
+	b, cancelB := bus.Subscribe(4)
+	defer cancelB()
+
+	bus.Publish(Event{ID: "1", Type: EventConfigApplied})
+	if got := (<-a).ID; got != "1" {
+		t.Fatalf("subscriber a got %q", got)
+	}
+	if got := (<-b).ID; got != "1" {
+		t.Fatalf("subscriber b got %q", got)
+	}
+
+	cancelA()
+	cancelA()
+	if _, ok := <-a; ok {
+		t.Fatalf("cancelled subscription should be closed")
+	}
+	bus.Publish(Event{ID: "2", Type: EventReloadFailed})
+	if got := (<-b).ID; got != "2" {
+		t.Fatalf("subscriber b got %q after a left", got)
+	}
+}
+
+func TestEventBusDropsForFullSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	ch, cancel := bus.Subscribe(1)
+	defer cancel()
+
+	bus.Publish(Event{ID: "1"})
+	bus.Publish(Event{ID: "2"})
+	if got := (<-ch).ID; got != "1" {
+		t.Fatalf("got %q, want the event that fit the queue", got)
+	}
+	select {
+	case e := <-ch:
+		t.Fatalf("overflowing event should be dropped, got %q", e.ID)
+	default:
+	}
+}
+
+func TestParseEventTopics(t *testing.T) {
+	all, appErr := ParseEventTopics(nil)
+	if appErr != nil || len(all) != len(EventTopics()) {
+		t.Fatalf("no topics should select all, got %v %v", all, appErr)
+	}
+	set, appErr := ParseEventTopics([]string{" Reload ", "probe", ""})
+	if appErr != nil {
+		t.Fatalf("unexpected error: %v", appErr)
+	}
+	if !set.Matches(EventReloadProgress) || !set.Matches(EventConfigApplied) || !set.Matches(EventNodeProbe) {
+		t.Fatalf("reload/probe events should match: %v", set)
+	}
+	if set.Matches(EventNodesChanged) || set.Matches(EventTrafficSample) {
+		t.Fatalf("events outside the topics should not match: %v", set)
+	}
+	if _, appErr := ParseEventTopics([]string{"logs"}); appErr == nil {
+		t.Fatalf("expected unsupported topic error")
+	}
+}
+
+func TestProgressEventsAreNotControlPlane(t *testing.T) {
+	for _, typ := range []string{EventReloadProgress, EventSubscriptionRefreshProgress, EventSubscriptionRefreshFailed, EventNodeProbe, EventTrafficSample} {
+		if IsControlPlaneEvent(typ) {
+			t.Fatalf("%s should be stream-only", typ)
+		}
+		if (WebhookTarget{Enabled: true}).Wants(typ) {
+			t.Fatalf("webhooks should not receive %s", typ)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package service
+
+import "boxpilot/server/internal/observability"
+
+// Subsystem loggers. Pass the caller's context to the *Context methods so
+// records carry its request id.
+var (
+	schedulerLog = observability.Logger(observability.SubsystemScheduler)
+	refreshLog   = observability.Logger(observability.SubsystemRefresh)
+	parserLog    = observability.Logger(observability.SubsystemParser)
+	applyLog     = observability.Logger(observability.SubsystemApply)
+	alertsLog    = observability.Logger(observability.SubsystemAlerts)
+	webhooksLog  = observability.Logger(observability.SubsystemWebhooks)
+	trafficLog   = observability.Logger(observability.SubsystemTraffic)
+	runtimeLog   = observability.Logger(observability.SubsystemRuntime)
+	authLog      = observability.Logger(observability.SubsystemAuth)
+)
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"boxpilot/server/internal/generator"
+	"boxpilot/server/internal/observability"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+)
+
+// Metric families served on /metrics. Event-driven ones are updated where the
+// work happens; the rest are read from the database and the runtime by
+// CollectMetrics on every scrape.
+var (
+	metricRefreshTotal = observability.Metrics.NewCounterVec("boxpilot_subscription_refresh_total",
+		"Subscription refresh attempts by subscription and result (success, not_modified, failure).", "sub_id", "result")
+	metricRefreshDuration = observability.Metrics.NewHistogramVec("boxpilot_subscription_refresh_duration_seconds",
+		"Subscription refresh duration by result.", observability.DefaultDurationBuckets, "result")
+
+	metricProbeTotal = observability.Metrics.NewCounterVec("boxpilot_node_probe_total",
+		"Node probes by mode and status (ok, error).", "mode", "status")
+	metricProbeLatency = observability.Metrics.NewHistogramVec("boxpilot_node_probe_latency_seconds",
+		"Latency of successful node probes by mode.", []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.75, 1, 1.5, 2, 3, 5}, "mode")
+	metricNodes = observability.Metrics.NewGaugeVec("boxpilot_nodes",
+		"Enabled nodes by last probe health (healthy, unhealthy, untested).", "health")
+	metricNodeHealthy = observability.Metrics.NewGaugeVec("boxpilot_node_healthy",
+		"1 if the node's last probe succeeded, 0 if it failed; untested nodes are omitted.", "node_id", "tag")
+
+	metricApplyTotal = observability.Metrics.NewCounterVec("boxpilot_config_apply_total",
+		"Config applies by result (success, failure).", "result")
+	metricApplyDuration = observability.Metrics.NewHistogramVec("boxpilot_config_apply_duration_seconds",
+		"Config apply duration, including check, restart and listener wait, by result.", observability.DefaultDurationBuckets, "result")
+	metricRollbackTotal = observability.Metrics.NewCounterVec("boxpilot_config_rollback_total",
+		"Config rollbacks after a failed apply by restored source and result.", "source", "result")
+	metricConfigVersion = observability.Metrics.NewGaugeVec("boxpilot_config_version",
+		"Version of the applied config.")
+
+	metricSchedulerLag = observability.Metrics.NewGaugeVec("boxpilot_scheduler_lag_seconds",
+		"Largest delay between a subscription falling due and the scheduler tick that started it, in the last tick.")
+	metricSchedulerDue = observability.Metrics.NewGaugeVec("boxpilot_scheduler_due_subscriptions",
+		"Subscriptions started by the last scheduler tick.")
+	metricSchedulerTick = observability.Metrics.NewGaugeVec("boxpilot_scheduler_last_tick_timestamp_seconds",
+		"Unix time of the last scheduler tick.")
+
+	metricForwardingRunning = observability.Metrics.NewGaugeVec("boxpilot_forwarding_running",
+		"1 while forwarding is started.")
+	metricForwardingNodes = observability.Metrics.NewGaugeVec("boxpilot_forwarding_nodes",
+		"Nodes included in the last applied config.")
+	metricInboundUp = observability.Metrics.NewGaugeVec("boxpilot_inbound_up",
+		"1 if the enabled proxy inbound accepts TCP connections, 0 if not.", "inbound", "address")
+
+	metricTrafficBytes = observability.Metrics.NewCounterVec("boxpilot_proxy_traffic_bytes_total",
+		"Bytes proxied by sing-box since it started, from the Clash API, by direction (upload, download).", "direction")
+	metricNodeTrafficBytes = observability.Metrics.NewCounterVec("boxpilot_proxy_node_traffic_bytes_total",
+		"Bytes proxied per outbound tag since BoxPilot started, as seen by the traffic sampler.", "outbound", "direction")
+	metricConnections = observability.Metrics.NewGaugeVec("boxpilot_proxy_connections",
+		"Live proxied connections at the last traffic sample.")
+)
+
+func recordRefreshMetrics(subID string, started time.Time, res RefreshResult, err error) {
+	result := "success"
+	switch {
+	case err != nil:
+		result = "failure"
+	case res.NotModified:
+		result = "not_modified"
+	}
+	metricRefreshTotal.Inc(subID, result)
+	metricRefreshDuration.Observe(time.Since(started).Seconds(), result)
+}
+
+// RecordNodeProbe counts one node probe; latencyMs is ignored unless the probe
+// succeeded.
+func RecordNodeProbe(mode, status string, latencyMs int) {
+	metricProbeTotal.Inc(mode, status)
+	if status == "ok" && latencyMs >= 0 {
+		metricProbeLatency.Observe(float64(latencyMs)/1000, mode)
+	}
+}
+
+func recordApplyMetrics(duration time.Duration, version int, err error) {
+	if err == nil {
+		metricApplyTotal.Inc("success")
+		metricApplyDuration.Observe(duration.Seconds(), "success")
+		metricConfigVersion.Set(float64(version))
+		return
+	}
+	metricApplyTotal.Inc("failure")
+	metricApplyDuration.Observe(duration.Seconds(), "failure")
+	appErr, ok := err.(*errorx.AppError)
+	if !ok {
+		return
+	}
+	if source, _ := appErr.Details["rollback_source"].(string); source != "" {
+		result := "failure"
+		if success, _ := appErr.Details["rollback_success"].(bool); success {
+			result = "success"
+		}
+		metricRollbackTotal.Inc(source, result)
+	}
+}
+
+func recordSchedulerTick(now time.Time, due int, lag time.Duration) {
+	metricSchedulerTick.Set(float64(now.Unix()))
+	metricSchedulerDue.Set(float64(due))
+	metricSchedulerLag.Set(lag.Seconds())
+}
+
+func recordTrafficMetrics(snapshot *ClashConnections, rows []repo.TrafficRollupRow) {
+	metricTrafficBytes.Set(float64(snapshot.UploadTotal), "upload")
+	metricTrafficBytes.Set(float64(snapshot.DownloadTotal), "download")
+	metricConnections.Set(float64(len(snapshot.Connections)))
+	for _, row := range rows {
+		// Sample emits every delta once per bucket; count the hourly copy only.
+		if row.Bucket != TrafficBucketHour || row.Dimension != TrafficDimensionNode {
+			continue
+		}
+		metricNodeTrafficBytes.Add(float64(row.UploadBytes), row.Key, "upload")
+		metricNodeTrafficBytes.Add(float64(row.DownloadBytes), row.Key, "download")
+	}
+}
+
+// CollectMetrics refreshes the scrape-time metrics: node health, forwarding
+// state and inbound listener health.
+func CollectMetrics(ctx context.Context, db *sql.DB) error {
+	enabled := 1
+	nodes, err := repo.ListNodes(db, "", &enabled)
+	if err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	counts := map[string]int{"healthy": 0, "unhealthy": 0, "untested": 0}
+	metricNodeHealthy.Reset()
+	for _, n := range nodes {
+		switch {
+		case !n.LastTestStatus.Valid || n.LastTestStatus.String == "":
+			counts["untested"]++
+		case n.LastTestStatus.String == "ok":
+			counts["healthy"]++
+			metricNodeHealthy.Set(1, n.ID, n.Tag)
+		default:
+			counts["unhealthy"]++
+			metricNodeHealthy.Set(0, n.ID, n.Tag)
+		}
+	}
+	for health, n := range counts {
+		metricNodes.Set(float64(n), health)
+	}
+
+	state, err := repo.GetRuntimeState(db)
+	if err != nil {
+		return fmt.Errorf("load runtime state: %w", err)
+	}
+	running := false
+	if state != nil {
+		running = state.ForwardingRunning == 1
+		metricForwardingNodes.Set(float64(state.LastNodesIncluded))
+		metricConfigVersion.Set(float64(state.ConfigVersion))
+	}
+	metricForwardingRunning.Set(boolGauge(running))
+
+	metricInboundUp.Reset()
+	if !running {
+		return nil
+	}
+	httpProxy, socksProxy, err := loadProxySettings(db)
+	if err != nil {
+		return fmt.Errorf("load proxy settings: %w", err)
+	}
+	for _, proxy := range []generator.ProxyInbound{httpProxy, socksProxy} {
+		if !proxy.Enabled {
+			continue
+		}
+		health := ObserveRuntimeHealth(ctx, proxy, generator.ProxyInbound{})
+		metricInboundUp.Set(boolGauge(len(health.ListenerErrors) == 0), proxy.Type, listenerProbeAddress(proxy.ListenAddress, proxy.Port))
+	}
+	return nil
+}
+
+func boolGauge(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
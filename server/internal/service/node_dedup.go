@@ -0,0 +1,177 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+)
+
+// DuplicateCluster is one endpoint that more than one subscription provides.
+// Only the nodes of CanonicalSubID make it into the generated config.
+type DuplicateCluster struct {
+	Fingerprint     string
+	PreferredSubID  string
+	CanonicalSubID  string
+	CanonicalNodeID string
+	Nodes           []repo.NodeRow
+}
+
+// rowFingerprint falls back to hashing the outbound for rows stored before
+// fingerprints existed.
+func rowFingerprint(n repo.NodeRow) string {
+	if n.Fingerprint != "" {
+		return n.Fingerprint
+	}
+	return NodeFingerprint(n.Type, json.RawMessage(n.OutboundJSON))
+}
+
+// FindDuplicateClusters groups nodes by fingerprint and returns the groups that
+// span several subscriptions. Duplicates inside one subscription (e.g. the same
+// server over two transports) are left alone. The canonical subscription is the
+// preferred one when it has a node in the group, otherwise the subscription of
+// the earliest node, so the choice is stable as long as nodes keep their rows.
+func FindDuplicateClusters(nodes []repo.NodeRow, prefs map[string]string) []DuplicateCluster {
+	var order []string
+	groups := map[string][]repo.NodeRow{}
+	for _, n := range nodes {
+		fp := rowFingerprint(n)
+		if fp == "" {
+			continue
+		}
+		if _, ok := groups[fp]; !ok {
+			order = append(order, fp)
+		}
+		groups[fp] = append(groups[fp], n)
+	}
+	var clusters []DuplicateCluster
+	for _, fp := range order {
+		members := groups[fp]
+		subs := map[string]struct{}{}
+		for _, n := range members {
+			subs[n.SubID] = struct{}{}
+		}
+		if len(subs) < 2 {
+			continue
+		}
+		cluster := DuplicateCluster{Fingerprint: fp, PreferredSubID: prefs[fp], Nodes: members}
+		canonical := members[0]
+		if _, ok := subs[cluster.PreferredSubID]; ok {
+			for _, n := range members {
+				if n.SubID == cluster.PreferredSubID {
+					canonical = n
+					break
+				}
+			}
+		}
+		cluster.CanonicalSubID = canonical.SubID
+		cluster.CanonicalNodeID = canonical.ID
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
+
+// DeduplicateNodes drops nodes whose endpoint is served by another
+// subscription's canonical copy. Order is preserved. The returned map takes the
+// tag of each dropped node to the tag of the canonical node that replaces it.
+func DeduplicateNodes(nodes []repo.NodeRow, prefs map[string]string) ([]repo.NodeRow, map[string]string) {
+	clusters := FindDuplicateClusters(nodes, prefs)
+	if len(clusters) == 0 {
+		return nodes, nil
+	}
+	drop := map[string]struct{}{}
+	replaced := map[string]string{}
+	for _, c := range clusters {
+		canonicalTag := ""
+		for _, n := range c.Nodes {
+			if n.ID == c.CanonicalNodeID {
+				canonicalTag = n.Tag
+				break
+			}
+		}
+		for _, n := range c.Nodes {
+			if n.SubID != c.CanonicalSubID {
+				drop[n.ID] = struct{}{}
+				replaced[n.Tag] = canonicalTag
+			}
+		}
+	}
+	out := make([]repo.NodeRow, 0, len(nodes)-len(drop))
+	for _, n := range nodes {
+		if _, ok := drop[n.ID]; !ok {
+			out = append(out, n)
+		}
+	}
+	return out, replaced
+}
+
+// CanonicalForwardingNodes applies DeduplicateNodes with the stored source
+// preferences. Callers pass the forwarding candidates after the policy filter,
+// so a duplicate takes over when the preferred copy is disabled or unhealthy.
+// References to dropped tags must go through RemapNodeTags or the returned map.
+func CanonicalForwardingNodes(db *sql.DB, nodes []repo.NodeRow) ([]repo.NodeRow, map[string]string, error) {
+	prefs, err := repo.ListNodeDedupPreferences(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes, replaced := DeduplicateNodes(nodes, prefs)
+	return nodes, replaced, nil
+}
+
+// RemapNodeTags points tags of deduplicated nodes at their canonical copy and
+// drops the repeats this creates, keeping the first occurrence.
+func RemapNodeTags(tags []string, replaced map[string]string) []string {
+	if len(replaced) == 0 {
+		return tags
+	}
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		if canonical, ok := replaced[tag]; ok {
+			tag = canonical
+		}
+		if _, dup := seen[tag]; dup {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	return out
+}
+
+// SetDuplicatePreference pins the subscription whose copy of an endpoint is
+// used; an empty subID clears the preference.
+func SetDuplicatePreference(db *sql.DB, fingerprint, subID string) *errorx.AppError {
+	if fingerprint == "" {
+		return errorx.New(errorx.REQMissingField, "fingerprint required")
+	}
+	if subID == "" {
+		if err := repo.DeleteNodeDedupPreference(db, fingerprint); err != nil {
+			return errorx.New(errorx.DBError, "delete dedup preference").WithDetails(map[string]any{"err": err.Error()})
+		}
+		return nil
+	}
+	nodes, err := repo.ListNodes(db, subID, nil)
+	if err != nil {
+		return errorx.New(errorx.DBError, "list subscription nodes").WithDetails(map[string]any{"err": err.Error()})
+	}
+	found := false
+	for _, n := range nodes {
+		if rowFingerprint(n) == fingerprint {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errorx.New(errorx.REQInvalidField, "subscription has no node with this fingerprint").WithDetails(map[string]any{
+			"fingerprint": fingerprint,
+			"sub_id":      subID,
+		})
+	}
+	if err := repo.SetNodeDedupPreference(db, fingerprint, subID, util.NowRFC3339()); err != nil {
+		return errorx.New(errorx.DBError, "save dedup preference").WithDetails(map[string]any{"err": err.Error()})
+	}
+	return nil
+}
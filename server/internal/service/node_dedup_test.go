@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+
+	"boxpilot/server/internal/store/repo"
+)
+
+func dedupNode(id, subID, fp string) repo.NodeRow {
+	return repo.NodeRow{ID: id, SubID: subID, Tag: id, Fingerprint: fp}
+}
+
+func TestDeduplicateNodes(t *testing.T) {
+	nodes := []repo.NodeRow{
+		dedupNode("a-hk", "sub-a", "fp-hk"),
+		dedupNode("a-hk-grpc", "sub-a", "fp-hk"),
+		dedupNode("b-hk", "sub-b", "fp-hk"),
+		dedupNode("b-jp", "sub-b", "fp-jp"),
+		dedupNode("c-jp", "sub-c", "fp-jp"),
+		dedupNode("c-sg", "sub-c", "fp-sg"),
+	}
+
+	ids := func(list []repo.NodeRow) []string {
+		out := make([]string, 0, len(list))
+		for _, n := range list {
+			out = append(out, n.ID)
+		}
+		return out
+	}
+	kept, replaced := DeduplicateNodes(nodes, nil)
+	got := ids(kept)
+	want := []string{"a-hk", "a-hk-grpc", "b-jp", "c-sg"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if len(replaced) != 2 || replaced["b-hk"] != "a-hk" || replaced["c-jp"] != "b-jp" {
+		t.Fatalf("unexpected replaced tags: %v", replaced)
+	}
+
+	kept, _ = DeduplicateNodes(nodes, map[string]string{"fp-hk": "sub-b", "fp-jp": "sub-missing"})
+	got = ids(kept)
+	want = []string{"b-hk", "b-jp", "c-sg"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("with preference: got %v, want %v", got, want)
+	}
+}
+
+func TestRemapNodeTags(t *testing.T) {
+	got := RemapNodeTags([]string{"b-hk", "a-hk", "c-jp", "c-sg"}, map[string]string{"b-hk": "a-hk", "c-jp": "b-jp"})
+	want := []string{"a-hk", "b-jp", "c-sg"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFindDuplicateClusters(t *testing.T) {
+	nodes := []repo.NodeRow{
+		dedupNode("a-hk", "sub-a", "fp-hk"),
+		dedupNode("a-hk-grpc", "sub-a", "fp-hk"),
+		dedupNode("a-us", "sub-a", "fp-us"),
+		dedupNode("a-us-2", "sub-a", "fp-us"),
+		dedupNode("b-hk", "sub-b", "fp-hk"),
+		{ID: "legacy", SubID: "sub-c", Type: "trojan", OutboundJSON: `{"type":"trojan","server":"x.com","server_port":443,"password":"p"}`},
+		{ID: "fresh", SubID: "sub-d", Type: "trojan", Fingerprint: NodeFingerprint("trojan", []byte(`{"type":"trojan","server":"x.com","server_port":443,"password":"p"}`))},
+	}
+	clusters := FindDuplicateClusters(nodes, map[string]string{"fp-hk": "sub-b"})
+	if len(clusters) != 2 {
+		t.Fatalf("expected hk and legacy clusters only, got %+v", clusters)
+	}
+	hk := clusters[0]
+	if hk.Fingerprint != "fp-hk" || len(hk.Nodes) != 3 || hk.CanonicalSubID != "sub-b" || hk.CanonicalNodeID != "b-hk" {
+		t.Fatalf("unexpected hk cluster: %+v", hk)
+	}
+	if legacy := clusters[1]; legacy.CanonicalNodeID != "legacy" || len(legacy.Nodes) != 2 {
+		t.Fatalf("expected rows without stored fingerprint to be hashed: %+v", legacy)
+	}
+}
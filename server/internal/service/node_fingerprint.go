@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"boxpilot/server/internal/util"
+)
+
+// fingerprintCredentialKeys are the outbound fields that tell two accounts on
+// the same server apart.
+var fingerprintCredentialKeys = []string{"uuid", "password", "username", "private_key", "method", "auth_str"}
+
+// NodeFingerprint identifies a node across subscription refreshes by type,
+// server, port and a hash of its credentials, so renamed nodes or nodes whose
+// transport options changed still match their stored row. It returns "" when
+// the outbound has no server to key on.
+func NodeFingerprint(typ string, raw json.RawMessage) string {
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return ""
+	}
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	server := fingerprintString(m["server"])
+	port := fingerprintString(m["server_port"])
+	credSource := m
+	// WireGuard keeps its server in the first peer.
+	if server == "" {
+		if peers, ok := m["peers"].([]any); ok && len(peers) > 0 {
+			if peer, ok := peers[0].(map[string]any); ok {
+				server = fingerprintString(peer["address"])
+				port = fingerprintString(peer["port"])
+				credSource = map[string]any{"private_key": m["private_key"], "password": peer["public_key"]}
+			}
+		}
+	}
+	if server == "" {
+		return ""
+	}
+	creds := make([]string, 0, len(fingerprintCredentialKeys))
+	for _, key := range fingerprintCredentialKeys {
+		creds = append(creds, key+"="+fingerprintString(credSource[key]))
+	}
+	credHash := util.SHA256Hex([]byte(strings.Join(creds, "\x00")))
+	return util.SHA256Hex([]byte(strings.Join([]string{typ, strings.ToLower(server), port, credHash}, "|")))[:32]
+}
+
+func fingerprintString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(x)
+	case float64:
+		return fmt.Sprintf("%d", int64(x))
+	default:
+		return fmt.Sprint(x)
+	}
+}
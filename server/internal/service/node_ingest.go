@@ -78,12 +78,30 @@ func IngestOutbounds(db *sql.DB, input IngestInput) (*IngestResult, *errorx.AppE
 			ForwardingEnabled: input.DefaultForwardingEnabled,
 			OutboundJSON:      outJSON,
 			CreatedAt:         now,
+			Fingerprint:       NodeFingerprint(rawType, node.Raw),
 		})
 		if src := strings.TrimSpace(node.SourceTag); src != "" {
 			sourceMap[src] = tag
 		}
 	}
 
+	// Chained outbounds (e.g. shadowsocks over shadowtls) reference their
+	// upstream by source tag; keep the detour pointing at the resolved tag.
+	for i := range rows {
+		rewritten, changed := rewriteOutboundDetour(rows[i].OutboundJSON, sourceMap)
+		if changed {
+			rows[i].OutboundJSON = rewritten
+		}
+	}
+
+	result := &IngestResult{
+		Source:      input.Source,
+		Mode:        input.Mode,
+		SubID:       input.SubID,
+		Created:     len(rows),
+		Rows:        rows,
+		SourceTagTo: sourceMap,
+	}
 	switch input.Mode {
 	case IngestModeAppend:
 		for _, row := range rows {
@@ -94,23 +112,76 @@ func IngestOutbounds(db *sql.DB, input IngestInput) (*IngestResult, *errorx.AppE
 				return nil, errorx.New(errorx.DBError, "create node").WithDetails(map[string]any{"err": err.Error()})
 			}
 		}
+		result.Added = len(rows)
 	case IngestModeReplace:
-		if err := repo.ReplaceNodesForSubscription(db, input.SubID, rows); err != nil {
+		existing, err := repo.ListNodes(db, input.SubID, nil)
+		if err != nil {
+			return nil, errorx.New(errorx.DBError, "list subscription nodes").WithDetails(map[string]any{"err": err.Error()})
+		}
+		updates, inserts := matchExistingNodes(rows, existing)
+		removed, err := repo.SyncSubscriptionNodes(db, input.SubID, updates, inserts)
+		if err != nil {
 			return nil, errorx.New(errorx.SUBReplaceNodesFailed, "replace nodes").WithDetails(map[string]any{
 				"sub_id": input.SubID,
 				"err":    err.Error(),
 			})
 		}
+		diff := diffSubscriptionNodes(existing, updates, inserts)
+		result.Added = len(inserts)
+		result.Updated = len(diff.Changed)
+		result.Removed = removed
+		result.Diff = &diff
 	}
+	return result, nil
+}
 
-	return &IngestResult{
-		Source:      input.Source,
-		Mode:        input.Mode,
-		SubID:       input.SubID,
-		Created:     len(rows),
-		Rows:        rows,
-		SourceTagTo: sourceMap,
-	}, nil
+// matchExistingNodes pairs incoming rows with stored rows of the same
+// subscription, first by fingerprint and then by tag. Matched rows take over the
+// stored id and the state a refresh must not reset; rows is updated in place.
+func matchExistingNodes(rows []repo.NodeRow, existing []repo.NodeRow) (updates, inserts []repo.NodeRow) {
+	byFingerprint := map[string][]int{}
+	byTag := map[string]int{}
+	for i, e := range existing {
+		if e.Fingerprint != "" {
+			byFingerprint[e.Fingerprint] = append(byFingerprint[e.Fingerprint], i)
+		}
+		byTag[e.Tag] = i
+	}
+	claimed := make([]bool, len(existing))
+	match := func(row repo.NodeRow) int {
+		for _, i := range byFingerprint[row.Fingerprint] {
+			if !claimed[i] {
+				return i
+			}
+		}
+		if i, ok := byTag[row.Tag]; ok && !claimed[i] {
+			return i
+		}
+		return -1
+	}
+	for idx := range rows {
+		i := match(rows[idx])
+		if i < 0 {
+			inserts = append(inserts, rows[idx])
+			continue
+		}
+		claimed[i] = true
+		old := existing[i]
+		rows[idx].ID = old.ID
+		rows[idx].Enabled = old.Enabled
+		rows[idx].ForwardingEnabled = old.ForwardingEnabled
+		rows[idx].CreatedAt = old.CreatedAt
+		rows[idx].LastTestAt = old.LastTestAt
+		rows[idx].LastLatencyMs = old.LastLatencyMs
+		rows[idx].LastTestStatus = old.LastTestStatus
+		rows[idx].LastTestError = old.LastTestError
+		rows[idx].NameLocked = old.NameLocked
+		if old.NameLocked == 1 {
+			rows[idx].Name = old.Name
+		}
+		updates = append(updates, rows[idx])
+	}
+	return updates, inserts
 }
 
 func BuildIngestNodesFromOutbounds(outbounds []parser.OutboundItem, tagPrefix string) []IngestNode {
@@ -214,3 +285,24 @@ func mergeOutboundJSONTag(raw []byte, tag string) (string, *errorx.AppError) {
 	}
 	return string(out), nil
 }
+
+func rewriteOutboundDetour(raw string, tagMap map[string]string) (string, bool) {
+	if len(tagMap) == 0 || !strings.Contains(raw, `"detour"`) {
+		return raw, false
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return raw, false
+	}
+	detour, _ := payload["detour"].(string)
+	mapped, ok := tagMap[strings.TrimSpace(detour)]
+	if !ok || mapped == detour {
+		return raw, false
+	}
+	payload["detour"] = mapped
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return raw, false
+	}
+	return string(out), true
+}
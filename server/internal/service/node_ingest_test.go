@@ -1,8 +1,11 @@
 package service
 
 import (
+	"database/sql"
 	"encoding/json"
 	"testing"
+
+	"boxpilot/server/internal/store/repo"
 )
 
 func TestMergeOutboundJSONTag(t *testing.T) {
@@ -37,3 +40,103 @@ func TestMergeOutboundJSONTag_idempotent(t *testing.T) {
 		t.Fatalf("unexpected payload: %#v", got)
 	}
 }
+
+func TestRewriteOutboundDetour(t *testing.T) {
+	raw := `{"type":"shadowsocks","tag":"ss","detour":"ss-shadowtls"}`
+	out, changed := rewriteOutboundDetour(raw, map[string]string{"ss-shadowtls": "ss-shadowtls-2"})
+	if !changed {
+		t.Fatalf("expected detour rewrite")
+	}
+	var m map[string]any
+	if e := json.Unmarshal([]byte(out), &m); e != nil {
+		t.Fatal(e)
+	}
+	if m["detour"] != "ss-shadowtls-2" {
+		t.Fatalf("detour: want ss-shadowtls-2, got %v", m["detour"])
+	}
+	if _, changed := rewriteOutboundDetour(`{"type":"vmess","tag":"v"}`, map[string]string{"a": "b"}); changed {
+		t.Fatalf("expected no rewrite without detour")
+	}
+}
+
+func TestNodeFingerprint(t *testing.T) {
+	a := NodeFingerprint("vmess", json.RawMessage(`{"type":"vmess","tag":"HK 01","server":"Hk.example.com","server_port":443,"uuid":"u1","transport":{"type":"ws","path":"/a"}}`))
+	renamed := NodeFingerprint("vmess", json.RawMessage(`{"type":"vmess","tag":"🇭🇰 香港 01","server":"hk.example.com","server_port":443,"uuid":"u1","transport":{"type":"ws","path":"/b"}}`))
+	if a == "" || a != renamed {
+		t.Fatalf("expected rename / transport change to keep fingerprint: %q vs %q", a, renamed)
+	}
+	if other := NodeFingerprint("vmess", json.RawMessage(`{"type":"vmess","server":"hk.example.com","server_port":443,"uuid":"u2"}`)); other == a {
+		t.Fatal("expected a different credential to change the fingerprint")
+	}
+	if other := NodeFingerprint("vmess", json.RawMessage(`{"type":"vmess","server":"hk.example.com","server_port":8443,"uuid":"u1"}`)); other == a {
+		t.Fatal("expected a different port to change the fingerprint")
+	}
+	wg := NodeFingerprint("wireguard", json.RawMessage(`{"type":"wireguard","private_key":"k","peers":[{"address":"w.example.com","port":51820,"public_key":"p"}]}`))
+	if wg == "" {
+		t.Fatal("expected wireguard fingerprint from the first peer")
+	}
+	if NodeFingerprint("selector", json.RawMessage(`{"type":"selector","outbounds":["a"]}`)) != "" {
+		t.Fatal("expected no fingerprint without a server")
+	}
+}
+
+func TestMatchExistingNodes(t *testing.T) {
+	existing := []repo.NodeRow{
+		{ID: "id-hk", Tag: "HK 01", Name: "My HK", NameLocked: 1, Enabled: 1, ForwardingEnabled: 0, Fingerprint: "fp-hk", CreatedAt: "t0",
+			LastLatencyMs: sql.NullInt64{Int64: 80, Valid: true}},
+		{ID: "id-jp", Tag: "JP 01", Name: "JP 01", Enabled: 0, ForwardingEnabled: 1, Fingerprint: ""},
+		{ID: "id-gone", Tag: "US 01", Name: "US 01", Enabled: 1, ForwardingEnabled: 1, Fingerprint: "fp-us"},
+	}
+	rows := []repo.NodeRow{
+		{ID: "new-1", Tag: "🇭🇰 HK 01", Name: "🇭🇰 HK 01", Enabled: 1, ForwardingEnabled: 1, Fingerprint: "fp-hk", CreatedAt: "t1"},
+		{ID: "new-2", Tag: "JP 01", Name: "JP 01", Enabled: 1, ForwardingEnabled: 1, Fingerprint: "fp-jp", CreatedAt: "t1"},
+		{ID: "new-3", Tag: "SG 01", Name: "SG 01", Enabled: 1, ForwardingEnabled: 1, Fingerprint: "fp-sg", CreatedAt: "t1"},
+	}
+	updates, inserts := matchExistingNodes(rows, existing)
+	if len(updates) != 2 || len(inserts) != 1 || inserts[0].ID != "new-3" {
+		t.Fatalf("unexpected split: updates=%+v inserts=%+v", updates, inserts)
+	}
+	hk := rows[0]
+	if hk.ID != "id-hk" || hk.Tag != "🇭🇰 HK 01" || hk.Name != "My HK" || hk.ForwardingEnabled != 0 || hk.CreatedAt != "t0" || hk.LastLatencyMs.Int64 != 80 {
+		t.Fatalf("fingerprint match lost stored state: %+v", hk)
+	}
+	jp := rows[1]
+	if jp.ID != "id-jp" || jp.Enabled != 0 || jp.Fingerprint != "fp-jp" {
+		t.Fatalf("expected tag fallback for rows stored before fingerprints: %+v", jp)
+	}
+}
+
+func TestIngestOutboundsReplaceCountsOnlyChangedNodes(t *testing.T) {
+	db := openTestDB(t)
+	if err := repo.CreateSubscription(db, "sub-1", "sub", "https://example.com/sub", "auto", SubscriptionSourceURL, 1, 0, 3600, 0); err != nil {
+		t.Fatal(err)
+	}
+	input := IngestInput{
+		SubID:                    "sub-1",
+		Source:                   IngestSourceSub,
+		Mode:                     IngestModeReplace,
+		DefaultEnabled:           1,
+		DefaultForwardingEnabled: 1,
+		Nodes: []IngestNode{
+			{SourceTag: "HK 01", PreferredTag: "HK 01", PreferredName: "HK 01", Type: "vless", Raw: json.RawMessage(`{"type":"vless","tag":"HK 01","server":"hk.example","server_port":443,"uuid":"u-1"}`)},
+			{SourceTag: "JP 01", PreferredTag: "JP 01", PreferredName: "JP 01", Type: "vless", Raw: json.RawMessage(`{"type":"vless","tag":"JP 01","server":"jp.example","server_port":443,"uuid":"u-2"}`)},
+		},
+	}
+	first, appErr := IngestOutbounds(db, input)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if first.Added != 2 || first.Updated != 0 {
+		t.Fatalf("first ingest: added=%d updated=%d", first.Added, first.Updated)
+	}
+	again, appErr := IngestOutbounds(db, input)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if again.Added != 0 || again.Updated != 0 || again.Removed != 0 {
+		t.Fatalf("identical re-ingest: added=%d updated=%d removed=%d", again.Added, again.Updated, again.Removed)
+	}
+	if n := len(again.Diff.Changed); n != 0 {
+		t.Fatalf("expected no changed nodes, got %d", n)
+	}
+}
@@ -41,10 +41,18 @@ type IngestInput struct {
 }
 
 type IngestResult struct {
-	Source      IngestSource
-	Mode        IngestMode
-	SubID       string
-	Created     int
+	Source  IngestSource
+	Mode    IngestMode
+	SubID   string
+	Created int
+	// Added, Updated and Removed count rows inserted, matched in place with a
+	// changed tag, name, endpoint or options, and deleted; Updated and Removed
+	// are only set in replace mode.
+	Added   int
+	Updated int
+	Removed int
+	// Diff is only set in replace mode.
+	Diff        *NodeDiff
 	Rows        []repo.NodeRow
 	SourceTagTo map[string]string
 }
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/util/errorx"
+)
+
+const (
+	NotifierWebhook  = "webhook"
+	NotifierTelegram = "telegram"
+	NotifierSMTP     = "smtp"
+	NotifierNtfy     = "ntfy"
+
+	notifierTimeout = 10 * time.Second
+
+	defaultTelegramAPIBase = "https://api.telegram.org"
+	defaultNtfyServer      = "https://ntfy.sh"
+	defaultSMTPPort        = 587
+	// smtpImplicitTLSPort speaks TLS from the first byte; other ports upgrade
+	// with STARTTLS when the server offers it.
+	smtpImplicitTLSPort = 465
+)
+
+// Notifier delivers an alert through one channel.
+type Notifier interface {
+	Send(ctx context.Context, a Alert) error
+}
+
+// notifierFactories decode and validate the stored config of each channel
+// type. A new channel type only needs an entry here.
+var notifierFactories = map[string]func(raw []byte) (Notifier, *errorx.AppError){
+	NotifierWebhook:  newWebhookNotifier,
+	NotifierTelegram: newTelegramNotifier,
+	NotifierSMTP:     newSMTPNotifier,
+	NotifierNtfy:     newNtfyNotifier,
+}
+
+var notifierHTTPClient = &http.Client{Timeout: notifierTimeout}
+
+// NotifierTypes lists the supported channel types.
+func NotifierTypes() []string {
+	return []string{NotifierWebhook, NotifierTelegram, NotifierSMTP, NotifierNtfy}
+}
+
+// NewNotifier builds the notifier of a channel from its type and JSON config.
+func NewNotifier(typ string, config []byte) (Notifier, *errorx.AppError) {
+	factory, ok := notifierFactories[strings.ToLower(strings.TrimSpace(typ))]
+	if !ok {
+		return nil, errorx.New(errorx.REQInvalidField, "unsupported notification channel type").WithDetails(map[string]any{
+			"type":      typ,
+			"supported": NotifierTypes(),
+		})
+	}
+	if len(bytes.TrimSpace(config)) == 0 {
+		config = []byte("{}")
+	}
+	return factory(config)
+}
+
+// NormalizeNotifierConfig validates a channel config and returns it with
+// defaults filled in, ready to be stored.
+func NormalizeNotifierConfig(typ string, config []byte) (string, []byte, *errorx.AppError) {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	n, appErr := NewNotifier(typ, config)
+	if appErr != nil {
+		return "", nil, appErr
+	}
+	out, err := json.Marshal(n)
+	if err != nil {
+		return "", nil, errorx.New(errorx.InternalError, "encode notification channel config")
+	}
+	return typ, out, nil
+}
+
+func decodeNotifierConfig(raw []byte, v any) *errorx.AppError {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errorx.New(errorx.REQInvalidField, "invalid notification channel config").WithDetails(map[string]any{"err": err.Error()})
+	}
+	return nil
+}
+
+func missingNotifierField(field string) *errorx.AppError {
+	return errorx.New(errorx.REQMissingField, field+" required").WithDetails(map[string]any{"field": "config." + field})
+}
+
+func validHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// postNotification sends one request and treats any non-2xx reply as a
+// failed delivery.
+func postNotification(ctx context.Context, target, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := notifierHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(reply)))
+	}
+	return nil
+}
+
+// webhookNotifier POSTs the alert as JSON.
+type webhookNotifier struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func newWebhookNotifier(raw []byte) (Notifier, *errorx.AppError) {
+	var n webhookNotifier
+	if appErr := decodeNotifierConfig(raw, &n); appErr != nil {
+		return nil, appErr
+	}
+	n.URL = strings.TrimSpace(n.URL)
+	if n.URL == "" {
+		return nil, missingNotifierField("url")
+	}
+	if !validHTTPURL(n.URL) {
+		return nil, errorx.New(errorx.REQInvalidField, "webhook url must be http(s)").WithDetails(map[string]any{"url": n.URL})
+	}
+	headers := make(map[string]string, len(n.Headers))
+	for k, v := range n.Headers {
+		if k = strings.TrimSpace(k); k != "" {
+			headers[http.CanonicalHeaderKey(k)] = strings.TrimSpace(v)
+		}
+	}
+	n.Headers = headers
+	return &n, nil
+}
+
+func (n *webhookNotifier) Send(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return postNotification(ctx, n.URL, "application/json", body, n.Headers)
+}
+
+// telegramNotifier calls the Bot API sendMessage method. APIBase can point at
+// a self-hosted Bot API server.
+type telegramNotifier struct {
+	BotToken string `json:"bot_token"`
+	ChatID   string `json:"chat_id"`
+	APIBase  string `json:"api_base"`
+}
+
+func newTelegramNotifier(raw []byte) (Notifier, *errorx.AppError) {
+	var n telegramNotifier
+	if appErr := decodeNotifierConfig(raw, &n); appErr != nil {
+		return nil, appErr
+	}
+	n.BotToken = strings.TrimSpace(n.BotToken)
+	n.ChatID = strings.TrimSpace(n.ChatID)
+	n.APIBase = strings.TrimRight(strings.TrimSpace(n.APIBase), "/")
+	if n.BotToken == "" {
+		return nil, missingNotifierField("bot_token")
+	}
+	if n.ChatID == "" {
+		return nil, missingNotifierField("chat_id")
+	}
+	if n.APIBase == "" {
+		n.APIBase = defaultTelegramAPIBase
+	}
+	if !validHTTPURL(n.APIBase) {
+		return nil, errorx.New(errorx.REQInvalidField, "api_base must be http(s)").WithDetails(map[string]any{"api_base": n.APIBase})
+	}
+	return &n, nil
+}
+
+func (n *telegramNotifier) Send(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  n.ChatID,
+		"text":                     a.Title + "\n\n" + a.Message,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	err = postNotification(ctx, n.APIBase+"/bot"+n.BotToken+"/sendMessage", "application/json", body, nil)
+	if err != nil {
+		// The token is part of the URL; keep it out of stored errors.
+		return fmt.Errorf("telegram: %s", strings.ReplaceAll(err.Error(), n.BotToken, "***"))
+	}
+	return nil
+}
+
+// ntfyNotifier publishes to a topic of an ntfy server.
+type ntfyNotifier struct {
+	Server   string `json:"server"`
+	Topic    string `json:"topic"`
+	Token    string `json:"token,omitempty"`
+	Priority string `json:"priority,omitempty"`
+}
+
+func newNtfyNotifier(raw []byte) (Notifier, *errorx.AppError) {
+	var n ntfyNotifier
+	if appErr := decodeNotifierConfig(raw, &n); appErr != nil {
+		return nil, appErr
+	}
+	n.Server = strings.TrimRight(strings.TrimSpace(n.Server), "/")
+	n.Topic = strings.Trim(strings.TrimSpace(n.Topic), "/")
+	n.Token = strings.TrimSpace(n.Token)
+	n.Priority = strings.ToLower(strings.TrimSpace(n.Priority))
+	if n.Server == "" {
+		n.Server = defaultNtfyServer
+	}
+	if !validHTTPURL(n.Server) {
+		return nil, errorx.New(errorx.REQInvalidField, "ntfy server must be http(s)").WithDetails(map[string]any{"server": n.Server})
+	}
+	if n.Topic == "" {
+		return nil, missingNotifierField("topic")
+	}
+	switch n.Priority {
+	case "", "min", "low", "default", "high", "max", "urgent", "1", "2", "3", "4", "5":
+	default:
+		return nil, errorx.New(errorx.REQInvalidField, "invalid ntfy priority").WithDetails(map[string]any{"priority": n.Priority})
+	}
+	return &n, nil
+}
+
+func (n *ntfyNotifier) Send(ctx context.Context, a Alert) error {
+	headers := map[string]string{
+		"Title": a.Title,
+		"Tags":  "warning," + a.Kind,
+	}
+	if n.Priority != "" {
+		headers["Priority"] = n.Priority
+	}
+	if n.Token != "" {
+		headers["Authorization"] = "Bearer " + n.Token
+	}
+	return postNotification(ctx, n.Server+"/"+url.PathEscape(n.Topic), "text/plain; charset=utf-8", []byte(a.Message), headers)
+}
+
+// smtpNotifier mails the alert. Port 465 uses implicit TLS; other ports
+// upgrade with STARTTLS when offered, which PLAIN auth requires off loopback.
+type smtpNotifier struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+func newSMTPNotifier(raw []byte) (Notifier, *errorx.AppError) {
+	var n smtpNotifier
+	if appErr := decodeNotifierConfig(raw, &n); appErr != nil {
+		return nil, appErr
+	}
+	n.Host = strings.TrimSpace(n.Host)
+	n.Username = strings.TrimSpace(n.Username)
+	n.From = strings.TrimSpace(n.From)
+	if n.Host == "" {
+		return nil, missingNotifierField("host")
+	}
+	if n.Port == 0 {
+		n.Port = defaultSMTPPort
+	}
+	if n.Port < 1 || n.Port > 65535 {
+		return nil, errorx.New(errorx.REQInvalidField, "smtp port must be between 1 and 65535")
+	}
+	if n.From == "" {
+		n.From = n.Username
+	}
+	if n.From == "" {
+		return nil, missingNotifierField("from")
+	}
+	to := make([]string, 0, len(n.To))
+	for _, addr := range n.To {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	if len(to) == 0 {
+		return nil, missingNotifierField("to")
+	}
+	for _, addr := range append([]string{n.From}, to...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return nil, errorx.New(errorx.REQInvalidField, "invalid mail address").WithDetails(map[string]any{"address": addr})
+		}
+	}
+	n.To = to
+	return &n, nil
+}
+
+func (n *smtpNotifier) Send(ctx context.Context, a Alert) error {
+	ctx, cancel := context.WithTimeout(ctx, notifierTimeout)
+	defer cancel()
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if n.Port == smtpImplicitTLSPort {
+		conn = tls.Client(conn, &tls.Config{ServerName: n.Host})
+	}
+	c, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if n.Port != smtpImplicitTLSPort {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: n.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if n.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.From); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildAlertMail(n.From, n.To, a)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildAlertMail renders a plain-text message with CRLF line endings.
+func buildAlertMail(from string, to []string, a Alert) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mimeHeader(a.Title) + "\r\n")
+	b.WriteString("Date: " + a.At.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(a.Message, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// mimeHeader keeps header values on one line and Q-encodes non-ASCII text
+// such as subscription names.
+func mimeHeader(s string) string {
+	return mime.QEncoding.Encode("UTF-8", strings.NewReplacer("\r", " ", "\n", " ").Replace(s))
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/util/errorx"
+)
+
+type capturedRequest struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+// notifierStandIn records the requests a notifier makes and answers with status.
+func notifierStandIn(t *testing.T, status int) (*httptest.Server, <-chan capturedRequest) {
+	t.Helper()
+	ch := make(chan capturedRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ch <- capturedRequest{path: r.URL.Path, header: r.Header.Clone(), body: string(body)}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ch
+}
+
+func testAlert() Alert {
+	return Alert{
+		Kind:    AlertKindQuotaLow,
+		Subject: "sub-a",
+		Title:   "BoxPilot: Provider quota low",
+		Message: "Subscription Provider has 1.0 GiB of 100.0 GiB left (below 10%).",
+		At:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	srv, got := notifierStandIn(t, http.StatusOK)
+	n, appErr := NewNotifier(NotifierWebhook, []byte(`{"url":"`+srv.URL+`/hook","headers":{"x-token":"secret"}}`))
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if err := n.Send(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	req := <-got
+	if req.path != "/hook" || req.header.Get("X-Token") != "secret" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	var a Alert
+	if err := json.Unmarshal([]byte(req.body), &a); err != nil || a.Kind != AlertKindQuotaLow || a.Subject != "sub-a" {
+		t.Fatalf("unexpected body %q (%v)", req.body, err)
+	}
+}
+
+func TestWebhookNotifierRejectsErrorStatus(t *testing.T) {
+	srv, _ := notifierStandIn(t, http.StatusInternalServerError)
+	n, appErr := NewNotifier(NotifierWebhook, []byte(`{"url":"`+srv.URL+`"}`))
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if err := n.Send(context.Background(), testAlert()); err == nil || !strings.Contains(err.Error(), "http 500") {
+		t.Fatalf("expected http 500 error, got %v", err)
+	}
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	srv, got := notifierStandIn(t, http.StatusOK)
+	n, appErr := NewNotifier(NotifierTelegram, []byte(`{"bot_token":"123:abc","chat_id":"42","api_base":"`+srv.URL+`/"}`))
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if err := n.Send(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	req := <-got
+	if req.path != "/bot123:abc/sendMessage" {
+		t.Fatalf("unexpected path %q", req.path)
+	}
+	var body map[string]any
+	_ = json.Unmarshal([]byte(req.body), &body)
+	if body["chat_id"] != "42" || !strings.HasPrefix(body["text"].(string), "BoxPilot: Provider quota low") {
+		t.Fatalf("unexpected body %q", req.body)
+	}
+}
+
+func TestTelegramNotifierHidesToken(t *testing.T) {
+	srv, _ := notifierStandIn(t, http.StatusUnauthorized)
+	srv.Close()
+	n, _ := NewNotifier(NotifierTelegram, []byte(`{"bot_token":"123:abc","chat_id":"42","api_base":"`+srv.URL+`"}`))
+	err := n.Send(context.Background(), testAlert())
+	if err == nil || strings.Contains(err.Error(), "123:abc") {
+		t.Fatalf("expected an error without the bot token, got %v", err)
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	srv, got := notifierStandIn(t, http.StatusOK)
+	n, appErr := NewNotifier(NotifierNtfy, []byte(`{"server":"`+srv.URL+`","topic":"boxpilot","token":"tk","priority":"high"}`))
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	if err := n.Send(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	req := <-got
+	if req.path != "/boxpilot" || req.header.Get("Title") != "BoxPilot: Provider quota low" ||
+		req.header.Get("Priority") != "high" || req.header.Get("Authorization") != "Bearer tk" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if req.body != testAlert().Message {
+		t.Fatalf("unexpected body %q", req.body)
+	}
+}
+
+func TestNormalizeNotifierConfig(t *testing.T) {
+	typ, raw, appErr := NormalizeNotifierConfig(" SMTP ", []byte(`{"host":"mail.example.com","username":"me@example.com","password":"pw","to":["a@example.com",""]}`))
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+	var cfg smtpNotifier
+	_ = json.Unmarshal(raw, &cfg)
+	if typ != NotifierSMTP || cfg.Port != defaultSMTPPort || cfg.From != "me@example.com" || len(cfg.To) != 1 {
+		t.Fatalf("unexpected normalized config %s %s", typ, raw)
+	}
+
+	_, raw, appErr = NormalizeNotifierConfig(NotifierNtfy, []byte(`{"topic":"alerts"}`))
+	if appErr != nil || !strings.Contains(string(raw), defaultNtfyServer) {
+		t.Fatalf("expected default ntfy server: %s %v", raw, appErr)
+	}
+
+	cases := []struct {
+		typ, config, code string
+	}{
+		{"pager", `{}`, errorx.REQInvalidField},
+		{NotifierWebhook, `{}`, errorx.REQMissingField},
+		{NotifierWebhook, `{"url":"ftp://example.com"}`, errorx.REQInvalidField},
+		{NotifierWebhook, `{"url":"https://example.com","method":"PUT"}`, errorx.REQInvalidField},
+		{NotifierTelegram, `{"bot_token":"t"}`, errorx.REQMissingField},
+		{NotifierSMTP, `{"host":"h","from":"a@b","to":["x@y\r\nBcc: z@w"]}`, errorx.REQInvalidField},
+		{NotifierNtfy, `{"topic":"t","priority":"loud"}`, errorx.REQInvalidField},
+	}
+	for _, tc := range cases {
+		if _, _, appErr := NormalizeNotifierConfig(tc.typ, []byte(tc.config)); appErr == nil || appErr.Code != tc.code {
+			t.Fatalf("%s %s: want %s got %v", tc.typ, tc.config, tc.code, appErr)
+		}
+	}
+}
+
+func TestBuildAlertMail(t *testing.T) {
+	a := testAlert()
+	a.Title = "BoxPilot: 香港 quota low"
+	a.Message = "line one\nline two"
+	msg := string(buildAlertMail("bp@example.com", []string{"a@example.com", "b@example.com"}, a))
+	for _, want := range []string{
+		"From: bp@example.com\r\n",
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: =?UTF-8?q?",
+		"\r\n\r\nline one\r\nline two\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("mail missing %q:\n%s", want, msg)
+		}
+	}
+}
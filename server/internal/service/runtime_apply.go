@@ -17,6 +17,20 @@ const (
 	lastKnownGoodSuffix = ".last-good"
 )
 
+type reloadProgressKey struct{}
+
+// withReloadProgress attaches a callback that is told each apply stage as it
+// starts.
+func withReloadProgress(ctx context.Context, fn func(stage string)) context.Context {
+	return context.WithValue(ctx, reloadProgressKey{}, fn)
+}
+
+func reportReloadStage(ctx context.Context, stage string) {
+	if fn, ok := ctx.Value(reloadProgressKey{}).(func(string)); ok {
+		fn(stage)
+	}
+}
+
 func applyConfigWithPreflight(
 	ctx context.Context,
 	configPath string,
@@ -53,6 +67,7 @@ func applyConfigWithPreflight(
 	}
 	// Removed defer Remove so user can inspect on failure.
 
+	reportReloadStage(ctx, "check")
 	if _, err := runtime.Check(ctx, candidatePath); err != nil {
 		return nil, err
 	}
@@ -67,6 +82,7 @@ func applyConfigWithPreflight(
 		})
 	}
 
+	reportReloadStage(ctx, "write")
 	if err := util.AtomicWrite(dir, base, cfg); err != nil {
 		return nil, errorx.New(errorx.CFGWriteFailed, "write runtime config failed").WithDetails(map[string]any{
 			"path": configPath,
@@ -74,8 +90,10 @@ func applyConfigWithPreflight(
 		})
 	}
 
+	reportReloadStage(ctx, "restart")
 	restartOut, restartErr := runtime.Restart(ctx, configPath)
 	if restartErr == nil {
+		reportReloadStage(ctx, "wait_ready")
 		restartErr = WaitForRuntimeReady(ctx, httpProxy, socksProxy, listenerReadyMaxMs)
 	}
 	if restartErr == nil {
@@ -97,6 +115,7 @@ func applyConfigWithPreflight(
 		return restartOut, attachRollbackDetails(restartErr, false, false, "")
 	}
 
+	reportReloadStage(ctx, "rollback")
 	if err := util.AtomicWrite(dir, base, rollbackConfig); err != nil {
 		return restartOut, errorx.New(errorx.CFGRollbackFailed, "restart failed and rollback write failed").WithDetails(map[string]any{
 			"path":            configPath,
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 	"testing"
 
 	"boxpilot/server/internal/generator"
+	"boxpilot/server/internal/observability"
 	"boxpilot/server/internal/util/errorx"
 )
 
@@ -168,3 +170,44 @@ func assertAppErrorCode(t *testing.T, err error, code string) {
 		t.Fatalf("expected code %s, got %s", code, appErr.Code)
 	}
 }
+
+func applyFailureCount(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := observability.Metrics.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, `boxpilot_config_apply_total{result="failure"} `) {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestReloadReportsBuildFailures(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec("DROP TABLE proxy_settings"); err != nil {
+		t.Fatal(err)
+	}
+	before := applyFailureCount(t)
+	events, cancel := Events.Subscribe(16)
+	defer cancel()
+
+	if _, _, _, err := Reload(context.Background(), db, filepath.Join(t.TempDir(), "sing-box.json")); err == nil {
+		t.Fatalf("expected Reload to fail without proxy settings")
+	}
+	if after := applyFailureCount(t); after == before {
+		t.Fatalf("expected the failed apply to be counted, still %q", after)
+	}
+	for {
+		select {
+		case e := <-events:
+			if e.Type == EventReloadFailed {
+				return
+			}
+		default:
+			t.Fatalf("expected %s to be published", EventReloadFailed)
+		}
+	}
+}
@@ -3,10 +3,10 @@ package service
 import (
 	"context"
 	"database/sql"
-	"log"
 	"sync"
 	"time"
 
+	"boxpilot/server/internal/observability"
 	"boxpilot/server/internal/store/repo"
 )
 
@@ -14,6 +14,9 @@ var (
 	autoReloadMu     sync.Mutex
 	autoReloadTimer  *time.Timer
 	autoReloadQueued bool
+	// autoReloadRequestID is the request id of the latest change that queued
+	// the reload, so its logs can be traced back to it.
+	autoReloadRequestID string
 )
 
 const autoReloadDebounce = 1200 * time.Millisecond
@@ -28,8 +31,8 @@ func ReloadIfForwardingRunning(ctx context.Context, db *sql.DB) error {
 	if !running {
 		return nil
 	}
-	_ = ctx // keep signature compatible for call sites.
-	queueAutoReload(db)
+	applyLog.DebugContext(ctx, "auto reload queued")
+	queueAutoReload(db, observability.RequestIDFrom(ctx))
 	return nil
 }
 
@@ -41,11 +44,12 @@ func isForwardingRunning(db *sql.DB) (bool, error) {
 	return row != nil && row.ForwardingRunning == 1, nil
 }
 
-func queueAutoReload(db *sql.DB) {
+func queueAutoReload(db *sql.DB, requestID string) {
 	autoReloadMu.Lock()
 	defer autoReloadMu.Unlock()
 
 	autoReloadQueued = true
+	autoReloadRequestID = requestID
 	if autoReloadTimer != nil {
 		autoReloadTimer.Reset(autoReloadDebounce)
 		return
@@ -64,11 +68,15 @@ func runQueuedReload(db *sql.DB) {
 	}
 	autoReloadQueued = false
 	autoReloadTimer = nil
+	ctx := context.Background()
+	if autoReloadRequestID != "" {
+		ctx = observability.WithRequestID(ctx, autoReloadRequestID)
+	}
 	autoReloadMu.Unlock()
 
 	running, err := isForwardingRunning(db)
 	if err != nil {
-		log.Printf("auto-reload: get runtime state failed: %v", err)
+		applyLog.ErrorContext(ctx, "auto reload: load runtime state failed", "error", err)
 		return
 	}
 	if !running {
@@ -76,7 +84,7 @@ func runQueuedReload(db *sql.DB) {
 	}
 
 	configPath := ResolveConfigPath()
-	if _, _, _, err := Reload(context.Background(), db, configPath); err != nil {
-		log.Printf("auto-reload: runtime reload failed: %v", err)
+	if _, _, _, err := Reload(ctx, db, configPath); err != nil {
+		applyLog.ErrorContext(ctx, "auto reload failed", "error", err)
 	}
 }
@@ -3,23 +3,37 @@ package service
 import (
 	"context"
 	"database/sql"
-	"log"
 	"time"
 
 	"boxpilot/server/internal/generator"
 	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
 	"boxpilot/server/internal/util/errorx"
 )
 
 func Reload(ctx context.Context, db *sql.DB, configPath string) (version int, hash string, output string, err error) {
 	startedAt := time.Now()
+	reloadID := util.NewID()
+	ctx = withReloadProgress(ctx, func(stage string) {
+		PublishEvent(EventReloadProgress, map[string]any{"reload_id": reloadID, "stage": stage})
+	})
+	applyLog.InfoContext(ctx, "config apply started", "reload_id", reloadID)
+	reportReloadStage(ctx, "build")
+	// Failures before the apply leave sing-box untouched but still count as a
+	// failed apply for metrics and reload.failed subscribers.
+	buildFailed := func(err error) (int, string, string, error) {
+		recordApplyMetrics(time.Since(startedAt), 0, err)
+		applyLog.ErrorContext(ctx, "config build failed", "reload_id", reloadID, "error", err)
+		publishReloadFailure(reloadID, err)
+		return 0, "", "", err
+	}
 	httpProxy, socksProxy, err := loadProxySettings(db)
 	if err != nil {
-		return 0, "", "", err
+		return buildFailed(err)
 	}
 	forwardingRunning, err := loadForwardingRunning(db)
 	if err != nil {
-		return 0, "", "", err
+		return buildFailed(err)
 	}
 	expectedHTTPProxy := httpProxy
 	expectedSocksProxy := socksProxy
@@ -29,11 +43,11 @@ func Reload(ctx context.Context, db *sql.DB, configPath string) (version int, ha
 	}
 	routing, _, err := LoadRoutingSettings(db)
 	if err != nil {
-		return 0, "", "", err
+		return buildFailed(err)
 	}
 	cfg, tags, h, err := BuildConfigFromDB(db, httpProxy, socksProxy, routing, forwardingRunning)
 	if err != nil {
-		return 0, "", "", err
+		return buildFailed(err)
 	}
 
 	prevRow, _ := repo.GetRuntimeState(db)
@@ -55,25 +69,57 @@ func Reload(ctx context.Context, db *sql.DB, configPath string) (version int, ha
 			keys := []string{"output", "restart_output", "rollback_output"}
 			for _, k := range keys {
 				if detailOut, ok := appErr.Details[k].(string); ok && detailOut != "" {
-					log.Printf("runtime reload failure %s:\n%s", k, detailOut)
+					applyLog.ErrorContext(ctx, "config apply output", "reload_id", reloadID, "field", k, "output", detailOut)
 				}
 			}
 			// Log specific error messages
 			msgKeys := []string{"rollback_restart", "rollback_error", "original_err"}
 			for _, k := range msgKeys {
 				if msg, ok := appErr.Details[k].(string); ok {
-					log.Printf("runtime reload error detail %s: %s", k, msg)
+					applyLog.ErrorContext(ctx, "config apply error detail", "reload_id", reloadID, "field", k, "detail", msg)
 				}
 			}
 		}
 		_ = repo.UpdateRuntimeState(db, prevVersion, prevHash, err.Error(), len(tags), durationMs, false)
+		recordApplyMetrics(time.Since(startedAt), prevVersion, err)
+		applyLog.ErrorContext(ctx, "config apply failed", "reload_id", reloadID, "duration_ms", durationMs, "error", err)
+		publishReloadFailure(reloadID, err)
 		return prevVersion, prevHash, string(out), err
 	}
 	v := prevVersion + 1
 	_ = repo.UpdateRuntimeState(db, v, h, "", len(tags), durationMs, true)
+	recordApplyMetrics(time.Since(startedAt), v, nil)
+	applyLog.InfoContext(ctx, "config applied", "reload_id", reloadID, "config_version", v, "nodes_included", len(tags), "duration_ms", durationMs)
+	PublishEvent(EventConfigApplied, map[string]any{
+		"reload_id":      reloadID,
+		"config_version": v,
+		"config_hash":    h,
+		"nodes_included": len(tags),
+		"duration_ms":    durationMs,
+	})
 	return v, h, string(out), nil
 }
 
+// publishReloadFailure emits reload.failed, plus rollback.performed when the
+// previous or last known good config was restored (or restoring it failed).
+func publishReloadFailure(reloadID string, err error) {
+	data := map[string]any{"reload_id": reloadID, "code": errorx.InternalError, "error": err.Error()}
+	appErr, ok := err.(*errorx.AppError)
+	if ok {
+		data["code"] = appErr.Code
+	}
+	PublishEvent(EventReloadFailed, data)
+	if !ok {
+		return
+	}
+	source, _ := appErr.Details["rollback_source"].(string)
+	if source == "" {
+		return
+	}
+	success, _ := appErr.Details["rollback_success"].(bool)
+	PublishEvent(EventRollbackPerformed, map[string]any{"reload_id": reloadID, "source": source, "success": success})
+}
+
 func loadForwardingRunning(db *sql.DB) (bool, error) {
 	row, err := repo.GetRuntimeState(db)
 	if err != nil {
@@ -16,3 +16,19 @@ func ResolveConfigPath() string {
 	}
 	return filepath.Join("data", "sing-box.json")
 }
+
+// ResolveClashAPIBaseURL returns the sing-box Clash API base URL from
+// SINGBOX_CLASH_API_ADDR (default 127.0.0.1:9090); "off" disables it.
+func ResolveClashAPIBaseURL() (string, bool) {
+	controller := strings.TrimSpace(os.Getenv("SINGBOX_CLASH_API_ADDR"))
+	if controller == "" {
+		controller = "127.0.0.1:9090"
+	}
+	if strings.EqualFold(controller, "off") {
+		return "", false
+	}
+	if !strings.HasPrefix(controller, "http://") && !strings.HasPrefix(controller, "https://") {
+		controller = "http://" + controller
+	}
+	return strings.TrimRight(controller, "/"), true
+}
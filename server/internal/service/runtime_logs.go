@@ -0,0 +1,658 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runtime log levels. sing-box levels are folded into these: trace into
+// debug, warning into warn, fatal and panic into error.
+const (
+	RuntimeLogDebug = "debug"
+	RuntimeLogInfo  = "info"
+	RuntimeLogWarn  = "warn"
+	RuntimeLogError = "error"
+)
+
+// Runtime log sources, as reported by the collector status.
+const (
+	RuntimeLogSourceClashAPI = "clash_api"
+	RuntimeLogSourceFile     = "file"
+	RuntimeLogSourceDisabled = "disabled"
+)
+
+const (
+	defaultRuntimeLogBuffer  = 5000
+	defaultRuntimeLogPersist = 2000
+	runtimeLogFileName       = "singbox-logs.jsonl"
+	runtimeLogPollInterval   = 500 * time.Millisecond
+	runtimeLogRetryBase      = time.Second
+	runtimeLogRetryMax       = 30 * time.Second
+	runtimeLogMaxLine        = 1 << 20
+)
+
+// RuntimeLogLevels lists the levels entries are normalized to.
+func RuntimeLogLevels() []string {
+	return []string{RuntimeLogDebug, RuntimeLogInfo, RuntimeLogWarn, RuntimeLogError}
+}
+
+// NormalizeRuntimeLogLevel maps a sing-box or Clash level name to one of
+// RuntimeLogLevels, or "" when it is not a level.
+func NormalizeRuntimeLogLevel(raw string) string {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "trace", "debug":
+		return RuntimeLogDebug
+	case "info":
+		return RuntimeLogInfo
+	case "warn", "warning":
+		return RuntimeLogWarn
+	case "error", "fatal", "panic":
+		return RuntimeLogError
+	}
+	return ""
+}
+
+// RuntimeLogEntry is one line sing-box logged. Seq increases by one per
+// entry for the life of the process, so clients can resume after it.
+type RuntimeLogEntry struct {
+	Seq       int64  `json:"seq"`
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Source    string `json:"source"`
+	Message   string `json:"message"`
+}
+
+// RuntimeLogQuery filters RuntimeLogBuffer.Query. Empty Level or "all"
+// matches every level; Keyword matches source and message case-insensitively.
+type RuntimeLogQuery struct {
+	Level    string
+	Keyword  string
+	Limit    int
+	AfterSeq int64
+}
+
+// Matches reports whether e passes the level and keyword filters.
+func (q RuntimeLogQuery) Matches(e RuntimeLogEntry) bool {
+	level := strings.ToLower(strings.TrimSpace(q.Level))
+	if level != "" && level != "all" && NormalizeRuntimeLogLevel(level) != e.Level {
+		return false
+	}
+	keyword := strings.ToLower(strings.TrimSpace(q.Keyword))
+	return keyword == "" || strings.Contains(strings.ToLower(e.Source+" "+e.Message), keyword)
+}
+
+// RuntimeLogBuffer keeps the newest entries in a ring and indexes them by
+// level, so a level filter walks only the matching entries.
+type RuntimeLogBuffer struct {
+	mu      sync.RWMutex
+	entries []RuntimeLogEntry
+	search  []string
+	size    int
+	seq     int64
+	byLevel map[string][]int64
+	nextSub int
+	subs    map[int]chan RuntimeLogEntry
+}
+
+func NewRuntimeLogBuffer(capacity int) *RuntimeLogBuffer {
+	if capacity <= 0 {
+		capacity = defaultRuntimeLogBuffer
+	}
+	return &RuntimeLogBuffer{
+		entries: make([]RuntimeLogEntry, capacity),
+		search:  make([]string, capacity),
+		byLevel: map[string][]int64{},
+		subs:    map[int]chan RuntimeLogEntry{},
+	}
+}
+
+// RuntimeLogs holds the sing-box log lines the collector has seen.
+var RuntimeLogs = NewRuntimeLogBuffer(positiveEnvInt("BOXPILOT_LOG_BUFFER_SIZE", defaultRuntimeLogBuffer))
+
+// Append stores e with the next sequence number and hands it to the
+// subscribers. Subscribers whose queue is full miss the entry.
+func (b *RuntimeLogBuffer) Append(e RuntimeLogEntry) RuntimeLogEntry {
+	if lvl := NormalizeRuntimeLogLevel(e.Level); lvl != "" {
+		e.Level = lvl
+	} else {
+		e.Level = RuntimeLogInfo
+	}
+	if e.Timestamp == "" {
+		e.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	if e.Source == "" {
+		e.Source = "singbox"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	capacity := len(b.entries)
+	b.seq++
+	e.Seq = b.seq
+	idx := int((e.Seq - 1) % int64(capacity))
+	if b.size == capacity {
+		evicted := b.entries[idx]
+		if q := b.byLevel[evicted.Level]; len(q) > 0 && q[0] == evicted.Seq {
+			q = q[1:]
+			// Reslicing keeps the evicted prefix reachable; copy once it dominates.
+			if cap(q) > 2*len(q)+64 {
+				q = append([]int64(nil), q...)
+			}
+			b.byLevel[evicted.Level] = q
+		}
+	} else {
+		b.size++
+	}
+	b.entries[idx] = e
+	b.search[idx] = strings.ToLower(e.Source + " " + e.Message)
+	b.byLevel[e.Level] = append(b.byLevel[e.Level], e.Seq)
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return e
+}
+
+// Query returns matching entries newest first.
+func (b *RuntimeLogBuffer) Query(q RuntimeLogQuery) []RuntimeLogEntry {
+	level := strings.ToLower(strings.TrimSpace(q.Level))
+	if level != "" && level != "all" {
+		level = NormalizeRuntimeLogLevel(level)
+		if level == "" {
+			return []RuntimeLogEntry{}
+		}
+	} else {
+		level = ""
+	}
+	keyword := strings.ToLower(strings.TrimSpace(q.Keyword))
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]RuntimeLogEntry, 0)
+	oldest := b.seq - int64(b.size) + 1
+	take := func(seq int64) bool {
+		if seq <= q.AfterSeq || seq < oldest {
+			return false
+		}
+		idx := int((seq - 1) % int64(len(b.entries)))
+		if keyword != "" && !strings.Contains(b.search[idx], keyword) {
+			return true
+		}
+		out = append(out, b.entries[idx])
+		return q.Limit <= 0 || len(out) < q.Limit
+	}
+	if level != "" {
+		seqs := b.byLevel[level]
+		for i := len(seqs) - 1; i >= 0; i-- {
+			if !take(seqs[i]) {
+				break
+			}
+		}
+		return out
+	}
+	for seq := b.seq; seq >= oldest && seq > 0; seq-- {
+		if !take(seq) {
+			break
+		}
+	}
+	return out
+}
+
+// Counts returns the number of buffered entries per level.
+func (b *RuntimeLogBuffer) Counts() map[string]int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make(map[string]int, len(RuntimeLogLevels()))
+	for _, lvl := range RuntimeLogLevels() {
+		out[lvl] = len(b.byLevel[lvl])
+	}
+	return out
+}
+
+// LastSeq returns the sequence number of the newest entry, 0 when empty.
+func (b *RuntimeLogBuffer) LastSeq() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.seq
+}
+
+// Subscribe returns a channel of future entries and a function that stops the
+// subscription and closes the channel.
+func (b *RuntimeLogBuffer) Subscribe(queue int) (<-chan RuntimeLogEntry, func()) {
+	if queue <= 0 {
+		queue = defaultEventQueue
+	}
+	ch := make(chan RuntimeLogEntry, queue)
+	b.mu.Lock()
+	id := b.nextSub
+	b.nextSub++
+	b.subs[id] = ch
+	b.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// RuntimeLogStatus describes where the collector reads logs from.
+type RuntimeLogStatus struct {
+	Source    string
+	Target    string
+	Connected bool
+	LastError string
+}
+
+var runtimeLogStatus struct {
+	mu sync.RWMutex
+	v  RuntimeLogStatus
+}
+
+// CurrentRuntimeLogStatus returns the collector status.
+func CurrentRuntimeLogStatus() RuntimeLogStatus {
+	runtimeLogStatus.mu.RLock()
+	defer runtimeLogStatus.mu.RUnlock()
+	if runtimeLogStatus.v.Source == "" {
+		return RuntimeLogStatus{Source: RuntimeLogSourceDisabled}
+	}
+	return runtimeLogStatus.v
+}
+
+func setRuntimeLogStatus(update func(*RuntimeLogStatus)) {
+	runtimeLogStatus.mu.Lock()
+	defer runtimeLogStatus.mu.Unlock()
+	update(&runtimeLogStatus.v)
+}
+
+// runtimeLogStore appends entries to a JSON-lines file and rewrites it with
+// the newest keep entries once it holds twice as many.
+type runtimeLogStore struct {
+	path  string
+	keep  int
+	file  *os.File
+	lines int
+}
+
+// ResolveRuntimeLogFile returns the rolling log file path from
+// BOXPILOT_LOG_PERSIST_FILE, defaulting to singbox-logs.jsonl next to the
+// runtime config; "off" disables persistence.
+func ResolveRuntimeLogFile() (string, bool) {
+	p := strings.TrimSpace(os.Getenv("BOXPILOT_LOG_PERSIST_FILE"))
+	if strings.EqualFold(p, "off") {
+		return "", false
+	}
+	if p == "" {
+		p = filepath.Join(filepath.Dir(ResolveConfigPath()), runtimeLogFileName)
+	}
+	return p, true
+}
+
+// openRuntimeLogStore loads the persisted window into buf and opens the file
+// for appending.
+func openRuntimeLogStore(path string, keep int, buf *RuntimeLogBuffer) (*runtimeLogStore, error) {
+	s := &runtimeLogStore{path: path, keep: keep}
+	entries, err := readRuntimeLogFile(path, keep)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		buf.Append(e)
+	}
+	if err := s.rewrite(entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func readRuntimeLogFile(path string, keep int) ([]RuntimeLogEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []RuntimeLogEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), runtimeLogMaxLine)
+	for scanner.Scan() {
+		var e RuntimeLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Message == "" {
+			continue
+		}
+		out = append(out, e)
+		if len(out) > 2*keep {
+			out = append(out[:0], out[len(out)-keep:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) > keep {
+		out = out[len(out)-keep:]
+	}
+	return out, nil
+}
+
+func (s *runtimeLogStore) append(e RuntimeLogEntry, buf *RuntimeLogBuffer) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.lines++
+	if s.lines < 2*s.keep {
+		return nil
+	}
+	newest := buf.Query(RuntimeLogQuery{Limit: s.keep})
+	for i, j := 0, len(newest)-1; i < j; i, j = i+1, j-1 {
+		newest[i], newest[j] = newest[j], newest[i]
+	}
+	return s.rewrite(newest)
+}
+
+// rewrite replaces the file with entries, oldest first, and reopens it for
+// appending.
+func (s *runtimeLogStore) rewrite(entries []RuntimeLogEntry) error {
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0644)
+	s.lines = len(entries)
+	return err
+}
+
+func (s *runtimeLogStore) close() {
+	if s.file != nil {
+		_ = s.file.Close()
+	}
+}
+
+// StartRuntimeLogCollector feeds RuntimeLogs from sing-box until ctx ends. It
+// tails SINGBOX_LOG_FILE when set and otherwise follows the Clash API /logs
+// stream, reconnecting with backoff. Entries are also appended to the rolling
+// file from ResolveRuntimeLogFile.
+func StartRuntimeLogCollector(ctx context.Context) {
+	var store *runtimeLogStore
+	if path, ok := ResolveRuntimeLogFile(); ok {
+		s, err := openRuntimeLogStore(path, positiveEnvInt("BOXPILOT_LOG_PERSIST_LINES", defaultRuntimeLogPersist), RuntimeLogs)
+		if err != nil {
+			runtimeLog.WarnContext(ctx, "sing-box log persistence disabled", "error", err)
+		} else {
+			store = s
+			defer store.close()
+		}
+	}
+
+	emit := func(e RuntimeLogEntry) {
+		e = RuntimeLogs.Append(e)
+		if store == nil {
+			return
+		}
+		if err := store.append(e, RuntimeLogs); err != nil {
+			runtimeLog.WarnContext(ctx, "sing-box log persistence disabled", "error", err)
+			store.close()
+			store = nil
+		}
+	}
+
+	if file := strings.TrimSpace(os.Getenv("SINGBOX_LOG_FILE")); file != "" {
+		setRuntimeLogStatus(func(s *RuntimeLogStatus) { *s = RuntimeLogStatus{Source: RuntimeLogSourceFile, Target: file} })
+		tailRuntimeLogFile(ctx, file, runtimeLogPollInterval, emit)
+		return
+	}
+	baseURL, ok := ResolveClashAPIBaseURL()
+	if !ok {
+		setRuntimeLogStatus(func(s *RuntimeLogStatus) { *s = RuntimeLogStatus{Source: RuntimeLogSourceDisabled} })
+		return
+	}
+	setRuntimeLogStatus(func(s *RuntimeLogStatus) {
+		*s = RuntimeLogStatus{Source: RuntimeLogSourceClashAPI, Target: baseURL + "/logs"}
+	})
+	followClashLogs(ctx, baseURL, strings.TrimSpace(os.Getenv("SINGBOX_CLASH_API_SECRET")), emit)
+}
+
+// followClashLogs reads the Clash API /logs stream, one JSON object per line,
+// reconnecting until ctx ends.
+func followClashLogs(ctx context.Context, baseURL, secret string, emit func(RuntimeLogEntry)) {
+	delay := runtimeLogRetryBase
+	for {
+		connected, err := readClashLogs(ctx, baseURL, secret, emit)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = runtimeLogRetryBase
+		}
+		msg := "stream closed"
+		if err != nil {
+			msg = err.Error()
+		}
+		setRuntimeLogStatus(func(s *RuntimeLogStatus) {
+			s.Connected = false
+			s.LastError = msg
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > runtimeLogRetryMax {
+			delay = runtimeLogRetryMax
+		}
+	}
+}
+
+func readClashLogs(ctx context.Context, baseURL, secret string, emit func(RuntimeLogEntry)) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/logs?level=debug", nil)
+	if err != nil {
+		return false, err
+	}
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("clash api /logs status %d", resp.StatusCode)
+	}
+	setRuntimeLogStatus(func(s *RuntimeLogStatus) {
+		s.Connected = true
+		s.LastError = ""
+	})
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), runtimeLogMaxLine)
+	for scanner.Scan() {
+		var msg struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || strings.TrimSpace(msg.Payload) == "" {
+			continue
+		}
+		emit(RuntimeLogEntry{Level: msg.Type, Message: strings.TrimSpace(msg.Payload)})
+	}
+	return true, scanner.Err()
+}
+
+// tailRuntimeLogFile follows path from its current end, polling every
+// interval. It starts over when the file is truncated or replaced.
+func tailRuntimeLogFile(ctx context.Context, path string, interval time.Duration, emit func(RuntimeLogEntry)) {
+	var (
+		f       *os.File
+		reader  *bufio.Reader
+		offset  int64
+		partial string
+	)
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	fail := func(err error) {
+		setRuntimeLogStatus(func(s *RuntimeLogStatus) {
+			s.Connected = false
+			s.LastError = err.Error()
+		})
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	first := true
+
+	for {
+		if f == nil {
+			opened, err := os.Open(path)
+			if err != nil {
+				fail(err)
+			} else {
+				f = opened
+				offset = 0
+				// Skip what was logged before the collector started; the
+				// persisted window already covers earlier runs.
+				if first {
+					if end, err := f.Seek(0, io.SeekEnd); err == nil {
+						offset = end
+					}
+				}
+				reader = bufio.NewReader(f)
+				partial = ""
+				setRuntimeLogStatus(func(s *RuntimeLogStatus) {
+					s.Connected = true
+					s.LastError = ""
+				})
+			}
+			first = false
+		}
+		if f != nil {
+			for {
+				chunk, err := reader.ReadString('\n')
+				offset += int64(len(chunk))
+				if err != nil {
+					partial += chunk
+					break
+				}
+				if e, ok := parseRuntimeLogLine(partial + chunk); ok {
+					emit(e)
+				}
+				partial = ""
+			}
+			if replaced, err := runtimeLogFileReplaced(f, path, offset); err != nil || replaced {
+				f.Close()
+				f = nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runtimeLogFileReplaced reports whether path no longer names the open file
+// or was truncated below what has been read.
+func runtimeLogFileReplaced(f *os.File, path string, offset int64) (bool, error) {
+	current, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	opened, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, opened) || current.Size() < offset, nil
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// parseRuntimeLogLine parses a sing-box log file line such as
+// "+0000 2024-05-01 10:00:00 INFO [123 0ms] inbound/mixed[in]: ...".
+// Lines without a recognizable level are kept at info level.
+func parseRuntimeLogLine(raw string) (RuntimeLogEntry, bool) {
+	line := strings.TrimSpace(ansiEscape.ReplaceAllString(raw, ""))
+	if line == "" {
+		return RuntimeLogEntry{}, false
+	}
+	fields := strings.Fields(line)
+	for i := 0; i < len(fields) && i < 4; i++ {
+		lvl := NormalizeRuntimeLogLevel(fields[i])
+		if lvl == "" || fields[i] != strings.ToUpper(fields[i]) {
+			continue
+		}
+		e := RuntimeLogEntry{Level: lvl, Message: strings.Join(fields[i+1:], " ")}
+		if ts, ok := parseRuntimeLogTime(fields[:i]); ok {
+			e.Timestamp = ts
+		}
+		return e, e.Message != ""
+	}
+	return RuntimeLogEntry{Level: RuntimeLogInfo, Message: line}, true
+}
+
+func parseRuntimeLogTime(fields []string) (string, bool) {
+	layouts := map[int]string{
+		3: "-0700 2006-01-02 15:04:05",
+		2: "2006-01-02 15:04:05",
+	}
+	layout, ok := layouts[len(fields)]
+	if !ok {
+		return "", false
+	}
+	t, err := time.Parse(layout, strings.Join(fields, " "))
+	if err != nil {
+		return "", false
+	}
+	return t.UTC().Format(time.RFC3339), true
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRuntimeLogBufferEvictsAndIndexesLevels(t *testing.T) {
+	buf := NewRuntimeLogBuffer(4)
+	buf.Append(RuntimeLogEntry{Level: "info", Message: "one"})
+	buf.Append(RuntimeLogEntry{Level: "warning", Message: "two"})
+	buf.Append(RuntimeLogEntry{Level: "info", Message: "three dial tcp"})
+	buf.Append(RuntimeLogEntry{Level: "fatal", Message: "four"})
+	buf.Append(RuntimeLogEntry{Level: "info", Message: "five dial udp"})
+	buf.Append(RuntimeLogEntry{Level: "TRACE", Message: "six"})
+
+	all := buf.Query(RuntimeLogQuery{})
+	if len(all) != 4 || all[0].Message != "six" || all[3].Message != "three dial tcp" {
+		t.Fatalf("expected the newest four entries newest first, got %+v", all)
+	}
+	if all[0].Seq != 6 || all[0].Level != RuntimeLogDebug || all[0].Source != "singbox" {
+		t.Fatalf("entry not stamped: %+v", all[0])
+	}
+
+	counts := buf.Counts()
+	if counts[RuntimeLogInfo] != 2 || counts[RuntimeLogWarn] != 0 || counts[RuntimeLogError] != 1 || counts[RuntimeLogDebug] != 1 {
+		t.Fatalf("level counts not maintained on eviction: %v", counts)
+	}
+
+	info := buf.Query(RuntimeLogQuery{Level: "info"})
+	if len(info) != 2 || info[0].Message != "five dial udp" || info[1].Message != "three dial tcp" {
+		t.Fatalf("level query wrong: %+v", info)
+	}
+	if got := buf.Query(RuntimeLogQuery{Level: "warning"}); len(got) != 0 {
+		t.Fatalf("evicted warn entry still indexed: %+v", got)
+	}
+	if got := buf.Query(RuntimeLogQuery{Level: "bogus"}); len(got) != 0 {
+		t.Fatalf("unknown level should match nothing: %+v", got)
+	}
+
+	dial := buf.Query(RuntimeLogQuery{Keyword: "DIAL", Limit: 1})
+	if len(dial) != 1 || dial[0].Message != "five dial udp" {
+		t.Fatalf("keyword query wrong: %+v", dial)
+	}
+	after := buf.Query(RuntimeLogQuery{AfterSeq: 4})
+	if len(after) != 2 || after[1].Seq != 5 {
+		t.Fatalf("after-seq query wrong: %+v", after)
+	}
+}
+
+func TestRuntimeLogBufferSubscribe(t *testing.T) {
+	buf := NewRuntimeLogBuffer(8)
+	ch, cancel := buf.Subscribe(4)
+	buf.Append(RuntimeLogEntry{Level: "error", Message: "boom"})
+	select {
+	case e := <-ch:
+		if e.Seq != 1 || e.Message != "boom" {
+			t.Fatalf("unexpected entry: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not receive the entry")
+	}
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed after cancel")
+	}
+	buf.Append(RuntimeLogEntry{Message: "after cancel"})
+}
+
+func TestParseRuntimeLogLine(t *testing.T) {
+	e, ok := parseRuntimeLogLine("+0800 2024-05-01 10:00:00 \x1b[36mINFO\x1b[0m [3340182452 0ms] inbound/mixed[mixed-in]: inbound connection from 127.0.0.1:5555\n")
+	if !ok || e.Level != RuntimeLogInfo || e.Timestamp != "2024-05-01T02:00:00Z" ||
+		e.Message != "[3340182452 0ms] inbound/mixed[mixed-in]: inbound connection from 127.0.0.1:5555" {
+		t.Fatalf("unexpected parse: %+v", e)
+	}
+	e, ok = parseRuntimeLogLine("WARN outbound/vless[hk-1]: handshake slow")
+	if !ok || e.Level != RuntimeLogWarn || e.Timestamp != "" || e.Message != "outbound/vless[hk-1]: handshake slow" {
+		t.Fatalf("unexpected parse without timestamp: %+v", e)
+	}
+	e, ok = parseRuntimeLogLine("sing-box started (0.12s)")
+	if !ok || e.Level != RuntimeLogInfo || e.Message != "sing-box started (0.12s)" {
+		t.Fatalf("unexpected parse without level: %+v", e)
+	}
+	if _, ok := parseRuntimeLogLine("  \n"); ok {
+		t.Fatal("blank line should be skipped")
+	}
+}
+
+func TestFollowClashLogsReconnects(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logs" || r.URL.Query().Get("level") != "debug" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer s3cret" {
+			t.Errorf("missing bearer secret, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch calls.Add(1) {
+		case 1:
+			fmt.Fprintln(w, `{"type":"info","payload":"inbound/mixed[mixed-in]: inbound connection"}`)
+			w.(http.Flusher).Flush()
+			fmt.Fprintln(w, `not json`)
+			fmt.Fprintln(w, `{"type":"warning","payload":"outbound/vless[hk-1]: dial timeout"}`)
+		default:
+			fmt.Fprintln(w, `{"type":"error","payload":"router: rule-set not found"}`)
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var mu sync.Mutex
+	var got []RuntimeLogEntry
+	buf := NewRuntimeLogBuffer(16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		followClashLogs(ctx, srv.URL, "s3cret", func(e RuntimeLogEntry) {
+			e = buf.Append(e)
+			mu.Lock()
+			got = append(got, e)
+			n := len(got)
+			mu.Unlock()
+			if n == 3 {
+				cancel()
+			}
+		})
+	}()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 entries across reconnects, got %+v", got)
+	}
+	if got[0].Level != RuntimeLogInfo || got[1].Level != RuntimeLogWarn || got[2].Level != RuntimeLogError {
+		t.Fatalf("levels not normalized: %+v", got)
+	}
+	if got[2].Message != "router: rule-set not found" || calls.Load() < 2 {
+		t.Fatalf("second connection not read: %+v calls=%d", got, calls.Load())
+	}
+}
+
+func TestReadClashLogsStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	connected, err := readClashLogs(context.Background(), srv.URL, "", func(RuntimeLogEntry) {
+		t.Fatal("no entries expected")
+	})
+	if connected || err == nil {
+		t.Fatalf("expected status error, got connected=%v err=%v", connected, err)
+	}
+}
+
+func TestTailRuntimeLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sing-box.log")
+	if err := os.WriteFile(path, []byte("INFO before start\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entries := make(chan RuntimeLogEntry, 8)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tailRuntimeLogFile(ctx, path, 10*time.Millisecond, func(e RuntimeLogEntry) { entries <- e })
+	}()
+	next := func() RuntimeLogEntry {
+		select {
+		case e := <-entries:
+			return e
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a log line")
+		}
+		return RuntimeLogEntry{}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(f, "ERROR partial")
+	time.Sleep(50 * time.Millisecond)
+	fmt.Fprint(f, " line\n")
+	f.Close()
+	if e := next(); e.Level != RuntimeLogError || e.Message != "partial line" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	// A replaced file is read from its start.
+	if err := os.WriteFile(path+".new", []byte("WARN rotated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(); e.Level != RuntimeLogWarn || e.Message != "rotated" {
+		t.Fatalf("unexpected entry after rotation: %+v", e)
+	}
+	cancel()
+	<-done
+}
+
+func TestRuntimeLogStoreRollsAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", runtimeLogFileName)
+	buf := NewRuntimeLogBuffer(16)
+	store, err := openRuntimeLogStore(path, 3, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 7; i++ {
+		e := buf.Append(RuntimeLogEntry{Level: "info", Message: fmt.Sprintf("line %d", i)})
+		if err := store.append(e, buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.close()
+
+	persisted, err := readRuntimeLogFile(path, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file was rewritten to the newest three at six lines, then gained one.
+	if len(persisted) != 4 || persisted[0].Message != "line 4" || persisted[3].Message != "line 7" {
+		t.Fatalf("unexpected rolling window: %+v", persisted)
+	}
+
+	reloaded := NewRuntimeLogBuffer(16)
+	store, err = openRuntimeLogStore(path, 3, reloaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.close()
+	got := reloaded.Query(RuntimeLogQuery{})
+	if len(got) != 3 || got[0].Message != "line 7" || got[2].Message != "line 5" || got[0].Seq != 3 {
+		t.Fatalf("unexpected reloaded entries: %+v", got)
+	}
+}
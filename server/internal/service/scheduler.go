@@ -3,14 +3,46 @@ package service
 import (
 	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"hash/fnv"
+	"sync"
 	"time"
 
 	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
 )
 
+const (
+	defaultRefreshConcurrency   = 4
+	defaultRefreshJitterPercent = 10
+	defaultRefreshMaxBackoffSec = 24 * 3600
+	// maxBackoffDoublings caps the shift so long failure streaks cannot overflow.
+	maxBackoffDoublings = 16
+)
+
+// SubscriptionSchedule is the scheduler's view of one subscription.
+// IntervalSource is "subscription" or "provider" (profile-update-interval);
+// NextRunAt is nil when the subscription is not auto-refreshed.
+type SubscriptionSchedule struct {
+	SubID          string
+	Name           string
+	SourceType     string
+	Scheduled      bool
+	Running        bool
+	Expired        bool
+	IntervalSec    int
+	IntervalSource string
+	JitterSec      int
+	FailureStreak  int
+	BackoffSec     int
+	LastFetchAt    string
+	LastError      string
+	NextRunAt      *time.Time
+}
+
 // StartSubscriptionScheduler runs periodic auto-refresh checks.
-// It refreshes subscriptions that are enabled, auto-update enabled, and due by interval.
+// Due subscriptions are refreshed by a bounded worker pool
+// (BOXPILOT_REFRESH_CONCURRENCY) and each tick ends with at most one reload.
 func StartSubscriptionScheduler(ctx context.Context, db *sql.DB, tick time.Duration) {
 	if tick <= 0 {
 		tick = 30 * time.Second
@@ -31,44 +63,191 @@ func StartSubscriptionScheduler(ctx context.Context, db *sql.DB, tick time.Durat
 func runSubscriptionAutoRefresh(ctx context.Context, db *sql.DB) {
 	subs, err := repo.ListSubscriptions(db, false)
 	if err != nil {
-		log.Printf("scheduler: list subscriptions failed: %v", err)
+		schedulerLog.ErrorContext(ctx, "list subscriptions failed", "error", err)
 		return
 	}
 	now := time.Now().UTC()
+	var due []repo.SubscriptionRow
+	var lag time.Duration
 	for _, s := range subs {
-		if s.Enabled != 1 || s.AutoUpdateEnabled != 1 {
+		if s.Enabled != 1 || isSubscriptionRefreshing(s.ID) || !shouldAutoRefresh(s, now) {
 			continue
 		}
-		interval := s.RefreshIntervalSec
-		if interval < 60 {
-			interval = 3600
-		}
-		if !shouldRefreshByInterval(s, now, time.Duration(interval)*time.Second) {
-			continue
-		}
-		notModified, _, _, err := RefreshSubscription(db, s.ID)
-		if err != nil {
-			log.Printf("scheduler: refresh %s failed: %v", s.ID, err)
-		} else if !notModified {
-			if err := ReloadIfForwardingRunning(ctx, db); err != nil {
-				log.Printf("scheduler: reload after refresh %s failed: %v", s.ID, err)
-			}
+		due = append(due, s)
+		// Never-fetched subscriptions are due at the zero time; they have no lag.
+		if next := subscriptionSchedule(s, now).NextRunAt; next != nil && !next.IsZero() && now.Sub(*next) > lag {
+			lag = now.Sub(*next)
 		}
+	}
+	recordSchedulerTick(now, len(due), lag)
+	schedulerLog.DebugContext(ctx, "scheduler tick", "subscriptions", len(subs), "due", len(due), "lag_ms", lag.Milliseconds())
+	if len(due) == 0 {
+		return
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		changed bool
+	)
+	sem := make(chan struct{}, refreshConcurrency())
+	for _, s := range due {
 		select {
 		case <-ctx.Done():
+			wg.Wait()
 			return
-		default:
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(s repo.SubscriptionRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			res, err := RefreshSubscription(ctx, db, s.ID)
+			if err != nil {
+				var appErr *errorx.AppError
+				if !errors.As(err, &appErr) || appErr.Code != errorx.JOBRefreshInProgress {
+					schedulerLog.WarnContext(ctx, "scheduled refresh failed", "sub_id", s.ID, "streak", s.FailureStreak+1, "error", err)
+				}
+				return
+			}
+			if !res.NotModified {
+				mu.Lock()
+				changed = true
+				mu.Unlock()
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	if changed {
+		if err := ReloadIfForwardingRunning(ctx, db); err != nil {
+			schedulerLog.ErrorContext(ctx, "reload after refresh failed", "error", err)
 		}
 	}
 }
 
-func shouldRefreshByInterval(s repo.SubscriptionRow, now time.Time, interval time.Duration) bool {
-	if !s.LastFetchAt.Valid || s.LastFetchAt.String == "" {
+// shouldAutoRefresh decides whether a scheduler tick refreshes s. File
+// subscriptions are re-read as soon as their mtime changes; inline ones only
+// change through the API, which refreshes them itself.
+func shouldAutoRefresh(s repo.SubscriptionRow, now time.Time) bool {
+	if s.SourceType == SubscriptionSourceFile && subscriptionFileChanged(s) {
 		return true
 	}
-	last, err := time.Parse(time.RFC3339, s.LastFetchAt.String)
+	sched := subscriptionSchedule(s, now)
+	return sched.NextRunAt != nil && !now.Before(*sched.NextRunAt)
+}
+
+// ListSubscriptionSchedules reports the next run and failure streak of every
+// subscription.
+func ListSubscriptionSchedules(db *sql.DB) ([]SubscriptionSchedule, error) {
+	subs, err := repo.ListSubscriptions(db, false)
 	if err != nil {
-		return true
+		return nil, err
+	}
+	now := time.Now().UTC()
+	out := make([]SubscriptionSchedule, 0, len(subs))
+	for _, s := range subs {
+		if s.ID == repo.ManualSubscriptionID {
+			continue
+		}
+		out = append(out, subscriptionSchedule(s, now))
+	}
+	return out, nil
+}
+
+// subscriptionSchedule computes when s is next due: the refresh interval (the
+// provider's profile-update-interval when s follows it), doubled for every
+// consecutive failure up to BOXPILOT_REFRESH_MAX_BACKOFF_SEC, plus a
+// per-subscription jitter so subscriptions added together drift apart.
+// Expired subscriptions are not auto-refreshed.
+func subscriptionSchedule(s repo.SubscriptionRow, now time.Time) SubscriptionSchedule {
+	sched := SubscriptionSchedule{
+		SubID:         s.ID,
+		Name:          s.Name,
+		SourceType:    s.SourceType,
+		Running:       isSubscriptionRefreshing(s.ID),
+		FailureStreak: s.FailureStreak,
+		LastFetchAt:   s.LastFetchAt.String,
+		LastError:     s.LastError.String,
+	}
+	interval := s.RefreshIntervalSec
+	if interval < 60 {
+		interval = 3600
+	}
+	sched.IntervalSource = "subscription"
+	if provider := providerIntervalSec(s); s.FollowProviderInterval == 1 && provider > 0 {
+		interval = provider
+		sched.IntervalSource = "provider"
+	}
+	sched.IntervalSec = interval
+	sched.Expired = EvaluateSubscriptionUsage(s, now).Expired
+	sched.Scheduled = s.Enabled == 1 && s.AutoUpdateEnabled == 1 && s.SourceType != SubscriptionSourceInline && !sched.Expired
+	if !sched.Scheduled {
+		return sched
+	}
+	sched.BackoffSec = refreshBackoffSec(interval, s.FailureStreak)
+	sched.JitterSec = refreshJitterSec(s.ID, interval)
+
+	last, err := time.Parse(time.RFC3339, s.LastFetchAt.String)
+	if !s.LastFetchAt.Valid || s.LastFetchAt.String == "" || err != nil {
+		// Never fetched (or unreadable timestamp): due right away.
+		next := time.Time{}
+		sched.NextRunAt = &next
+		return sched
+	}
+	next := last.Add(time.Duration(sched.BackoffSec+sched.JitterSec) * time.Second)
+	sched.NextRunAt = &next
+	return sched
+}
+
+// refreshBackoffSec is the delay after the last attempt: the interval itself,
+// or interval * 2^streak after consecutive failures, capped at the max backoff
+// (which never shortens the configured interval).
+func refreshBackoffSec(interval, streak int) int {
+	if streak <= 0 {
+		return interval
 	}
-	return now.Sub(last) >= interval
+	if streak > maxBackoffDoublings {
+		streak = maxBackoffDoublings
+	}
+	limit := refreshMaxBackoffSec()
+	if limit < interval {
+		limit = interval
+	}
+	delay := int64(interval) << streak
+	if delay > int64(limit) {
+		return limit
+	}
+	return int(delay)
+}
+
+// refreshJitterSec spreads subscriptions over the first
+// BOXPILOT_REFRESH_JITTER_PERCENT of their interval. It is derived from the id
+// so a subscription keeps its slot across restarts.
+func refreshJitterSec(subID string, interval int) int {
+	span := interval * refreshJitterPercent() / 100
+	if span <= 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(subID))
+	return int(h.Sum32() % uint32(span))
+}
+
+// refreshConcurrency bounds parallel refreshes per tick
+// (BOXPILOT_REFRESH_CONCURRENCY, default 4).
+func refreshConcurrency() int {
+	return positiveEnvInt("BOXPILOT_REFRESH_CONCURRENCY", defaultRefreshConcurrency)
+}
+
+func refreshJitterPercent() int {
+	p := positiveEnvInt("BOXPILOT_REFRESH_JITTER_PERCENT", defaultRefreshJitterPercent)
+	if p > 50 {
+		p = 50
+	}
+	return p
+}
+
+func refreshMaxBackoffSec() int {
+	return positiveEnvInt("BOXPILOT_REFRESH_MAX_BACKOFF_SEC", defaultRefreshMaxBackoffSec)
 }
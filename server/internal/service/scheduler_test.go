@@ -0,0 +1,121 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+)
+
+func TestRefreshBackoffSec(t *testing.T) {
+	t.Setenv("BOXPILOT_REFRESH_MAX_BACKOFF_SEC", "20000")
+	cases := []struct {
+		interval, streak, want int
+	}{
+		{3600, 0, 3600},
+		{3600, 1, 7200},
+		{3600, 2, 14400},
+		{3600, 3, 20000},
+		{3600, 1000, 20000},
+		// The cap never shortens a long configured interval.
+		{86400, 2, 86400},
+	}
+	for _, tc := range cases {
+		if got := refreshBackoffSec(tc.interval, tc.streak); got != tc.want {
+			t.Fatalf("interval=%d streak=%d: want %d got %d", tc.interval, tc.streak, tc.want, got)
+		}
+	}
+}
+
+func TestRefreshJitterSec(t *testing.T) {
+	a := refreshJitterSec("sub-a", 3600)
+	if a != refreshJitterSec("sub-a", 3600) {
+		t.Fatalf("jitter must be stable for one subscription")
+	}
+	if a < 0 || a >= 360 {
+		t.Fatalf("jitter %d outside the first 10%% of the interval", a)
+	}
+	seen := map[int]struct{}{}
+	for _, id := range []string{"sub-a", "sub-b", "sub-c", "sub-d", "sub-e"} {
+		seen[refreshJitterSec(id, 3600)] = struct{}{}
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected subscriptions to get different slots")
+	}
+}
+
+func TestSubscriptionSchedule(t *testing.T) {
+	last := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := repo.SubscriptionRow{
+		ID:                 "sub-a",
+		Enabled:            1,
+		AutoUpdateEnabled:  1,
+		SourceType:         SubscriptionSourceURL,
+		RefreshIntervalSec: 3600,
+		LastFetchAt:        sql.NullString{String: last.Format(time.RFC3339), Valid: true},
+		FailureStreak:      2,
+	}
+	sched := subscriptionSchedule(s, last)
+	if !sched.Scheduled || sched.NextRunAt == nil {
+		t.Fatalf("expected scheduled: %+v", sched)
+	}
+	want := last.Add(time.Duration(4*3600+sched.JitterSec) * time.Second)
+	if !sched.NextRunAt.Equal(want) {
+		t.Fatalf("next run: want %s got %s", want, sched.NextRunAt)
+	}
+	if shouldAutoRefresh(s, want.Add(-time.Second)) || !shouldAutoRefresh(s, want) {
+		t.Fatalf("due exactly at next run")
+	}
+
+	s.AutoUpdateEnabled = 0
+	if sched := subscriptionSchedule(s, last); sched.Scheduled || sched.NextRunAt != nil {
+		t.Fatalf("auto update off should not be scheduled: %+v", sched)
+	}
+}
+
+func TestBeginSubscriptionRefresh(t *testing.T) {
+	if !beginSubscriptionRefresh("sub-x") {
+		t.Fatalf("first refresh should start")
+	}
+	if beginSubscriptionRefresh("sub-x") || !isSubscriptionRefreshing("sub-x") {
+		t.Fatalf("second refresh of the same subscription should be rejected")
+	}
+	endSubscriptionRefresh("sub-x")
+	if isSubscriptionRefreshing("sub-x") {
+		t.Fatalf("refresh should be finished")
+	}
+}
+
+func TestSubscriptionScheduleProviderIntervalAndExpiry(t *testing.T) {
+	t.Setenv("BOXPILOT_REFRESH_JITTER_PERCENT", "1")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := repo.SubscriptionRow{
+		ID:                 "sub-p",
+		Enabled:            1,
+		AutoUpdateEnabled:  1,
+		SourceType:         SubscriptionSourceURL,
+		RefreshIntervalSec: 3600,
+		SubProfileInterval: sql.NullInt64{Int64: 12 * 3600, Valid: true},
+	}
+	if sched := subscriptionSchedule(s, now); sched.IntervalSec != 3600 || sched.IntervalSource != "subscription" {
+		t.Fatalf("provider interval must be opt-in: %+v", sched)
+	}
+	s.FollowProviderInterval = 1
+	if sched := subscriptionSchedule(s, now); sched.IntervalSec != 12*3600 || sched.IntervalSource != "provider" {
+		t.Fatalf("expected provider interval: %+v", sched)
+	}
+	s.SubProfileInterval = sql.NullInt64{}
+	if sched := subscriptionSchedule(s, now); sched.IntervalSec != 3600 {
+		t.Fatalf("missing provider interval should fall back: %+v", sched)
+	}
+
+	s.SubExpireUnix = sql.NullInt64{Int64: now.Add(-time.Hour).Unix(), Valid: true}
+	sched := subscriptionSchedule(s, now)
+	if !sched.Expired || sched.Scheduled || sched.NextRunAt != nil {
+		t.Fatalf("expired subscription must not be scheduled: %+v", sched)
+	}
+	if shouldAutoRefresh(s, now) {
+		t.Fatalf("expired subscription must not auto refresh")
+	}
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/generator"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	FetchViaDirect       = "direct"
+	FetchViaHTTPInbound  = "http_inbound"
+	FetchViaSocksInbound = "socks_inbound"
+	FetchViaNode         = "node"
+
+	defaultFetchTimeoutSec   = 30
+	maxFetchTimeoutSec       = 300
+	defaultFetchMaxBodyBytes = 5 * 1024 * 1024
+	maxFetchMaxBodyBytes     = 64 * 1024 * 1024
+
+	// defaultFetchProxyPort is the loopback mixed inbound used to fetch
+	// subscriptions through a specific node; override with FETCH_PROXY_PORT.
+	defaultFetchProxyPort = 17899
+)
+
+// userAgentPresets maps preset names to the User-Agent providers key their
+// response format on. "default" keeps Go's own User-Agent.
+var userAgentPresets = map[string]string{
+	"default":  "",
+	"clash":    "clash.meta",
+	"sing-box": "sing-box",
+	"v2rayn":   "v2rayN/7.0",
+	"custom":   "",
+}
+
+// FetchOptions controls how one subscription URL is requested.
+type FetchOptions struct {
+	UserAgentPreset string
+	UserAgent       string
+	Headers         map[string]string
+	TimeoutSec      int
+	MaxBodyBytes    int
+	FetchVia        string
+	FetchViaNodeTag string
+}
+
+func DefaultFetchOptions() FetchOptions {
+	return FetchOptions{
+		UserAgentPreset: "default",
+		Headers:         map[string]string{},
+		TimeoutSec:      defaultFetchTimeoutSec,
+		MaxBodyBytes:    defaultFetchMaxBodyBytes,
+		FetchVia:        FetchViaDirect,
+	}
+}
+
+// LoadFetchOptions returns the stored fetch options of a subscription, or the
+// defaults when none were saved.
+func LoadFetchOptions(db *sql.DB, subID string) (FetchOptions, error) {
+	row, err := repo.GetSubscriptionFetchOptions(db, subID)
+	if err != nil {
+		return FetchOptions{}, err
+	}
+	if row == nil {
+		return DefaultFetchOptions(), nil
+	}
+	return FetchOptionsFromRow(*row), nil
+}
+
+func FetchOptionsFromRow(row repo.SubscriptionFetchOptionsRow) FetchOptions {
+	opts := FetchOptions{
+		UserAgentPreset: row.UserAgentPreset,
+		UserAgent:       row.UserAgent,
+		Headers:         map[string]string{},
+		TimeoutSec:      row.TimeoutSec,
+		MaxBodyBytes:    row.MaxBodyBytes,
+		FetchVia:        row.FetchVia,
+		FetchViaNodeTag: row.FetchViaNodeTag,
+	}
+	_ = json.Unmarshal([]byte(row.HeadersJSON), &opts.Headers)
+	return opts
+}
+
+// NormalizeFetchOptions fills defaults and validates the options before they are saved.
+func NormalizeFetchOptions(opts FetchOptions) (FetchOptions, *errorx.AppError) {
+	opts.UserAgentPreset = strings.ToLower(strings.TrimSpace(opts.UserAgentPreset))
+	if opts.UserAgentPreset == "" {
+		opts.UserAgentPreset = "default"
+	}
+	if _, ok := userAgentPresets[opts.UserAgentPreset]; !ok {
+		return opts, errorx.New(errorx.REQInvalidField, "invalid user_agent_preset").WithDetails(map[string]any{"user_agent_preset": opts.UserAgentPreset})
+	}
+	opts.UserAgent = strings.TrimSpace(opts.UserAgent)
+	if opts.UserAgentPreset == "custom" && opts.UserAgent == "" {
+		return opts, errorx.New(errorx.REQMissingField, "user_agent required for custom preset")
+	}
+	if opts.UserAgentPreset != "custom" {
+		opts.UserAgent = ""
+	}
+	headers := make(map[string]string, len(opts.Headers))
+	for k, v := range opts.Headers {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if strings.EqualFold(k, "User-Agent") {
+			return opts, errorx.New(errorx.REQInvalidField, "set User-Agent through user_agent_preset")
+		}
+		headers[http.CanonicalHeaderKey(k)] = strings.TrimSpace(v)
+	}
+	opts.Headers = headers
+	if opts.TimeoutSec == 0 {
+		opts.TimeoutSec = defaultFetchTimeoutSec
+	}
+	if opts.TimeoutSec < 1 || opts.TimeoutSec > maxFetchTimeoutSec {
+		return opts, errorx.New(errorx.REQInvalidField, fmt.Sprintf("timeout_sec must be between 1 and %d", maxFetchTimeoutSec))
+	}
+	if opts.MaxBodyBytes == 0 {
+		opts.MaxBodyBytes = defaultFetchMaxBodyBytes
+	}
+	if opts.MaxBodyBytes < 1024 || opts.MaxBodyBytes > maxFetchMaxBodyBytes {
+		return opts, errorx.New(errorx.REQInvalidField, fmt.Sprintf("max_body_bytes must be between 1024 and %d", maxFetchMaxBodyBytes))
+	}
+	opts.FetchVia = strings.ToLower(strings.TrimSpace(opts.FetchVia))
+	if opts.FetchVia == "" {
+		opts.FetchVia = FetchViaDirect
+	}
+	switch opts.FetchVia {
+	case FetchViaDirect, FetchViaHTTPInbound, FetchViaSocksInbound:
+		opts.FetchViaNodeTag = ""
+	case FetchViaNode:
+		opts.FetchViaNodeTag = strings.TrimSpace(opts.FetchViaNodeTag)
+		if opts.FetchViaNodeTag == "" {
+			return opts, errorx.New(errorx.REQMissingField, "fetch_via_node_tag required when fetch_via is node")
+		}
+	default:
+		return opts, errorx.New(errorx.REQInvalidField, "invalid fetch_via").WithDetails(map[string]any{"fetch_via": opts.FetchVia})
+	}
+	return opts, nil
+}
+
+// SaveFetchOptions stores the fetch options of a subscription. When the
+// request changes (User-Agent, headers or route) the stored etag is cleared:
+// the provider may answer another client differently, so the next refresh must
+// not be satisfied by a 304 for the old response.
+func SaveFetchOptions(db *sql.DB, subID string, opts FetchOptions) error {
+	prev, err := LoadFetchOptions(db, subID)
+	if err != nil {
+		return err
+	}
+	headers, err := json.Marshal(opts.Headers)
+	if err != nil {
+		return err
+	}
+	if err := repo.UpsertSubscriptionFetchOptions(db, repo.SubscriptionFetchOptionsRow{
+		SubID:           subID,
+		UserAgentPreset: opts.UserAgentPreset,
+		UserAgent:       opts.UserAgent,
+		HeadersJSON:     string(headers),
+		TimeoutSec:      opts.TimeoutSec,
+		MaxBodyBytes:    opts.MaxBodyBytes,
+		FetchVia:        opts.FetchVia,
+		FetchViaNodeTag: opts.FetchViaNodeTag,
+		UpdatedAt:       util.NowRFC3339(),
+	}); err != nil {
+		return err
+	}
+	if prev.sameRequest(opts) {
+		return nil
+	}
+	return repo.ClearSubscriptionValidators(db, subID)
+}
+
+// sameRequest reports whether two option sets send the same request through
+// the same route; timeout and body limit do not change the response.
+func (o FetchOptions) sameRequest(other FetchOptions) bool {
+	return o.ResolvedUserAgent() == other.ResolvedUserAgent() &&
+		maps.Equal(o.Headers, other.Headers) &&
+		o.FetchVia == other.FetchVia &&
+		o.FetchViaNodeTag == other.FetchViaNodeTag
+}
+
+// ResolvedUserAgent resolves the preset to the header value; empty keeps Go's default.
+func (o FetchOptions) ResolvedUserAgent() string {
+	if o.UserAgentPreset == "custom" {
+		return o.UserAgent
+	}
+	return userAgentPresets[o.UserAgentPreset]
+}
+
+// applyHeaders sets the User-Agent and extra headers on a subscription request.
+func (o FetchOptions) applyHeaders(req *http.Request) {
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+	if ua := o.ResolvedUserAgent(); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+}
+
+// buildFetchClient returns an HTTP client that honours the fetch-via setting.
+func buildFetchClient(db *sql.DB, opts FetchOptions) (*http.Client, error) {
+	timeout := time.Duration(opts.TimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = defaultFetchTimeoutSec * time.Second
+	}
+	switch opts.FetchVia {
+	case FetchViaHTTPInbound, FetchViaSocksInbound:
+		proxyType := "http"
+		if opts.FetchVia == FetchViaSocksInbound {
+			proxyType = "socks"
+		}
+		row, err := repo.GetProxySetting(db, proxyType)
+		if err != nil {
+			return nil, err
+		}
+		if row == nil || row.Enabled != 1 {
+			return nil, fmt.Errorf("BoxPilot %s inbound is disabled", proxyType)
+		}
+		var user *url.Userinfo
+		if row.AuthMode == "basic" {
+			user = url.UserPassword(row.Username, row.Password)
+		}
+		return proxiedClient(proxyType, listenerProbeAddress(row.ListenAddress, row.Port), user, timeout)
+	case FetchViaNode:
+		secret, err := loadFetchProxySecret(db)
+		if err != nil {
+			return nil, err
+		}
+		username, password := FetchProxyCredentials(secret, opts.FetchViaNodeTag)
+		address := net.JoinHostPort("127.0.0.1", strconv.Itoa(FetchProxyPort()))
+		return proxiedClient("http", address, url.UserPassword(username, password), timeout)
+	default:
+		return &http.Client{Timeout: timeout}, nil
+	}
+}
+
+func proxiedClient(proxyType, address string, user *url.Userinfo, timeout time.Duration) (*http.Client, error) {
+	transport := &http.Transport{ForceAttemptHTTP2: false}
+	switch proxyType {
+	case "socks":
+		var auth *proxy.Auth
+		if user != nil {
+			password, _ := user.Password()
+			auth = &proxy.Auth{User: user.Username(), Password: password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", address, auth, &net.Dialer{Timeout: timeout})
+		if err != nil {
+			return nil, err
+		}
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("socks dialer does not support contexts")
+		}
+		transport.DialContext = contextDialer.DialContext
+	default:
+		transport.Proxy = http.ProxyURL(&url.URL{Scheme: "http", Host: address, User: user})
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// FetchProxyPort is the loopback port of the fetch-in inbound.
+func FetchProxyPort() int {
+	if raw := strings.TrimSpace(os.Getenv("FETCH_PROXY_PORT")); raw != "" {
+		if port, err := strconv.Atoi(raw); err == nil && port > 0 && port <= 65535 {
+			return port
+		}
+	}
+	return defaultFetchProxyPort
+}
+
+// FetchProxyCredentials derives the fetch-in user that routes to one node from
+// the per-install secret, so they are stable across restarts but cannot be
+// computed by other local processes that can reach the loopback inbound.
+func FetchProxyCredentials(secret, nodeTag string) (string, string) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(nodeTag))
+	sum := hex.EncodeToString(mac.Sum(nil))
+	return "fetch-" + sum[:12], sum[12:44]
+}
+
+// loadFetchProxySecret reads the secret migration 0005 generated.
+func loadFetchProxySecret(db *sql.DB) (string, error) {
+	secret, err := repo.GetFetchProxySecret(db)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", errors.New("fetch proxy secret is missing")
+	}
+	return secret, nil
+}
+
+// LoadFetchProxy returns the fetch-in inbound for subscriptions that are fetched
+// through one of the given config nodes.
+func LoadFetchProxy(db *sql.DB, nodeTags []string) (generator.FetchProxy, error) {
+	options, err := repo.ListSubscriptionFetchOptions(db)
+	if err != nil {
+		return generator.FetchProxy{}, err
+	}
+	available := make(map[string]struct{}, len(nodeTags))
+	for _, tag := range nodeTags {
+		available[tag] = struct{}{}
+	}
+	secret, err := loadFetchProxySecret(db)
+	if err != nil {
+		return generator.FetchProxy{}, err
+	}
+	return generator.FetchProxy{Port: FetchProxyPort(), Users: fetchInboundUsers(options, available, secret)}, nil
+}
+
+// fetchInboundUsers builds the fetch-in users for subscriptions fetched through
+// a node that is part of the generated config.
+func fetchInboundUsers(options map[string]repo.SubscriptionFetchOptionsRow, nodeTags map[string]struct{}, secret string) []generator.FetchProxyUser {
+	seen := map[string]struct{}{}
+	var users []generator.FetchProxyUser
+	for _, row := range options {
+		tag := strings.TrimSpace(row.FetchViaNodeTag)
+		if row.FetchVia != FetchViaNode || tag == "" {
+			continue
+		}
+		if _, ok := nodeTags[tag]; !ok {
+			continue
+		}
+		if _, dup := seen[tag]; dup {
+			continue
+		}
+		seen[tag] = struct{}{}
+		username, password := FetchProxyCredentials(secret, tag)
+		users = append(users, generator.FetchProxyUser{Username: username, Password: password, Outbound: tag})
+	}
+	// Map order is random; keep the generated config stable across builds.
+	sort.Slice(users, func(i, j int) bool { return users[i].Outbound < users[j].Outbound })
+	return users
+}
+
+// fetchSubscriptionBody reads at most opts.MaxBodyBytes and reports bodies that
+// exceed the limit instead of silently truncating them.
+func fetchSubscriptionBody(resp *http.Response, opts FetchOptions) ([]byte, error) {
+	limit := opts.MaxBodyBytes
+	if limit <= 0 {
+		limit = defaultFetchMaxBodyBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > limit {
+		return nil, errorx.New(errorx.SUBResponseTooLarge, "subscription response too large").WithDetails(map[string]any{"max_body_bytes": limit})
+	}
+	return body, nil
+}
+
+// fetchErrorCode distinguishes timeouts from other transport failures.
+func fetchErrorCode(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return errorx.SUBFetchTimeout
+	}
+	return errorx.SUBFetchFailed
+}
+
+// clientProviderFetcher fetches Clash providers with the same client and headers
+// as the subscription itself.
+func clientProviderFetcher(client *http.Client, opts FetchOptions) ProviderFetcher {
+	return func(ctx context.Context, target string) ([]byte, error) {
+		return fetchProvider(ctx, client, target, opts.applyHeaders)
+	}
+}
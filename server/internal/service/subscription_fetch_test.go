@@ -0,0 +1,134 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+)
+
+func TestNormalizeFetchOptions(t *testing.T) {
+	opts, appErr := NormalizeFetchOptions(FetchOptions{
+		UserAgentPreset: " Clash ",
+		UserAgent:       "ignored",
+		Headers:         map[string]string{"x-token": " abc ", " ": "dropped"},
+	})
+	if appErr != nil {
+		t.Fatalf("NormalizeFetchOptions: %v", appErr)
+	}
+	if opts.UserAgentPreset != "clash" || opts.UserAgent != "" || opts.ResolvedUserAgent() != "clash.meta" {
+		t.Fatalf("unexpected user agent normalization: %+v", opts)
+	}
+	if len(opts.Headers) != 1 || opts.Headers["X-Token"] != "abc" {
+		t.Fatalf("unexpected headers: %#v", opts.Headers)
+	}
+	if opts.TimeoutSec != defaultFetchTimeoutSec || opts.MaxBodyBytes != defaultFetchMaxBodyBytes || opts.FetchVia != FetchViaDirect {
+		t.Fatalf("expected defaults, got %+v", opts)
+	}
+
+	cases := []FetchOptions{
+		{UserAgentPreset: "curl"},
+		{UserAgentPreset: "custom"},
+		{Headers: map[string]string{"user-agent": "x"}},
+		{TimeoutSec: maxFetchTimeoutSec + 1},
+		{MaxBodyBytes: 100},
+		{FetchVia: "tunnel"},
+		{FetchVia: FetchViaNode},
+	}
+	for _, tc := range cases {
+		if _, appErr := NormalizeFetchOptions(tc); appErr == nil {
+			t.Fatalf("expected validation error for %+v", tc)
+		}
+	}
+}
+
+func TestFetchInboundUsers(t *testing.T) {
+	options := map[string]repo.SubscriptionFetchOptionsRow{
+		"s1": {SubID: "s1", FetchVia: FetchViaNode, FetchViaNodeTag: "node-b"},
+		"s2": {SubID: "s2", FetchVia: FetchViaNode, FetchViaNodeTag: "node-a"},
+		"s3": {SubID: "s3", FetchVia: FetchViaNode, FetchViaNodeTag: "node-a"},
+		"s4": {SubID: "s4", FetchVia: FetchViaNode, FetchViaNodeTag: "missing"},
+		"s5": {SubID: "s5", FetchVia: FetchViaDirect, FetchViaNodeTag: "node-c"},
+	}
+	tags := map[string]struct{}{"node-a": {}, "node-b": {}, "node-c": {}}
+	users := fetchInboundUsers(options, tags, "secret")
+	if len(users) != 2 || users[0].Outbound != "node-a" || users[1].Outbound != "node-b" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+	username, password := FetchProxyCredentials("secret", "node-a")
+	if users[0].Username != username || users[0].Password != password {
+		t.Fatalf("credentials mismatch: %+v", users[0])
+	}
+	if other, _ := FetchProxyCredentials("secret", "node-b"); other == username {
+		t.Fatal("expected distinct usernames per node")
+	}
+	if _, otherPassword := FetchProxyCredentials("other-install", "node-a"); otherPassword == password {
+		t.Fatal("expected credentials to depend on the install secret")
+	}
+}
+
+func TestFetchProxySecretIsRandomPerInstall(t *testing.T) {
+	a, err := loadFetchProxySecret(openTestDB(t))
+	if err != nil {
+		t.Fatalf("loadFetchProxySecret: %v", err)
+	}
+	b, err := loadFetchProxySecret(openTestDB(t))
+	if err != nil {
+		t.Fatalf("loadFetchProxySecret: %v", err)
+	}
+	if len(a) != 64 || a == b {
+		t.Fatalf("expected distinct 32-byte secrets, got %q and %q", a, b)
+	}
+}
+
+func TestSaveFetchOptionsClearsValidatorsOnRequestChange(t *testing.T) {
+	db := openTestDB(t)
+	if err := repo.CreateSubscription(db, "sub-1", "sub", "https://example.com/sub", "auto", SubscriptionSourceURL, 1, 0, 3600, 0); err != nil {
+		t.Fatal(err)
+	}
+	validators := func() (string, string) {
+		row, err := repo.GetSubscription(db, "sub-1")
+		if err != nil || row == nil {
+			t.Fatalf("GetSubscription: %+v, %v", row, err)
+		}
+		return row.Etag, row.LastModified
+	}
+
+	opts := DefaultFetchOptions()
+	if err := repo.SetSubscriptionFetchResult(db, "sub-1", `"v1"`, "Mon, 02 Jan 2026 15:04:05 GMT", "", true); err != nil {
+		t.Fatal(err)
+	}
+	opts.TimeoutSec = 60
+	if err := SaveFetchOptions(db, "sub-1", opts); err != nil {
+		t.Fatalf("SaveFetchOptions: %v", err)
+	}
+	if etag, _ := validators(); etag != `"v1"` {
+		t.Fatalf("a timeout change must keep the etag, got %q", etag)
+	}
+
+	opts.UserAgentPreset = "clash"
+	if err := SaveFetchOptions(db, "sub-1", opts); err != nil {
+		t.Fatalf("SaveFetchOptions: %v", err)
+	}
+	if etag, lastModified := validators(); etag != "" || lastModified != "" {
+		t.Fatalf("a User-Agent change must clear the validators, got %q / %q", etag, lastModified)
+	}
+}
+
+func TestFetchSubscriptionBody_RejectsOversizedBody(t *testing.T) {
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader(strings.Repeat("a", 2048)))}
+	_, err := fetchSubscriptionBody(resp, FetchOptions{MaxBodyBytes: 1024})
+	appErr, ok := err.(*errorx.AppError)
+	if !ok || appErr.Code != errorx.SUBResponseTooLarge {
+		t.Fatalf("expected %s, got %v", errorx.SUBResponseTooLarge, err)
+	}
+
+	resp = &http.Response{Body: io.NopCloser(strings.NewReader(strings.Repeat("a", 1024)))}
+	body, err := fetchSubscriptionBody(resp, FetchOptions{MaxBodyBytes: 1024})
+	if err != nil || len(body) != 1024 {
+		t.Fatalf("expected full body at the limit, got %d bytes, err %v", len(body), err)
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+)
+
+const (
+	defaultRefreshHistoryKeep    = 50
+	defaultRefreshHistoryMaxDays = 30
+)
+
+// NodeDiff lists the endpoints a replace-mode ingest added, removed or changed.
+// It is stored as JSON with each refresh history entry.
+type NodeDiff struct {
+	Added   []NodeDiffEntry `json:"added"`
+	Removed []NodeDiffEntry `json:"removed"`
+	Changed []NodeDiffEntry `json:"changed"`
+}
+
+// Empty reports whether the ingest left the subscription's nodes as they were.
+func (d NodeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// NodeDiffEntry describes one node; Changes names what differs from the stored
+// row ("tag", "name", "endpoint", "options") and is only set for changed nodes.
+type NodeDiffEntry struct {
+	Tag         string   `json:"tag"`
+	PreviousTag string   `json:"previous_tag,omitempty"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Server      string   `json:"server,omitempty"`
+	ServerPort  int      `json:"server_port,omitempty"`
+	Changes     []string `json:"changes,omitempty"`
+}
+
+// diffSubscriptionNodes compares the stored rows of a subscription with the
+// rows matchExistingNodes produced; updates carry the id of the row they
+// replace, so stored rows without a counterpart were removed.
+func diffSubscriptionNodes(existing, updates, inserts []repo.NodeRow) NodeDiff {
+	diff := NodeDiff{
+		Added:   make([]NodeDiffEntry, 0, len(inserts)),
+		Removed: []NodeDiffEntry{},
+		Changed: []NodeDiffEntry{},
+	}
+	for _, n := range inserts {
+		diff.Added = append(diff.Added, nodeDiffEntry(n))
+	}
+	byID := make(map[string]repo.NodeRow, len(existing))
+	for _, e := range existing {
+		byID[e.ID] = e
+	}
+	kept := make(map[string]struct{}, len(updates))
+	for _, n := range updates {
+		kept[n.ID] = struct{}{}
+		old, ok := byID[n.ID]
+		if !ok {
+			continue
+		}
+		var changes []string
+		if n.Tag != old.Tag {
+			changes = append(changes, "tag")
+		}
+		if n.Name != old.Name {
+			changes = append(changes, "name")
+		}
+		if old.Fingerprint != "" && n.Fingerprint != old.Fingerprint {
+			changes = append(changes, "endpoint")
+		} else if n.OutboundJSON != withOutboundTag(old.OutboundJSON, n.Tag) {
+			changes = append(changes, "options")
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		entry := nodeDiffEntry(n)
+		entry.Changes = changes
+		if n.Tag != old.Tag {
+			entry.PreviousTag = old.Tag
+		}
+		diff.Changed = append(diff.Changed, entry)
+	}
+	for _, e := range existing {
+		if _, ok := kept[e.ID]; !ok {
+			diff.Removed = append(diff.Removed, nodeDiffEntry(e))
+		}
+	}
+	return diff
+}
+
+// withOutboundTag rewrites the JSON tag so a renamed node is not also reported
+// as having changed options.
+func withOutboundTag(raw, tag string) string {
+	out, appErr := mergeOutboundJSONTag([]byte(raw), tag)
+	if appErr != nil {
+		return raw
+	}
+	return out
+}
+
+func nodeDiffEntry(n repo.NodeRow) NodeDiffEntry {
+	entry := NodeDiffEntry{Tag: n.Tag, Name: n.Name, Type: n.Type}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(n.OutboundJSON), &m); err == nil {
+		entry.Server = fingerprintString(m["server"])
+		entry.ServerPort, _ = strconv.Atoi(fingerprintString(m["server_port"]))
+	}
+	return entry
+}
+
+// refreshAttempt collects what RefreshSubscription learned before it returned.
+type refreshAttempt struct {
+	subID      string
+	startedAt  time.Time
+	httpStatus int
+	bytes      int
+	format     string
+	diff       *NodeDiff
+}
+
+// recordRefreshAttempt stores one history entry and applies the retention
+// limits. Failures are logged; history must never fail a refresh.
+func recordRefreshAttempt(ctx context.Context, db *sql.DB, attempt refreshAttempt, res RefreshResult, refreshErr error) {
+	if attempt.subID == "" {
+		return
+	}
+	row := repo.SubscriptionRefreshHistoryRow{
+		ID:           util.NewID(),
+		SubID:        attempt.subID,
+		StartedAt:    attempt.startedAt.UTC().Format(time.RFC3339),
+		DurationMs:   time.Since(attempt.startedAt).Milliseconds(),
+		HTTPStatus:   attempt.httpStatus,
+		Bytes:        attempt.bytes,
+		Format:       attempt.format,
+		NodesTotal:   res.NodesTotal,
+		NodesAdded:   res.Added,
+		NodesUpdated: res.Updated,
+		NodesRemoved: res.Removed,
+		DiffJSON:     "{}",
+	}
+	if refreshErr != nil {
+		row.ErrorCode, row.Error = refreshErrorSummary(refreshErr)
+	} else {
+		row.Success = 1
+		if res.NotModified {
+			row.NotModified = 1
+		}
+	}
+	if attempt.diff != nil {
+		if raw, err := json.Marshal(attempt.diff); err == nil {
+			row.DiffJSON = string(raw)
+		}
+	}
+	if err := repo.InsertSubscriptionRefreshHistory(db, row); err != nil {
+		refreshLog.ErrorContext(ctx, "insert refresh history failed", "sub_id", attempt.subID, "error", err)
+		return
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -refreshHistoryMaxDays()).Format(time.RFC3339)
+	if err := repo.PruneSubscriptionRefreshHistory(db, attempt.subID, refreshHistoryKeep(), cutoff); err != nil {
+		refreshLog.ErrorContext(ctx, "prune refresh history failed", "sub_id", attempt.subID, "error", err)
+	}
+}
+
+func refreshErrorSummary(err error) (string, string) {
+	var appErr *errorx.AppError
+	if !errors.As(err, &appErr) {
+		return errorx.SUBFetchFailed, err.Error()
+	}
+	msg := appErr.Message
+	if detail, ok := appErr.Details["err"].(string); ok && detail != "" {
+		msg += ": " + detail
+	}
+	return appErr.Code, msg
+}
+
+// refreshHistoryKeep is the number of attempts kept per subscription
+// (BOXPILOT_REFRESH_HISTORY_KEEP, default 50).
+func refreshHistoryKeep() int {
+	return positiveEnvInt("BOXPILOT_REFRESH_HISTORY_KEEP", defaultRefreshHistoryKeep)
+}
+
+// refreshHistoryMaxDays drops older attempts regardless of count
+// (BOXPILOT_REFRESH_HISTORY_MAX_DAYS, default 30).
+func refreshHistoryMaxDays() int {
+	return positiveEnvInt("BOXPILOT_REFRESH_HISTORY_MAX_DAYS", defaultRefreshHistoryMaxDays)
+}
+
+func positiveEnvInt(name string, fallback int) int {
+	if raw := strings.TrimSpace(os.Getenv(name)); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+)
+
+func historyNode(id, tag, raw string) repo.NodeRow {
+	out, appErr := mergeOutboundJSONTag([]byte(raw), tag)
+	if appErr != nil {
+		panic(appErr)
+	}
+	var m map[string]any
+	_ = json.Unmarshal([]byte(raw), &m)
+	typ, _ := m["type"].(string)
+	return repo.NodeRow{ID: id, Tag: tag, Name: tag, Type: typ, OutboundJSON: out, Fingerprint: NodeFingerprint(typ, json.RawMessage(raw))}
+}
+
+func TestDiffSubscriptionNodes(t *testing.T) {
+	existing := []repo.NodeRow{
+		historyNode("1", "hk", `{"type":"trojan","server":"hk.example","server_port":443,"password":"p"}`),
+		historyNode("2", "jp", `{"type":"trojan","server":"jp.example","server_port":443,"password":"p"}`),
+		historyNode("3", "sg", `{"type":"trojan","server":"sg.example","server_port":443,"password":"p"}`),
+		historyNode("4", "us", `{"type":"trojan","server":"us.example","server_port":443,"password":"p"}`),
+	}
+	incoming := []repo.NodeRow{
+		// renamed, same endpoint
+		historyNode("n1", "hk-01", `{"type":"trojan","server":"hk.example","server_port":443,"password":"p"}`),
+		// same tag, rotated server
+		historyNode("n2", "jp", `{"type":"trojan","server":"jp2.example","server_port":443,"password":"p"}`),
+		// unchanged
+		historyNode("n3", "sg", `{"type":"trojan","server":"sg.example","server_port":443,"password":"p"}`),
+		historyNode("n5", "tw", `{"type":"trojan","server":"tw.example","server_port":8443,"password":"p"}`),
+	}
+	updates, inserts := matchExistingNodes(incoming, existing)
+	diff := diffSubscriptionNodes(existing, updates, inserts)
+
+	if len(diff.Added) != 1 || diff.Added[0].Tag != "tw" || diff.Added[0].Server != "tw.example" || diff.Added[0].ServerPort != 8443 {
+		t.Fatalf("added: %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Tag != "us" {
+		t.Fatalf("removed: %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 2 {
+		t.Fatalf("changed: %+v", diff.Changed)
+	}
+	if c := diff.Changed[0]; c.Tag != "hk-01" || c.PreviousTag != "hk" || !reflect.DeepEqual(c.Changes, []string{"tag", "name"}) {
+		t.Fatalf("renamed node: %+v", c)
+	}
+	if c := diff.Changed[1]; c.Tag != "jp" || c.Server != "jp2.example" || !reflect.DeepEqual(c.Changes, []string{"endpoint"}) {
+		t.Fatalf("rotated node: %+v", c)
+	}
+}
+
+func TestRefreshErrorSummary(t *testing.T) {
+	code, msg := refreshErrorSummary(errorx.New(errorx.SUBFetchTimeout, "fetch failed").WithDetails(map[string]any{"err": "i/o timeout"}))
+	if code != errorx.SUBFetchTimeout || msg != "fetch failed: i/o timeout" {
+		t.Fatalf("got %q %q", code, msg)
+	}
+	code, _ = refreshErrorSummary(errorx.New(errorx.SUBHTTPStatusError, "bad status"))
+	if code != errorx.SUBHTTPStatusError {
+		t.Fatalf("got %q", code)
+	}
+}
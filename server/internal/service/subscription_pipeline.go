@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"boxpilot/server/internal/parser"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+)
+
+// lastBodyFile is the raw body of the latest successful fetch, kept next to the
+// provider cache so pipeline changes can be previewed without refetching.
+const lastBodyFile = "subscription.body"
+
+// SubscriptionPipeline filters and renames subscription nodes before they are
+// ingested. Steps run in order: type allowlist, include / exclude regex on the
+// provider name, rename rules, name template, emoji flag prefix.
+type SubscriptionPipeline struct {
+	IncludeRegex  string
+	ExcludeRegex  string
+	TypeAllowlist []string
+	RenameRules   []RenameRule
+	EmojiFlag     bool
+	// NameTemplate supports {name}, {flag}, {region}, {type} and {index}.
+	NameTemplate string
+	UpdatedAt    string
+}
+
+// RenameRule replaces Pattern matches in the node name; Replace may reference
+// capture groups as $1 or ${name}.
+type RenameRule struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// PipelineEntry reports what the pipeline did with one parsed node.
+type PipelineEntry struct {
+	SourceName string
+	Name       string
+	Type       string
+	Dropped    bool
+	Reason     string
+}
+
+type compiledPipeline struct {
+	include  *regexp.Regexp
+	exclude  *regexp.Regexp
+	types    map[string]struct{}
+	renames  []compiledRename
+	emoji    bool
+	template string
+}
+
+type compiledRename struct {
+	re      *regexp.Regexp
+	replace string
+}
+
+// IsZero reports whether the pipeline leaves nodes untouched.
+func (p SubscriptionPipeline) IsZero() bool {
+	return p.IncludeRegex == "" && p.ExcludeRegex == "" && len(p.TypeAllowlist) == 0 &&
+		len(p.RenameRules) == 0 && !p.EmojiFlag && p.NameTemplate == ""
+}
+
+// LoadSubscriptionPipeline returns the stored pipeline, or an empty one.
+func LoadSubscriptionPipeline(db *sql.DB, subID string) (SubscriptionPipeline, error) {
+	row, err := repo.GetSubscriptionPipeline(db, subID)
+	if err != nil || row == nil {
+		return SubscriptionPipeline{TypeAllowlist: []string{}, RenameRules: []RenameRule{}}, err
+	}
+	p := SubscriptionPipeline{
+		IncludeRegex:  row.IncludeRegex,
+		ExcludeRegex:  row.ExcludeRegex,
+		TypeAllowlist: []string{},
+		RenameRules:   []RenameRule{},
+		EmojiFlag:     row.EmojiFlag == 1,
+		NameTemplate:  row.NameTemplate,
+		UpdatedAt:     row.UpdatedAt,
+	}
+	_ = json.Unmarshal([]byte(row.TypeAllowlistJSON), &p.TypeAllowlist)
+	_ = json.Unmarshal([]byte(row.RenameRulesJSON), &p.RenameRules)
+	return p, nil
+}
+
+// NormalizeSubscriptionPipeline trims the pipeline and checks that every
+// regular expression compiles.
+func NormalizeSubscriptionPipeline(p SubscriptionPipeline) (SubscriptionPipeline, *errorx.AppError) {
+	p.IncludeRegex = strings.TrimSpace(p.IncludeRegex)
+	p.ExcludeRegex = strings.TrimSpace(p.ExcludeRegex)
+	p.NameTemplate = strings.TrimSpace(p.NameTemplate)
+	types := make([]string, 0, len(p.TypeAllowlist))
+	seen := map[string]struct{}{}
+	for _, t := range p.TypeAllowlist {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if _, dup := seen[t]; dup {
+			continue
+		}
+		seen[t] = struct{}{}
+		types = append(types, t)
+	}
+	p.TypeAllowlist = types
+	rules := make([]RenameRule, 0, len(p.RenameRules))
+	for _, r := range p.RenameRules {
+		if strings.TrimSpace(r.Pattern) == "" {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	p.RenameRules = rules
+	if _, appErr := compileSubscriptionPipeline(p); appErr != nil {
+		return p, appErr
+	}
+	return p, nil
+}
+
+// SaveSubscriptionPipeline stores p and clears the subscription's etag and
+// Last-Modified, so the next refresh re-ingests the body through the new
+// pipeline even when upstream answers 304 or the file is unchanged.
+func SaveSubscriptionPipeline(db *sql.DB, subID string, p SubscriptionPipeline) (SubscriptionPipeline, error) {
+	types, err := json.Marshal(p.TypeAllowlist)
+	if err != nil {
+		return p, err
+	}
+	rules, err := json.Marshal(p.RenameRules)
+	if err != nil {
+		return p, err
+	}
+	emoji := 0
+	if p.EmojiFlag {
+		emoji = 1
+	}
+	p.UpdatedAt = util.NowRFC3339()
+	if err := repo.UpsertSubscriptionPipeline(db, repo.SubscriptionPipelineRow{
+		SubID:             subID,
+		IncludeRegex:      p.IncludeRegex,
+		ExcludeRegex:      p.ExcludeRegex,
+		TypeAllowlistJSON: string(types),
+		RenameRulesJSON:   string(rules),
+		EmojiFlag:         emoji,
+		NameTemplate:      p.NameTemplate,
+		UpdatedAt:         p.UpdatedAt,
+	}); err != nil {
+		return p, err
+	}
+	return p, repo.ClearSubscriptionValidators(db, subID)
+}
+
+func compileSubscriptionPipeline(p SubscriptionPipeline) (*compiledPipeline, *errorx.AppError) {
+	c := &compiledPipeline{emoji: p.EmojiFlag, template: p.NameTemplate}
+	var err error
+	if p.IncludeRegex != "" {
+		if c.include, err = regexp.Compile(p.IncludeRegex); err != nil {
+			return nil, pipelineRegexError("include_regex", p.IncludeRegex, err)
+		}
+	}
+	if p.ExcludeRegex != "" {
+		if c.exclude, err = regexp.Compile(p.ExcludeRegex); err != nil {
+			return nil, pipelineRegexError("exclude_regex", p.ExcludeRegex, err)
+		}
+	}
+	if len(p.TypeAllowlist) > 0 {
+		c.types = make(map[string]struct{}, len(p.TypeAllowlist))
+		for _, t := range p.TypeAllowlist {
+			c.types[t] = struct{}{}
+		}
+	}
+	for i, r := range p.RenameRules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, pipelineRegexError(fmt.Sprintf("rename_rules[%d].pattern", i), r.Pattern, err)
+		}
+		c.renames = append(c.renames, compiledRename{re: re, replace: r.Replace})
+	}
+	return c, nil
+}
+
+func pipelineRegexError(field, expr string, err error) *errorx.AppError {
+	return errorx.New(errorx.SUBPipelineInvalid, "invalid regular expression").WithDetails(map[string]any{
+		"field": field,
+		"regex": expr,
+		"err":   err.Error(),
+	})
+}
+
+// ApplySubscriptionPipeline filters and renames nodes. Outbounds that a kept node
+// uses as detour (e.g. the shadowtls leg of a shadowsocks chain) are always kept
+// under their source name. The returned entries follow the input order.
+func ApplySubscriptionPipeline(nodes []IngestNode, p SubscriptionPipeline) ([]IngestNode, []PipelineEntry, *errorx.AppError) {
+	c, appErr := compileSubscriptionPipeline(p)
+	if appErr != nil {
+		return nil, nil, appErr
+	}
+	entries := make([]PipelineEntry, len(nodes))
+	kept := make([]bool, len(nodes))
+	for i, n := range nodes {
+		name := strings.TrimSpace(n.PreferredName)
+		entries[i] = PipelineEntry{SourceName: name, Name: name, Type: n.Type}
+		switch {
+		case c.types != nil && !hasType(c.types, n.Type):
+			entries[i].Reason = "type not allowed"
+		case c.include != nil && !c.include.MatchString(name):
+			entries[i].Reason = "not matched by include_regex"
+		case c.exclude != nil && c.exclude.MatchString(name):
+			entries[i].Reason = "matched by exclude_regex"
+		default:
+			kept[i] = true
+		}
+	}
+
+	detours := map[string]struct{}{}
+	for i, n := range nodes {
+		if kept[i] {
+			if d := outboundDetour(n.Raw); d != "" {
+				detours[d] = struct{}{}
+			}
+		}
+	}
+
+	out := make([]IngestNode, 0, len(nodes))
+	used := map[string]int{}
+	index := 0
+	for i, n := range nodes {
+		_, isDetour := detours[strings.TrimSpace(n.SourceTag)]
+		if !kept[i] && !isDetour {
+			entries[i].Dropped = true
+			continue
+		}
+		entries[i].Reason = ""
+		if isDetour && !kept[i] {
+			entries[i].Reason = "kept as detour of another node"
+			out = append(out, n)
+			continue
+		}
+		index++
+		name := c.rename(entries[i].SourceName, n.Type, index)
+		if count := used[name]; count > 0 {
+			used[name] = count + 1
+			name = name + " " + strconv.Itoa(count+1)
+		} else {
+			used[name] = 1
+		}
+		entries[i].Name = name
+		n.PreferredName = name
+		n.PreferredTag = name
+		out = append(out, n)
+	}
+	return out, entries, nil
+}
+
+func (c *compiledPipeline) rename(source, typ string, index int) string {
+	name := source
+	for _, r := range c.renames {
+		name = r.re.ReplaceAllString(name, r.replace)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = source
+	}
+	code := detectRegion(name)
+	if code == "" {
+		code = detectRegion(source)
+	}
+	if c.template != "" {
+		name = strings.NewReplacer(
+			"{name}", name,
+			"{flag}", flagEmoji(code),
+			"{region}", code,
+			"{type}", typ,
+			"{index}", strconv.Itoa(index),
+		).Replace(c.template)
+		name = strings.Join(strings.Fields(name), " ")
+	}
+	if c.emoji && code != "" && !startsWithFlag(name) {
+		name = flagEmoji(code) + " " + name
+	}
+	if name == "" {
+		return source
+	}
+	return name
+}
+
+func hasType(types map[string]struct{}, typ string) bool {
+	_, ok := types[strings.ToLower(strings.TrimSpace(typ))]
+	return ok
+}
+
+func outboundDetour(raw json.RawMessage) string {
+	var payload struct {
+		Detour string `json:"detour"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(payload.Detour)
+}
+
+// regionCode builds a pattern for an upper-case region code that is not part of
+// a longer word, so "HK01" matches but "CHKS" and "12GB" do not.
+func regionCode(codes string) string {
+	return `(?:^|[^A-Za-z0-9])(?:` + codes + `)(?:[^A-Za-z]|$)`
+}
+
+// pipelineRegions is checked against node names; the earliest match wins so
+// "香港-日本 IPLC" resolves to HK.
+var pipelineRegions = []struct {
+	code    string
+	pattern *regexp.Regexp
+}{
+	{"HK", regexp.MustCompile(`香港|(?i:hong\s*kong)|` + regionCode(`HKG?`))},
+	{"TW", regexp.MustCompile(`台湾|臺灣|(?i:taiwan)|` + regionCode(`TWN?`))},
+	{"MO", regexp.MustCompile(`澳门|澳門|(?i:macao|macau)|` + regionCode(`MO`))},
+	{"JP", regexp.MustCompile(`日本|东京|東京|大阪|(?i:japan|tokyo|osaka)|` + regionCode(`JPN?`))},
+	{"KR", regexp.MustCompile(`韩国|韓國|首尔|首爾|(?i:korea|seoul)|` + regionCode(`KOR?|KR`))},
+	{"SG", regexp.MustCompile(`新加坡|狮城|獅城|(?i:singapore)|` + regionCode(`SGP?`))},
+	{"US", regexp.MustCompile(`美国|美國|洛杉矶|硅谷|西雅图|纽约|(?i:united\s*states|america|los\s*angeles|seattle|new\s*york|silicon\s*valley)|` + regionCode(`USA?`))},
+	{"GB", regexp.MustCompile(`英国|英國|伦敦|(?i:united\s*kingdom|britain|london)|` + regionCode(`UK|GBR`))},
+	{"DE", regexp.MustCompile(`德国|德國|法兰克福|(?i:germany|frankfurt)|` + regionCode(`DEU?`))},
+	{"FR", regexp.MustCompile(`法国|法國|巴黎|(?i:france|paris)|` + regionCode(`FRA?`))},
+	{"NL", regexp.MustCompile(`荷兰|荷蘭|阿姆斯特丹|(?i:netherlands|amsterdam)|` + regionCode(`NLD?`))},
+	{"CA", regexp.MustCompile(`加拿大|(?i:canada)|` + regionCode(`CAN?`))},
+	{"AU", regexp.MustCompile(`澳大利亚|澳洲|悉尼|(?i:australia|sydney)|` + regionCode(`AUS?`))},
+	{"RU", regexp.MustCompile(`俄罗斯|俄羅斯|莫斯科|(?i:russia|moscow)|` + regionCode(`RUS?`))},
+	{"IN", regexp.MustCompile(`印度(?:[^尼]|$)|(?i:india)|` + regionCode(`IND?`))},
+	{"TR", regexp.MustCompile(`土耳其|(?i:turkey|türkiye)|` + regionCode(`TUR?|TR`))},
+	{"MY", regexp.MustCompile(`马来西亚|馬來西亞|(?i:malaysia)|` + regionCode(`MYS?`))},
+	{"TH", regexp.MustCompile(`泰国|泰國|(?i:thailand)|` + regionCode(`THA?`))},
+	{"VN", regexp.MustCompile(`越南|(?i:vietnam)|` + regionCode(`VNM?`))},
+	{"PH", regexp.MustCompile(`菲律宾|菲律賓|(?i:philippines)|` + regionCode(`PHL?`))},
+	{"ID", regexp.MustCompile(`印尼|印度尼西亚|(?i:indonesia)|` + regionCode(`IDN`))},
+	{"AR", regexp.MustCompile(`阿根廷|(?i:argentina)|` + regionCode(`ARG?`))},
+	{"BR", regexp.MustCompile(`巴西|(?i:brazil)|` + regionCode(`BRA?`))},
+}
+
+// detectRegion returns the ISO 3166 code of the first region named in s.
+func detectRegion(s string) string {
+	if code := flagRegion(s); code != "" {
+		return code
+	}
+	best, bestAt := "", -1
+	for _, r := range pipelineRegions {
+		loc := r.pattern.FindStringIndex(s)
+		if loc != nil && (bestAt < 0 || loc[0] < bestAt) {
+			best, bestAt = r.code, loc[0]
+		}
+	}
+	return best
+}
+
+// flagEmoji turns a two-letter region code into its regional-indicator flag.
+func flagEmoji(code string) string {
+	if len(code) != 2 {
+		return ""
+	}
+	code = strings.ToUpper(code)
+	return string([]rune{0x1F1E6 + rune(code[0]-'A'), 0x1F1E6 + rune(code[1]-'A')})
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func startsWithFlag(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return isRegionalIndicator(r)
+}
+
+// flagRegion reads the region back from a leading flag emoji.
+func flagRegion(s string) string {
+	s = strings.TrimSpace(s)
+	a, size := utf8.DecodeRuneInString(s)
+	b, _ := utf8.DecodeRuneInString(s[size:])
+	if !isRegionalIndicator(a) || !isRegionalIndicator(b) {
+		return ""
+	}
+	return string([]rune{'A' + (a - 0x1F1E6), 'A' + (b - 0x1F1E6)})
+}
+
+// saveLastSubscriptionBody keeps the fetched body for pipeline previews.
+func saveLastSubscriptionBody(configPath, subID string, body []byte) error {
+	return util.AtomicWrite(SubscriptionProviderDir(configPath, subID), lastBodyFile, body)
+}
+
+// PipelinePreview is the dry-run result of a pipeline against the last fetched body.
+type PipelinePreview struct {
+	Format  string
+	Entries []PipelineEntry
+	Kept    int
+	Dropped int
+}
+
+// PreviewSubscriptionPipeline runs p against the last fetched body of a
+// subscription without writing nodes. Clash proxy-providers are resolved from
+// the local cache only; rule-providers are left unconverted so the preview does
+// not rewrite the rule sets the running config points at.
+func PreviewSubscriptionPipeline(configPath, subID string, p SubscriptionPipeline) (PipelinePreview, *errorx.AppError) {
+	body, err := os.ReadFile(filepath.Join(SubscriptionProviderDir(configPath, subID), lastBodyFile))
+	if err != nil {
+		return PipelinePreview{}, errorx.New(errorx.SUBNoCachedBody, "subscription has no fetched body yet; refresh it first").WithDetails(map[string]any{"id": subID})
+	}
+	parsed, err := parser.ParseSubscriptionBundle(body)
+	if err != nil {
+		return PipelinePreview{}, errorx.New(errorx.SUBParseFailed, "parse cached subscription body").WithDetails(map[string]any{"id": subID, "err": err.Error()})
+	}
+	parsed = resolveClashProxyProviders(context.Background(), SubscriptionProviderDir(configPath, subID), body, parsed, offlineProviderFetcher)
+	nodes := BuildIngestNodesFromOutbounds(parsed.Outbounds, "")
+	_, entries, appErr := ApplySubscriptionPipeline(nodes, p)
+	if appErr != nil {
+		return PipelinePreview{}, appErr
+	}
+	preview := PipelinePreview{Format: parsed.Format, Entries: entries}
+	for _, e := range entries {
+		if e.Dropped {
+			preview.Dropped++
+		} else {
+			preview.Kept++
+		}
+	}
+	return preview, nil
+}
+
+func offlineProviderFetcher(context.Context, string) ([]byte, error) {
+	return nil, errors.New("preview uses cached providers only")
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+)
+
+func pipelineNode(name, typ, raw string) IngestNode {
+	return IngestNode{SourceTag: name, PreferredTag: name, PreferredName: name, Type: typ, Raw: json.RawMessage(raw)}
+}
+
+func TestApplySubscriptionPipeline(t *testing.T) {
+	nodes := []IngestNode{
+		pipelineNode("剩余流量：12GB", "shadowsocks", `{"type":"shadowsocks"}`),
+		pipelineNode("官网地址", "shadowsocks", `{"type":"shadowsocks"}`),
+		pipelineNode("香港 IPLC 01", "vmess", `{"type":"vmess"}`),
+		pipelineNode("香港 IPLC 02", "vmess", `{"type":"vmess"}`),
+		pipelineNode("Japan Tokyo 01", "trojan", `{"type":"trojan"}`),
+		pipelineNode("SG 01", "shadowsocks", `{"type":"shadowsocks","detour":"SG 01-shadowtls"}`),
+		pipelineNode("SG 01-shadowtls", "shadowtls", `{"type":"shadowtls"}`),
+		pipelineNode("US ssh", "ssh", `{"type":"ssh"}`),
+	}
+	p, appErr := NormalizeSubscriptionPipeline(SubscriptionPipeline{
+		ExcludeRegex:  `剩余流量|官网`,
+		TypeAllowlist: []string{" VMess ", "trojan", "shadowsocks"},
+		RenameRules:   []RenameRule{{Pattern: `IPLC (\d+)`, Replace: "$1"}, {Pattern: `\s*0?1$`, Replace: ""}},
+		EmojiFlag:     true,
+		NameTemplate:  "{name} [{type}]",
+	})
+	if appErr != nil {
+		t.Fatalf("NormalizeSubscriptionPipeline: %v", appErr)
+	}
+	out, entries, appErr := ApplySubscriptionPipeline(nodes, p)
+	if appErr != nil {
+		t.Fatalf("ApplySubscriptionPipeline: %v", appErr)
+	}
+	got := map[string]string{}
+	for _, n := range out {
+		got[n.SourceTag] = n.PreferredTag
+	}
+	want := map[string]string{
+		"香港 IPLC 01":      "🇭🇰 香港 [vmess]",
+		"香港 IPLC 02":      "🇭🇰 香港 02 [vmess]",
+		"Japan Tokyo 01":  "🇯🇵 Japan Tokyo [trojan]",
+		"SG 01":           "🇸🇬 SG [shadowsocks]",
+		"SG 01-shadowtls": "SG 01-shadowtls",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected kept nodes: %#v", got)
+	}
+	for src, name := range want {
+		if got[src] != name {
+			t.Fatalf("%s renamed to %q, want %q (all: %#v)", src, got[src], name, got)
+		}
+	}
+	reasons := map[string]string{}
+	for _, e := range entries {
+		if e.Dropped {
+			reasons[e.SourceName] = e.Reason
+		}
+	}
+	if reasons["剩余流量：12GB"] != "matched by exclude_regex" || reasons["US ssh"] != "type not allowed" || len(reasons) != 3 {
+		t.Fatalf("unexpected drops: %#v", reasons)
+	}
+}
+
+func TestApplySubscriptionPipeline_DeduplicatesNames(t *testing.T) {
+	nodes := []IngestNode{
+		pipelineNode("HK 01", "vmess", `{}`),
+		pipelineNode("HK 02", "vmess", `{}`),
+	}
+	out, _, appErr := ApplySubscriptionPipeline(nodes, SubscriptionPipeline{NameTemplate: "{flag} {region}"})
+	if appErr != nil {
+		t.Fatalf("ApplySubscriptionPipeline: %v", appErr)
+	}
+	if out[0].PreferredTag != "🇭🇰 HK" || out[1].PreferredTag != "🇭🇰 HK 2" {
+		t.Fatalf("unexpected names: %q, %q", out[0].PreferredTag, out[1].PreferredTag)
+	}
+}
+
+func TestNormalizeSubscriptionPipeline_InvalidRegex(t *testing.T) {
+	_, appErr := NormalizeSubscriptionPipeline(SubscriptionPipeline{RenameRules: []RenameRule{{Pattern: "(", Replace: ""}}})
+	if appErr == nil || appErr.Code != errorx.SUBPipelineInvalid || appErr.Details["field"] != "rename_rules[0].pattern" {
+		t.Fatalf("expected %s for rename rule, got %+v", errorx.SUBPipelineInvalid, appErr)
+	}
+}
+
+func TestDetectRegion(t *testing.T) {
+	cases := map[string]string{
+		"香港-日本 IPLC":       "HK",
+		"🇯🇵 东京 01":         "JP",
+		"HK01 | 1x":        "HK",
+		"CHKS relay":       "",
+		"印度尼西亚 01":         "ID",
+		"印度 孟买":            "IN",
+		"美国 洛杉矶 GIA":       "US",
+		"United Kingdom 1": "GB",
+		"剩余流量：12GB":        "",
+	}
+	for name, want := range cases {
+		if got := detectRegion(name); got != want {
+			t.Fatalf("detectRegion(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPreviewSubscriptionPipeline(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "sing-box.json")
+	if _, appErr := PreviewSubscriptionPipeline(configPath, "sub1", SubscriptionPipeline{}); appErr == nil || appErr.Code != errorx.SUBNoCachedBody {
+		t.Fatalf("expected %s without a fetched body, got %+v", errorx.SUBNoCachedBody, appErr)
+	}
+	body := []byte("proxies:\n  - {name: \"HK 01\", type: ss, server: a.example.com, port: 8388, cipher: aes-128-gcm, password: p}\n  - {name: \"官网\", type: ss, server: b.example.com, port: 8388, cipher: aes-128-gcm, password: p}\n")
+	if err := saveLastSubscriptionBody(configPath, "sub1", body); err != nil {
+		t.Fatalf("save body: %v", err)
+	}
+	preview, appErr := PreviewSubscriptionPipeline(configPath, "sub1", SubscriptionPipeline{ExcludeRegex: "官网", EmojiFlag: true})
+	if appErr != nil {
+		t.Fatalf("PreviewSubscriptionPipeline: %v", appErr)
+	}
+	if preview.Kept != 1 || preview.Dropped != 1 || preview.Entries[0].Name != "🇭🇰 HK 01" {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if _, err := os.Stat(filepath.Join(SubscriptionProviderDir(configPath, "sub1"), lastBodyFile)); err != nil {
+		t.Fatalf("expected cached body: %v", err)
+	}
+}
+
+func TestPreviewSubscriptionPipeline_LeavesRuleSetsAlone(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "sing-box.json")
+	body := []byte(clashProviderSubscription)
+	dir := SubscriptionProviderDir(configPath, "sub1")
+	if err := util.AtomicWrite(dir, "proxy-"+providerFileKey("airport")+".cache", []byte("proxies:\n  - {name: us-1, type: trojan, server: us.example.com, port: 443, password: pw}\n")); err != nil {
+		t.Fatalf("seed provider cache: %v", err)
+	}
+	if err := util.AtomicWrite(dir, "rule-"+providerFileKey("openai")+".cache", []byte("DOMAIN-SUFFIX,example.org\n")); err != nil {
+		t.Fatalf("seed rule cache: %v", err)
+	}
+	if err := saveLastSubscriptionBody(configPath, "sub1", body); err != nil {
+		t.Fatalf("save body: %v", err)
+	}
+
+	preview, appErr := PreviewSubscriptionPipeline(configPath, "sub1", SubscriptionPipeline{})
+	if appErr != nil {
+		t.Fatalf("PreviewSubscriptionPipeline: %v", appErr)
+	}
+	if preview.Kept != 2 {
+		t.Fatalf("expected inline and cached provider nodes, got %+v", preview)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "rule-"+providerFileKey("openai")+".json")); !os.IsNotExist(err) {
+		t.Fatalf("expected preview not to write a rule set, got %v", err)
+	}
+}
@@ -1,11 +1,13 @@
 package service
 
 import (
+	"context"
 	"database/sql"
-	"io"
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"boxpilot/server/internal/parser"
@@ -14,49 +16,218 @@ import (
 	"boxpilot/server/internal/util/errorx"
 )
 
-// RefreshSubscription fetches one subscription URL, parses, and replaces nodes.
-func RefreshSubscription(db *sql.DB, subID string) (notModified bool, nodesTotal, nodesEnabled int, err error) {
-	row, err := repo.GetSubscription(db, subID)
-	if err != nil || row == nil {
-		return false, 0, 0, errorx.New(errorx.SUBNotFound, "subscription not found").WithDetails(map[string]any{"id": subID})
+// RefreshResult summarizes one subscription refresh. Added, Updated and Removed
+// count nodes inserted, matched in place and changed, and deleted; Diff lists
+// them and is nil when the source was not modified.
+type RefreshResult struct {
+	NotModified  bool
+	NodesTotal   int
+	NodesEnabled int
+	Added        int
+	Updated      int
+	Removed      int
+	Diff         *NodeDiff
+}
+
+var (
+	refreshInFlightMu sync.Mutex
+	refreshInFlight   = map[string]struct{}{}
+	// refreshWriteMu serializes the node and routing replacement of concurrent
+	// refreshes; fetching and parsing still run in parallel.
+	refreshWriteMu sync.Mutex
+)
+
+// RefreshSubscription fetches or reads one subscription, parses, and replaces nodes.
+// Every attempt on an existing subscription is recorded in its refresh history.
+// A subscription that is already being refreshed is rejected with
+// JOB_REFRESH_IN_PROGRESS. ctx carries the request id for logs; a started
+// refresh runs to completion even if ctx is cancelled.
+func RefreshSubscription(ctx context.Context, db *sql.DB, subID string) (RefreshResult, error) {
+	ctx = context.WithoutCancel(ctx)
+	if !beginSubscriptionRefresh(subID) {
+		return RefreshResult{}, errorx.New(errorx.JOBRefreshInProgress, "subscription refresh already in progress").WithDetails(map[string]any{"id": subID})
 	}
-	req, _ := http.NewRequest(http.MethodGet, row.URL, nil)
-	if row.Etag != "" {
-		req.Header.Set("If-None-Match", row.Etag)
+	defer endSubscriptionRefresh(subID)
+	attempt := refreshAttempt{startedAt: time.Now()}
+	res, err := refreshSubscription(ctx, db, subID, &attempt)
+	recordRefreshAttempt(ctx, db, attempt, res, err)
+	recordRefreshMetrics(subID, attempt.startedAt, res, err)
+	durationMs := time.Since(attempt.startedAt).Milliseconds()
+	if err != nil {
+		code, msg := refreshErrorSummary(err)
+		refreshLog.WarnContext(ctx, "subscription refresh failed", "sub_id", subID, "code", code, "error", msg, "duration_ms", durationMs)
+		PublishEvent(EventSubscriptionRefreshFailed, map[string]any{"sub_id": subID, "code": code, "error": msg})
+	} else {
+		refreshLog.InfoContext(ctx, "subscription refreshed", "sub_id", subID, "not_modified", res.NotModified,
+			"nodes_total", res.NodesTotal, "added", res.Added, "updated", res.Updated, "removed", res.Removed, "duration_ms", durationMs)
+		publishRefreshEvents(subID, res)
 	}
-	if row.LastModified != "" {
-		req.Header.Set("If-Modified-Since", row.LastModified)
+	return res, err
+}
+
+func reportRefreshStage(subID, stage string) {
+	PublishEvent(EventSubscriptionRefreshProgress, map[string]any{"sub_id": subID, "stage": stage})
+}
+
+func publishRefreshEvents(subID string, res RefreshResult) {
+	PublishEvent(EventSubscriptionRefreshed, map[string]any{
+		"sub_id":        subID,
+		"not_modified":  res.NotModified,
+		"nodes_total":   res.NodesTotal,
+		"nodes_added":   res.Added,
+		"nodes_updated": res.Updated,
+		"nodes_removed": res.Removed,
+	})
+	if res.Diff != nil && !res.Diff.Empty() {
+		PublishEvent(EventNodesChanged, map[string]any{
+			"source":        "refresh",
+			"sub_id":        subID,
+			"nodes_added":   res.Added,
+			"nodes_updated": res.Updated,
+			"nodes_removed": res.Removed,
+		})
 	}
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		repo.SetSubscriptionFetchResult(db, row.ID, row.Etag, row.LastModified, err.Error(), false)
-		return false, 0, 0, errorx.New(errorx.SUBFetchFailed, "fetch failed").WithDetails(map[string]any{"id": subID, "err": err.Error()})
+}
+
+func beginSubscriptionRefresh(subID string) bool {
+	refreshInFlightMu.Lock()
+	defer refreshInFlightMu.Unlock()
+	if _, busy := refreshInFlight[subID]; busy {
+		return false
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusNotModified {
-		repo.SetSubscriptionFetchResult(db, row.ID, row.Etag, row.LastModified, "", false)
-		return true, 0, 0, nil
+	refreshInFlight[subID] = struct{}{}
+	return true
+}
+
+func endSubscriptionRefresh(subID string) {
+	refreshInFlightMu.Lock()
+	delete(refreshInFlight, subID)
+	refreshInFlightMu.Unlock()
+}
+
+func isSubscriptionRefreshing(subID string) bool {
+	refreshInFlightMu.Lock()
+	defer refreshInFlightMu.Unlock()
+	_, busy := refreshInFlight[subID]
+	return busy
+}
+
+func refreshSubscription(ctx context.Context, db *sql.DB, subID string, attempt *refreshAttempt) (_ RefreshResult, refreshErr error) {
+	row, err := repo.GetSubscription(db, subID)
+	if err != nil || row == nil {
+		return RefreshResult{}, errorx.New(errorx.SUBNotFound, "subscription not found").WithDetails(map[string]any{"id": subID})
 	}
-	if resp.StatusCode != http.StatusOK {
-		repo.SetSubscriptionFetchResult(db, row.ID, row.Etag, row.LastModified, resp.Status, false)
-		return false, 0, 0, errorx.New(errorx.SUBHTTPStatusError, "bad status").WithDetails(map[string]any{"id": subID, "status": resp.StatusCode})
+	attempt.subID = row.ID
+	// Every failure keeps the last good etag and bumps the failure streak, so
+	// the scheduler backs off and the subscription is flagged as failing.
+	defer func() {
+		if refreshErr != nil {
+			_, msg := refreshErrorSummary(refreshErr)
+			repo.SetSubscriptionFetchResult(db, row.ID, row.Etag, row.LastModified, msg, false)
+		}
+	}()
+	reportRefreshStage(row.ID, "fetch")
+	opts, err := LoadFetchOptions(db, row.ID)
+	if err != nil {
+		return RefreshResult{}, errorx.New(errorx.DBError, "load subscription fetch options").WithDetails(map[string]any{"id": subID})
 	}
-	maxSize := 5 * 1024 * 1024
-	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)))
+	client, err := buildFetchClient(db, opts)
 	if err != nil {
-		return false, 0, 0, err
+		return RefreshResult{}, errorx.New(errorx.SUBFetchFailed, "build fetch client").WithDetails(map[string]any{"id": subID, "fetch_via": opts.FetchVia, "err": err.Error()})
+	}
+	// File and inline subscriptions are read locally; their etag is a content
+	// hash, so an unchanged body is reported as not modified like a 304.
+	var body []byte
+	var etag, lastMod string
+	var header http.Header
+	if IsLocalSubscription(row.SourceType) {
+		local, err := readLocalSubscription(db, *row, opts)
+		if err != nil {
+			return RefreshResult{}, err
+		}
+		if local.Etag == row.Etag {
+			repo.SetSubscriptionFetchResult(db, row.ID, row.Etag, local.LastModified, "", false)
+			return RefreshResult{NotModified: true}, nil
+		}
+		body, etag, lastMod = local.Body, local.Etag, local.LastModified
+		// Failures below keep the old etag but store the new mtime, so the
+		// scheduler does not re-read a broken file until it changes again.
+		row.LastModified = local.LastModified
+	} else {
+		req, _ := http.NewRequest(http.MethodGet, row.URL, nil)
+		opts.applyHeaders(req)
+		if row.Etag != "" {
+			req.Header.Set("If-None-Match", row.Etag)
+		}
+		if row.LastModified != "" {
+			req.Header.Set("If-Modified-Since", row.LastModified)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return RefreshResult{}, errorx.New(fetchErrorCode(err), "fetch failed").WithDetails(map[string]any{"id": subID, "err": err.Error()})
+		}
+		defer resp.Body.Close()
+		attempt.httpStatus = resp.StatusCode
+		if resp.StatusCode == http.StatusNotModified {
+			repo.SetSubscriptionFetchResult(db, row.ID, row.Etag, row.LastModified, "", false)
+			return RefreshResult{NotModified: true}, nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			return RefreshResult{}, errorx.New(errorx.SUBHTTPStatusError, "bad status").WithDetails(map[string]any{"id": subID, "status": resp.StatusCode, "err": resp.Status})
+		}
+		body, err = fetchSubscriptionBody(resp, opts)
+		if err != nil {
+			return RefreshResult{}, err
+		}
+		etag, lastMod, header = resp.Header.Get("Etag"), resp.Header.Get("Last-Modified"), resp.Header
 	}
+	attempt.bytes = len(body)
+	_ = saveLastSubscriptionBody(ResolveConfigPath(), row.ID, body)
+	reportRefreshStage(row.ID, "parse")
 	parsed, err := parser.ParseSubscriptionBundle(body)
+	if err == nil {
+		parsed = ResolveClashProviders(ctx, ResolveConfigPath(), row.ID, body, parsed, clientProviderFetcher(client, opts))
+		if len(parsed.Outbounds) == 0 {
+			err = errorx.New(errorx.SUBEmptyOutbounds, "no supported outbounds found").WithDetails(map[string]any{
+				"format":  parsed.Format,
+				"skipped": len(parsed.Skipped),
+			})
+		}
+	}
+	attempt.format = parsed.Format
+	parserLog.DebugContext(ctx, "subscription parsed", "sub_id", row.ID, "format", parsed.Format, "bytes", len(body),
+		"outbounds", len(parsed.Outbounds), "skipped", len(parsed.Skipped), "rules", len(parsed.Rules), "error", err)
+	if parsed.Format != "" {
+		_ = repo.UpsertSubscriptionParseDiagnostics(db, buildParseDiagnosticsRow(row.ID, parsed))
+	}
 	if err != nil {
-		repo.SetSubscriptionFetchResult(db, row.ID, row.Etag, row.LastModified, err.Error(), false)
-		return false, 0, 0, err
+		return RefreshResult{}, err
 	}
 	subShort := row.ID
 	if len(subShort) > 8 {
 		subShort = subShort[:8]
 	}
 	ingestNodes := BuildIngestNodesFromOutbounds(parsed.Outbounds, subShort+"-node")
+	pipeline, err := LoadSubscriptionPipeline(db, row.ID)
+	if err != nil {
+		return RefreshResult{}, errorx.New(errorx.DBError, "load subscription pipeline").WithDetails(map[string]any{"id": subID})
+	}
+	if !pipeline.IsZero() {
+		var appErr *errorx.AppError
+		if ingestNodes, _, appErr = ApplySubscriptionPipeline(ingestNodes, pipeline); appErr != nil {
+			return RefreshResult{}, appErr
+		}
+		if len(ingestNodes) == 0 {
+			err := errorx.New(errorx.SUBEmptyOutbounds, "subscription pipeline filtered out every node").WithDetails(map[string]any{
+				"format": parsed.Format,
+				"parsed": parsed.NodeCount(),
+			})
+			return RefreshResult{}, err
+		}
+	}
+	reportRefreshStage(row.ID, "ingest")
+	refreshWriteMu.Lock()
+	defer refreshWriteMu.Unlock()
 	ingestResult, ingestErr := IngestOutbounds(db, IngestInput{
 		SubID:                    row.ID,
 		Source:                   IngestSourceSub,
@@ -67,8 +238,9 @@ func RefreshSubscription(db *sql.DB, subID string) (notModified bool, nodesTotal
 		Nodes:                    ingestNodes,
 	})
 	if ingestErr != nil {
-		return false, 0, 0, ingestErr
+		return RefreshResult{}, ingestErr
 	}
+	attempt.diff = ingestResult.Diff
 	nodes := ingestResult.Rows
 	sourceToFinalTag := ingestResult.SourceTagTo
 	ruleSets := make([]repo.SubscriptionRuleSetRow, 0, len(parsed.RuleSets))
@@ -139,20 +311,95 @@ func RefreshSubscription(db *sql.DB, subID string) (notModified bool, nodesTotal
 			})
 		}
 	}
-	if err := repo.ReplaceSubscriptionRouting(db, row.ID, ruleSets, rules, groupMembers); err != nil {
-		return false, 0, 0, errorx.New(errorx.DBError, "replace subscription routing").WithDetails(map[string]any{"id": subID})
+	if err := repo.ReplaceSubscriptionRouting(db, row.ID, ruleSets, rules, groupMembers, buildBusinessGroupRows(row.ID, parsed.BusinessGroups)); err != nil {
+		return RefreshResult{}, errorx.New(errorx.DBError, "replace subscription routing").WithDetails(map[string]any{"id": subID})
 	}
-	etag := resp.Header.Get("Etag")
-	lastMod := resp.Header.Get("Last-Modified")
 	repo.SetSubscriptionFetchResult(db, row.ID, etag, lastMod, "", true)
-	meta := parseSubscriptionUsageMeta(resp.Header)
-	if err := repo.UpdateSubscriptionUsageMeta(db, row.ID, meta); err != nil {
-		return false, 0, 0, errorx.New(errorx.DBError, "update subscription usage meta").WithDetails(map[string]any{
-			"id":  subID,
-			"err": err.Error(),
+	if header != nil {
+		meta := parseSubscriptionUsageMeta(header)
+		if err := repo.UpdateSubscriptionUsageMeta(db, row.ID, meta); err != nil {
+			return RefreshResult{}, errorx.New(errorx.DBError, "update subscription usage meta").WithDetails(map[string]any{
+				"id":  subID,
+				"err": err.Error(),
+			})
+		}
+		if updated, err := repo.GetSubscription(db, row.ID); err == nil && updated != nil {
+			logUsageWarnings(ctx, *updated, time.Now())
+		}
+	}
+	enabled := 0
+	for _, n := range nodes {
+		if n.Enabled == 1 {
+			enabled++
+		}
+	}
+	return RefreshResult{
+		NodesTotal:   len(nodes),
+		NodesEnabled: enabled,
+		Added:        ingestResult.Added,
+		Updated:      ingestResult.Updated,
+		Removed:      ingestResult.Removed,
+		Diff:         ingestResult.Diff,
+	}, nil
+}
+
+// buildBusinessGroupRows keeps the url-test / fallback / load-balance semantics
+// of business targets; plain selectors are not stored.
+func buildBusinessGroupRows(subID string, groups []parser.BusinessGroupItem) []repo.SubscriptionBusinessGroupRow {
+	out := make([]repo.SubscriptionBusinessGroupRow, 0, len(groups))
+	seen := map[string]struct{}{}
+	for _, g := range groups {
+		target := strings.TrimSpace(g.TargetOutbound)
+		if target == "" || g.Policy.Type == "" {
+			continue
+		}
+		if _, ok := seen[target]; ok {
+			continue
+		}
+		seen[target] = struct{}{}
+		out = append(out, repo.SubscriptionBusinessGroupRow{
+			SubID:          subID,
+			TargetOutbound: target,
+			GroupType:      g.Policy.Type,
+			TestURL:        g.Policy.URL,
+			IntervalSec:    g.Policy.IntervalSec,
+			ToleranceMs:    g.Policy.ToleranceMs,
+			Strategy:       g.Policy.Strategy,
+			CreatedAt:      util.NowRFC3339(),
 		})
 	}
-	return false, len(nodes), len(nodes), nil
+	return out
+}
+
+type parseSkippedEntryJSON struct {
+	Index    int    `json:"index"`
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Reason   string `json:"reason"`
+}
+
+func buildParseDiagnosticsRow(subID string, parsed parser.ParsedSubscription) repo.SubscriptionParseDiagnosticsRow {
+	entries := make([]parseSkippedEntryJSON, 0, len(parsed.Skipped))
+	for _, s := range parsed.Skipped {
+		entries = append(entries, parseSkippedEntryJSON{
+			Index:    s.Index,
+			Name:     s.Name,
+			Protocol: s.Protocol,
+			Reason:   s.Reason,
+		})
+	}
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		raw = []byte("[]")
+	}
+	return repo.SubscriptionParseDiagnosticsRow{
+		SubID:        subID,
+		Format:       parsed.Format,
+		ParsedCount:  parsed.NodeCount(),
+		SkippedCount: len(parsed.Skipped),
+		SkippedJSON:  string(raw),
+		UpdatedAt:    util.NowRFC3339(),
+	}
 }
 
 func parseSubscriptionUsageMeta(headers http.Header) repo.SubscriptionUsageMeta {
@@ -189,8 +436,10 @@ func parseSubscriptionUsageMeta(headers http.Header) repo.SubscriptionUsageMeta
 		meta.ProfileWebPage = &page
 	}
 
+	// profile-update-interval is advertised in hours.
 	if intervalRaw := strings.TrimSpace(headers.Get("profile-update-interval")); intervalRaw != "" {
-		if interval, err := strconv.Atoi(intervalRaw); err == nil && interval > 0 {
+		if hours, err := strconv.Atoi(intervalRaw); err == nil && hours > 0 && hours <= maxProfileUpdateHours {
+			interval := hours * 3600
 			meta.ProfileUpdateSeconds = &interval
 		}
 	}
@@ -1,15 +1,24 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
 	"testing"
+
+	"boxpilot/server/internal/parser"
+	"boxpilot/server/internal/store/repo"
 )
 
 func TestParseSubscriptionUsageMeta(t *testing.T) {
 	headers := http.Header{}
 	headers.Set("subscription-userinfo", "upload=1024; download=2048; total=10240; expire=1767225600")
 	headers.Set("profile-web-page", "https://example.com/user")
-	headers.Set("profile-update-interval", "3600")
+	headers.Set("profile-update-interval", "24")
 
 	meta := parseSubscriptionUsageMeta(headers)
 
@@ -28,7 +37,7 @@ func TestParseSubscriptionUsageMeta(t *testing.T) {
 	if meta.ProfileWebPage == nil || *meta.ProfileWebPage != "https://example.com/user" {
 		t.Fatalf("unexpected profile web page: %#v", meta.ProfileWebPage)
 	}
-	if meta.ProfileUpdateSeconds == nil || *meta.ProfileUpdateSeconds != 3600 {
+	if meta.ProfileUpdateSeconds == nil || *meta.ProfileUpdateSeconds != 86400 {
 		t.Fatalf("unexpected profile interval: %#v", meta.ProfileUpdateSeconds)
 	}
 	if meta.UserinfoRaw == nil || *meta.UserinfoRaw == "" {
@@ -67,3 +76,156 @@ func TestIsSpecialBusinessMemberTag(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildParseDiagnosticsRow(t *testing.T) {
+	parsed := parser.ParsedSubscription{
+		Format:    "clash_yaml",
+		Outbounds: []parser.OutboundItem{{Tag: "a", Type: "vmess"}, {Tag: "b", Type: "trojan"}},
+		Skipped: []parser.SkippedEntry{
+			{Index: 3, Name: "snell-node", Protocol: "snell", Reason: "unsupported protocol"},
+		},
+	}
+	row := buildParseDiagnosticsRow("sub-1", parsed)
+	if row.SubID != "sub-1" || row.Format != "clash_yaml" || row.ParsedCount != 2 || row.SkippedCount != 1 {
+		t.Fatalf("unexpected diagnostics row: %+v", row)
+	}
+	var entries []map[string]any
+	if err := json.Unmarshal([]byte(row.SkippedJSON), &entries); err != nil {
+		t.Fatalf("skipped_json invalid: %v", err)
+	}
+	if len(entries) != 1 || entries[0]["protocol"] != "snell" || entries[0]["reason"] != "unsupported protocol" || entries[0]["index"].(float64) != 3 {
+		t.Fatalf("unexpected skipped entries: %+v", entries)
+	}
+
+	empty := buildParseDiagnosticsRow("sub-2", parser.ParsedSubscription{Format: "traditional_uri"})
+	if empty.SkippedJSON != "[]" {
+		t.Fatalf("expected empty skipped list, got %q", empty.SkippedJSON)
+	}
+}
+
+// conditionalSubscriptionServer serves body with a fixed etag and answers 304
+// to requests that already carry it.
+func conditionalSubscriptionServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	const etag = `"v1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Etag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func subscriptionNodeNames(t *testing.T, db *sql.DB, subID string) []string {
+	t.Helper()
+	rows, err := repo.ListNodes(db, subID, nil)
+	if err != nil {
+		t.Fatalf("ListNodes: %v", err)
+	}
+	names := make([]string, 0, len(rows))
+	for _, r := range rows {
+		names = append(names, r.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSavedPipelineAppliesOnNextRefresh(t *testing.T) {
+	t.Setenv("SINGBOX_CONFIG", filepath.Join(t.TempDir(), "sing-box.json"))
+	db := openTestDB(t)
+	srv := conditionalSubscriptionServer(t, "trojan://p@hk.example.com:443#HK-01\ntrojan://p@us.example.com:443#US-01\n")
+	if err := repo.CreateSubscription(db, "sub-1", "sub", srv.URL, "auto", SubscriptionSourceURL, 1, 0, 3600, 0); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := RefreshSubscription(ctx, db, "sub-1"); err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if res, err := RefreshSubscription(ctx, db, "sub-1"); err != nil || !res.NotModified {
+		t.Fatalf("expected 304 before the pipeline changes, got %+v, %v", res, err)
+	}
+
+	if _, err := SaveSubscriptionPipeline(db, "sub-1", SubscriptionPipeline{ExcludeRegex: "^US"}); err != nil {
+		t.Fatalf("SaveSubscriptionPipeline: %v", err)
+	}
+	res, err := RefreshSubscription(ctx, db, "sub-1")
+	if err != nil || res.NotModified || res.Removed != 1 {
+		t.Fatalf("expected the saved pipeline to re-ingest the body, got %+v, %v", res, err)
+	}
+	if names := subscriptionNodeNames(t, db, "sub-1"); len(names) != 1 || names[0] != "HK-01" {
+		t.Fatalf("expected only HK-01 after the pipeline, got %v", names)
+	}
+
+	if res, err := RefreshSubscription(ctx, db, "sub-1"); err != nil || !res.NotModified {
+		t.Fatalf("expected 304 once the pipeline is applied, got %+v, %v", res, err)
+	}
+	if names := subscriptionNodeNames(t, db, "sub-1"); len(names) != 1 || names[0] != "HK-01" {
+		t.Fatalf("expected the filtered nodes to stay after a 304, got %v", names)
+	}
+}
+
+func TestIdenticalRefetchDoesNotPublishNodesChanged(t *testing.T) {
+	t.Setenv("SINGBOX_CONFIG", filepath.Join(t.TempDir(), "sing-box.json"))
+	db := openTestDB(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("trojan://p@hk.example.com:443#HK-01\ntrojan://p@us.example.com:443#US-01\n"))
+	}))
+	t.Cleanup(srv.Close)
+	if err := repo.CreateSubscription(db, "sub-1", "sub", srv.URL, "auto", SubscriptionSourceURL, 1, 0, 3600, 0); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := RefreshSubscription(ctx, db, "sub-1"); err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+
+	events, cancel := Events.Subscribe(16)
+	defer cancel()
+	res, err := RefreshSubscription(ctx, db, "sub-1")
+	if err != nil || res.NotModified {
+		t.Fatalf("expected a full re-fetch, got %+v, %v", res, err)
+	}
+	if res.Added+res.Updated+res.Removed != 0 {
+		t.Fatalf("expected no node changes, got %+v", res)
+	}
+	for {
+		select {
+		case e := <-events:
+			if e.Type == EventNodesChanged {
+				t.Fatalf("identical re-fetch published %s: %v", e.Type, e.Data)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func TestRefreshRecordsFailureAfterFetch(t *testing.T) {
+	t.Setenv("SINGBOX_CONFIG", filepath.Join(t.TempDir(), "sing-box.json"))
+	db := openTestDB(t)
+	srv := conditionalSubscriptionServer(t, "trojan://p@hk.example.com:443#HK-01\n")
+	if err := repo.CreateSubscription(db, "sub-1", "sub", srv.URL, "auto", SubscriptionSourceURL, 1, 0, 3600, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Loading the pipeline fails after the body was fetched and parsed.
+	if _, err := db.Exec("DROP TABLE subscription_pipelines"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RefreshSubscription(context.Background(), db, "sub-1"); err == nil {
+		t.Fatalf("expected the refresh to fail")
+	}
+	row, err := repo.GetSubscription(db, "sub-1")
+	if err != nil || row == nil {
+		t.Fatalf("GetSubscription: %v", err)
+	}
+	if row.FailureStreak != 1 || !row.LastError.Valid || row.LastError.String == "" {
+		t.Fatalf("expected the failure to be recorded, got streak=%d last_error=%+v", row.FailureStreak, row.LastError)
+	}
+	if row.Etag != "" {
+		t.Fatalf("expected the etag of the failed fetch to be discarded, got %q", row.Etag)
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"boxpilot/server/internal/export"
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+)
+
+// ShareSubscription is the node set BoxPilot serves to downstream clients on
+// /sub/:token, rendered in one export format.
+type ShareSubscription struct {
+	Result export.Result
+	// Userinfo is the aggregated subscription-userinfo header value; empty when
+	// no upstream subscription reported usage.
+	Userinfo string
+}
+
+// shareTokenPrefixLength is how much of a share token is kept in clear so
+// lists can tell tokens apart.
+const shareTokenPrefixLength = 8
+
+// NewShareToken returns a random, URL-safe share token.
+func NewShareToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashShareToken(token string) string {
+	return util.SHA256Hex([]byte(token))
+}
+
+// CreateShareToken stores a new share token and returns it; the token itself
+// is only available here.
+func CreateShareToken(db *sql.DB, name string) (repo.ShareTokenRow, string, error) {
+	token, err := NewShareToken()
+	if err != nil {
+		return repo.ShareTokenRow{}, "", err
+	}
+	row := repo.ShareTokenRow{
+		ID:        util.NewID(),
+		Name:      name,
+		TokenHash: hashShareToken(token),
+		Prefix:    token[:shareTokenPrefixLength],
+		Enabled:   1,
+		CreatedAt: util.NowRFC3339(),
+	}
+	if err := repo.CreateShareToken(db, row); err != nil {
+		return repo.ShareTokenRow{}, "", err
+	}
+	return row, token, nil
+}
+
+// ShareTokenByToken resolves the token from a /sub/:token request; it returns
+// nil for unknown tokens.
+func ShareTokenByToken(db *sql.DB, token string) (*repo.ShareTokenRow, error) {
+	if token == "" {
+		return nil, nil
+	}
+	return repo.GetShareTokenByHash(db, hashShareToken(token))
+}
+
+// BuildShareSubscription renders the current forwarding node set, after the
+// forwarding policy filter and cross-subscription dedup, exactly as the
+// generated sing-box config uses it.
+func BuildShareSubscription(db *sql.DB, format string) (ShareSubscription, error) {
+	nodes, err := repo.ListEnabledForwardingNodes(db)
+	if err != nil {
+		return ShareSubscription{}, err
+	}
+	policy, err := LoadForwardingPolicy(db)
+	if err != nil {
+		return ShareSubscription{}, err
+	}
+	nodes = FilterForwardingNodes(nodes, policy)
+	if nodes, _, err = CanonicalForwardingNodes(db, nodes); err != nil {
+		return ShareSubscription{}, err
+	}
+
+	items := make([]export.Node, 0, len(nodes))
+	subIDs := map[string]struct{}{}
+	for _, n := range nodes {
+		items = append(items, export.Node{Tag: n.Tag, Type: n.Type, OutboundJSON: n.OutboundJSON})
+		subIDs[n.SubID] = struct{}{}
+	}
+	res, err := export.Export(format, items)
+	if err != nil {
+		return ShareSubscription{}, err
+	}
+	subs, err := repo.ListSubscriptions(db, true)
+	if err != nil {
+		return ShareSubscription{}, err
+	}
+	return ShareSubscription{Result: res, Userinfo: AggregateSubscriptionUsage(subs, subIDs)}, nil
+}
+
+// AggregateSubscriptionUsage sums upload / download / total of the upstream
+// subscriptions that contribute nodes and reports the earliest expiry, in
+// subscription-userinfo header syntax.
+func AggregateSubscriptionUsage(subs []repo.SubscriptionRow, subIDs map[string]struct{}) string {
+	var upload, download, total, expire int64
+	var hasUsage, hasTotal bool
+	for _, s := range subs {
+		if _, ok := subIDs[s.ID]; !ok {
+			continue
+		}
+		if s.SubUploadBytes.Valid {
+			upload += s.SubUploadBytes.Int64
+			hasUsage = true
+		}
+		if s.SubDownloadBytes.Valid {
+			download += s.SubDownloadBytes.Int64
+			hasUsage = true
+		}
+		if s.SubTotalBytes.Valid {
+			total += s.SubTotalBytes.Int64
+			hasTotal = true
+		}
+		if s.SubExpireUnix.Valid && s.SubExpireUnix.Int64 > 0 && (expire == 0 || s.SubExpireUnix.Int64 < expire) {
+			expire = s.SubExpireUnix.Int64
+		}
+	}
+	if !hasUsage && !hasTotal && expire == 0 {
+		return ""
+	}
+	parts := []string{
+		"upload=" + strconv.FormatInt(upload, 10),
+		"download=" + strconv.FormatInt(download, 10),
+		"total=" + strconv.FormatInt(total, 10),
+	}
+	if expire > 0 {
+		parts = append(parts, "expire="+strconv.FormatInt(expire, 10))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+
+	"boxpilot/server/internal/store/repo"
+)
+
+func TestAggregateSubscriptionUsage(t *testing.T) {
+	subs := []repo.SubscriptionRow{
+		{
+			ID:               "a",
+			SubUploadBytes:   sql.NullInt64{Int64: 100, Valid: true},
+			SubDownloadBytes: sql.NullInt64{Int64: 200, Valid: true},
+			SubTotalBytes:    sql.NullInt64{Int64: 1000, Valid: true},
+			SubExpireUnix:    sql.NullInt64{Int64: 1900000000, Valid: true},
+		},
+		{
+			ID:               "b",
+			SubUploadBytes:   sql.NullInt64{Int64: 1, Valid: true},
+			SubDownloadBytes: sql.NullInt64{Int64: 2, Valid: true},
+			SubTotalBytes:    sql.NullInt64{Int64: 10, Valid: true},
+			SubExpireUnix:    sql.NullInt64{Int64: 1800000000, Valid: true},
+		},
+		{
+			// Contributes no nodes, so its quota must not be counted.
+			ID:            "c",
+			SubTotalBytes: sql.NullInt64{Int64: 99999, Valid: true},
+		},
+	}
+	got := AggregateSubscriptionUsage(subs, map[string]struct{}{"a": {}, "b": {}, repo.ManualSubscriptionID: {}})
+	want := "upload=101; download=202; total=1010; expire=1800000000"
+	if got != want {
+		t.Fatalf("AggregateSubscriptionUsage = %q, want %q", got, want)
+	}
+
+	if got := AggregateSubscriptionUsage(subs[2:], map[string]struct{}{"a": {}}); got != "" {
+		t.Fatalf("expected empty header without usage, got %q", got)
+	}
+}
+
+func TestShareTokensAreStoredHashed(t *testing.T) {
+	db := openTestDB(t)
+	row, token, err := CreateShareToken(db, "phone")
+	if err != nil {
+		t.Fatalf("CreateShareToken: %v", err)
+	}
+	if row.TokenHash == token || row.Prefix != token[:shareTokenPrefixLength] {
+		t.Fatalf("unexpected stored token %+v", row)
+	}
+	var stored string
+	if err := db.QueryRow("SELECT token_hash FROM share_tokens WHERE id = ?", row.ID).Scan(&stored); err != nil || stored != hashShareToken(token) {
+		t.Fatalf("expected the token hash to be stored, got %q (%v)", stored, err)
+	}
+	got, err := ShareTokenByToken(db, token)
+	if err != nil || got == nil || got.ID != row.ID {
+		t.Fatalf("ShareTokenByToken = %+v, %v", got, err)
+	}
+	if got, err := ShareTokenByToken(db, row.TokenHash); err != nil || got != nil {
+		t.Fatalf("the stored hash must not work as a token, got %+v, %v", got, err)
+	}
+}
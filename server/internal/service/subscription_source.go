@@ -0,0 +1,188 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+)
+
+const (
+	SubscriptionSourceURL    = "url"
+	SubscriptionSourceFile   = "file"
+	SubscriptionSourceInline = "inline"
+
+	// InlineSubscriptionURL fills the url column of inline subscriptions.
+	InlineSubscriptionURL = "inline://local"
+)
+
+// SubscriptionFileRoot is the directory file subscriptions must live in
+// (BOXPILOT_SUBSCRIPTION_FILE_ROOT), so the API cannot read arbitrary files.
+func SubscriptionFileRoot() string {
+	if p := strings.TrimSpace(os.Getenv("BOXPILOT_SUBSCRIPTION_FILE_ROOT")); p != "" {
+		return p
+	}
+	if stat, err := os.Stat("/data"); err == nil && stat.IsDir() {
+		return "/data/subscriptions"
+	}
+	return filepath.Join("data", "subscriptions")
+}
+
+// NormalizeSubscriptionSource validates the source type and location of a new
+// or updated subscription. File paths are resolved against SubscriptionFileRoot
+// and must stay inside it; inline subscriptions get InlineSubscriptionURL.
+func NormalizeSubscriptionSource(sourceType, location string) (string, string, *errorx.AppError) {
+	sourceType = strings.ToLower(strings.TrimSpace(sourceType))
+	location = strings.TrimSpace(location)
+	switch sourceType {
+	case "", SubscriptionSourceURL:
+		if location == "" {
+			return "", "", errorx.New(errorx.REQMissingField, "url required").WithDetails(map[string]any{"field": "url"})
+		}
+		return SubscriptionSourceURL, location, nil
+	case SubscriptionSourceFile:
+		if location == "" {
+			return "", "", errorx.New(errorx.REQMissingField, "file path required").WithDetails(map[string]any{"field": "url"})
+		}
+		path, err := resolveSubscriptionFilePath(location)
+		if err != nil {
+			return "", "", errorx.New(errorx.SUBInvalidURL, "file path must be inside the subscription file root").WithDetails(map[string]any{
+				"path": location,
+				"root": SubscriptionFileRoot(),
+			})
+		}
+		return SubscriptionSourceFile, path, nil
+	case SubscriptionSourceInline:
+		return SubscriptionSourceInline, InlineSubscriptionURL, nil
+	default:
+		return "", "", errorx.New(errorx.REQInvalidField, "source_type must be url/file/inline").WithDetails(map[string]any{"source_type": sourceType})
+	}
+}
+
+// resolveSubscriptionFilePath follows symlinks in both the root and the path
+// before the containment check, so a link inside the root cannot point outside
+// it. A path that does not exist yet is checked as written; it is resolved
+// again when the file is read.
+func resolveSubscriptionFilePath(p string) (string, error) {
+	root, err := filepath.Abs(SubscriptionFileRoot())
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(root, p)
+	}
+	p = filepath.Clean(p)
+	if real, err := filepath.EvalSymlinks(p); err == nil {
+		p = real
+		if root, err = filepath.EvalSymlinks(root); err != nil {
+			return "", err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", os.ErrPermission
+	}
+	return p, nil
+}
+
+// SaveInlineSubscriptionContent stores the body an inline subscription parses.
+func SaveInlineSubscriptionContent(db *sql.DB, subID, content string) *errorx.AppError {
+	if len(content) > defaultFetchMaxBodyBytes {
+		return errorx.New(errorx.SUBResponseTooLarge, "inline content too large").WithDetails(map[string]any{"max_body_bytes": defaultFetchMaxBodyBytes})
+	}
+	if err := repo.UpsertSubscriptionInlineContent(db, subID, content, util.NowRFC3339()); err != nil {
+		return errorx.New(errorx.DBError, "save inline subscription content").WithDetails(map[string]any{"err": err.Error()})
+	}
+	return nil
+}
+
+// localSubscriptionBody is the content of a file or inline subscription. Etag
+// is a content hash and LastModified the file mtime (see fileModTime) or the
+// time the inline content was saved, mirroring the columns a URL fetch fills.
+type localSubscriptionBody struct {
+	Body         []byte
+	Etag         string
+	LastModified string
+}
+
+func readLocalSubscription(db *sql.DB, row repo.SubscriptionRow, opts FetchOptions) (localSubscriptionBody, error) {
+	limit := opts.MaxBodyBytes
+	if limit <= 0 {
+		limit = defaultFetchMaxBodyBytes
+	}
+	var out localSubscriptionBody
+	switch row.SourceType {
+	case SubscriptionSourceFile:
+		path, err := resolveSubscriptionFilePath(row.URL)
+		if err != nil {
+			return out, errorx.New(errorx.SUBInvalidURL, "file path must be inside the subscription file root").WithDetails(map[string]any{"path": row.URL})
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return out, errorx.New(errorx.SUBFetchFailed, "open subscription file").WithDetails(map[string]any{"path": path, "err": err.Error()})
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil || !stat.Mode().IsRegular() {
+			return out, errorx.New(errorx.SUBFetchFailed, "subscription file is not a regular file").WithDetails(map[string]any{"path": path})
+		}
+		body, err := io.ReadAll(io.LimitReader(f, int64(limit)+1))
+		if err != nil {
+			return out, errorx.New(errorx.SUBFetchFailed, "read subscription file").WithDetails(map[string]any{"path": path, "err": err.Error()})
+		}
+		if len(body) > limit {
+			return out, errorx.New(errorx.SUBResponseTooLarge, "subscription file too large").WithDetails(map[string]any{"max_body_bytes": limit})
+		}
+		out.Body = body
+		out.LastModified = fileModTime(stat)
+	case SubscriptionSourceInline:
+		content, updatedAt, ok, err := repo.GetSubscriptionInlineContent(db, row.ID)
+		if err != nil {
+			return out, errorx.New(errorx.DBError, "load inline subscription content").WithDetails(map[string]any{"err": err.Error()})
+		}
+		if !ok {
+			return out, errorx.New(errorx.SUBEmptyOutbounds, "inline subscription has no content").WithDetails(map[string]any{"id": row.ID})
+		}
+		out.Body = []byte(content)
+		out.LastModified = updatedAt
+	}
+	out.Etag = "sha256:" + util.SHA256Hex(out.Body)
+	return out, nil
+}
+
+// subscriptionFileChanged reports whether a file subscription's mtime moved
+// since the last refresh. A missing file counts as changed until the failed
+// read has been recorded as last_error.
+func subscriptionFileChanged(row repo.SubscriptionRow) bool {
+	path, err := resolveSubscriptionFilePath(row.URL)
+	if err != nil {
+		return false
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return row.LastError.String == ""
+	}
+	return fileModTime(stat) != row.LastModified
+}
+
+// fileModTime keeps the full mtime precision, so a rewrite within the same
+// second as the last refresh is still noticed.
+func fileModTime(stat fs.FileInfo) string {
+	return stat.ModTime().UTC().Format(time.RFC3339Nano)
+}
+
+// IsLocalSubscription reports sources that are read on the server instead of
+// fetched, for which fetch options other than the body limit do not apply.
+func IsLocalSubscription(sourceType string) bool {
+	return sourceType == SubscriptionSourceFile || sourceType == SubscriptionSourceInline
+}
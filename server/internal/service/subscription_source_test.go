@@ -0,0 +1,134 @@
+package service
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+)
+
+func TestNormalizeSubscriptionSource(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("BOXPILOT_SUBSCRIPTION_FILE_ROOT", root)
+
+	if typ, loc, err := NormalizeSubscriptionSource("", "https://example.com/sub"); err != nil || typ != SubscriptionSourceURL || loc != "https://example.com/sub" {
+		t.Fatalf("url: %q %q %v", typ, loc, err)
+	}
+	if typ, loc, err := NormalizeSubscriptionSource("file", "team/nodes.yaml"); err != nil || typ != SubscriptionSourceFile || loc != filepath.Join(root, "team", "nodes.yaml") {
+		t.Fatalf("relative file: %q %q %v", typ, loc, err)
+	}
+	for _, bad := range []string{"../etc/passwd", "/etc/passwd", root} {
+		if _, _, err := NormalizeSubscriptionSource("file", bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+	if typ, loc, err := NormalizeSubscriptionSource("inline", ""); err != nil || typ != SubscriptionSourceInline || loc != InlineSubscriptionURL {
+		t.Fatalf("inline: %q %q %v", typ, loc, err)
+	}
+	if _, _, err := NormalizeSubscriptionSource("ftp", "x"); err == nil {
+		t.Fatalf("expected unknown source type to be rejected")
+	}
+}
+
+func TestResolveSubscriptionFilePathFollowsSymlinks(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("BOXPILOT_SUBSCRIPTION_FILE_ROOT", root)
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(outside), filepath.Join(root, "escape-dir")); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"escape.txt", "escape-dir/secret.txt"} {
+		if _, err := resolveSubscriptionFilePath(bad); err == nil {
+			t.Fatalf("expected symlink %q pointing outside the root to be rejected", bad)
+		}
+	}
+
+	inside := filepath.Join(root, "nodes.txt")
+	if err := os.WriteFile(inside, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(inside, filepath.Join(root, "current.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := resolveSubscriptionFilePath("current.txt"); err != nil || got != inside {
+		t.Fatalf("symlink inside the root: %q %v", got, err)
+	}
+}
+
+func TestReadLocalSubscriptionFile(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("BOXPILOT_SUBSCRIPTION_FILE_ROOT", root)
+	path := filepath.Join(root, "nodes.txt")
+	if err := os.WriteFile(path, []byte("trojan://p@hk.example:443#hk\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	row := repo.SubscriptionRow{ID: "s1", URL: path, SourceType: SubscriptionSourceFile}
+	got, err := readLocalSubscription(nil, row, DefaultFetchOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got.Etag, "sha256:") || string(got.Body) != "trojan://p@hk.example:443#hk\n" {
+		t.Fatalf("unexpected body: %+v", got)
+	}
+
+	row.LastModified = got.LastModified
+	if subscriptionFileChanged(row) {
+		t.Fatalf("unchanged file reported as changed")
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nudged := stat.ModTime().Add(time.Millisecond)
+	if err := os.Chtimes(path, nudged, nudged); err != nil {
+		t.Fatal(err)
+	}
+	if !subscriptionFileChanged(row) {
+		t.Fatalf("sub-second mtime change not reported as changed")
+	}
+
+	small := DefaultFetchOptions()
+	small.MaxBodyBytes = 4
+	if _, err := readLocalSubscription(nil, row, small); err == nil {
+		t.Fatalf("expected size limit error")
+	}
+}
+
+func TestShouldAutoRefresh(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("BOXPILOT_SUBSCRIPTION_FILE_ROOT", root)
+	path := filepath.Join(root, "nodes.txt")
+	if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stat, _ := os.Stat(path)
+	now := time.Now().UTC()
+	recent := sql.NullString{String: now.Format(time.RFC3339), Valid: true}
+
+	file := repo.SubscriptionRow{SourceType: SubscriptionSourceFile, URL: path, LastFetchAt: recent, LastModified: fileModTime(stat)}
+	if shouldAutoRefresh(file, now) {
+		t.Fatalf("unchanged file without auto update should not refresh")
+	}
+	file.LastModified = ""
+	if !shouldAutoRefresh(file, now) {
+		t.Fatalf("changed file should refresh without auto update")
+	}
+	inline := repo.SubscriptionRow{SourceType: SubscriptionSourceInline, AutoUpdateEnabled: 1}
+	if shouldAutoRefresh(inline, now) {
+		t.Fatalf("inline subscriptions are refreshed by the API only")
+	}
+	url := repo.SubscriptionRow{SourceType: SubscriptionSourceURL, Enabled: 1, AutoUpdateEnabled: 1, RefreshIntervalSec: 3600}
+	if !shouldAutoRefresh(url, now) {
+		t.Fatalf("never fetched url subscription should refresh")
+	}
+}
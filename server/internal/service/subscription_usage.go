@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+)
+
+const (
+	// maxProfileUpdateHours drops absurd provider intervals (over a year).
+	maxProfileUpdateHours = 24 * 365
+
+	defaultQuotaWarnPercent = 10
+
+	UsageWarningExpired  = "expired"
+	UsageWarningQuotaLow = "quota_low"
+)
+
+// SubscriptionUsageStatus is the provider-reported quota and expiry of one
+// subscription, evaluated against the warning thresholds.
+type SubscriptionUsageStatus struct {
+	Expired        bool
+	ExpireAt       *time.Time
+	TotalBytes     *int64
+	RemainingBytes *int64
+	QuotaLow       bool
+}
+
+// Warnings lists the usage warnings in a stable order.
+func (u SubscriptionUsageStatus) Warnings() []string {
+	var out []string
+	if u.Expired {
+		out = append(out, UsageWarningExpired)
+	}
+	if u.QuotaLow {
+		out = append(out, UsageWarningQuotaLow)
+	}
+	return out
+}
+
+// EvaluateSubscriptionUsage reads the subscription-userinfo columns. Quota is
+// low when the remaining bytes drop below BOXPILOT_QUOTA_WARN_BYTES, or when
+// that is unset, below BOXPILOT_QUOTA_WARN_PERCENT (default 10) of the total.
+func EvaluateSubscriptionUsage(s repo.SubscriptionRow, now time.Time) SubscriptionUsageStatus {
+	var u SubscriptionUsageStatus
+	if s.SubExpireUnix.Valid && s.SubExpireUnix.Int64 > 0 {
+		exp := time.Unix(s.SubExpireUnix.Int64, 0).UTC()
+		u.ExpireAt = &exp
+		u.Expired = !now.Before(exp)
+	}
+	if !s.SubTotalBytes.Valid || s.SubTotalBytes.Int64 <= 0 {
+		return u
+	}
+	total := s.SubTotalBytes.Int64
+	u.TotalBytes = &total
+	if !s.SubUploadBytes.Valid && !s.SubDownloadBytes.Valid {
+		return u
+	}
+	remaining := total - s.SubUploadBytes.Int64 - s.SubDownloadBytes.Int64
+	if remaining < 0 {
+		remaining = 0
+	}
+	u.RemainingBytes = &remaining
+	if threshold := quotaWarnBytes(); threshold > 0 {
+		u.QuotaLow = remaining < threshold
+	} else {
+		u.QuotaLow = remaining*100 < total*int64(quotaWarnPercent())
+	}
+	return u
+}
+
+// providerIntervalSec is the interval the provider advertises through
+// profile-update-interval, or 0 when there is none.
+func providerIntervalSec(s repo.SubscriptionRow) int {
+	if !s.SubProfileInterval.Valid || s.SubProfileInterval.Int64 < 60 {
+		return 0
+	}
+	return int(s.SubProfileInterval.Int64)
+}
+
+// logUsageWarnings reports expired or nearly exhausted subscriptions after a
+// refresh stored fresh usage data.
+func logUsageWarnings(ctx context.Context, s repo.SubscriptionRow, now time.Time) {
+	u := EvaluateSubscriptionUsage(s, now)
+	if u.Expired {
+		refreshLog.WarnContext(ctx, "subscription expired; auto refresh stopped", "sub_id", s.ID, "name", s.Name, "expire_at", u.ExpireAt.Format(time.RFC3339))
+	}
+	if u.QuotaLow {
+		refreshLog.WarnContext(ctx, "subscription quota low", "sub_id", s.ID, "name", s.Name, "remaining_bytes", *u.RemainingBytes, "total_bytes", *u.TotalBytes)
+	}
+}
+
+func quotaWarnBytes() int64 {
+	if raw := strings.TrimSpace(os.Getenv("BOXPILOT_QUOTA_WARN_BYTES")); raw != "" {
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+func quotaWarnPercent() int {
+	p := positiveEnvInt("BOXPILOT_QUOTA_WARN_PERCENT", defaultQuotaWarnPercent)
+	if p > 100 {
+		p = 100
+	}
+	return p
+}
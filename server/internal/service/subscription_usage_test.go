@@ -0,0 +1,56 @@
+package service
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+)
+
+func TestEvaluateSubscriptionUsage(t *testing.T) {
+	t.Setenv("BOXPILOT_QUOTA_WARN_BYTES", "")
+	t.Setenv("BOXPILOT_QUOTA_WARN_PERCENT", "")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	gib := int64(1 << 30)
+	s := repo.SubscriptionRow{
+		SubUploadBytes:   sql.NullInt64{Int64: gib, Valid: true},
+		SubDownloadBytes: sql.NullInt64{Int64: 85 * gib, Valid: true},
+		SubTotalBytes:    sql.NullInt64{Int64: 100 * gib, Valid: true},
+		SubExpireUnix:    sql.NullInt64{Int64: now.Add(24 * time.Hour).Unix(), Valid: true},
+	}
+
+	u := EvaluateSubscriptionUsage(s, now)
+	if u.Expired || u.QuotaLow || u.RemainingBytes == nil || *u.RemainingBytes != 14*gib {
+		t.Fatalf("unexpected usage: %+v", u)
+	}
+	if len(u.Warnings()) != 0 {
+		t.Fatalf("expected no warnings, got %v", u.Warnings())
+	}
+
+	s.SubDownloadBytes.Int64 = 95 * gib
+	if u := EvaluateSubscriptionUsage(s, now); !u.QuotaLow {
+		t.Fatalf("4%% left should be below the default 10%% threshold: %+v", u)
+	}
+
+	t.Setenv("BOXPILOT_QUOTA_WARN_BYTES", "1073741824")
+	if u := EvaluateSubscriptionUsage(s, now); u.QuotaLow {
+		t.Fatalf("4 GiB left is above a 1 GiB threshold: %+v", u)
+	}
+
+	u = EvaluateSubscriptionUsage(s, now.Add(48*time.Hour))
+	if !u.Expired {
+		t.Fatalf("expected expired: %+v", u)
+	}
+	if want := []string{UsageWarningExpired}; !reflect.DeepEqual(u.Warnings(), want) {
+		t.Fatalf("warnings: want %v got %v", want, u.Warnings())
+	}
+}
+
+func TestEvaluateSubscriptionUsageWithoutUserinfo(t *testing.T) {
+	u := EvaluateSubscriptionUsage(repo.SubscriptionRow{}, time.Now())
+	if u.Expired || u.QuotaLow || u.ExpireAt != nil || u.TotalBytes != nil {
+		t.Fatalf("no userinfo should yield no status: %+v", u)
+	}
+}
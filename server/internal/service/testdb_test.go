@@ -0,0 +1,20 @@
+package service
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"boxpilot/server/internal/store"
+)
+
+// openTestDB opens a fully migrated SQLite database in a temp dir.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "boxpilot.db"))
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.DB
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+)
+
+// Traffic dimensions. node is the outbound that carried a connection, group
+// every group in its chain, inbound the listener it arrived on.
+const (
+	TrafficDimensionNode    = "node"
+	TrafficDimensionGroup   = "group"
+	TrafficDimensionInbound = "inbound"
+)
+
+// Traffic rollup buckets.
+const (
+	TrafficBucketHour = "hour"
+	TrafficBucketDay  = "day"
+)
+
+const (
+	defaultTrafficSampleSec   = 10
+	defaultTrafficHourlyDays  = 7
+	defaultTrafficDailyDays   = 365
+	trafficPruneInterval      = time.Hour
+	trafficHourlyRangeCeiling = 48 * time.Hour
+	maxTrafficRange           = 366 * 24 * time.Hour
+)
+
+// TrafficDimensions lists the dimensions traffic is rolled up by.
+func TrafficDimensions() []string {
+	return []string{TrafficDimensionNode, TrafficDimensionGroup, TrafficDimensionInbound}
+}
+
+type trafficCounters struct {
+	upload   int64
+	download int64
+}
+
+type trafficRollupKey struct {
+	dimension string
+	key       string
+}
+
+// TrafficSampler turns successive Clash API /connections snapshots into
+// per-key byte deltas. The first snapshot only sets the baseline, so bytes
+// counted before a restart are not counted again. Bytes a connection moves
+// between its last sample and closing are not seen.
+type TrafficSampler struct {
+	last   map[string]trafficCounters
+	primed bool
+}
+
+func NewTrafficSampler() *TrafficSampler {
+	return &TrafficSampler{last: map[string]trafficCounters{}}
+}
+
+// Sample returns the hourly and daily rollup rows for the bytes moved since
+// the previous snapshot.
+func (s *TrafficSampler) Sample(snapshot *ClashConnections, now time.Time) []repo.TrafficRollupRow {
+	seen := make(map[string]trafficCounters, len(snapshot.Connections))
+	deltas := map[trafficRollupKey]*repo.TrafficRollupRow{}
+	add := func(dimension, key string, d trafficCounters, opened int64) {
+		if key == "" {
+			return
+		}
+		k := trafficRollupKey{dimension: dimension, key: key}
+		row := deltas[k]
+		if row == nil {
+			row = &repo.TrafficRollupRow{Dimension: dimension, Key: key}
+			deltas[k] = row
+		}
+		row.UploadBytes += d.upload
+		row.DownloadBytes += d.download
+		row.Connections += opened
+	}
+
+	for _, conn := range snapshot.Connections {
+		cur := trafficCounters{upload: conn.Upload, download: conn.Download}
+		seen[conn.ID] = cur
+		prev, known := s.last[conn.ID]
+		if !known && !s.primed {
+			continue
+		}
+		d := cur
+		opened := int64(1)
+		if known {
+			opened = 0
+			d = trafficCounters{upload: cur.upload - prev.upload, download: cur.download - prev.download}
+			// Counters never shrink for one connection; treat a reset as new.
+			if d.upload < 0 || d.download < 0 {
+				d = cur
+			}
+		}
+		if d.upload == 0 && d.download == 0 && opened == 0 {
+			continue
+		}
+		add(TrafficDimensionNode, conn.Outbound(), d, opened)
+		add(TrafficDimensionInbound, conn.Inbound, d, opened)
+		groups := map[string]bool{}
+		for _, g := range conn.Groups() {
+			if !groups[g] {
+				groups[g] = true
+				add(TrafficDimensionGroup, g, d, opened)
+			}
+		}
+	}
+	s.last = seen
+	s.primed = true
+
+	keys := make([]trafficRollupKey, 0, len(deltas))
+	for k := range deltas {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].dimension != keys[j].dimension {
+			return keys[i].dimension < keys[j].dimension
+		}
+		return keys[i].key < keys[j].key
+	})
+	out := make([]repo.TrafficRollupRow, 0, 2*len(keys))
+	for _, bucket := range []string{TrafficBucketHour, TrafficBucketDay} {
+		start := TrafficPeriodStart(bucket, now)
+		for _, k := range keys {
+			row := *deltas[k]
+			row.Bucket = bucket
+			row.PeriodStart = start
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// TrafficPeriodStart returns the UTC start of the bucket period containing t.
+func TrafficPeriodStart(bucket string, t time.Time) string {
+	size := time.Hour
+	if bucket == TrafficBucketDay {
+		size = 24 * time.Hour
+	}
+	return t.UTC().Truncate(size).Format(time.RFC3339)
+}
+
+// ParseTrafficRange parses a range such as 6h, 24h, 7d or 30d and picks the
+// bucket to read: hourly up to 48 hours, daily beyond.
+func ParseTrafficRange(raw string) (time.Duration, string, *errorx.AppError) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	invalid := func() (time.Duration, string, *errorx.AppError) {
+		return 0, "", errorx.New(errorx.REQInvalidField, "invalid traffic range").WithDetails(map[string]any{
+			"range":   raw,
+			"example": "24h, 7d, 30d",
+			"max":     "366d",
+		})
+	}
+	if len(raw) < 2 {
+		return invalid()
+	}
+	n, err := strconv.Atoi(raw[:len(raw)-1])
+	if err != nil || n <= 0 {
+		return invalid()
+	}
+	var d time.Duration
+	switch raw[len(raw)-1] {
+	case 'h':
+		d = time.Duration(n) * time.Hour
+	case 'd':
+		d = time.Duration(n) * 24 * time.Hour
+	default:
+		return invalid()
+	}
+	if d > maxTrafficRange {
+		return invalid()
+	}
+	if d <= trafficHourlyRangeCeiling {
+		return d, TrafficBucketHour, nil
+	}
+	return d, TrafficBucketDay, nil
+}
+
+// StartTrafficSampler samples the Clash API connection counters every
+// BOXPILOT_TRAFFIC_SAMPLE_SEC seconds and adds the per-node, per-group and
+// per-inbound deltas to the hourly and daily rollups. Hourly rollups are kept
+// BOXPILOT_TRAFFIC_HOURLY_DAYS days, daily ones BOXPILOT_TRAFFIC_DAILY_DAYS.
+func StartTrafficSampler(ctx context.Context, db *sql.DB) {
+	if _, enabled := ResolveClashAPIBaseURL(); !enabled {
+		trafficLog.InfoContext(ctx, "clash api disabled, traffic sampler not started")
+		return
+	}
+	interval := time.Duration(positiveEnvInt("BOXPILOT_TRAFFIC_SAMPLE_SEC", defaultTrafficSampleSec)) * time.Second
+	sampler := NewTrafficSampler()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastPrune time.Time
+	failing := false
+
+	for {
+		now := time.Now()
+		if now.Sub(lastPrune) >= trafficPruneInterval {
+			pruneTrafficRollups(ctx, db, now)
+			lastPrune = now
+		}
+		snapshot, err := FetchClashConnections(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			// Keep the previous counters: a short outage must not recount
+			// live connections, and after a sing-box restart every id is new.
+			if !failing {
+				trafficLog.WarnContext(ctx, "traffic sample failed", "error", err)
+			}
+			failing = true
+		case err == nil:
+			failing = false
+			rows := sampler.Sample(snapshot, now)
+			recordTrafficMetrics(snapshot, rows)
+			if err := repo.AddTrafficRollups(db, rows); err != nil {
+				trafficLog.ErrorContext(ctx, "save traffic rollups failed", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func pruneTrafficRollups(ctx context.Context, db *sql.DB, now time.Time) {
+	hourly := now.Add(-time.Duration(positiveEnvInt("BOXPILOT_TRAFFIC_HOURLY_DAYS", defaultTrafficHourlyDays)) * 24 * time.Hour)
+	daily := now.Add(-time.Duration(positiveEnvInt("BOXPILOT_TRAFFIC_DAILY_DAYS", defaultTrafficDailyDays)) * 24 * time.Hour)
+	if err := repo.PruneTrafficRollups(db, TrafficBucketHour, TrafficPeriodStart(TrafficBucketHour, hourly)); err != nil {
+		trafficLog.ErrorContext(ctx, "prune hourly traffic rollups failed", "error", err)
+	}
+	if err := repo.PruneTrafficRollups(db, TrafficBucketDay, TrafficPeriodStart(TrafficBucketDay, daily)); err != nil {
+		trafficLog.ErrorContext(ctx, "prune daily traffic rollups failed", "error", err)
+	}
+}
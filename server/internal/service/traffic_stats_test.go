@@ -0,0 +1,98 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util/errorx"
+)
+
+func trafficConn(id, inbound string, up, down int64, chain ...string) ClashConnection {
+	return ClashConnection{ID: id, Inbound: inbound, Upload: up, Download: down, Chain: chain}
+}
+
+func rollupsByKey(rows []repo.TrafficRollupRow, bucket string) map[string]repo.TrafficRollupRow {
+	out := map[string]repo.TrafficRollupRow{}
+	for _, r := range rows {
+		if r.Bucket == bucket {
+			out[r.Dimension+"/"+r.Key] = r
+		}
+	}
+	return out
+}
+
+func TestTrafficSamplerDeltas(t *testing.T) {
+	s := NewTrafficSampler()
+	now := time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC)
+
+	// The first snapshot is only a baseline.
+	first := s.Sample(&ClashConnections{Connections: []ClashConnection{
+		trafficConn("c1", "mixed-in", 100, 1000, "proxy", "hk-auto", "hk-1"),
+	}}, now)
+	if len(first) != 0 {
+		t.Fatalf("baseline should not produce rows: %+v", first)
+	}
+
+	rows := s.Sample(&ClashConnections{Connections: []ClashConnection{
+		trafficConn("c1", "mixed-in", 150, 1600, "proxy", "hk-auto", "hk-1"),
+		trafficConn("c2", "socks-in", 10, 20, "direct"),
+	}}, now.Add(10*time.Second))
+	hourly := rollupsByKey(rows, TrafficBucketHour)
+	if len(rows) != 2*len(hourly) || len(hourly) != 6 {
+		t.Fatalf("expected 6 keys in both buckets, got %+v", rows)
+	}
+	if r := hourly["node/hk-1"]; r.UploadBytes != 50 || r.DownloadBytes != 600 || r.Connections != 0 || r.PeriodStart != "2024-05-01T10:00:00Z" {
+		t.Fatalf("unexpected node delta: %+v", r)
+	}
+	if r := hourly["node/direct"]; r.UploadBytes != 10 || r.DownloadBytes != 20 || r.Connections != 1 {
+		t.Fatalf("new connection should count in full: %+v", r)
+	}
+	if hourly["group/proxy"].DownloadBytes != 600 || hourly["group/hk-auto"].DownloadBytes != 600 {
+		t.Fatalf("every group in the chain should be counted: %+v", hourly)
+	}
+	if _, ok := hourly["group/direct"]; ok {
+		t.Fatal("a node must not be counted as a group")
+	}
+	if hourly["inbound/mixed-in"].UploadBytes != 50 || hourly["inbound/socks-in"].UploadBytes != 10 {
+		t.Fatalf("unexpected inbound deltas: %+v", hourly)
+	}
+	if daily := rollupsByKey(rows, TrafficBucketDay); daily["node/hk-1"].PeriodStart != "2024-05-01T00:00:00Z" {
+		t.Fatalf("unexpected daily period: %+v", daily["node/hk-1"])
+	}
+
+	// Idle connections produce nothing; closed ones are forgotten.
+	if rows := s.Sample(&ClashConnections{Connections: []ClashConnection{
+		trafficConn("c1", "mixed-in", 150, 1600, "proxy", "hk-auto", "hk-1"),
+	}}, now.Add(20*time.Second)); len(rows) != 0 {
+		t.Fatalf("idle sample should not produce rows: %+v", rows)
+	}
+	if _, ok := s.last["c2"]; ok {
+		t.Fatal("closed connection still tracked")
+	}
+}
+
+func TestParseTrafficRange(t *testing.T) {
+	cases := []struct {
+		raw    string
+		want   time.Duration
+		bucket string
+	}{
+		{"6h", 6 * time.Hour, TrafficBucketHour},
+		{"48h", 48 * time.Hour, TrafficBucketHour},
+		{"2d", 48 * time.Hour, TrafficBucketHour},
+		{"7D", 7 * 24 * time.Hour, TrafficBucketDay},
+		{"366d", 366 * 24 * time.Hour, TrafficBucketDay},
+	}
+	for _, tc := range cases {
+		d, bucket, appErr := ParseTrafficRange(tc.raw)
+		if appErr != nil || d != tc.want || bucket != tc.bucket {
+			t.Errorf("%s: got %v %s %v", tc.raw, d, bucket, appErr)
+		}
+	}
+	for _, raw := range []string{"", "h", "0h", "-1d", "7w", "367d", "abc"} {
+		if _, _, appErr := ParseTrafficRange(raw); appErr == nil || appErr.Code != errorx.REQInvalidField {
+			t.Errorf("%q: expected invalid field, got %v", raw, appErr)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxpilot/server/internal/store/repo"
+	"boxpilot/server/internal/util"
+	"boxpilot/server/internal/util/errorx"
+)
+
+// Webhook request headers. The signature is "sha256=" + hex HMAC-SHA256 of
+// timestamp + "." + body keyed with the target secret.
+const (
+	WebhookHeaderEvent     = "X-BoxPilot-Event"
+	WebhookHeaderDelivery  = "X-BoxPilot-Delivery"
+	WebhookHeaderTimestamp = "X-BoxPilot-Timestamp"
+	WebhookHeaderSignature = "X-BoxPilot-Signature"
+
+	// EventWebhookTest is sent by the test endpoint only; targets cannot
+	// subscribe to it.
+	EventWebhookTest = "webhook.test"
+
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed"
+
+	defaultWebhookMaxAttempts  = 5
+	defaultWebhookDeliveryKeep = 200
+	webhookTimeout             = 10 * time.Second
+	webhookMaxRetryDelay       = 5 * time.Minute
+	webhookConcurrency         = 4
+)
+
+var (
+	webhookHTTPClient = &http.Client{Timeout: webhookTimeout}
+	// webhookRetryBase is the delay before the first retry; it doubles per
+	// attempt up to webhookMaxRetryDelay.
+	webhookRetryBase = 5 * time.Second
+)
+
+// WebhookTarget is a validated webhook endpoint. An empty Events receives
+// every control-plane event type.
+type WebhookTarget struct {
+	ID      string
+	Name    string
+	URL     string
+	Secret  string
+	Events  []string
+	Enabled bool
+}
+
+// WebhookAttempt is the outcome of one POST to a target.
+type WebhookAttempt struct {
+	Attempt    int
+	HTTPStatus int
+	Err        error
+	Duration   time.Duration
+}
+
+// NewWebhookSecret returns a random signing secret.
+func NewWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// NormalizeWebhookTarget validates a target before it is saved and generates
+// a secret when none is set.
+func NormalizeWebhookTarget(t WebhookTarget) (WebhookTarget, *errorx.AppError) {
+	t.Name = strings.TrimSpace(t.Name)
+	t.URL = strings.TrimSpace(t.URL)
+	t.Secret = strings.TrimSpace(t.Secret)
+	if t.URL == "" {
+		return t, errorx.New(errorx.REQMissingField, "url required")
+	}
+	if !validHTTPURL(t.URL) {
+		return t, errorx.New(errorx.REQInvalidField, "webhook url must be http(s)").WithDetails(map[string]any{"url": t.URL})
+	}
+	if t.Name == "" {
+		t.Name = t.URL
+	}
+	known := map[string]struct{}{}
+	for _, typ := range EventTypes() {
+		known[typ] = struct{}{}
+	}
+	events := make([]string, 0, len(t.Events))
+	seen := map[string]struct{}{}
+	for _, typ := range t.Events {
+		typ = strings.ToLower(strings.TrimSpace(typ))
+		if _, dup := seen[typ]; typ == "" || dup {
+			continue
+		}
+		if _, ok := known[typ]; !ok {
+			return t, errorx.New(errorx.REQInvalidField, "unsupported event type").WithDetails(map[string]any{
+				"event":     typ,
+				"supported": EventTypes(),
+			})
+		}
+		seen[typ] = struct{}{}
+		events = append(events, typ)
+	}
+	t.Events = events
+	if t.Secret == "" {
+		secret, err := NewWebhookSecret()
+		if err != nil {
+			return t, errorx.New(errorx.InternalError, "generate webhook secret")
+		}
+		t.Secret = secret
+	}
+	return t, nil
+}
+
+func WebhookTargetFromRow(row repo.WebhookTargetRow) WebhookTarget {
+	t := WebhookTarget{
+		ID:      row.ID,
+		Name:    row.Name,
+		URL:     row.URL,
+		Secret:  row.Secret,
+		Enabled: row.Enabled == 1,
+	}
+	_ = json.Unmarshal([]byte(row.EventsJSON), &t.Events)
+	return t
+}
+
+// WebhookTargetToRow encodes a target for storage; timestamps are left to the caller.
+func WebhookTargetToRow(t WebhookTarget) repo.WebhookTargetRow {
+	events := t.Events
+	if events == nil {
+		events = []string{}
+	}
+	raw, _ := json.Marshal(events)
+	enabled := 0
+	if t.Enabled {
+		enabled = 1
+	}
+	return repo.WebhookTargetRow{
+		ID:         t.ID,
+		Name:       t.Name,
+		URL:        t.URL,
+		Secret:     t.Secret,
+		EventsJSON: string(raw),
+		Enabled:    enabled,
+	}
+}
+
+// Wants reports whether the target receives events of type typ. Progress
+// events are never delivered.
+func (t WebhookTarget) Wants(typ string) bool {
+	if !t.Enabled || !IsControlPlaneEvent(typ) {
+		return false
+	}
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// SignWebhookPayload returns the X-BoxPilot-Signature value for body sent at
+// timestamp (unix seconds).
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookMaxAttempts is the number of tries per delivery
+// (BOXPILOT_WEBHOOK_MAX_ATTEMPTS, default 5).
+func webhookMaxAttempts() int {
+	return positiveEnvInt("BOXPILOT_WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts)
+}
+
+// webhookDeliveryKeep is the number of deliveries logged per target
+// (BOXPILOT_WEBHOOK_DELIVERY_KEEP, default 200).
+func webhookDeliveryKeep() int {
+	return positiveEnvInt("BOXPILOT_WEBHOOK_DELIVERY_KEEP", defaultWebhookDeliveryKeep)
+}
+
+// webhookRetryDelay is the wait after the given failed attempt.
+func webhookRetryDelay(attempt int) time.Duration {
+	d := webhookRetryBase
+	for i := 1; i < attempt && d < webhookMaxRetryDelay; i++ {
+		d *= 2
+	}
+	if d > webhookMaxRetryDelay {
+		d = webhookMaxRetryDelay
+	}
+	return d
+}
+
+// webhookRetryable reports whether a failed attempt is worth repeating:
+// network errors, 5xx and 429 are; other client errors are not.
+func webhookRetryable(status int, err error) bool {
+	if status == 0 {
+		return err != nil
+	}
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// postWebhook sends one signed request.
+func postWebhook(ctx context.Context, t WebhookTarget, deliveryID, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BoxPilot-Webhook")
+	req.Header.Set(WebhookHeaderEvent, eventType)
+	req.Header.Set(WebhookHeaderDelivery, deliveryID)
+	req.Header.Set(WebhookHeaderTimestamp, ts)
+	req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(t.Secret, ts, body))
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(reply)))
+	}
+	return resp.StatusCode, nil
+}
+
+// sendWebhook posts body until it is accepted, a non-retryable reply comes
+// back or maxAttempts is used up. onAttempt sees every attempt, including the
+// last, which is also returned.
+func sendWebhook(ctx context.Context, t WebhookTarget, deliveryID, eventType string, body []byte, maxAttempts int, onAttempt func(WebhookAttempt)) WebhookAttempt {
+	var last WebhookAttempt
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		start := time.Now()
+		status, err := postWebhook(ctx, t, deliveryID, eventType, body)
+		last = WebhookAttempt{Attempt: attempt, HTTPStatus: status, Err: err, Duration: time.Since(start)}
+		if onAttempt != nil {
+			onAttempt(last)
+		}
+		if err == nil || !webhookRetryable(status, err) || attempt == maxAttempts {
+			return last
+		}
+		timer := time.NewTimer(webhookRetryDelay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last
+		case <-timer.C:
+		}
+	}
+	return last
+}
+
+// deliverWebhook sends e to one target with retries and logs every attempt
+// in webhook_deliveries.
+func deliverWebhook(ctx context.Context, db *sql.DB, t WebhookTarget, e Event, maxAttempts int) (repo.WebhookDeliveryRow, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return repo.WebhookDeliveryRow{}, err
+	}
+	now := util.NowRFC3339()
+	row := repo.WebhookDeliveryRow{
+		ID:        util.NewID(),
+		TargetID:  t.ID,
+		EventID:   e.ID,
+		EventType: e.Type,
+		Payload:   string(body),
+		Status:    WebhookDeliveryPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := repo.InsertWebhookDelivery(db, row); err != nil {
+		return row, err
+	}
+	final := sendWebhook(ctx, t, row.ID, e.Type, body, maxAttempts, func(a WebhookAttempt) {
+		row.Attempts = a.Attempt
+		row.HTTPStatus = a.HTTPStatus
+		row.DurationMs = a.Duration.Milliseconds()
+		row.Error = ""
+		switch {
+		case a.Err == nil:
+			row.Status = WebhookDeliverySuccess
+		case a.Attempt < maxAttempts && webhookRetryable(a.HTTPStatus, a.Err):
+			row.Status = WebhookDeliveryPending
+			row.Error = a.Err.Error()
+		default:
+			row.Status = WebhookDeliveryFailed
+			row.Error = a.Err.Error()
+		}
+		row.UpdatedAt = util.NowRFC3339()
+		if err := repo.UpdateWebhookDelivery(db, row); err != nil {
+			webhooksLog.ErrorContext(ctx, "update webhook delivery failed", "delivery_id", row.ID, "error", err)
+		}
+	})
+	if final.Err != nil && row.Status == WebhookDeliveryPending {
+		// Cancelled while waiting for a retry.
+		row.Status = WebhookDeliveryFailed
+		row.UpdatedAt = util.NowRFC3339()
+		if err := repo.UpdateWebhookDelivery(db, row); err != nil {
+			webhooksLog.ErrorContext(ctx, "update webhook delivery failed", "delivery_id", row.ID, "error", err)
+		}
+	}
+	if err := repo.PruneWebhookDeliveries(db, t.ID, webhookDeliveryKeep()); err != nil {
+		webhooksLog.ErrorContext(ctx, "prune webhook deliveries failed", "webhook_id", t.ID, "error", err)
+	}
+	return row, nil
+}
+
+// StartWebhookDispatcher forwards every published event to the enabled
+// webhook targets that subscribe to it. Deliveries run concurrently, so a
+// slow target never holds up events for the others.
+func StartWebhookDispatcher(ctx context.Context, db *sql.DB) {
+	events, cancel := Events.Subscribe(256)
+	defer cancel()
+	sem := make(chan struct{}, webhookConcurrency)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			rows, err := repo.ListWebhookTargets(db)
+			if err != nil {
+				webhooksLog.ErrorContext(ctx, "list webhook targets failed", "error", err)
+				continue
+			}
+			for _, r := range rows {
+				t := WebhookTargetFromRow(r)
+				if !t.Wants(e.Type) {
+					continue
+				}
+				go func() {
+					select {
+					case sem <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+					defer func() { <-sem }()
+					row, err := deliverWebhook(ctx, db, t, e, webhookMaxAttempts())
+					if err != nil {
+						webhooksLog.ErrorContext(ctx, "webhook delivery failed", "event", e.Type, "webhook", t.Name, "error", err)
+						return
+					}
+					if row.Status != WebhookDeliverySuccess {
+						webhooksLog.WarnContext(ctx, "webhook delivery gave up", "event", e.Type, "webhook", t.Name, "attempts", row.Attempts, "error", row.Error)
+					}
+				}()
+			}
+		}
+	}
+}
+
+// SendTestWebhook delivers one webhook.test event to a saved target without
+// retries and returns the logged delivery.
+func SendTestWebhook(ctx context.Context, db *sql.DB, t WebhookTarget) (repo.WebhookDeliveryRow, *errorx.AppError) {
+	e := Event{
+		ID:   util.NewID(),
+		Type: EventWebhookTest,
+		At:   time.Now().UTC(),
+		Data: map[string]any{"message": "This webhook is configured correctly."},
+	}
+	row, err := deliverWebhook(ctx, db, t, e, 1)
+	if err != nil {
+		return row, errorx.New(errorx.DBError, "record webhook delivery").WithDetails(map[string]any{"err": err.Error()})
+	}
+	if row.Status != WebhookDeliverySuccess {
+		return row, errorx.New(errorx.WEBHOOKSendFailed, "send test webhook").WithDetails(map[string]any{
+			"id":          t.ID,
+			"http_status": row.HTTPStatus,
+			"err":         row.Error,
+		})
+	}
+	return row, nil
+}
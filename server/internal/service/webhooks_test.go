@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"boxpilot/server/internal/util/errorx"
+)
+
+func TestNormalizeWebhookTarget(t *testing.T) {
+	got, appErr := NormalizeWebhookTarget(WebhookTarget{
+		URL:    " https://hooks.example.com/boxpilot ",
+		Events: []string{"Config.Applied", "", "config.applied", EventReloadFailed},
+	})
+	if appErr != nil {
+		t.Fatalf("unexpected error: %v", appErr)
+	}
+	if got.Name != "https://hooks.example.com/boxpilot" {
+		t.Fatalf("name should default to the url, got %q", got.Name)
+	}
+	if len(got.Events) != 2 || got.Events[0] != EventConfigApplied || got.Events[1] != EventReloadFailed {
+		t.Fatalf("events not normalized: %v", got.Events)
+	}
+	if len(got.Secret) != 64 {
+		t.Fatalf("expected a generated secret, got %q", got.Secret)
+	}
+
+	kept, _ := NormalizeWebhookTarget(WebhookTarget{URL: "http://x", Secret: "s3cret"})
+	if kept.Secret != "s3cret" {
+		t.Fatalf("explicit secret replaced: %q", kept.Secret)
+	}
+
+	if _, appErr := NormalizeWebhookTarget(WebhookTarget{URL: "ftp://x"}); appErr == nil || appErr.Code != errorx.REQInvalidField {
+		t.Fatalf("expected invalid url error, got %v", appErr)
+	}
+	if _, appErr := NormalizeWebhookTarget(WebhookTarget{URL: "http://x", Events: []string{"nope"}}); appErr == nil || appErr.Code != errorx.REQInvalidField {
+		t.Fatalf("expected unsupported event error, got %v", appErr)
+	}
+}
+
+func TestWebhookTargetWants(t *testing.T) {
+	all := WebhookTarget{Enabled: true}
+	if !all.Wants(EventNodesChanged) {
+		t.Fatalf("empty events should receive everything")
+	}
+	some := WebhookTarget{Enabled: true, Events: []string{EventReloadFailed}}
+	if some.Wants(EventNodesChanged) || !some.Wants(EventReloadFailed) {
+		t.Fatalf("event filter not applied")
+	}
+	if (WebhookTarget{}).Wants(EventReloadFailed) {
+		t.Fatalf("disabled target should receive nothing")
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	cases := map[int]time.Duration{1: 5 * time.Second, 2: 10 * time.Second, 3: 20 * time.Second, 20: webhookMaxRetryDelay}
+	for attempt, want := range cases {
+		if got := webhookRetryDelay(attempt); got != want {
+			t.Fatalf("attempt %d: got %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestSendWebhookSignsAndRetries(t *testing.T) {
+	prevBase := webhookRetryBase
+	webhookRetryBase = time.Millisecond
+	t.Cleanup(func() { webhookRetryBase = prevBase })
+
+	target := WebhookTarget{Secret: "topsecret", Enabled: true}
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts := r.Header.Get(WebhookHeaderTimestamp)
+		if r.Header.Get(WebhookHeaderSignature) != SignWebhookPayload(target.Secret, ts, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(WebhookHeaderEvent) != EventConfigApplied || r.Header.Get(WebhookHeaderDelivery) != "dlv-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	target.URL = srv.URL
+
+	body, _ := json.Marshal(Event{ID: "evt-1", Type: EventConfigApplied})
+	var seen []WebhookAttempt
+	got := sendWebhook(context.Background(), target, "dlv-1", EventConfigApplied, body, 5, func(a WebhookAttempt) {
+		seen = append(seen, a)
+	})
+	if got.Err != nil || got.HTTPStatus != http.StatusNoContent || got.Attempt != 3 {
+		t.Fatalf("unexpected final attempt: %+v", got)
+	}
+	if len(seen) != 3 || seen[0].HTTPStatus != http.StatusServiceUnavailable {
+		t.Fatalf("attempts not reported: %+v", seen)
+	}
+}
+
+func TestSendWebhookStopsOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	got := sendWebhook(context.Background(), WebhookTarget{URL: srv.URL}, "dlv", EventNodesChanged, []byte(`{}`), 5, nil)
+	if got.Err == nil || got.HTTPStatus != http.StatusGone {
+		t.Fatalf("expected 410 failure, got %+v", got)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("4xx should not be retried, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS subscription_parse_diagnostics (
+  sub_id TEXT PRIMARY KEY,
+  format TEXT NOT NULL DEFAULT '',
+  parsed_count INTEGER NOT NULL DEFAULT 0,
+  skipped_count INTEGER NOT NULL DEFAULT 0,
+  skipped_json TEXT NOT NULL DEFAULT '[]',
+  updated_at TEXT NOT NULL,
+  FOREIGN KEY (sub_id) REFERENCES subscriptions(id) ON DELETE CASCADE
+);
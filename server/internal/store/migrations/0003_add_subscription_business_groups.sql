@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS subscription_business_groups (
+  sub_id TEXT NOT NULL,
+  target_outbound TEXT NOT NULL,
+  group_type TEXT NOT NULL,
+  test_url TEXT,
+  interval_sec INTEGER NOT NULL DEFAULT 0,
+  tolerance_ms INTEGER NOT NULL DEFAULT 0,
+  strategy TEXT,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (sub_id, target_outbound),
+  FOREIGN KEY (sub_id) REFERENCES subscriptions(id) ON DELETE CASCADE
+);
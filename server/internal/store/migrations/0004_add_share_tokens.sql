@@ -0,0 +1,13 @@
+-- Only the SHA-256 of a share token is stored, like API tokens; prefix keeps
+-- the start of the token so lists can tell tokens apart.
+CREATE TABLE IF NOT EXISTS share_tokens (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  token_hash TEXT NOT NULL,
+  prefix TEXT NOT NULL DEFAULT '',
+  enabled INTEGER NOT NULL DEFAULT 1,
+  created_at TEXT NOT NULL,
+  last_used_at TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_share_tokens_token_hash ON share_tokens(token_hash);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS subscription_fetch_options (
+  sub_id TEXT PRIMARY KEY,
+  user_agent_preset TEXT NOT NULL DEFAULT 'default',
+  user_agent TEXT NOT NULL DEFAULT '',
+  headers_json TEXT NOT NULL DEFAULT '{}',
+  timeout_sec INTEGER NOT NULL DEFAULT 30,
+  max_body_bytes INTEGER NOT NULL DEFAULT 5242880,
+  fetch_via TEXT NOT NULL DEFAULT 'direct',
+  fetch_via_node_tag TEXT NOT NULL DEFAULT '',
+  updated_at TEXT NOT NULL,
+  FOREIGN KEY (sub_id) REFERENCES subscriptions(id) ON DELETE CASCADE
+);
+
+-- Per-install secret the fetch-in inbound credentials are derived from, so
+-- other local processes cannot compute them from the node tag alone.
+ALTER TABLE runtime_state ADD COLUMN fetch_proxy_secret TEXT NOT NULL DEFAULT '';
+
+UPDATE runtime_state SET fetch_proxy_secret = lower(hex(randomblob(32))) WHERE fetch_proxy_secret = '';
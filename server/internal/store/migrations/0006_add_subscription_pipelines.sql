@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS subscription_pipelines (
+  sub_id TEXT PRIMARY KEY,
+  include_regex TEXT NOT NULL DEFAULT '',
+  exclude_regex TEXT NOT NULL DEFAULT '',
+  type_allowlist_json TEXT NOT NULL DEFAULT '[]',
+  rename_rules_json TEXT NOT NULL DEFAULT '[]',
+  emoji_flag INTEGER NOT NULL DEFAULT 0,
+  name_template TEXT NOT NULL DEFAULT '',
+  updated_at TEXT NOT NULL,
+  FOREIGN KEY (sub_id) REFERENCES subscriptions(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE nodes ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE nodes ADD COLUMN name_locked INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_nodes_sub_fingerprint ON nodes(sub_id, fingerprint);
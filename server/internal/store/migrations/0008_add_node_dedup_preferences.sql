@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS node_dedup_preferences (
+  fingerprint TEXT PRIMARY KEY,
+  preferred_sub_id TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  FOREIGN KEY (preferred_sub_id) REFERENCES subscriptions(id) ON DELETE CASCADE
+);
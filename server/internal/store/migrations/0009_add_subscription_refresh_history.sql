@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS subscription_refresh_history (
+  id TEXT PRIMARY KEY,
+  sub_id TEXT NOT NULL,
+  started_at TEXT NOT NULL,
+  duration_ms INTEGER NOT NULL DEFAULT 0,
+  http_status INTEGER NOT NULL DEFAULT 0,
+  bytes INTEGER NOT NULL DEFAULT 0,
+  format TEXT NOT NULL DEFAULT '',
+  success INTEGER NOT NULL DEFAULT 0,
+  not_modified INTEGER NOT NULL DEFAULT 0,
+  error_code TEXT NOT NULL DEFAULT '',
+  error TEXT NOT NULL DEFAULT '',
+  nodes_total INTEGER NOT NULL DEFAULT 0,
+  nodes_added INTEGER NOT NULL DEFAULT 0,
+  nodes_updated INTEGER NOT NULL DEFAULT 0,
+  nodes_removed INTEGER NOT NULL DEFAULT 0,
+  diff_json TEXT NOT NULL DEFAULT '{}',
+  FOREIGN KEY (sub_id) REFERENCES subscriptions(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_subscription_refresh_history_sub ON subscription_refresh_history(sub_id, started_at);
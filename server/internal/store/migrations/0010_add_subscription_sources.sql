@@ -0,0 +1,8 @@
+ALTER TABLE subscriptions ADD COLUMN source_type TEXT NOT NULL DEFAULT 'url';
+
+CREATE TABLE IF NOT EXISTS subscription_inline_content (
+  sub_id TEXT PRIMARY KEY,
+  content TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  FOREIGN KEY (sub_id) REFERENCES subscriptions(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE subscriptions ADD COLUMN failure_streak INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE subscriptions ADD COLUMN follow_provider_interval INTEGER NOT NULL DEFAULT 0;
+
+-- profile-update-interval is advertised in hours; earlier versions stored the
+-- raw header value in this seconds column.
+UPDATE subscriptions
+SET sub_profile_update_interval_sec = sub_profile_update_interval_sec * 3600
+WHERE sub_profile_update_interval_sec IS NOT NULL AND sub_profile_update_interval_sec < 3600;
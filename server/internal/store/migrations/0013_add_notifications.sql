@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS notification_channels (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  type TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  config_json TEXT NOT NULL DEFAULT '{}',
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notification_rules (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  threshold INTEGER NOT NULL DEFAULT 0,
+  cooldown_sec INTEGER NOT NULL DEFAULT 21600,
+  channel_ids_json TEXT NOT NULL DEFAULT '[]',
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notification_alert_state (
+  rule_id TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  subject_name TEXT NOT NULL DEFAULT '',
+  message TEXT NOT NULL DEFAULT '',
+  first_seen_at TEXT NOT NULL,
+  last_sent_at TEXT,
+  last_error TEXT,
+  PRIMARY KEY (rule_id, subject),
+  FOREIGN KEY (rule_id) REFERENCES notification_rules(id) ON DELETE CASCADE
+);
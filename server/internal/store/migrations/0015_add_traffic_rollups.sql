@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS traffic_rollups (
+  bucket TEXT NOT NULL,
+  period_start TEXT NOT NULL,
+  dimension TEXT NOT NULL,
+  key TEXT NOT NULL,
+  upload_bytes INTEGER NOT NULL DEFAULT 0,
+  download_bytes INTEGER NOT NULL DEFAULT 0,
+  connections INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (bucket, period_start, dimension, key)
+);
+CREATE INDEX IF NOT EXISTS idx_traffic_rollups_range ON traffic_rollups(bucket, dimension, period_start);
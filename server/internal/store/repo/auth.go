@@ -0,0 +1,178 @@
+package repo
+
+import "database/sql"
+
+// AuthUserRow is a local user. PasswordHash is a bcrypt hash.
+type AuthUserRow struct {
+	ID           string
+	Username     string
+	PasswordHash string
+	CreatedAt    string
+	UpdatedAt    string
+}
+
+// AuthSessionRow is a browser session. Only the SHA-256 of the cookie value
+// is stored.
+type AuthSessionRow struct {
+	TokenHash string
+	UserID    string
+	UserAgent string
+	ClientIP  string
+	CreatedAt string
+	ExpiresAt string
+}
+
+// APITokenRow is a long-lived API token. Only its SHA-256 is stored; Prefix
+// is the start of the token, kept so it can be recognized in lists.
+type APITokenRow struct {
+	ID         string
+	Name       string
+	Scope      string
+	TokenHash  string
+	Prefix     string
+	CreatedAt  string
+	ExpiresAt  sql.NullString
+	LastUsedAt sql.NullString
+}
+
+func CountAuthUsers(db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM auth_users").Scan(&n)
+	return n, err
+}
+
+func GetAuthUserByUsername(db *sql.DB, username string) (*AuthUserRow, error) {
+	return scanAuthUser(db.QueryRow("SELECT id, username, password_hash, created_at, updated_at FROM auth_users WHERE username = ?", username))
+}
+
+func GetAuthUser(db *sql.DB, id string) (*AuthUserRow, error) {
+	return scanAuthUser(db.QueryRow("SELECT id, username, password_hash, created_at, updated_at FROM auth_users WHERE id = ?", id))
+}
+
+func scanAuthUser(row *sql.Row) (*AuthUserRow, error) {
+	var r AuthUserRow
+	err := row.Scan(&r.ID, &r.Username, &r.PasswordHash, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateFirstAuthUser inserts r only while no user exists, so two concurrent
+// bootstrap requests cannot both succeed. It reports whether r was inserted.
+func CreateFirstAuthUser(db *sql.DB, r AuthUserRow) (bool, error) {
+	res, err := db.Exec(
+		`INSERT INTO auth_users (id, username, password_hash, created_at, updated_at)
+		 SELECT ?, ?, ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM auth_users)`,
+		r.ID, r.Username, r.PasswordHash, r.CreatedAt, r.UpdatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func UpdateAuthUserPassword(db *sql.DB, id, passwordHash, updatedAt string) error {
+	_, err := db.Exec("UPDATE auth_users SET password_hash = ?, updated_at = ? WHERE id = ?", passwordHash, updatedAt, id)
+	return err
+}
+
+func CreateAuthSession(db *sql.DB, r AuthSessionRow) error {
+	_, err := db.Exec(
+		"INSERT INTO auth_sessions (token_hash, user_id, user_agent, client_ip, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		r.TokenHash, r.UserID, r.UserAgent, r.ClientIP, r.CreatedAt, r.ExpiresAt,
+	)
+	return err
+}
+
+func GetAuthSession(db *sql.DB, tokenHash string) (*AuthSessionRow, error) {
+	var r AuthSessionRow
+	err := db.QueryRow(
+		"SELECT token_hash, user_id, user_agent, client_ip, created_at, expires_at FROM auth_sessions WHERE token_hash = ?", tokenHash,
+	).Scan(&r.TokenHash, &r.UserID, &r.UserAgent, &r.ClientIP, &r.CreatedAt, &r.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func DeleteAuthSession(db *sql.DB, tokenHash string) error {
+	_, err := db.Exec("DELETE FROM auth_sessions WHERE token_hash = ?", tokenHash)
+	return err
+}
+
+// DeleteAuthSessionsForUser removes every session of userID except keep.
+func DeleteAuthSessionsForUser(db *sql.DB, userID, keep string) error {
+	_, err := db.Exec("DELETE FROM auth_sessions WHERE user_id = ? AND token_hash != ?", userID, keep)
+	return err
+}
+
+func PruneAuthSessions(db *sql.DB, now string) error {
+	_, err := db.Exec("DELETE FROM auth_sessions WHERE expires_at <= ?", now)
+	return err
+}
+
+const apiTokenColumns = "id, name, scope, token_hash, prefix, created_at, expires_at, last_used_at"
+
+func scanAPIToken(scan func(...any) error) (APITokenRow, error) {
+	var r APITokenRow
+	err := scan(&r.ID, &r.Name, &r.Scope, &r.TokenHash, &r.Prefix, &r.CreatedAt, &r.ExpiresAt, &r.LastUsedAt)
+	return r, err
+}
+
+func ListAPITokens(db *sql.DB) ([]APITokenRow, error) {
+	rows, err := db.Query("SELECT " + apiTokenColumns + " FROM api_tokens ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []APITokenRow
+	for rows.Next() {
+		r, err := scanAPIToken(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func GetAPITokenByHash(db *sql.DB, tokenHash string) (*APITokenRow, error) {
+	r, err := scanAPIToken(db.QueryRow("SELECT "+apiTokenColumns+" FROM api_tokens WHERE token_hash = ?", tokenHash).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func CreateAPIToken(db *sql.DB, r APITokenRow) error {
+	_, err := db.Exec(
+		"INSERT INTO api_tokens ("+apiTokenColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		r.ID, r.Name, r.Scope, r.TokenHash, r.Prefix, r.CreatedAt, r.ExpiresAt, r.LastUsedAt,
+	)
+	return err
+}
+
+func DeleteAPIToken(db *sql.DB, id string) (bool, error) {
+	res, err := db.Exec("DELETE FROM api_tokens WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func TouchAPIToken(db *sql.DB, id, usedAt string) error {
+	_, err := db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", usedAt, id)
+	return err
+}
@@ -0,0 +1,39 @@
+package repo
+
+import "database/sql"
+
+// ListNodeDedupPreferences maps a node fingerprint to the subscription whose
+// copy of the endpoint should be used.
+func ListNodeDedupPreferences(db *sql.DB) (map[string]string, error) {
+	rows, err := db.Query("SELECT fingerprint, preferred_sub_id FROM node_dedup_preferences")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]string{}
+	for rows.Next() {
+		var fingerprint, subID string
+		if err := rows.Scan(&fingerprint, &subID); err != nil {
+			return nil, err
+		}
+		out[fingerprint] = subID
+	}
+	return out, rows.Err()
+}
+
+func SetNodeDedupPreference(db *sql.DB, fingerprint, subID, updatedAt string) error {
+	_, err := db.Exec(
+		`INSERT INTO node_dedup_preferences (fingerprint, preferred_sub_id, updated_at)
+		 VALUES (?, ?, ?)
+		 ON CONFLICT(fingerprint) DO UPDATE SET
+		   preferred_sub_id = excluded.preferred_sub_id,
+		   updated_at = excluded.updated_at`,
+		fingerprint, subID, updatedAt,
+	)
+	return err
+}
+
+func DeleteNodeDedupPreference(db *sql.DB, fingerprint string) error {
+	_, err := db.Exec("DELETE FROM node_dedup_preferences WHERE fingerprint = ?", fingerprint)
+	return err
+}
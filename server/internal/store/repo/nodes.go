@@ -22,10 +22,15 @@ type NodeRow struct {
 	LastLatencyMs     sql.NullInt64
 	LastTestStatus    sql.NullString
 	LastTestError     sql.NullString
+	// Fingerprint identifies the node across subscription refreshes; see
+	// service.NodeFingerprint.
+	Fingerprint string
+	// NameLocked is set once the user renames the node; refreshes keep the name.
+	NameLocked int
 }
 
 func ListNodes(db *sql.DB, subID string, enabled *int) ([]NodeRow, error) {
-	query := "SELECT id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error FROM nodes WHERE 1=1"
+	query := "SELECT id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error, fingerprint, name_locked FROM nodes WHERE 1=1"
 	args := []any{}
 	if subID != "" {
 		query += " AND sub_id = ?"
@@ -47,6 +52,7 @@ func ListNodes(db *sql.DB, subID string, enabled *int) ([]NodeRow, error) {
 		if err := rows.Scan(
 			&r.ID, &r.SubID, &r.Tag, &r.Name, &r.Type, &r.Enabled, &r.ForwardingEnabled,
 			&r.OutboundJSON, &r.CreatedAt, &r.LastTestAt, &r.LastLatencyMs, &r.LastTestStatus, &r.LastTestError,
+			&r.Fingerprint, &r.NameLocked,
 		); err != nil {
 			return nil, err
 		}
@@ -57,9 +63,9 @@ func ListNodes(db *sql.DB, subID string, enabled *int) ([]NodeRow, error) {
 
 func GetNode(db *sql.DB, id string) (*NodeRow, error) {
 	var r NodeRow
-	err := db.QueryRow("SELECT id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error FROM nodes WHERE id = ?", id).Scan(
+	err := db.QueryRow("SELECT id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error, fingerprint, name_locked FROM nodes WHERE id = ?", id).Scan(
 		&r.ID, &r.SubID, &r.Tag, &r.Name, &r.Type, &r.Enabled, &r.ForwardingEnabled, &r.OutboundJSON, &r.CreatedAt,
-		&r.LastTestAt, &r.LastLatencyMs, &r.LastTestStatus, &r.LastTestError,
+		&r.LastTestAt, &r.LastLatencyMs, &r.LastTestStatus, &r.LastTestError, &r.Fingerprint, &r.NameLocked,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -70,42 +76,76 @@ func GetNode(db *sql.DB, id string) (*NodeRow, error) {
 	return &r, nil
 }
 
-func ReplaceNodesForSubscription(db *sql.DB, subID string, nodes []NodeRow) error {
+// SyncSubscriptionNodes makes the nodes of a subscription match updates plus
+// inserts. Updated rows keep their id, enabled / forwarding toggles, probe
+// history and, when NameLocked, their name; rows of the subscription that are in
+// neither list are deleted. It returns the number of deleted rows.
+func SyncSubscriptionNodes(db *sql.DB, subID string, updates, inserts []NodeRow) (int, error) {
 	tx, err := db.Begin()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback()
-	oldForwarding := map[string]int{}
-	oldRows, err := tx.Query("SELECT tag, forwarding_enabled FROM nodes WHERE sub_id = ?", subID)
-	if err == nil {
-		defer oldRows.Close()
-		for oldRows.Next() {
-			var tag string
-			var forwardingEnabled int
-			if scanErr := oldRows.Scan(&tag, &forwardingEnabled); scanErr == nil {
-				oldForwarding[tag] = forwardingEnabled
-			}
+	keep := make(map[string]struct{}, len(updates))
+	for _, n := range updates {
+		keep[n.ID] = struct{}{}
+	}
+	oldRows, err := tx.Query("SELECT id FROM nodes WHERE sub_id = ?", subID)
+	if err != nil {
+		return 0, err
+	}
+	var removeIDs []string
+	for oldRows.Next() {
+		var id string
+		if err := oldRows.Scan(&id); err != nil {
+			oldRows.Close()
+			return 0, err
+		}
+		if _, ok := keep[id]; !ok {
+			removeIDs = append(removeIDs, id)
+		}
+	}
+	oldRows.Close()
+	if err := oldRows.Err(); err != nil {
+		return 0, err
+	}
+	for _, id := range removeIDs {
+		if _, err := tx.Exec("DELETE FROM nodes WHERE id = ?", id); err != nil {
+			return 0, err
 		}
 	}
-	if _, err := tx.Exec("DELETE FROM nodes WHERE sub_id = ?", subID); err != nil {
-		return err
+	// Tags are unique; park updated rows on their id first so two nodes can
+	// swap tags within one refresh.
+	for _, n := range updates {
+		if _, err := tx.Exec("UPDATE nodes SET tag = id WHERE id = ?", n.ID); err != nil {
+			return 0, err
+		}
 	}
-	for _, n := range nodes {
-		forwardingEnabled := n.ForwardingEnabled
-		if old, ok := oldForwarding[n.Tag]; ok {
-			forwardingEnabled = old
+	for _, n := range updates {
+		if _, err := tx.Exec(
+			`UPDATE nodes SET tag = ?, name = CASE WHEN name_locked = 1 THEN name ELSE ? END, type = ?, outbound_json = ?, fingerprint = ?
+			 WHERE id = ? AND sub_id = ?`,
+			n.Tag, n.Name, n.Type, n.OutboundJSON, n.Fingerprint, n.ID, subID,
+		); err != nil {
+			return 0, err
 		}
-		if _, err := tx.Exec("INSERT INTO nodes (id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL, NULL, NULL)",
-			n.ID, n.SubID, n.Tag, n.Name, n.Type, n.Enabled, forwardingEnabled, n.OutboundJSON, n.CreatedAt); err != nil {
-			return err
+	}
+	for _, n := range inserts {
+		if _, err := tx.Exec("INSERT INTO nodes (id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error, fingerprint) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL, NULL, NULL, ?)",
+			n.ID, n.SubID, n.Tag, n.Name, n.Type, n.Enabled, n.ForwardingEnabled, n.OutboundJSON, n.CreatedAt, n.Fingerprint); err != nil {
+			return 0, err
 		}
 	}
-	return tx.Commit()
+	return len(removeIDs), tx.Commit()
 }
 
+// UpdateNode applies user edits. Setting a name locks it against refreshes.
 func UpdateNode(db *sql.DB, id string, name *string, enabled *int, forwardingEnabled *int) (bool, error) {
-	res, err := db.Exec("UPDATE nodes SET name = COALESCE(?, name), enabled = COALESCE(?, enabled), forwarding_enabled = COALESCE(?, forwarding_enabled) WHERE id = ?", name, enabled, forwardingEnabled, id)
+	res, err := db.Exec(
+		`UPDATE nodes SET name = COALESCE(?, name), name_locked = CASE WHEN ? IS NULL THEN name_locked ELSE 1 END,
+		 enabled = COALESCE(?, enabled), forwarding_enabled = COALESCE(?, forwarding_enabled) WHERE id = ?`,
+		name, name, enabled, forwardingEnabled, id,
+	)
 	if err != nil {
 		return false, err
 	}
@@ -152,7 +192,7 @@ func ListEnabledNodes(db *sql.DB) ([]NodeRow, error) {
 
 func ListEnabledForwardingNodes(db *sql.DB) ([]NodeRow, error) {
 	rows, err := db.Query(
-		"SELECT id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error FROM nodes WHERE enabled = 1 AND forwarding_enabled = 1 ORDER BY created_at",
+		"SELECT id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error, fingerprint, name_locked FROM nodes WHERE enabled = 1 AND forwarding_enabled = 1 ORDER BY created_at",
 	)
 	if err != nil {
 		return nil, err
@@ -164,6 +204,7 @@ func ListEnabledForwardingNodes(db *sql.DB) ([]NodeRow, error) {
 		if err := rows.Scan(
 			&r.ID, &r.SubID, &r.Tag, &r.Name, &r.Type, &r.Enabled, &r.ForwardingEnabled,
 			&r.OutboundJSON, &r.CreatedAt, &r.LastTestAt, &r.LastLatencyMs, &r.LastTestStatus, &r.LastTestError,
+			&r.Fingerprint, &r.NameLocked,
 		); err != nil {
 			return nil, err
 		}
@@ -175,8 +216,8 @@ func ListEnabledForwardingNodes(db *sql.DB) ([]NodeRow, error) {
 func CreateNode(db *sql.DB, row NodeRow) error {
 	_, err := db.Exec(
 		`INSERT INTO nodes (
-			id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL, NULL, NULL)`,
+			id, sub_id, tag, name, type, enabled, forwarding_enabled, outbound_json, created_at, last_test_at, last_latency_ms, last_test_status, last_test_error, fingerprint
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL, NULL, NULL, ?)`,
 		row.ID,
 		row.SubID,
 		strings.TrimSpace(row.Tag),
@@ -186,6 +227,7 @@ func CreateNode(db *sql.DB, row NodeRow) error {
 		row.ForwardingEnabled,
 		row.OutboundJSON,
 		row.CreatedAt,
+		row.Fingerprint,
 	)
 	return err
 }
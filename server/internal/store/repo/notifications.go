@@ -0,0 +1,225 @@
+package repo
+
+import "database/sql"
+
+// NotificationChannelRow is one alert delivery target. ConfigJSON holds the
+// type-specific settings (webhook URL, Telegram bot, SMTP server, ntfy topic).
+type NotificationChannelRow struct {
+	ID         string
+	Name       string
+	Type       string
+	Enabled    int
+	ConfigJSON string
+	CreatedAt  string
+	UpdatedAt  string
+}
+
+// NotificationRuleRow raises alerts of one kind. Threshold is interpreted per
+// kind; ChannelIDsJSON lists the channels to notify, empty meaning all enabled
+// channels.
+type NotificationRuleRow struct {
+	ID             string
+	Name           string
+	Kind           string
+	Enabled        int
+	Threshold      int
+	CooldownSec    int
+	ChannelIDsJSON string
+	CreatedAt      string
+	UpdatedAt      string
+}
+
+// NotificationAlertStateRow tracks a firing alert so it is sent once per
+// cooldown window instead of on every check.
+type NotificationAlertStateRow struct {
+	RuleID      string
+	Subject     string
+	SubjectName string
+	Message     string
+	FirstSeenAt string
+	LastSentAt  sql.NullString
+	LastError   sql.NullString
+}
+
+const notificationChannelColumns = "id, name, type, enabled, config_json, created_at, updated_at"
+
+func ListNotificationChannels(db *sql.DB) ([]NotificationChannelRow, error) {
+	rows, err := db.Query("SELECT " + notificationChannelColumns + " FROM notification_channels ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []NotificationChannelRow
+	for rows.Next() {
+		var r NotificationChannelRow
+		if err := rows.Scan(&r.ID, &r.Name, &r.Type, &r.Enabled, &r.ConfigJSON, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func GetNotificationChannel(db *sql.DB, id string) (*NotificationChannelRow, error) {
+	var r NotificationChannelRow
+	err := db.QueryRow("SELECT "+notificationChannelColumns+" FROM notification_channels WHERE id = ?", id).
+		Scan(&r.ID, &r.Name, &r.Type, &r.Enabled, &r.ConfigJSON, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func CreateNotificationChannel(db *sql.DB, r NotificationChannelRow) error {
+	_, err := db.Exec(
+		"INSERT INTO notification_channels ("+notificationChannelColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+		r.ID, r.Name, r.Type, r.Enabled, r.ConfigJSON, r.CreatedAt, r.UpdatedAt,
+	)
+	return err
+}
+
+func UpdateNotificationChannel(db *sql.DB, r NotificationChannelRow) (bool, error) {
+	res, err := db.Exec(
+		"UPDATE notification_channels SET name = ?, type = ?, enabled = ?, config_json = ?, updated_at = ? WHERE id = ?",
+		r.Name, r.Type, r.Enabled, r.ConfigJSON, r.UpdatedAt, r.ID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func DeleteNotificationChannel(db *sql.DB, id string) (bool, error) {
+	res, err := db.Exec("DELETE FROM notification_channels WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+const notificationRuleColumns = "id, name, kind, enabled, threshold, cooldown_sec, channel_ids_json, created_at, updated_at"
+
+func ListNotificationRules(db *sql.DB) ([]NotificationRuleRow, error) {
+	rows, err := db.Query("SELECT " + notificationRuleColumns + " FROM notification_rules ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []NotificationRuleRow
+	for rows.Next() {
+		var r NotificationRuleRow
+		if err := rows.Scan(&r.ID, &r.Name, &r.Kind, &r.Enabled, &r.Threshold, &r.CooldownSec, &r.ChannelIDsJSON, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func GetNotificationRule(db *sql.DB, id string) (*NotificationRuleRow, error) {
+	var r NotificationRuleRow
+	err := db.QueryRow("SELECT "+notificationRuleColumns+" FROM notification_rules WHERE id = ?", id).
+		Scan(&r.ID, &r.Name, &r.Kind, &r.Enabled, &r.Threshold, &r.CooldownSec, &r.ChannelIDsJSON, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func CreateNotificationRule(db *sql.DB, r NotificationRuleRow) error {
+	_, err := db.Exec(
+		"INSERT INTO notification_rules ("+notificationRuleColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		r.ID, r.Name, r.Kind, r.Enabled, r.Threshold, r.CooldownSec, r.ChannelIDsJSON, r.CreatedAt, r.UpdatedAt,
+	)
+	return err
+}
+
+// UpdateNotificationRule rewrites a rule. Firing alerts are forgotten so the
+// new threshold is evaluated (and notified) from scratch.
+func UpdateNotificationRule(db *sql.DB, r NotificationRuleRow) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(
+		"UPDATE notification_rules SET name = ?, kind = ?, enabled = ?, threshold = ?, cooldown_sec = ?, channel_ids_json = ?, updated_at = ? WHERE id = ?",
+		r.Name, r.Kind, r.Enabled, r.Threshold, r.CooldownSec, r.ChannelIDsJSON, r.UpdatedAt, r.ID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec("DELETE FROM notification_alert_state WHERE rule_id = ?", r.ID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func DeleteNotificationRule(db *sql.DB, id string) (bool, error) {
+	res, err := db.Exec("DELETE FROM notification_rules WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+const notificationAlertStateColumns = "rule_id, subject, subject_name, message, first_seen_at, last_sent_at, last_error"
+
+// ListNotificationAlertStates returns the firing alerts, all rules when ruleID
+// is empty.
+func ListNotificationAlertStates(db *sql.DB, ruleID string) ([]NotificationAlertStateRow, error) {
+	query := "SELECT " + notificationAlertStateColumns + " FROM notification_alert_state"
+	args := []any{}
+	if ruleID != "" {
+		query += " WHERE rule_id = ?"
+		args = append(args, ruleID)
+	}
+	query += " ORDER BY first_seen_at, rule_id, subject"
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []NotificationAlertStateRow
+	for rows.Next() {
+		var r NotificationAlertStateRow
+		if err := rows.Scan(&r.RuleID, &r.Subject, &r.SubjectName, &r.Message, &r.FirstSeenAt, &r.LastSentAt, &r.LastError); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func UpsertNotificationAlertState(db *sql.DB, r NotificationAlertStateRow) error {
+	_, err := db.Exec(
+		`INSERT INTO notification_alert_state (`+notificationAlertStateColumns+`)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(rule_id, subject) DO UPDATE SET
+		   subject_name = excluded.subject_name,
+		   message = excluded.message,
+		   last_sent_at = excluded.last_sent_at,
+		   last_error = excluded.last_error`,
+		r.RuleID, r.Subject, r.SubjectName, r.Message, r.FirstSeenAt, r.LastSentAt, r.LastError,
+	)
+	return err
+}
+
+// DeleteNotificationAlertState clears a resolved alert so the next occurrence
+// is notified right away.
+func DeleteNotificationAlertState(db *sql.DB, ruleID, subject string) error {
+	_, err := db.Exec("DELETE FROM notification_alert_state WHERE rule_id = ? AND subject = ?", ruleID, subject)
+	return err
+}
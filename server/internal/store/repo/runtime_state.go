@@ -58,3 +58,13 @@ func SetForwardingRunning(db *sql.DB, running int) error {
 	_, err := db.Exec("UPDATE runtime_state SET forwarding_running = ? WHERE id = 'runtime'", running)
 	return err
 }
+
+// GetFetchProxySecret returns the per-install secret of the fetch-in inbound.
+func GetFetchProxySecret(db *sql.DB) (string, error) {
+	var secret string
+	err := db.QueryRow("SELECT fetch_proxy_secret FROM runtime_state WHERE id = 'runtime'").Scan(&secret)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return secret, err
+}
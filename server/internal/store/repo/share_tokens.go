@@ -0,0 +1,83 @@
+package repo
+
+import "database/sql"
+
+// ShareTokenRow grants a downstream client access to the /sub/:token endpoint.
+// Only the SHA-256 of the token is stored; Prefix is the start of the token,
+// kept so it can be recognized in lists.
+type ShareTokenRow struct {
+	ID         string
+	Name       string
+	TokenHash  string
+	Prefix     string
+	Enabled    int
+	CreatedAt  string
+	LastUsedAt sql.NullString
+}
+
+const shareTokenColumns = "id, name, token_hash, prefix, enabled, created_at, last_used_at"
+
+func scanShareToken(scan func(dest ...any) error) (ShareTokenRow, error) {
+	var r ShareTokenRow
+	err := scan(&r.ID, &r.Name, &r.TokenHash, &r.Prefix, &r.Enabled, &r.CreatedAt, &r.LastUsedAt)
+	return r, err
+}
+
+func ListShareTokens(db *sql.DB) ([]ShareTokenRow, error) {
+	rows, err := db.Query("SELECT " + shareTokenColumns + " FROM share_tokens ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ShareTokenRow
+	for rows.Next() {
+		r, err := scanShareToken(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func GetShareTokenByHash(db *sql.DB, tokenHash string) (*ShareTokenRow, error) {
+	r, err := scanShareToken(db.QueryRow("SELECT "+shareTokenColumns+" FROM share_tokens WHERE token_hash = ?", tokenHash).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func CreateShareToken(db *sql.DB, r ShareTokenRow) error {
+	_, err := db.Exec(
+		"INSERT INTO share_tokens (id, name, token_hash, prefix, enabled, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		r.ID, r.Name, r.TokenHash, r.Prefix, r.Enabled, r.CreatedAt,
+	)
+	return err
+}
+
+func UpdateShareTokenEnabled(db *sql.DB, id string, enabled int) (bool, error) {
+	res, err := db.Exec("UPDATE share_tokens SET enabled = ? WHERE id = ?", enabled, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func TouchShareToken(db *sql.DB, id, usedAt string) error {
+	_, err := db.Exec("UPDATE share_tokens SET last_used_at = ? WHERE id = ?", usedAt, id)
+	return err
+}
+
+func DeleteShareToken(db *sql.DB, id string) (bool, error) {
+	res, err := db.Exec("DELETE FROM share_tokens WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,61 @@
+package repo
+
+import "database/sql"
+
+// SubscriptionParseDiagnosticsRow keeps the outcome of the latest parse for one subscription.
+// SkippedJSON is a JSON array of skipped entries (index, name, protocol, reason).
+type SubscriptionParseDiagnosticsRow struct {
+	SubID        string
+	Format       string
+	ParsedCount  int
+	SkippedCount int
+	SkippedJSON  string
+	UpdatedAt    string
+}
+
+func UpsertSubscriptionParseDiagnostics(db *sql.DB, row SubscriptionParseDiagnosticsRow) error {
+	_, err := db.Exec(
+		`INSERT INTO subscription_parse_diagnostics (sub_id, format, parsed_count, skipped_count, skipped_json, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(sub_id) DO UPDATE SET
+		   format = excluded.format,
+		   parsed_count = excluded.parsed_count,
+		   skipped_count = excluded.skipped_count,
+		   skipped_json = excluded.skipped_json,
+		   updated_at = excluded.updated_at`,
+		row.SubID, row.Format, row.ParsedCount, row.SkippedCount, row.SkippedJSON, row.UpdatedAt,
+	)
+	return err
+}
+
+func GetSubscriptionParseDiagnostics(db *sql.DB, subID string) (*SubscriptionParseDiagnosticsRow, error) {
+	var r SubscriptionParseDiagnosticsRow
+	err := db.QueryRow(
+		"SELECT sub_id, format, parsed_count, skipped_count, skipped_json, updated_at FROM subscription_parse_diagnostics WHERE sub_id = ?",
+		subID,
+	).Scan(&r.SubID, &r.Format, &r.ParsedCount, &r.SkippedCount, &r.SkippedJSON, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func ListSubscriptionParseDiagnostics(db *sql.DB) (map[string]SubscriptionParseDiagnosticsRow, error) {
+	rows, err := db.Query("SELECT sub_id, format, parsed_count, skipped_count, skipped_json, updated_at FROM subscription_parse_diagnostics")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]SubscriptionParseDiagnosticsRow{}
+	for rows.Next() {
+		var r SubscriptionParseDiagnosticsRow
+		if err := rows.Scan(&r.SubID, &r.Format, &r.ParsedCount, &r.SkippedCount, &r.SkippedJSON, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out[r.SubID] = r
+	}
+	return out, rows.Err()
+}
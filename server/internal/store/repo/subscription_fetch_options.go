@@ -0,0 +1,71 @@
+package repo
+
+import "database/sql"
+
+// SubscriptionFetchOptionsRow holds how one subscription URL is fetched.
+// HeadersJSON is a JSON object of extra request headers.
+type SubscriptionFetchOptionsRow struct {
+	SubID           string
+	UserAgentPreset string
+	UserAgent       string
+	HeadersJSON     string
+	TimeoutSec      int
+	MaxBodyBytes    int
+	FetchVia        string
+	FetchViaNodeTag string
+	UpdatedAt       string
+}
+
+func UpsertSubscriptionFetchOptions(db *sql.DB, row SubscriptionFetchOptionsRow) error {
+	_, err := db.Exec(
+		`INSERT INTO subscription_fetch_options (sub_id, user_agent_preset, user_agent, headers_json, timeout_sec, max_body_bytes, fetch_via, fetch_via_node_tag, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(sub_id) DO UPDATE SET
+		   user_agent_preset = excluded.user_agent_preset,
+		   user_agent = excluded.user_agent,
+		   headers_json = excluded.headers_json,
+		   timeout_sec = excluded.timeout_sec,
+		   max_body_bytes = excluded.max_body_bytes,
+		   fetch_via = excluded.fetch_via,
+		   fetch_via_node_tag = excluded.fetch_via_node_tag,
+		   updated_at = excluded.updated_at`,
+		row.SubID, row.UserAgentPreset, row.UserAgent, row.HeadersJSON, row.TimeoutSec, row.MaxBodyBytes, row.FetchVia, row.FetchViaNodeTag, row.UpdatedAt,
+	)
+	return err
+}
+
+func GetSubscriptionFetchOptions(db *sql.DB, subID string) (*SubscriptionFetchOptionsRow, error) {
+	var r SubscriptionFetchOptionsRow
+	err := db.QueryRow(
+		`SELECT sub_id, user_agent_preset, user_agent, headers_json, timeout_sec, max_body_bytes, fetch_via, fetch_via_node_tag, updated_at
+		 FROM subscription_fetch_options WHERE sub_id = ?`,
+		subID,
+	).Scan(&r.SubID, &r.UserAgentPreset, &r.UserAgent, &r.HeadersJSON, &r.TimeoutSec, &r.MaxBodyBytes, &r.FetchVia, &r.FetchViaNodeTag, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func ListSubscriptionFetchOptions(db *sql.DB) (map[string]SubscriptionFetchOptionsRow, error) {
+	rows, err := db.Query(
+		`SELECT sub_id, user_agent_preset, user_agent, headers_json, timeout_sec, max_body_bytes, fetch_via, fetch_via_node_tag, updated_at
+		 FROM subscription_fetch_options`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]SubscriptionFetchOptionsRow{}
+	for rows.Next() {
+		var r SubscriptionFetchOptionsRow
+		if err := rows.Scan(&r.SubID, &r.UserAgentPreset, &r.UserAgent, &r.HeadersJSON, &r.TimeoutSec, &r.MaxBodyBytes, &r.FetchVia, &r.FetchViaNodeTag, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out[r.SubID] = r
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,28 @@
+package repo
+
+import "database/sql"
+
+// GetSubscriptionInlineContent returns the stored body of an inline
+// subscription; ok is false when none was saved.
+func GetSubscriptionInlineContent(db *sql.DB, subID string) (content, updatedAt string, ok bool, err error) {
+	err = db.QueryRow("SELECT content, updated_at FROM subscription_inline_content WHERE sub_id = ?", subID).Scan(&content, &updatedAt)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	return content, updatedAt, true, nil
+}
+
+func UpsertSubscriptionInlineContent(db *sql.DB, subID, content, updatedAt string) error {
+	_, err := db.Exec(
+		`INSERT INTO subscription_inline_content (sub_id, content, updated_at)
+		 VALUES (?, ?, ?)
+		 ON CONFLICT(sub_id) DO UPDATE SET
+		   content = excluded.content,
+		   updated_at = excluded.updated_at`,
+		subID, content, updatedAt,
+	)
+	return err
+}
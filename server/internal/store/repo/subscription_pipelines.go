@@ -0,0 +1,50 @@
+package repo
+
+import "database/sql"
+
+// SubscriptionPipelineRow holds the node filter / rename pipeline of one
+// subscription. TypeAllowlistJSON is a JSON string array and RenameRulesJSON a
+// JSON array of {pattern, replace} objects.
+type SubscriptionPipelineRow struct {
+	SubID             string
+	IncludeRegex      string
+	ExcludeRegex      string
+	TypeAllowlistJSON string
+	RenameRulesJSON   string
+	EmojiFlag         int
+	NameTemplate      string
+	UpdatedAt         string
+}
+
+func UpsertSubscriptionPipeline(db *sql.DB, row SubscriptionPipelineRow) error {
+	_, err := db.Exec(
+		`INSERT INTO subscription_pipelines (sub_id, include_regex, exclude_regex, type_allowlist_json, rename_rules_json, emoji_flag, name_template, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(sub_id) DO UPDATE SET
+		   include_regex = excluded.include_regex,
+		   exclude_regex = excluded.exclude_regex,
+		   type_allowlist_json = excluded.type_allowlist_json,
+		   rename_rules_json = excluded.rename_rules_json,
+		   emoji_flag = excluded.emoji_flag,
+		   name_template = excluded.name_template,
+		   updated_at = excluded.updated_at`,
+		row.SubID, row.IncludeRegex, row.ExcludeRegex, row.TypeAllowlistJSON, row.RenameRulesJSON, row.EmojiFlag, row.NameTemplate, row.UpdatedAt,
+	)
+	return err
+}
+
+func GetSubscriptionPipeline(db *sql.DB, subID string) (*SubscriptionPipelineRow, error) {
+	var r SubscriptionPipelineRow
+	err := db.QueryRow(
+		`SELECT sub_id, include_regex, exclude_regex, type_allowlist_json, rename_rules_json, emoji_flag, name_template, updated_at
+		 FROM subscription_pipelines WHERE sub_id = ?`,
+		subID,
+	).Scan(&r.SubID, &r.IncludeRegex, &r.ExcludeRegex, &r.TypeAllowlistJSON, &r.RenameRulesJSON, &r.EmojiFlag, &r.NameTemplate, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,72 @@
+package repo
+
+import "database/sql"
+
+// SubscriptionRefreshHistoryRow records one refresh attempt. DiffJSON holds the
+// added / removed / changed nodes of a successful refresh.
+type SubscriptionRefreshHistoryRow struct {
+	ID           string
+	SubID        string
+	StartedAt    string
+	DurationMs   int64
+	HTTPStatus   int
+	Bytes        int
+	Format       string
+	Success      int
+	NotModified  int
+	ErrorCode    string
+	Error        string
+	NodesTotal   int
+	NodesAdded   int
+	NodesUpdated int
+	NodesRemoved int
+	DiffJSON     string
+}
+
+const subscriptionRefreshHistoryColumns = "id, sub_id, started_at, duration_ms, http_status, bytes, format, success, not_modified, error_code, error, nodes_total, nodes_added, nodes_updated, nodes_removed, diff_json"
+
+func InsertSubscriptionRefreshHistory(db *sql.DB, r SubscriptionRefreshHistoryRow) error {
+	_, err := db.Exec(
+		"INSERT INTO subscription_refresh_history ("+subscriptionRefreshHistoryColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		r.ID, r.SubID, r.StartedAt, r.DurationMs, r.HTTPStatus, r.Bytes, r.Format, r.Success, r.NotModified,
+		r.ErrorCode, r.Error, r.NodesTotal, r.NodesAdded, r.NodesUpdated, r.NodesRemoved, r.DiffJSON,
+	)
+	return err
+}
+
+// ListSubscriptionRefreshHistory returns the newest attempts first.
+func ListSubscriptionRefreshHistory(db *sql.DB, subID string, limit int) ([]SubscriptionRefreshHistoryRow, error) {
+	rows, err := db.Query(
+		"SELECT "+subscriptionRefreshHistoryColumns+" FROM subscription_refresh_history WHERE sub_id = ? ORDER BY started_at DESC, rowid DESC LIMIT ?",
+		subID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SubscriptionRefreshHistoryRow
+	for rows.Next() {
+		var r SubscriptionRefreshHistoryRow
+		if err := rows.Scan(&r.ID, &r.SubID, &r.StartedAt, &r.DurationMs, &r.HTTPStatus, &r.Bytes, &r.Format, &r.Success, &r.NotModified,
+			&r.ErrorCode, &r.Error, &r.NodesTotal, &r.NodesAdded, &r.NodesUpdated, &r.NodesRemoved, &r.DiffJSON); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// PruneSubscriptionRefreshHistory keeps the newest keep attempts of one
+// subscription and drops every attempt that started before cutoff.
+func PruneSubscriptionRefreshHistory(db *sql.DB, subID string, keep int, cutoff string) error {
+	if _, err := db.Exec(
+		`DELETE FROM subscription_refresh_history WHERE sub_id = ? AND id NOT IN (
+		   SELECT id FROM subscription_refresh_history WHERE sub_id = ? ORDER BY started_at DESC, rowid DESC LIMIT ?
+		 )`,
+		subID, subID, keep,
+	); err != nil {
+		return err
+	}
+	_, err := db.Exec("DELETE FROM subscription_refresh_history WHERE sub_id = ? AND started_at < ?", subID, cutoff)
+	return err
+}
@@ -36,12 +36,26 @@ type SubscriptionGroupMemberRow struct {
 	CreatedAt      string
 }
 
+// SubscriptionBusinessGroupRow keeps the source group semantics of a business
+// target (url-test / fallback / load-balance). Plain selectors have no row.
+type SubscriptionBusinessGroupRow struct {
+	SubID          string
+	TargetOutbound string
+	GroupType      string
+	TestURL        string
+	IntervalSec    int
+	ToleranceMs    int
+	Strategy       string
+	CreatedAt      string
+}
+
 func ReplaceSubscriptionRouting(
 	db *sql.DB,
 	subID string,
 	ruleSets []SubscriptionRuleSetRow,
 	rules []SubscriptionRuleRow,
 	groupMembers []SubscriptionGroupMemberRow,
+	businessGroups []SubscriptionBusinessGroupRow,
 ) error {
 	tx, err := db.Begin()
 	if err != nil {
@@ -61,6 +75,9 @@ func ReplaceSubscriptionRouting(
 		}
 		groupMembers = nil
 	}
+	if _, err := tx.Exec("DELETE FROM subscription_business_groups WHERE sub_id = ?", subID); err != nil {
+		return err
+	}
 
 	for _, rs := range ruleSets {
 		if _, err := tx.Exec(
@@ -89,6 +106,14 @@ func ReplaceSubscriptionRouting(
 			return err
 		}
 	}
+	for _, g := range businessGroups {
+		if _, err := tx.Exec(
+			"INSERT INTO subscription_business_groups (sub_id, target_outbound, group_type, test_url, interval_sec, tolerance_ms, strategy, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			subID, g.TargetOutbound, g.GroupType, nullStr(g.TestURL), g.IntervalSec, g.ToleranceMs, nullStr(g.Strategy), g.CreatedAt,
+		); err != nil {
+			return err
+		}
+	}
 
 	return tx.Commit()
 }
@@ -155,7 +180,7 @@ func ListEnabledSubscriptionGroupMembers(db *sql.DB) ([]SubscriptionGroupMemberR
 		FROM subscription_group_members g
 		INNER JOIN subscriptions s ON s.id = g.sub_id
 		WHERE s.enabled = 1
-		ORDER BY g.target_outbound, g.created_at
+		ORDER BY g.target_outbound, g.created_at, g.rowid
 	`)
 	if err != nil {
 		if isNoSuchTableErr(err, "subscription_group_members") {
@@ -175,6 +200,32 @@ func ListEnabledSubscriptionGroupMembers(db *sql.DB) ([]SubscriptionGroupMemberR
 	return out, rows.Err()
 }
 
+func ListEnabledSubscriptionBusinessGroups(db *sql.DB) ([]SubscriptionBusinessGroupRow, error) {
+	rows, err := db.Query(`
+		SELECT b.sub_id, b.target_outbound, b.group_type, b.test_url, b.interval_sec, b.tolerance_ms, b.strategy, b.created_at
+		FROM subscription_business_groups b
+		INNER JOIN subscriptions s ON s.id = b.sub_id
+		WHERE s.enabled = 1
+		ORDER BY s.created_at, b.target_outbound
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SubscriptionBusinessGroupRow
+	for rows.Next() {
+		var r SubscriptionBusinessGroupRow
+		var testURL, strategy sql.NullString
+		if err := rows.Scan(&r.SubID, &r.TargetOutbound, &r.GroupType, &testURL, &r.IntervalSec, &r.ToleranceMs, &strategy, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.TestURL = testURL.String
+		r.Strategy = strategy.String
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
 func isNoSuchTableErr(err error, table string) bool {
 	if err == nil {
 		return false
@@ -11,29 +11,36 @@ const (
 	ManualSubscriptionName = "Manual Nodes"
 )
 
+// SubscriptionRow is one subscription. SourceType is url, file (URL holds the
+// server path) or inline (body in subscription_inline_content).
+// FollowProviderInterval schedules by SubProfileInterval when the provider
+// advertises one.
 type SubscriptionRow struct {
-	ID                 string
-	Name               string
-	URL                string
-	Type               string
-	Enabled            int
-	AutoUpdateEnabled  int
-	RefreshIntervalSec int
-	Etag               string
-	LastModified       string
-	LastFetchAt        sql.NullString
-	LastSuccessAt      sql.NullString
-	LastError          sql.NullString
-	SubUploadBytes     sql.NullInt64
-	SubDownloadBytes   sql.NullInt64
-	SubTotalBytes      sql.NullInt64
-	SubExpireUnix      sql.NullInt64
-	SubUserinfoRaw     sql.NullString
-	SubProfileWebPage  sql.NullString
-	SubProfileInterval sql.NullInt64
-	SubUserinfoUpdated sql.NullString
-	CreatedAt          string
-	UpdatedAt          string
+	ID                     string
+	Name                   string
+	URL                    string
+	Type                   string
+	SourceType             string
+	Enabled                int
+	AutoUpdateEnabled      int
+	RefreshIntervalSec     int
+	FollowProviderInterval int
+	Etag                   string
+	LastModified           string
+	LastFetchAt            sql.NullString
+	LastSuccessAt          sql.NullString
+	LastError              sql.NullString
+	FailureStreak          int
+	SubUploadBytes         sql.NullInt64
+	SubDownloadBytes       sql.NullInt64
+	SubTotalBytes          sql.NullInt64
+	SubExpireUnix          sql.NullInt64
+	SubUserinfoRaw         sql.NullString
+	SubProfileWebPage      sql.NullString
+	SubProfileInterval     sql.NullInt64
+	SubUserinfoUpdated     sql.NullString
+	CreatedAt              string
+	UpdatedAt              string
 }
 
 type SubscriptionUsageMeta struct {
@@ -48,7 +55,7 @@ type SubscriptionUsageMeta struct {
 }
 
 func ListSubscriptions(db *sql.DB, onlyEnabled bool) ([]SubscriptionRow, error) {
-	query := `SELECT id, name, url, type, enabled, auto_update_enabled, refresh_interval_sec, etag, last_modified, last_fetch_at, last_success_at, last_error,
+	query := `SELECT id, name, url, type, source_type, enabled, auto_update_enabled, refresh_interval_sec, follow_provider_interval, etag, last_modified, last_fetch_at, last_success_at, last_error, failure_streak,
 		sub_upload_bytes, sub_download_bytes, sub_total_bytes, sub_expire_unix, sub_userinfo_raw, sub_profile_web_page, sub_profile_update_interval_sec, sub_userinfo_updated_at,
 		created_at, updated_at FROM subscriptions`
 	var rows *sql.Rows
@@ -66,8 +73,8 @@ func ListSubscriptions(db *sql.DB, onlyEnabled bool) ([]SubscriptionRow, error)
 	for rows.Next() {
 		var r SubscriptionRow
 		err := rows.Scan(
-			&r.ID, &r.Name, &r.URL, &r.Type, &r.Enabled, &r.AutoUpdateEnabled, &r.RefreshIntervalSec, &r.Etag, &r.LastModified,
-			&r.LastFetchAt, &r.LastSuccessAt, &r.LastError,
+			&r.ID, &r.Name, &r.URL, &r.Type, &r.SourceType, &r.Enabled, &r.AutoUpdateEnabled, &r.RefreshIntervalSec, &r.FollowProviderInterval, &r.Etag, &r.LastModified,
+			&r.LastFetchAt, &r.LastSuccessAt, &r.LastError, &r.FailureStreak,
 			&r.SubUploadBytes, &r.SubDownloadBytes, &r.SubTotalBytes, &r.SubExpireUnix, &r.SubUserinfoRaw, &r.SubProfileWebPage, &r.SubProfileInterval, &r.SubUserinfoUpdated,
 			&r.CreatedAt, &r.UpdatedAt,
 		)
@@ -81,11 +88,11 @@ func ListSubscriptions(db *sql.DB, onlyEnabled bool) ([]SubscriptionRow, error)
 
 func GetSubscription(db *sql.DB, id string) (*SubscriptionRow, error) {
 	var r SubscriptionRow
-	err := db.QueryRow(`SELECT id, name, url, type, enabled, auto_update_enabled, refresh_interval_sec, etag, last_modified, last_fetch_at, last_success_at, last_error,
+	err := db.QueryRow(`SELECT id, name, url, type, source_type, enabled, auto_update_enabled, refresh_interval_sec, follow_provider_interval, etag, last_modified, last_fetch_at, last_success_at, last_error, failure_streak,
 		sub_upload_bytes, sub_download_bytes, sub_total_bytes, sub_expire_unix, sub_userinfo_raw, sub_profile_web_page, sub_profile_update_interval_sec, sub_userinfo_updated_at,
 		created_at, updated_at FROM subscriptions WHERE id = ?`, id).Scan(
-		&r.ID, &r.Name, &r.URL, &r.Type, &r.Enabled, &r.AutoUpdateEnabled, &r.RefreshIntervalSec, &r.Etag, &r.LastModified,
-		&r.LastFetchAt, &r.LastSuccessAt, &r.LastError,
+		&r.ID, &r.Name, &r.URL, &r.Type, &r.SourceType, &r.Enabled, &r.AutoUpdateEnabled, &r.RefreshIntervalSec, &r.FollowProviderInterval, &r.Etag, &r.LastModified,
+		&r.LastFetchAt, &r.LastSuccessAt, &r.LastError, &r.FailureStreak,
 		&r.SubUploadBytes, &r.SubDownloadBytes, &r.SubTotalBytes, &r.SubExpireUnix, &r.SubUserinfoRaw, &r.SubProfileWebPage, &r.SubProfileInterval, &r.SubUserinfoUpdated,
 		&r.CreatedAt, &r.UpdatedAt,
 	)
@@ -98,18 +105,18 @@ func GetSubscription(db *sql.DB, id string) (*SubscriptionRow, error) {
 	return &r, nil
 }
 
-func CreateSubscription(db *sql.DB, id, name, url, subType string, enabled, autoUpdateEnabled, refreshIntervalSec int) error {
+func CreateSubscription(db *sql.DB, id, name, url, subType, sourceType string, enabled, autoUpdateEnabled, refreshIntervalSec, followProviderInterval int) error {
 	now := util.NowRFC3339()
-	_, err := db.Exec("INSERT INTO subscriptions (id, name, url, type, enabled, auto_update_enabled, refresh_interval_sec, etag, last_modified, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, '', '', ?, ?)",
-		id, name, url, subType, enabled, autoUpdateEnabled, refreshIntervalSec, now, now)
+	_, err := db.Exec("INSERT INTO subscriptions (id, name, url, type, source_type, enabled, auto_update_enabled, refresh_interval_sec, follow_provider_interval, etag, last_modified, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, '', '', ?, ?)",
+		id, name, url, subType, sourceType, enabled, autoUpdateEnabled, refreshIntervalSec, followProviderInterval, now, now)
 	return err
 }
 
-func UpdateSubscription(db *sql.DB, id string, name *string, url *string, enabled *int, autoUpdateEnabled *int, refreshIntervalSec *int) error {
+func UpdateSubscription(db *sql.DB, id string, name *string, url *string, enabled *int, autoUpdateEnabled *int, refreshIntervalSec *int, followProviderInterval *int) error {
 	// Build update dynamically to only touch provided fields
 	now := util.NowRFC3339()
-	_, err := db.Exec("UPDATE subscriptions SET name = COALESCE(?, name), url = COALESCE(?, url), enabled = COALESCE(?, enabled), auto_update_enabled = COALESCE(?, auto_update_enabled), refresh_interval_sec = COALESCE(?, refresh_interval_sec), updated_at = ? WHERE id = ?",
-		name, url, enabled, autoUpdateEnabled, refreshIntervalSec, now, id)
+	_, err := db.Exec("UPDATE subscriptions SET name = COALESCE(?, name), url = COALESCE(?, url), enabled = COALESCE(?, enabled), auto_update_enabled = COALESCE(?, auto_update_enabled), refresh_interval_sec = COALESCE(?, refresh_interval_sec), follow_provider_interval = COALESCE(?, follow_provider_interval), updated_at = ? WHERE id = ?",
+		name, url, enabled, autoUpdateEnabled, refreshIntervalSec, followProviderInterval, now, id)
 	return err
 }
 
@@ -134,6 +141,8 @@ func DeleteSubscription(db *sql.DB, id string) (bool, error) {
 	return n > 0, nil
 }
 
+// SetSubscriptionFetchResult records the outcome of one refresh. A non-empty
+// lastError extends the failure streak; anything else (including 304) resets it.
 func SetSubscriptionFetchResult(db *sql.DB, id, etag, lastModified, lastError string, success bool) error {
 	now := util.NowRFC3339()
 	successFlag := 0
@@ -146,9 +155,18 @@ func SetSubscriptionFetchResult(db *sql.DB, id, etag, lastModified, lastError st
 		    last_fetch_at = ?,
 		    last_success_at = CASE WHEN ? = 1 THEN ? ELSE last_success_at END,
 		    last_error = ?,
+		    failure_streak = CASE WHEN ? = '' THEN 0 ELSE failure_streak + 1 END,
 		    updated_at = ?
 		WHERE id = ?`,
-		etag, lastModified, now, successFlag, now, nullStr(lastError), now, id)
+		etag, lastModified, now, successFlag, now, nullStr(lastError), lastError, now, id)
+	return err
+}
+
+// ClearSubscriptionValidators forgets the stored etag and Last-Modified (the
+// file mtime for file subscriptions), so the next refresh re-reads and
+// re-ingests the body even if upstream did not change.
+func ClearSubscriptionValidators(db *sql.DB, id string) error {
+	_, err := db.Exec("UPDATE subscriptions SET etag = '', last_modified = '', updated_at = ? WHERE id = ?", util.NowRFC3339(), id)
 	return err
 }
 
@@ -0,0 +1,103 @@
+package repo
+
+import "database/sql"
+
+// TrafficRollupRow holds the bytes one key moved in one period. Bucket is
+// hour or day, PeriodStart the UTC start of the period in RFC 3339, Dimension
+// node, group or inbound, and Connections the connections first seen in the
+// period.
+type TrafficRollupRow struct {
+	Bucket        string
+	PeriodStart   string
+	Dimension     string
+	Key           string
+	UploadBytes   int64
+	DownloadBytes int64
+	Connections   int64
+}
+
+// AddTrafficRollups adds the rows onto the stored counters in one
+// transaction.
+func AddTrafficRollups(db *sql.DB, rows []TrafficRollupRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(
+		`INSERT INTO traffic_rollups (bucket, period_start, dimension, key, upload_bytes, download_bytes, connections)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(bucket, period_start, dimension, key) DO UPDATE SET
+		   upload_bytes = upload_bytes + excluded.upload_bytes,
+		   download_bytes = download_bytes + excluded.download_bytes,
+		   connections = connections + excluded.connections`,
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range rows {
+		if _, err := stmt.Exec(r.Bucket, r.PeriodStart, r.Dimension, r.Key, r.UploadBytes, r.DownloadBytes, r.Connections); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SumTrafficRollups totals one dimension per key over periods starting at or
+// after from, largest total first.
+func SumTrafficRollups(db *sql.DB, bucket, dimension, from string) ([]TrafficRollupRow, error) {
+	rows, err := db.Query(
+		`SELECT key, SUM(upload_bytes), SUM(download_bytes), SUM(connections) FROM traffic_rollups
+		 WHERE bucket = ? AND dimension = ? AND period_start >= ?
+		 GROUP BY key ORDER BY SUM(upload_bytes) + SUM(download_bytes) DESC, key`,
+		bucket, dimension, from,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []TrafficRollupRow
+	for rows.Next() {
+		r := TrafficRollupRow{Bucket: bucket, Dimension: dimension}
+		if err := rows.Scan(&r.Key, &r.UploadBytes, &r.DownloadBytes, &r.Connections); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// ListTrafficRollupSeries totals one dimension per period over periods
+// starting at or after from, oldest first. A non-empty key limits it to that
+// key.
+func ListTrafficRollupSeries(db *sql.DB, bucket, dimension, key, from string) ([]TrafficRollupRow, error) {
+	rows, err := db.Query(
+		`SELECT period_start, SUM(upload_bytes), SUM(download_bytes), SUM(connections) FROM traffic_rollups
+		 WHERE bucket = ? AND dimension = ? AND period_start >= ? AND (? = '' OR key = ?)
+		 GROUP BY period_start ORDER BY period_start`,
+		bucket, dimension, from, key, key,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []TrafficRollupRow
+	for rows.Next() {
+		r := TrafficRollupRow{Bucket: bucket, Dimension: dimension}
+		if err := rows.Scan(&r.PeriodStart, &r.UploadBytes, &r.DownloadBytes, &r.Connections); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// PruneTrafficRollups drops periods of bucket that start before cutoff.
+func PruneTrafficRollups(db *sql.DB, bucket, cutoff string) error {
+	_, err := db.Exec("DELETE FROM traffic_rollups WHERE bucket = ? AND period_start < ?", bucket, cutoff)
+	return err
+}
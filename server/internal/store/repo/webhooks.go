@@ -0,0 +1,146 @@
+package repo
+
+import "database/sql"
+
+// WebhookTargetRow receives control-plane events. EventsJSON lists the event
+// types to deliver, empty meaning all; Secret signs every payload.
+type WebhookTargetRow struct {
+	ID         string
+	Name       string
+	URL        string
+	Secret     string
+	EventsJSON string
+	Enabled    int
+	CreatedAt  string
+	UpdatedAt  string
+}
+
+// WebhookDeliveryRow is one event sent to one target. Status is pending while
+// retries remain, then success or failed.
+type WebhookDeliveryRow struct {
+	ID         string
+	TargetID   string
+	EventID    string
+	EventType  string
+	Payload    string
+	Status     string
+	Attempts   int
+	HTTPStatus int
+	Error      string
+	DurationMs int64
+	CreatedAt  string
+	UpdatedAt  string
+}
+
+const webhookTargetColumns = "id, name, url, secret, events_json, enabled, created_at, updated_at"
+
+func ListWebhookTargets(db *sql.DB) ([]WebhookTargetRow, error) {
+	rows, err := db.Query("SELECT " + webhookTargetColumns + " FROM webhook_targets ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []WebhookTargetRow
+	for rows.Next() {
+		var r WebhookTargetRow
+		if err := rows.Scan(&r.ID, &r.Name, &r.URL, &r.Secret, &r.EventsJSON, &r.Enabled, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func GetWebhookTarget(db *sql.DB, id string) (*WebhookTargetRow, error) {
+	var r WebhookTargetRow
+	err := db.QueryRow("SELECT "+webhookTargetColumns+" FROM webhook_targets WHERE id = ?", id).
+		Scan(&r.ID, &r.Name, &r.URL, &r.Secret, &r.EventsJSON, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func CreateWebhookTarget(db *sql.DB, r WebhookTargetRow) error {
+	_, err := db.Exec(
+		"INSERT INTO webhook_targets ("+webhookTargetColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		r.ID, r.Name, r.URL, r.Secret, r.EventsJSON, r.Enabled, r.CreatedAt, r.UpdatedAt,
+	)
+	return err
+}
+
+func UpdateWebhookTarget(db *sql.DB, r WebhookTargetRow) (bool, error) {
+	res, err := db.Exec(
+		"UPDATE webhook_targets SET name = ?, url = ?, secret = ?, events_json = ?, enabled = ?, updated_at = ? WHERE id = ?",
+		r.Name, r.URL, r.Secret, r.EventsJSON, r.Enabled, r.UpdatedAt, r.ID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func DeleteWebhookTarget(db *sql.DB, id string) (bool, error) {
+	res, err := db.Exec("DELETE FROM webhook_targets WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+const webhookDeliveryColumns = "id, target_id, event_id, event_type, payload, status, attempts, http_status, error, duration_ms, created_at, updated_at"
+
+func InsertWebhookDelivery(db *sql.DB, r WebhookDeliveryRow) error {
+	_, err := db.Exec(
+		"INSERT INTO webhook_deliveries ("+webhookDeliveryColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		r.ID, r.TargetID, r.EventID, r.EventType, r.Payload, r.Status, r.Attempts, r.HTTPStatus, r.Error, r.DurationMs, r.CreatedAt, r.UpdatedAt,
+	)
+	return err
+}
+
+// UpdateWebhookDelivery records the outcome of the latest attempt.
+func UpdateWebhookDelivery(db *sql.DB, r WebhookDeliveryRow) error {
+	_, err := db.Exec(
+		"UPDATE webhook_deliveries SET status = ?, attempts = ?, http_status = ?, error = ?, duration_ms = ?, updated_at = ? WHERE id = ?",
+		r.Status, r.Attempts, r.HTTPStatus, r.Error, r.DurationMs, r.UpdatedAt, r.ID,
+	)
+	return err
+}
+
+// ListWebhookDeliveries returns the newest deliveries of one target first.
+func ListWebhookDeliveries(db *sql.DB, targetID string, limit int) ([]WebhookDeliveryRow, error) {
+	rows, err := db.Query(
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE target_id = ? ORDER BY created_at DESC, rowid DESC LIMIT ?",
+		targetID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []WebhookDeliveryRow
+	for rows.Next() {
+		var r WebhookDeliveryRow
+		if err := rows.Scan(&r.ID, &r.TargetID, &r.EventID, &r.EventType, &r.Payload, &r.Status, &r.Attempts, &r.HTTPStatus,
+			&r.Error, &r.DurationMs, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// PruneWebhookDeliveries keeps the newest keep deliveries of one target.
+func PruneWebhookDeliveries(db *sql.DB, targetID string, keep int) error {
+	_, err := db.Exec(
+		`DELETE FROM webhook_deliveries WHERE target_id = ? AND id NOT IN (
+		   SELECT id FROM webhook_deliveries WHERE target_id = ? ORDER BY created_at DESC, rowid DESC LIMIT ?
+		 )`,
+		targetID, targetID, keep,
+	)
+	return err
+}
@@ -10,6 +10,15 @@ const (
 	REQUnsupportedOperation = "REQ_UNSUPPORTED_OPERATION"
 	REQTooLarge             = "REQ_TOO_LARGE"
 
+	// AUTH_*
+	AUTHUnauthorized       = "AUTH_UNAUTHORIZED"
+	AUTHInvalidCredentials = "AUTH_INVALID_CREDENTIALS"
+	AUTHForbidden          = "AUTH_FORBIDDEN"
+	AUTHSetupRequired      = "AUTH_SETUP_REQUIRED"
+	AUTHAlreadySetUp       = "AUTH_ALREADY_SET_UP"
+	AUTHRateLimited        = "AUTH_RATE_LIMITED"
+	AUTHTokenNotFound      = "AUTH_TOKEN_NOT_FOUND"
+
 	// DB_*
 	DBError               = "DB_ERROR"
 	DBMigrationFailed     = "DB_MIGRATION_FAILED"
@@ -29,6 +38,8 @@ const (
 	SUBFormatUnsupported  = "SUB_FORMAT_UNSUPPORTED"
 	SUBEmptyOutbounds     = "SUB_EMPTY_OUTBOUNDS"
 	SUBReplaceNodesFailed = "SUB_REPLACE_NODES_FAILED"
+	SUBPipelineInvalid    = "SUB_PIPELINE_INVALID"
+	SUBNoCachedBody       = "SUB_NO_CACHED_BODY"
 
 	// NODE_*
 	NODENotFound        = "NODE_NOT_FOUND"
@@ -37,6 +48,19 @@ const (
 	NODEUpdateFailed    = "NODE_UPDATE_FAILED"
 	NODEListFailed      = "NODE_LIST_FAILED"
 
+	// SHARE_*
+	SHARETokenNotFound = "SHARE_TOKEN_NOT_FOUND"
+	SHAREExportFailed  = "SHARE_EXPORT_FAILED"
+
+	// NOTIFY_*
+	NOTIFYChannelNotFound = "NOTIFY_CHANNEL_NOT_FOUND"
+	NOTIFYRuleNotFound    = "NOTIFY_RULE_NOT_FOUND"
+	NOTIFYSendFailed      = "NOTIFY_SEND_FAILED"
+
+	// WEBHOOK_*
+	WEBHOOKNotFound   = "WEBHOOK_NOT_FOUND"
+	WEBHOOKSendFailed = "WEBHOOK_SEND_FAILED"
+
 	// CFG_*
 	CFGBuildFailed    = "CFG_BUILD_FAILED"
 	CFGNoEnabledNodes = "CFG_NO_ENABLED_NODES"
@@ -47,10 +71,12 @@ const (
 	CFGCheckFailed    = "CFG_CHECK_FAILED"
 
 	// RT_*
-	RTRestartFailed = "RT_RESTART_FAILED"
-	RTStartFailed   = "RT_START_FAILED"
-	RTStopFailed    = "RT_STOP_FAILED"
-	RTStatusFailed  = "RT_STATUS_FAILED"
+	RTRestartFailed       = "RT_RESTART_FAILED"
+	RTStartFailed         = "RT_START_FAILED"
+	RTStopFailed          = "RT_STOP_FAILED"
+	RTStatusFailed        = "RT_STATUS_FAILED"
+	RTClashAPIUnavailable = "RT_CLASH_API_UNAVAILABLE"
+	RTConnectionNotFound  = "RT_CONNECTION_NOT_FOUND"
 
 	// JOB_*
 	JOBReloadInProgress  = "JOB_RELOAD_IN_PROGRESS"
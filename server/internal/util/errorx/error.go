@@ -28,19 +28,30 @@ func (e *AppError) HTTPStatus() int {
 	case e.Code == REQBadRequest || e.Code == REQValidationFailed || e.Code == REQMissingField ||
 		e.Code == REQInvalidField || e.Code == REQUnsupportedOperation || e.Code == SUBInvalidURL ||
 		e.Code == SUBParseFailed || e.Code == SUBFormatUnsupported || e.Code == SUBEmptyOutbounds ||
-		e.Code == NODEInvalidOutbound:
+		e.Code == SUBPipelineInvalid || e.Code == NODEInvalidOutbound:
 		return http.StatusBadRequest
 	case e.Code == REQTooLarge || e.Code == SUBResponseTooLarge:
 		return http.StatusRequestEntityTooLarge
-	case e.Code == DBNotFound || e.Code == SUBNotFound || e.Code == NODENotFound:
+	case e.Code == AUTHUnauthorized || e.Code == AUTHInvalidCredentials || e.Code == AUTHSetupRequired:
+		return http.StatusUnauthorized
+	case e.Code == AUTHForbidden:
+		return http.StatusForbidden
+	case e.Code == DBNotFound || e.Code == SUBNotFound || e.Code == SUBNoCachedBody ||
+		e.Code == NODENotFound || e.Code == SHARETokenNotFound || e.Code == NOTIFYChannelNotFound ||
+		e.Code == NOTIFYRuleNotFound || e.Code == WEBHOOKNotFound || e.Code == RTConnectionNotFound ||
+		e.Code == AUTHTokenNotFound:
 		return http.StatusNotFound
 	case e.Code == DBConstraintViolation || e.Code == SUBDisabled || e.Code == NODETagConflict ||
-		e.Code == CFGNoEnabledNodes || e.Code == JOBReloadInProgress || e.Code == JOBRefreshInProgress:
+		e.Code == CFGNoEnabledNodes || e.Code == JOBReloadInProgress || e.Code == JOBRefreshInProgress ||
+		e.Code == AUTHAlreadySetUp:
 		return http.StatusConflict
-	case e.Code == JOBRateLimited:
+	case e.Code == JOBRateLimited || e.Code == AUTHRateLimited:
 		return http.StatusTooManyRequests
-	case e.Code == SUBFetchFailed || e.Code == SUBFetchTimeout || e.Code == SUBHTTPStatusError:
+	case e.Code == SUBFetchFailed || e.Code == SUBFetchTimeout || e.Code == SUBHTTPStatusError ||
+		e.Code == NOTIFYSendFailed || e.Code == WEBHOOKSendFailed:
 		return http.StatusBadGateway
+	case e.Code == RTClashAPIUnavailable:
+		return http.StatusServiceUnavailable
 	case e.Code == NotImplemented:
 		return http.StatusNotImplemented
 	default:
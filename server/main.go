@@ -2,17 +2,28 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
 
 	"boxpilot/server/internal/api"
+	"boxpilot/server/internal/observability"
 	"boxpilot/server/internal/service"
 	"boxpilot/server/internal/store"
 )
 
 func main() {
+	logCfg, err := observability.LogConfigFromEnv()
+	if err != nil {
+		fatal("logging config", err)
+	}
+	logFile, err := observability.SetupLogging(logCfg)
+	if err != nil {
+		fatal("open log file", err)
+	}
+	defer logFile.Close()
+
 	dbPath := os.Getenv("DB_PATH")
 	if dbPath == "" {
 		if stat, err := os.Stat("/data"); err == nil && stat.IsDir() {
@@ -22,24 +33,36 @@ func main() {
 		}
 	}
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-		log.Fatalf("prepare db dir: %v", err)
+		fatal("prepare db dir", err)
 	}
 	db, err := store.Open(dbPath)
 	if err != nil {
-		log.Fatalf("db: %v", err)
+		fatal("open db", err)
 	}
 	defer db.Close()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	if err := service.BootstrapAuth(ctx, db.DB); err != nil {
+		fatal("bootstrap auth", err)
+	}
 	go service.StartSubscriptionScheduler(ctx, db.DB, 30*time.Second)
+	go service.StartAlertMonitor(ctx, db.DB, time.Minute)
+	go service.StartWebhookDispatcher(ctx, db.DB)
+	go service.StartRuntimeLogCollector(ctx)
+	go service.StartTrafficSampler(ctx, db.DB)
 
 	addr := ":8080"
 	if a := os.Getenv("ADDR"); a != "" {
 		addr = a
 	}
 	r := api.Router(db.DB)
-	log.Printf("listen %s", addr)
+	slog.Info("listen", "addr", addr)
 	if err := r.Run(addr); err != nil {
-		log.Fatal(err)
+		fatal("serve", err)
 	}
 }
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
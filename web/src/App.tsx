@@ -14,6 +14,7 @@ import { Button, Input, Skeleton, Tag } from "antd";
 import { useI18n } from "./i18n/context";
 import LocaleSwitcher from "./components/common/LocaleSwitcher";
 import { useRuntimeGroups, useRuntimeProxyCheck } from "./hooks/useRuntime";
+import { useAuthStatus, useLogout } from "./hooks/useAuth";
 import { formatDateTime } from "./utils/datetime";
 
 export default function App() {
@@ -40,6 +41,9 @@ export default function App() {
   const startForwarding = useStartForwardingRuntime();
   const stopForwarding = useStopForwardingRuntime();
   const proxyCheck = useRuntimeProxyCheck();
+  const { data: authStatus } = useAuthStatus();
+  const logoutMutation = useLogout();
+  const signedInUser = authStatus?.principal?.kind === "session" ? authStatus.principal.username : undefined;
   const [proxyCheckTarget, setProxyCheckTarget] = useState("https://www.gstatic.com/generate_204");
   const toggling = startForwarding.isPending || stopForwarding.isPending;
   const isRunning = !!summary?.running;
@@ -322,6 +326,20 @@ export default function App() {
                 <path d="M19.14 12.94a7.8 7.8 0 0 0 .05-.94 7.8 7.8 0 0 0-.05-.94l2.03-1.58a.5.5 0 0 0 .12-.65l-1.92-3.32a.5.5 0 0 0-.61-.22l-2.39.96a7.4 7.4 0 0 0-1.62-.94l-.36-2.54a.5.5 0 0 0-.5-.43h-3.84a.5.5 0 0 0-.5.43l-.36 2.54a7.4 7.4 0 0 0-1.62.94l-2.39-.96a.5.5 0 0 0-.61.22L2.71 8.83a.5.5 0 0 0 .12.65l2.03 1.58a7.8 7.8 0 0 0-.05.94 7.8 7.8 0 0 0 .05.94l-2.03 1.58a.5.5 0 0 0-.12.65l1.92 3.32a.5.5 0 0 0 .61.22l2.39-.96c.5.39 1.05.71 1.62.94l.36 2.54a.5.5 0 0 0 .5.43h3.84a.5.5 0 0 0 .5-.43l.36-2.54c.57-.23 1.12-.55 1.62-.94l2.39.96a.5.5 0 0 0 .61-.22l1.92-3.32a.5.5 0 0 0-.12-.65zM12 15.2a3.2 3.2 0 1 1 0-6.4 3.2 3.2 0 0 1 0 6.4z" />
               </svg>
             </NavLink>
+            {signedInUser ? (
+              <button
+                type="button"
+                className="bp-settings-link"
+                aria-label={tr("nav.logout", "Sign out")}
+                title={tr("nav.logout.user", "Sign out {user}", { user: signedInUser })}
+                disabled={logoutMutation.isPending}
+                onClick={() => logoutMutation.mutate()}
+              >
+                <svg viewBox="0 0 24 24" aria-hidden="true">
+                  <path d="M10 3H5a2 2 0 0 0-2 2v14a2 2 0 0 0 2 2h5v-2H5V5h5V3zm6.59 4.59L15.17 9l2 2H9v2h8.17l-2 2 1.42 1.41L21 12l-4.41-4.41z" />
+                </svg>
+              </button>
+            ) : null}
           </div>
         </nav>
         <main className="bp-main">
@@ -0,0 +1,55 @@
+import { api } from "./client";
+import type { ApiToken, AuthScope, AuthStatus, CreatedApiToken, LoginResult } from "./types";
+
+/** Public: tells whether to show setup, login or the app. */
+export async function getAuthStatus(): Promise<AuthStatus> {
+  const { data } = await api.get<{ data: AuthStatus }>("/auth/status");
+  return data.data;
+}
+
+export interface SetupBody {
+  /** Printed in the server log on first start, or BOXPILOT_SETUP_TOKEN. */
+  setup_token: string;
+  username: string;
+  password: string;
+}
+
+export async function setupAdmin(body: SetupBody): Promise<LoginResult> {
+  const { data } = await api.post<{ data: LoginResult }>("/auth/setup", body);
+  return data.data;
+}
+
+export async function login(body: { username: string; password: string }): Promise<LoginResult> {
+  const { data } = await api.post<{ data: LoginResult }>("/auth/login", body);
+  return data.data;
+}
+
+export async function logout(): Promise<void> {
+  await api.post("/auth/logout", {});
+}
+
+/** Signs out every other session of the user. */
+export async function changePassword(body: { current_password: string; new_password: string }): Promise<void> {
+  await api.post("/auth/password", body);
+}
+
+export async function getApiTokens(): Promise<ApiToken[]> {
+  const { data } = await api.get<{ data: ApiToken[] }>("/auth/tokens");
+  return data.data;
+}
+
+export interface CreateApiTokenBody {
+  name: string;
+  scope: AuthScope;
+  /** 0 never expires. */
+  expires_in_days?: number;
+}
+
+export async function createApiToken(body: CreateApiTokenBody): Promise<CreatedApiToken> {
+  const { data } = await api.post<{ data: CreatedApiToken }>("/auth/tokens/create", body);
+  return data.data;
+}
+
+export async function deleteApiToken(id: string): Promise<void> {
+  await api.post("/auth/tokens/delete", { id });
+}
@@ -10,5 +10,20 @@ function resolveApiBaseURL(): string {
 
 const baseURL = resolveApiBaseURL();
 
-export const api = axios.create({ baseURL, headers: { "Content-Type": "application/json" } });
-api.interceptors.response.use((r) => r, (e) => { if (e.response?.data?.error) e.appError = e.response.data.error; return Promise.reject(e); });
+// withCredentials sends the session cookie when VITE_API_ORIGIN points at another origin
+// (listed in the server's BOXPILOT_CORS_ORIGINS).
+export const api = axios.create({ baseURL, withCredentials: true, headers: { "Content-Type": "application/json" } });
+
+const unauthorizedListeners = new Set<() => void>();
+
+/** Runs listener when an API call fails with 401, e.g. after the session expired. Returns an unsubscribe function. */
+export function onUnauthorized(listener: () => void): () => void {
+  unauthorizedListeners.add(listener);
+  return () => unauthorizedListeners.delete(listener);
+}
+
+api.interceptors.response.use((r) => r, (e) => {
+  if (e.response?.data?.error) e.appError = e.response.data.error;
+  if (e.response?.status === 401 && !String(e.config?.url ?? "").startsWith("/auth/")) unauthorizedListeners.forEach((l) => l());
+  return Promise.reject(e);
+});
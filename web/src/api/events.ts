@@ -0,0 +1,91 @@
+import { api } from "./client";
+import type { EventStreamTopic, RuntimeLogItem, StreamEvent } from "./types";
+
+const streamEventTypes: StreamEvent["type"][] = [
+  "subscription.refreshed",
+  "nodes.changed",
+  "config.applied",
+  "reload.failed",
+  "rollback.performed",
+  "group.selection_changed",
+  "forwarding.started",
+  "forwarding.stopped",
+  "reload.progress",
+  "subscription.refresh_progress",
+  "subscription.refresh_failed",
+  "node.probe",
+  "traffic.sample",
+  "subscribed",
+  "heartbeat",
+  "error",
+];
+
+export interface EventStreamOptions {
+  topics?: EventStreamTopic[];
+  trafficIntervalMs?: number;
+  onEvent: (event: StreamEvent) => void;
+  onOpenChange?: (open: boolean) => void;
+}
+
+/** Opens GET /events (Server-Sent Events); the browser reconnects on its own. Returns a close function. */
+export function openEventStream(opts: EventStreamOptions): () => void {
+  const params = new URLSearchParams();
+  if (opts.topics?.length) params.set("topics", opts.topics.join(","));
+  if (opts.trafficIntervalMs) params.set("traffic_interval_ms", String(opts.trafficIntervalMs));
+  const base = api.defaults.baseURL ?? "/api/v1";
+  const query = params.toString();
+  const source = new EventSource(`${base}/events${query ? `?${query}` : ""}`, { withCredentials: true });
+
+  const handle = (msg: MessageEvent<string>) => {
+    try {
+      opts.onEvent(JSON.parse(msg.data) as StreamEvent);
+    } catch {
+      // Ignore malformed frames.
+    }
+  };
+  streamEventTypes.forEach((type) => source.addEventListener(type, handle as EventListener));
+  source.onopen = () => opts.onOpenChange?.(true);
+  source.onerror = () => opts.onOpenChange?.(source.readyState === EventSource.OPEN);
+
+  return () => {
+    source.close();
+    opts.onOpenChange?.(false);
+  };
+}
+
+export interface RuntimeLogStreamOptions {
+  level?: string;
+  q?: string;
+  tail?: number;
+  onLog: (item: RuntimeLogItem) => void;
+  onOpenChange?: (open: boolean) => void;
+}
+
+/**
+ * Opens GET /runtime/logs/stream (Server-Sent Events). On reconnect the browser
+ * sends Last-Event-ID, so missed lines are replayed. Returns a close function.
+ */
+export function openRuntimeLogStream(opts: RuntimeLogStreamOptions): () => void {
+  const params = new URLSearchParams();
+  if (opts.level && opts.level !== "all") params.set("level", opts.level);
+  if (opts.q) params.set("q", opts.q);
+  if (opts.tail !== undefined) params.set("tail", String(opts.tail));
+  const base = api.defaults.baseURL ?? "/api/v1";
+  const query = params.toString();
+  const source = new EventSource(`${base}/runtime/logs/stream${query ? `?${query}` : ""}`, { withCredentials: true });
+
+  source.addEventListener("log", ((msg: MessageEvent<string>) => {
+    try {
+      opts.onLog(JSON.parse(msg.data) as RuntimeLogItem);
+    } catch {
+      // Ignore malformed frames.
+    }
+  }) as EventListener);
+  source.onopen = () => opts.onOpenChange?.(true);
+  source.onerror = () => opts.onOpenChange?.(source.readyState === EventSource.OPEN);
+
+  return () => {
+    source.close();
+    opts.onOpenChange?.(false);
+  };
+}
@@ -1,5 +1,5 @@
 import { api } from "./client";
-import type { NodeForwardingData, ProxyType } from "./types";
+import type { NodeDuplicateCluster, NodeForwardingData, ProxyType } from "./types";
 
 export async function getNodeForwarding(nodeId: string): Promise<NodeForwardingData> {
   const { data } = await api.get<{ data: NodeForwardingData }>("/nodes/forwarding", {
@@ -29,3 +29,12 @@ export async function updateNodeForwarding(
 export async function restartNodeForwarding(nodeId: string): Promise<void> {
   await api.post("/nodes/forwarding/restart", { node_id: nodeId });
 }
+
+export async function getNodeDuplicates(): Promise<NodeDuplicateCluster[]> {
+  const { data } = await api.get<{ data: NodeDuplicateCluster[] }>("/nodes/duplicates");
+  return data.data;
+}
+
+export async function preferNodeDuplicate(fingerprint: string, subId: string): Promise<void> {
+  await api.post("/nodes/duplicates/prefer", { fingerprint, sub_id: subId });
+}
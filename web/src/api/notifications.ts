@@ -0,0 +1,80 @@
+import { api } from "./client";
+import type {
+  NotificationAlert,
+  NotificationChannel,
+  NotificationChannelType,
+  NotificationRule,
+  NotificationRuleKind,
+} from "./types";
+
+export async function getNotificationChannels(): Promise<NotificationChannel[]> {
+  const { data } = await api.get<{ data: NotificationChannel[] }>("/notifications/channels");
+  return data.data;
+}
+
+export interface NotificationChannelBody {
+  name?: string;
+  type: NotificationChannelType;
+  enabled?: boolean;
+  config: Record<string, unknown>;
+}
+
+export async function createNotificationChannel(body: NotificationChannelBody): Promise<NotificationChannel> {
+  const { data } = await api.post<{ data: NotificationChannel }>("/notifications/channels/create", body);
+  return data.data;
+}
+
+export async function updateNotificationChannel(
+  body: { id: string } & Partial<NotificationChannelBody>,
+): Promise<NotificationChannel> {
+  const { data } = await api.post<{ data: NotificationChannel }>("/notifications/channels/update", body);
+  return data.data;
+}
+
+export async function deleteNotificationChannel(id: string): Promise<void> {
+  await api.post("/notifications/channels/delete", { id });
+}
+
+/** Sends a sample alert through a saved channel (id) or an unsaved type + config. */
+export async function testNotificationChannel(
+  body: { id: string } | { type: NotificationChannelType; config: Record<string, unknown> },
+): Promise<void> {
+  await api.post("/notifications/channels/test", body);
+}
+
+export async function getNotificationRules(): Promise<NotificationRule[]> {
+  const { data } = await api.get<{ data: NotificationRule[] }>("/notifications/rules");
+  return data.data;
+}
+
+export interface NotificationRuleBody {
+  name?: string;
+  kind: NotificationRuleKind;
+  enabled?: boolean;
+  threshold?: number;
+  cooldown_sec?: number;
+  channel_ids?: string[];
+}
+
+export async function createNotificationRule(body: NotificationRuleBody): Promise<NotificationRule> {
+  const { data } = await api.post<{ data: NotificationRule }>("/notifications/rules/create", body);
+  return data.data;
+}
+
+export async function updateNotificationRule(
+  body: { id: string } & Partial<NotificationRuleBody>,
+): Promise<NotificationRule> {
+  const { data } = await api.post<{ data: NotificationRule }>("/notifications/rules/update", body);
+  return data.data;
+}
+
+export async function deleteNotificationRule(id: string): Promise<void> {
+  await api.post("/notifications/rules/delete", { id });
+}
+
+export async function getNotificationAlerts(ruleId?: string): Promise<NotificationAlert[]> {
+  const { data } = await api.get<{ data: NotificationAlert[] }>("/notifications/alerts", {
+    params: ruleId ? { rule_id: ruleId } : undefined,
+  });
+  return data.data;
+}
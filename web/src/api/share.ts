@@ -0,0 +1,20 @@
+import { api } from "./client";
+import type { CreatedShareToken, ShareToken } from "./types";
+
+export async function getShareTokens(): Promise<ShareToken[]> {
+  const { data } = await api.get<{ data: ShareToken[] }>("/share/tokens");
+  return data.data;
+}
+
+export async function createShareToken(name: string): Promise<CreatedShareToken> {
+  const { data } = await api.post<{ data: CreatedShareToken }>("/share/tokens/create", { name });
+  return data.data;
+}
+
+export async function updateShareToken(id: string, enabled: boolean): Promise<void> {
+  await api.post("/share/tokens/update", { id, enabled });
+}
+
+export async function deleteShareToken(id: string): Promise<void> {
+  await api.post("/share/tokens/delete", { id });
+}
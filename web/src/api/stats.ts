@@ -0,0 +1,12 @@
+import { api } from "./client";
+import type { TrafficStatsData, TrafficStatsDimension } from "./types";
+
+/** Reads persisted traffic rollups; range is like 24h, 7d or 30d. */
+export async function getTrafficStats(params: {
+  by: TrafficStatsDimension;
+  range: string;
+  key?: string;
+}): Promise<TrafficStatsData> {
+  const { data } = await api.get<{ data: TrafficStatsData }>("/stats/traffic", { params });
+  return data.data;
+}
@@ -1,5 +1,14 @@
 import { api } from "./client";
-import type { Subscription } from "./types";
+import type {
+  Subscription,
+  SubscriptionFetchOptions,
+  SubscriptionInlineContent,
+  SubscriptionPipeline,
+  SubscriptionPipelinePreview,
+  SubscriptionRefreshHistoryEntry,
+  SubscriptionSchedule,
+  SubscriptionSourceType,
+} from "./types";
 
 export async function getSubscriptions(): Promise<Subscription[]> {
   const { data } = await api.get<{ data: Subscription[] }>("/subscriptions");
@@ -7,11 +16,15 @@ export async function getSubscriptions(): Promise<Subscription[]> {
 }
 
 export interface CreateSubscriptionBody {
-  url: string;
+  url?: string;
   name?: string;
   type?: string;
+  source_type?: SubscriptionSourceType;
+  content?: string;
   auto_update_enabled?: boolean;
   refresh_interval_sec?: number;
+  follow_provider_interval?: boolean;
+  fetch_options?: Partial<SubscriptionFetchOptions>;
 }
 
 export async function createSubscription(body: CreateSubscriptionBody): Promise<Subscription> {
@@ -29,20 +42,43 @@ export interface UpdateSubscriptionBody {
   enabled?: boolean;
   auto_update_enabled?: boolean;
   refresh_interval_sec?: number;
+  follow_provider_interval?: boolean;
+  content?: string;
+  fetch_options?: Partial<SubscriptionFetchOptions>;
 }
 
 export async function updateSubscription(body: UpdateSubscriptionBody): Promise<Subscription> {
-  const { id, name, url, enabled, auto_update_enabled, refresh_interval_sec } = body;
+  const {
+    id,
+    name,
+    url,
+    enabled,
+    auto_update_enabled,
+    refresh_interval_sec,
+    follow_provider_interval,
+    content,
+    fetch_options,
+  } = body;
   const payload: any = { id };
   if (name !== undefined) payload.name = name;
   if (url !== undefined) payload.url = url;
   if (enabled !== undefined) payload.enabled = enabled;
   if (auto_update_enabled !== undefined) payload.auto_update_enabled = auto_update_enabled;
   if (refresh_interval_sec !== undefined) payload.refresh_interval_sec = refresh_interval_sec;
+  if (follow_provider_interval !== undefined) payload.follow_provider_interval = follow_provider_interval;
+  if (content !== undefined) payload.content = content;
+  if (fetch_options !== undefined) payload.fetch_options = fetch_options;
   const { data } = await api.post<{ data: Subscription }>("/subscriptions/update", payload);
   return data.data;
 }
 
+export async function getSubscriptionContent(subId: string): Promise<SubscriptionInlineContent> {
+  const { data } = await api.get<{ data: SubscriptionInlineContent }>("/subscriptions/content", {
+    params: { sub_id: subId },
+  });
+  return data.data;
+}
+
 export async function deleteSubscription(id: string): Promise<void> {
   await api.post("/subscriptions/delete", { id });
 }
@@ -52,6 +88,9 @@ export interface RefreshSubscriptionResult {
   not_modified: boolean;
   nodes_total: number;
   nodes_enabled: number;
+  nodes_added?: number;
+  nodes_updated?: number;
+  nodes_removed?: number;
   fetched_at: string;
 }
 
@@ -59,3 +98,42 @@ export async function refreshSubscription(id: string): Promise<RefreshSubscripti
   const { data } = await api.post<RefreshSubscriptionResult>("/subscriptions/refresh", { id });
   return data;
 }
+
+export async function getSubscriptionSchedule(): Promise<SubscriptionSchedule[]> {
+  const { data } = await api.get<{ data: SubscriptionSchedule[] }>("/subscriptions/schedule");
+  return data.data;
+}
+
+export async function getSubscriptionHistory(
+  id: string,
+  limit?: number,
+): Promise<SubscriptionRefreshHistoryEntry[]> {
+  const { data } = await api.get<{ data: SubscriptionRefreshHistoryEntry[] }>(
+    `/subscriptions/${encodeURIComponent(id)}/history`,
+    { params: limit ? { limit } : undefined },
+  );
+  return data.data;
+}
+
+export async function getSubscriptionPipeline(subId: string): Promise<SubscriptionPipeline> {
+  const { data } = await api.get<{ data: SubscriptionPipeline }>("/subscriptions/pipeline", {
+    params: { sub_id: subId },
+  });
+  return data.data;
+}
+
+export async function updateSubscriptionPipeline(body: SubscriptionPipeline): Promise<SubscriptionPipeline> {
+  const { data } = await api.post<{ data: SubscriptionPipeline }>("/subscriptions/pipeline/update", body);
+  return data.data;
+}
+
+export async function previewSubscriptionPipeline(
+  subId: string,
+  pipeline?: SubscriptionPipeline,
+): Promise<SubscriptionPipelinePreview> {
+  const { data } = await api.post<{ data: SubscriptionPipelinePreview }>("/subscriptions/pipeline/preview", {
+    sub_id: subId,
+    pipeline,
+  });
+  return data.data;
+}
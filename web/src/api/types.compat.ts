@@ -1,8 +1,11 @@
+export type SubscriptionSourceType = "url" | "file" | "inline";
+
 export type Subscription = {
   id: string;
   name: string;
   url: string;
   type: string;
+  source_type?: SubscriptionSourceType;
   enabled: boolean;
   refresh_interval_sec: number;
   created_at: string;
@@ -16,6 +19,106 @@ export type Subscription = {
   usage_percent?: number;
   expire_at?: string | null;
   profile_web_page?: string | null;
+  profile_update_interval_sec?: number | null;
+  follow_provider_interval?: boolean;
+  expired?: boolean;
+  warnings?: SubscriptionUsageWarning[];
+  fetch_options?: SubscriptionFetchOptions;
+};
+
+export type SubscriptionUsageWarning = "expired" | "quota_low";
+
+export type SubscriptionFetchOptions = {
+  user_agent_preset: "default" | "clash" | "sing-box" | "v2rayn" | "custom";
+  user_agent?: string;
+  headers: Record<string, string>;
+  timeout_sec: number;
+  max_body_bytes: number;
+  fetch_via: "direct" | "http_inbound" | "socks_inbound" | "node";
+  fetch_via_node_tag?: string;
+};
+
+export type SubscriptionSchedule = {
+  sub_id: string;
+  name: string;
+  source_type: SubscriptionSourceType;
+  scheduled: boolean;
+  running: boolean;
+  expired?: boolean;
+  interval_sec: number;
+  interval_source?: "subscription" | "provider";
+  jitter_sec: number;
+  failure_streak: number;
+  backoff_sec: number;
+  last_fetch_at?: string;
+  last_error?: string;
+  next_run_at?: string;
+};
+
+export type SubscriptionInlineContent = {
+  sub_id: string;
+  content: string;
+  updated_at?: string;
+};
+
+export type SubscriptionPipeline = {
+  sub_id: string;
+  include_regex: string;
+  exclude_regex: string;
+  type_allowlist: string[];
+  rename_rules: { pattern: string; replace: string }[];
+  emoji_flag: boolean;
+  name_template: string;
+  updated_at?: string;
+};
+
+export type SubscriptionPipelineEntry = {
+  source_name: string;
+  name: string;
+  type: string;
+  dropped: boolean;
+  reason?: string;
+};
+
+export type SubscriptionPipelinePreview = {
+  sub_id: string;
+  format: string;
+  kept: number;
+  dropped: number;
+  entries: SubscriptionPipelineEntry[];
+};
+
+export type SubscriptionNodeDiffEntry = {
+  tag: string;
+  previous_tag?: string;
+  name: string;
+  type: string;
+  server?: string;
+  server_port?: number;
+  changes?: ("tag" | "name" | "endpoint" | "options")[];
+};
+
+export type SubscriptionRefreshHistoryEntry = {
+  id: string;
+  sub_id: string;
+  started_at: string;
+  duration_ms: number;
+  http_status?: number;
+  bytes: number;
+  format?: string;
+  success: boolean;
+  not_modified: boolean;
+  error_code?: string;
+  error?: string;
+  nodes_total: number;
+  nodes_added: number;
+  nodes_updated: number;
+  nodes_removed: number;
+  diff?: {
+    added: SubscriptionNodeDiffEntry[];
+    removed: SubscriptionNodeDiffEntry[];
+    changed: SubscriptionNodeDiffEntry[];
+  };
 };
 
 export type Node = {
@@ -26,6 +129,7 @@ export type Node = {
   type: string;
   enabled: boolean;
   forwarding_enabled: boolean;
+  name_locked?: boolean;
   created_at: string;
   server?: string;
   server_port?: number;
@@ -122,36 +226,71 @@ export type RuntimeTrafficData = {
 
 export type RuntimeConnection = {
   id: string;
-  node_id: string;
-  node_name: string;
-  node_type: string;
-  target: string;
-  status: string;
-  last_test_at?: string | null;
-  latency_ms?: number | null;
-  error?: string | null;
-  forwarding: boolean;
-  last_updated: string;
+  network: string;
+  inbound: string;
+  source: string;
+  destination: string;
+  host?: string;
+  destination_ip?: string;
+  process?: string;
+  rule: string;
+  rule_payload?: string;
+  /** Outbound that carried the connection; the last element of chain. */
+  outbound: string;
+  /** From the outbound the route picked to the final outbound. */
+  chain: string[];
+  node_id?: string;
+  node_name?: string;
+  upload_bytes: number;
+  download_bytes: number;
+  started_at: string;
+  duration_ms: number;
 };
 
 export type RuntimeConnectionsData = {
+  source: "singbox_clash_api" | "singbox_clash_api_disabled" | "singbox_clash_api_unavailable";
+  error?: string;
   active_count: number;
+  matched: number;
+  upload_total: number;
+  download_total: number;
   items: RuntimeConnection[];
 };
 
 export type RuntimeLogItem = {
+  seq: number;
   timestamp: string;
   level: string;
   source: string;
   message: string;
 };
 
+export type RuntimeLogCollector = {
+  source: "clash_api" | "file" | "disabled";
+  target?: string;
+  connected: boolean;
+  last_error?: string;
+};
+
 export type RuntimeLogsData = {
   items: RuntimeLogItem[];
+  last_seq: number;
+  counts: Record<string, number>;
+  collector: RuntimeLogCollector;
+};
+
+export type RuntimeGroupPolicy = {
+  type: string;
+  url?: string;
+  interval?: string;
+  tolerance?: number;
+  strategy?: string;
+  unsupported?: string;
 };
 
 export type RuntimeGroupItem = {
   tag: string;
+  display_name?: string;
   type: string;
   outbounds: string[];
   default: string;
@@ -162,6 +301,7 @@ export type RuntimeGroupItem = {
   runtime_effective_outbound?: string;
   persisted_selected_outbound?: string;
   persisted_updated_at?: string;
+  policy?: RuntimeGroupPolicy;
 };
 
 export type RuntimeGroupSummaryData = {
@@ -245,3 +385,197 @@ export type RoutingSettingsData = {
   bypass_cidrs: string[];
   updated_at?: string;
 };
+
+export type ShareToken = {
+  id: string;
+  name: string;
+  prefix: string;
+  enabled: boolean;
+  created_at: string;
+  last_used_at?: string;
+};
+
+export type CreatedShareToken = ShareToken & {
+  /** Only returned once, when the token is created. */
+  token: string;
+};
+
+export type NodeDuplicateCluster = {
+  fingerprint: string;
+  preferred_sub_id?: string;
+  canonical_sub_id: string;
+  canonical_node_id: string;
+  nodes: Node[];
+};
+
+export type NotificationChannelType = "webhook" | "telegram" | "smtp" | "ntfy";
+
+export type NotificationChannel = {
+  id: string;
+  name: string;
+  type: NotificationChannelType;
+  enabled: boolean;
+  config: Record<string, unknown>;
+  created_at: string;
+  updated_at: string;
+};
+
+export type NotificationRuleKind =
+  | "quota_low"
+  | "expiring"
+  | "expired"
+  | "refresh_failed"
+  | "reload_failed"
+  | "nodes_unhealthy";
+
+export type NotificationRule = {
+  id: string;
+  name: string;
+  kind: NotificationRuleKind;
+  enabled: boolean;
+  threshold: number;
+  cooldown_sec: number;
+  channel_ids: string[];
+  created_at: string;
+  updated_at: string;
+};
+
+export type NotificationAlert = {
+  rule_id: string;
+  subject: string;
+  subject_name?: string;
+  message: string;
+  first_seen_at: string;
+  last_sent_at?: string;
+  last_error?: string;
+};
+
+export type ControlPlaneEventType =
+  | "subscription.refreshed"
+  | "nodes.changed"
+  | "config.applied"
+  | "reload.failed"
+  | "rollback.performed"
+  | "group.selection_changed"
+  | "forwarding.started"
+  | "forwarding.stopped";
+
+export type ControlPlaneEvent = {
+  id: string;
+  type: ControlPlaneEventType | "webhook.test";
+  at: string;
+  data?: Record<string, unknown>;
+};
+
+export type Webhook = {
+  id: string;
+  name: string;
+  url: string;
+  secret: string;
+  events: ControlPlaneEventType[];
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+};
+
+export type WebhookDelivery = {
+  id: string;
+  webhook_id: string;
+  event_id: string;
+  event_type: string;
+  payload: ControlPlaneEvent | null;
+  status: "pending" | "success" | "failed";
+  attempts: number;
+  http_status: number;
+  error?: string;
+  duration_ms: number;
+  created_at: string;
+  updated_at: string;
+};
+
+export type EventStreamTopic = "control" | "reload" | "refresh" | "probe" | "traffic";
+
+export type StreamEvent = {
+  /** Empty for the stream-only subscribed / heartbeat / error messages. */
+  id?: string;
+  type:
+    | ControlPlaneEventType
+    | "reload.progress"
+    | "subscription.refresh_progress"
+    | "subscription.refresh_failed"
+    | "node.probe"
+    | "traffic.sample"
+    | "subscribed"
+    | "heartbeat"
+    | "error";
+  at: string;
+  data?: Record<string, unknown>;
+};
+
+export type TrafficStatsDimension = "node" | "group" | "inbound";
+
+export type TrafficStatsItem = {
+  key: string;
+  node_id?: string;
+  name?: string;
+  sub_id?: string;
+  sub_name?: string;
+  upload_bytes: number;
+  download_bytes: number;
+  total_bytes: number;
+  connections: number;
+};
+
+export type TrafficStatsPoint = {
+  period_start: string;
+  upload_bytes: number;
+  download_bytes: number;
+  connections: number;
+};
+
+export type TrafficStatsData = {
+  by: TrafficStatsDimension;
+  range: string;
+  bucket: "hour" | "day";
+  from: string;
+  to: string;
+  upload_bytes: number;
+  download_bytes: number;
+  items: TrafficStatsItem[];
+  series: TrafficStatsPoint[];
+};
+
+export type AuthScope = "read-only" | "operator" | "admin";
+
+export type AuthPrincipal = {
+  kind: "session" | "token" | "anonymous";
+  scope: AuthScope;
+  username?: string;
+  token_name?: string;
+};
+
+export type AuthStatus = {
+  enabled: boolean;
+  setup_required: boolean;
+  principal?: AuthPrincipal;
+};
+
+export type LoginResult = {
+  username: string;
+  expires_at: string;
+};
+
+export type ApiToken = {
+  id: string;
+  name: string;
+  scope: AuthScope;
+  prefix: string;
+  created_at: string;
+  expires_at?: string;
+  last_used_at?: string;
+};
+
+export type CreatedApiToken = ApiToken & {
+  /** Only returned once, when the token is created. */
+  token: string;
+};
@@ -0,0 +1,44 @@
+import { api } from "./client";
+import type { ControlPlaneEventType, Webhook, WebhookDelivery } from "./types";
+
+export async function getWebhooks(): Promise<Webhook[]> {
+  const { data } = await api.get<{ data: Webhook[] }>("/webhooks");
+  return data.data;
+}
+
+export interface WebhookBody {
+  name?: string;
+  url: string;
+  /** Empty generates a random secret; on update it rotates the secret. */
+  secret?: string;
+  /** Empty subscribes to every event type. */
+  events?: ControlPlaneEventType[];
+  enabled?: boolean;
+}
+
+export async function createWebhook(body: WebhookBody): Promise<Webhook> {
+  const { data } = await api.post<{ data: Webhook }>("/webhooks/create", body);
+  return data.data;
+}
+
+export async function updateWebhook(body: { id: string } & Partial<WebhookBody>): Promise<Webhook> {
+  const { data } = await api.post<{ data: Webhook }>("/webhooks/update", body);
+  return data.data;
+}
+
+export async function deleteWebhook(id: string): Promise<void> {
+  await api.post("/webhooks/delete", { id });
+}
+
+/** Sends one webhook.test event, without retries, and returns the logged delivery. */
+export async function testWebhook(id: string): Promise<WebhookDelivery> {
+  const { data } = await api.post<{ data: WebhookDelivery }>("/webhooks/test", { id });
+  return data.data;
+}
+
+export async function getWebhookDeliveries(id: string, limit?: number): Promise<WebhookDelivery[]> {
+  const { data } = await api.get<{ data: WebhookDelivery[] }>(`/webhooks/${encodeURIComponent(id)}/deliveries`, {
+    params: limit ? { limit } : undefined,
+  });
+  return data.data;
+}
@@ -0,0 +1,159 @@
+import { useEffect, type ReactNode } from "react";
+import { useQueryClient } from "@tanstack/react-query";
+import { Button, Card, Form, Input, Spin } from "antd";
+import { onUnauthorized } from "../../api/client";
+import { signedOut, useAuthStatus, useLogin, useSetupAdmin } from "../../hooks/useAuth";
+import { ErrorState } from "../common/ErrorState";
+import LocaleSwitcher from "../common/LocaleSwitcher";
+import { useI18n } from "../../i18n/context";
+
+/** Renders children once signed in (or when auth is off); otherwise the setup or login form. */
+export default function AuthGate({ children }: { children: ReactNode }) {
+  const { tr } = useI18n();
+  const q = useQueryClient();
+  const { data, isLoading, isError, error, refetch } = useAuthStatus();
+
+  // A 401 from any API call means the session ended; go back to the login form.
+  useEffect(() => onUnauthorized(() => signedOut(q)), [q]);
+
+  if (isLoading) {
+    return (
+      <div className="bp-auth-screen">
+        <Spin />
+      </div>
+    );
+  }
+  if (isError || !data) {
+    const message = (error as any)?.appError?.message ?? (error as Error | null)?.message ?? "";
+    return (
+      <div className="bp-auth-screen">
+        <ErrorState
+          message={tr("auth.status_error", "Failed to reach BoxPilot: {message}", { message })}
+          onRetry={() => void refetch()}
+        />
+      </div>
+    );
+  }
+  if (!data.enabled || data.principal) {
+    return <>{children}</>;
+  }
+  return (
+    <div className="bp-auth-screen">
+      <Card className="bp-auth-card">
+        <div className="bp-card-header">
+          <div>
+            <p className="bp-card-kicker">BoxPilot</p>
+            <h2 className="bp-card-title">
+              {data.setup_required
+                ? tr("auth.setup.title", "Create the admin user")
+                : tr("auth.login.title", "Sign in")}
+            </h2>
+          </div>
+          <LocaleSwitcher />
+        </div>
+        {data.setup_required ? <SetupForm /> : <LoginForm />}
+      </Card>
+    </div>
+  );
+}
+
+function LoginForm() {
+  const { tr } = useI18n();
+  const loginMutation = useLogin();
+  return (
+    <Form layout="vertical" onFinish={(values) => loginMutation.mutate(values)} requiredMark={false}>
+      <Form.Item
+        name="username"
+        label={tr("auth.username", "Username")}
+        rules={[{ required: true, message: tr("auth.username.required", "Enter the username") }]}
+      >
+        <Input autoComplete="username" autoFocus />
+      </Form.Item>
+      <Form.Item
+        name="password"
+        label={tr("auth.password", "Password")}
+        rules={[{ required: true, message: tr("auth.password.required", "Enter the password") }]}
+      >
+        <Input.Password autoComplete="current-password" />
+      </Form.Item>
+      <Button type="primary" htmlType="submit" block loading={loginMutation.isPending}>
+        {tr("auth.login.submit", "Sign in")}
+      </Button>
+    </Form>
+  );
+}
+
+function SetupForm() {
+  const { tr } = useI18n();
+  const setup = useSetupAdmin();
+  return (
+    <Form
+      layout="vertical"
+      initialValues={{ username: "admin" }}
+      onFinish={(values) =>
+        setup.mutate({ setup_token: values.setup_token.trim(), username: values.username, password: values.password })
+      }
+      requiredMark={false}
+    >
+      <p className="bp-muted" style={{ marginTop: 0 }}>
+        {tr(
+          "auth.setup.desc",
+          "No admin user exists yet. Paste the setup token printed in the server log on start (or BOXPILOT_SETUP_TOKEN).",
+        )}
+      </p>
+      <Form.Item
+        name="setup_token"
+        label={tr("auth.setup.token", "Setup token")}
+        rules={[{ required: true, message: tr("auth.setup.token.required", "Enter the setup token") }]}
+      >
+        <Input autoComplete="off" autoFocus />
+      </Form.Item>
+      <Form.Item
+        name="username"
+        label={tr("auth.username", "Username")}
+        rules={[{ required: true, message: tr("auth.username.required", "Enter the username") }]}
+      >
+        <Input autoComplete="username" />
+      </Form.Item>
+      <PasswordFields name="password" />
+      <Button type="primary" htmlType="submit" block loading={setup.isPending}>
+        {tr("auth.setup.submit", "Create and sign in")}
+      </Button>
+    </Form>
+  );
+}
+
+/** A new password and its confirmation; the server accepts 8 to 72 bytes. */
+export function PasswordFields({ name }: { name: string }) {
+  const { tr } = useI18n();
+  return (
+    <>
+      <Form.Item
+        name={name}
+        label={tr("auth.password.new", "New password")}
+        rules={[
+          { required: true, message: tr("auth.password.required", "Enter the password") },
+          { min: 8, message: tr("auth.password.min", "At least 8 characters") },
+        ]}
+      >
+        <Input.Password autoComplete="new-password" />
+      </Form.Item>
+      <Form.Item
+        name={`${name}_confirm`}
+        label={tr("auth.password.confirm", "Confirm password")}
+        dependencies={[name]}
+        rules={[
+          { required: true, message: tr("auth.password.required", "Enter the password") },
+          ({ getFieldValue }) => ({
+            validator: (_, value) =>
+              !value || value === getFieldValue(name)
+                ? Promise.resolve()
+                : Promise.reject(new Error(tr("auth.password.mismatch", "Passwords do not match"))),
+          }),
+        ]}
+      >
+        <Input.Password autoComplete="new-password" />
+      </Form.Item>
+    </>
+  );
+}
@@ -0,0 +1,181 @@
+import { useState } from "react";
+import { Alert, Button, Card, Form, Input, InputNumber, Popconfirm, Select, Table, Tag, Typography } from "antd";
+import type { ApiToken, AuthScope, CreatedApiToken } from "../../api/types";
+import { useApiTokens, useChangePassword, useCreateApiToken, useDeleteApiToken } from "../../hooks/useAuth";
+import { PasswordFields } from "./AuthGate";
+import { useI18n } from "../../i18n/context";
+import { formatDateTime } from "../../utils/datetime";
+
+const scopeColors: Record<AuthScope, string> = { "read-only": "blue", operator: "gold", admin: "red" };
+
+export function ChangePasswordCard() {
+  const { tr } = useI18n();
+  const [form] = Form.useForm();
+  const change = useChangePassword();
+
+  const onFinish = async (values: { current_password: string; new_password: string }) => {
+    await change.mutateAsync({ current_password: values.current_password, new_password: values.new_password });
+    form.resetFields();
+  };
+
+  return (
+    <Card className="bp-settings-card">
+      <div className="bp-card-header">
+        <div>
+          <p className="bp-card-kicker">{tr("settings.security.kicker", "Security")}</p>
+          <h2 className="bp-card-title">{tr("settings.security.password.title", "Admin Password")}</h2>
+        </div>
+      </div>
+      <p className="bp-muted" style={{ marginTop: 0, marginBottom: 12 }}>
+        {tr("settings.security.password.desc", "Changing the password signs out every other browser session.")}
+      </p>
+      <Form form={form} layout="vertical" onFinish={(values) => void onFinish(values)} requiredMark={false}>
+        <Form.Item
+          name="current_password"
+          label={tr("auth.password.current", "Current password")}
+          rules={[{ required: true, message: tr("auth.password.required", "Enter the password") }]}
+        >
+          <Input.Password autoComplete="current-password" />
+        </Form.Item>
+        <PasswordFields name="new_password" />
+        <Button type="primary" htmlType="submit" loading={change.isPending}>
+          {tr("settings.security.password.submit", "Change password")}
+        </Button>
+      </Form>
+    </Card>
+  );
+}
+
+export function ApiTokensCard() {
+  const { tr } = useI18n();
+  const [form] = Form.useForm();
+  const { data: tokens, isLoading } = useApiTokens();
+  const create = useCreateApiToken();
+  const remove = useDeleteApiToken();
+  const [created, setCreated] = useState<CreatedApiToken | null>(null);
+
+  const scopeOptions: { value: AuthScope; label: string }[] = [
+    { value: "read-only", label: tr("auth.scope.read-only", "Read-only") },
+    { value: "operator", label: tr("auth.scope.operator", "Operator") },
+    { value: "admin", label: tr("auth.scope.admin", "Admin") },
+  ];
+  const scopeLabel = (scope: AuthScope) => scopeOptions.find((o) => o.value === scope)?.label ?? scope;
+
+  const onFinish = async (values: { name: string; scope: AuthScope; expires_in_days?: number | null }) => {
+    const token = await create.mutateAsync({
+      name: values.name,
+      scope: values.scope,
+      expires_in_days: values.expires_in_days ?? 0,
+    });
+    setCreated(token);
+    form.resetFields();
+  };
+
+  return (
+    <Card className="bp-settings-card">
+      <div className="bp-card-header">
+        <div>
+          <p className="bp-card-kicker">{tr("settings.security.kicker", "Security")}</p>
+          <h2 className="bp-card-title">{tr("settings.security.tokens.title", "API Tokens")}</h2>
+        </div>
+      </div>
+      <p className="bp-muted" style={{ marginTop: 0, marginBottom: 12 }}>
+        {tr(
+          "settings.security.tokens.desc",
+          "For scripts and automation: send as Authorization: Bearer <token>. Read-only can view, operator can also refresh, test and reload, admin can change everything.",
+        )}
+      </p>
+      {created ? (
+        <Alert
+          type="success"
+          showIcon
+          closable
+          onClose={() => setCreated(null)}
+          style={{ marginBottom: 12 }}
+          title={tr("settings.security.tokens.created", "Copy the token now; it will not be shown again.")}
+          description={
+            <Typography.Text code copyable className="bp-auth-token">
+              {created.token}
+            </Typography.Text>
+          }
+        />
+      ) : null}
+      <Form
+        form={form}
+        layout="inline"
+        initialValues={{ scope: "read-only" }}
+        onFinish={(values) => void onFinish(values)}
+        requiredMark={false}
+        style={{ marginBottom: 12, rowGap: 8 }}
+      >
+        <Form.Item
+          name="name"
+          rules={[{ required: true, message: tr("settings.security.tokens.name.required", "Enter a name") }]}
+        >
+          <Input placeholder={tr("settings.security.tokens.name", "Name, e.g. grafana")} maxLength={64} />
+        </Form.Item>
+        <Form.Item name="scope">
+          <Select options={scopeOptions} style={{ width: 130 }} />
+        </Form.Item>
+        <Form.Item name="expires_in_days">
+          <InputNumber
+            min={1}
+            max={3650}
+            placeholder={tr("settings.security.tokens.expires", "Days (empty = never)")}
+            style={{ width: 190 }}
+          />
+        </Form.Item>
+        <Button type="primary" htmlType="submit" loading={create.isPending}>
+          {tr("settings.security.tokens.create", "Create token")}
+        </Button>
+      </Form>
+      <Table<ApiToken>
+        size="small"
+        rowKey="id"
+        loading={isLoading}
+        dataSource={tokens ?? []}
+        pagination={false}
+        locale={{ emptyText: tr("settings.security.tokens.empty", "No API tokens.") }}
+        columns={[
+          { title: tr("settings.security.tokens.col.name", "Name"), dataIndex: "name" },
+          {
+            title: tr("settings.security.tokens.col.scope", "Scope"),
+            dataIndex: "scope",
+            render: (scope: AuthScope) => <Tag color={scopeColors[scope]}>{scopeLabel(scope)}</Tag>,
+          },
+          {
+            title: tr("settings.security.tokens.col.prefix", "Token"),
+            dataIndex: "prefix",
+            render: (prefix: string) => <Typography.Text code>{prefix}…</Typography.Text>,
+          },
+          {
+            title: tr("settings.security.tokens.col.expires", "Expires"),
+            dataIndex: "expires_at",
+            render: (v?: string) => (v ? formatDateTime(v) : tr("settings.security.tokens.never", "Never")),
+          },
+          {
+            title: tr("settings.security.tokens.col.last_used", "Last used"),
+            dataIndex: "last_used_at",
+            render: (v?: string) => (v ? formatDateTime(v) : "-"),
+          },
+          {
+            key: "actions",
+            align: "right",
+            render: (_: unknown, record: ApiToken) => (
+              <Popconfirm
+                title={tr("settings.security.tokens.revoke.confirm", "Revoke this token?")}
+                okText={tr("settings.security.tokens.revoke", "Revoke")}
+                cancelText={tr("common.cancel", "Cancel")}
+                onConfirm={() => remove.mutate(record.id)}
+              >
+                <Button type="link" danger size="small" disabled={remove.isPending}>
+                  {tr("settings.security.tokens.revoke", "Revoke")}
+                </Button>
+              </Popconfirm>
+            ),
+          },
+        ]}
+      />
+    </Card>
+  );
+}
@@ -0,0 +1,140 @@
+import { useQuery, useMutation, useQueryClient, type QueryClient } from "@tanstack/react-query";
+import type { AuthStatus } from "../api/types";
+import {
+  getAuthStatus,
+  setupAdmin,
+  login,
+  logout,
+  changePassword,
+  getApiTokens,
+  createApiToken,
+  deleteApiToken,
+  type SetupBody,
+  type CreateApiTokenBody,
+} from "../api/auth";
+import { useToast } from "../components/common/ToastContext";
+import { useI18n } from "../i18n/context";
+
+const authStatusKey = ["auth-status"];
+
+export function useAuthStatus() {
+  return useQuery<AuthStatus>({
+    queryKey: authStatusKey,
+    queryFn: getAuthStatus,
+    staleTime: 30_000,
+    retry: false,
+  });
+}
+
+export function useSetupAdmin() {
+  const { tr } = useI18n();
+  const q = useQueryClient();
+  const { addToast } = useToast();
+  return useMutation({
+    mutationFn: (body: SetupBody) => setupAdmin(body),
+    onSuccess: () => {
+      q.invalidateQueries({ queryKey: authStatusKey });
+      addToast("success", tr("toast.auth.setup_done", "Admin user created"));
+    },
+    onError: (error: unknown) => {
+      addToast("error", tr("toast.auth.setup_failed", "Setup failed: {message}", { message: extractErrorMessage(error) }));
+    },
+  });
+}
+
+export function useLogin() {
+  const { tr } = useI18n();
+  const q = useQueryClient();
+  const { addToast } = useToast();
+  return useMutation({
+    mutationFn: (body: { username: string; password: string }) => login(body),
+    onSuccess: () => {
+      q.invalidateQueries({ queryKey: authStatusKey });
+    },
+    onError: (error: unknown) => {
+      addToast("error", tr("toast.auth.login_failed", "Sign in failed: {message}", { message: extractErrorMessage(error) }));
+    },
+  });
+}
+
+export function useLogout() {
+  const { tr } = useI18n();
+  const q = useQueryClient();
+  const { addToast } = useToast();
+  return useMutation({
+    mutationFn: logout,
+    onSuccess: () => signedOut(q),
+    onError: (error: unknown) => {
+      addToast("error", tr("toast.auth.logout_failed", "Sign out failed: {message}", { message: extractErrorMessage(error) }));
+    },
+  });
+}
+
+/** Drops the principal and every cached API response, so the next user starts clean. */
+export function signedOut(q: QueryClient) {
+  q.setQueryData<AuthStatus>(authStatusKey, (prev) => (prev ? { ...prev, principal: undefined } : prev));
+  q.removeQueries({ predicate: (query) => query.queryKey[0] !== authStatusKey[0] });
+}
+
+export function useChangePassword() {
+  const { tr } = useI18n();
+  const { addToast } = useToast();
+  return useMutation({
+    mutationFn: (body: { current_password: string; new_password: string }) => changePassword(body),
+    onSuccess: () => {
+      addToast("success", tr("toast.auth.password_changed", "Password changed; other sessions were signed out"));
+    },
+    onError: (error: unknown) => {
+      addToast("error", tr("toast.auth.password_failed", "Change password failed: {message}", { message: extractErrorMessage(error) }));
+    },
+  });
+}
+
+export function useApiTokens(enabled = true) {
+  return useQuery({
+    queryKey: ["api-tokens"],
+    queryFn: getApiTokens,
+    enabled,
+    staleTime: 0,
+  });
+}
+
+export function useCreateApiToken() {
+  const { tr } = useI18n();
+  const q = useQueryClient();
+  const { addToast } = useToast();
+  return useMutation({
+    mutationFn: (body: CreateApiTokenBody) => createApiToken(body),
+    onSuccess: () => {
+      q.invalidateQueries({ queryKey: ["api-tokens"] });
+    },
+    onError: (error: unknown) => {
+      addToast("error", tr("toast.auth.token_create_failed", "Create token failed: {message}", { message: extractErrorMessage(error) }));
+    },
+  });
+}
+
+export function useDeleteApiToken() {
+  const { tr } = useI18n();
+  const q = useQueryClient();
+  const { addToast } = useToast();
+  return useMutation({
+    mutationFn: (id: string) => deleteApiToken(id),
+    onSuccess: () => {
+      q.invalidateQueries({ queryKey: ["api-tokens"] });
+      addToast("success", tr("toast.auth.token_deleted", "Token revoked"));
+    },
+    onError: (error: unknown) => {
+      addToast("error", tr("toast.auth.token_delete_failed", "Revoke token failed: {message}", { message: extractErrorMessage(error) }));
+    },
+  });
+}
+
+function extractErrorMessage(error: unknown): string {
+  const anyErr = error as any;
+  if (anyErr?.appError?.message) return anyErr.appError.message as string;
+  if (anyErr?.response?.data?.error?.message)
+    return anyErr.response.data.error.message as string;
+  if (anyErr?.message) return anyErr.message as string;
+  return "Unknown error";
+}
@@ -1,5 +1,8 @@
+import { useEffect, useState } from "react";
 import { useQuery, useMutation, useQueryClient } from "@tanstack/react-query";
 import { api } from "../api/client";
+import { openEventStream, openRuntimeLogStream } from "../api/events";
+import { getTrafficStats } from "../api/stats";
 import type {
   RuntimeConnectionsData,
   RuntimeGroupSelectData,
@@ -8,11 +11,54 @@ import type {
   RuntimeProxyCheckData,
   RuntimeStatusData,
   RuntimeTrafficData,
+  TrafficStatsDimension,
 } from "../api/types";
 import { useToast } from "../components/common/ToastContext";
 import { useI18n } from "../i18n/context";
 
-export function useRuntimeStatus() {
+/**
+ * Subscribes to the live event stream: traffic samples replace the traffic
+ * query data and control-plane events refresh the affected queries. Returns
+ * whether the stream is connected, so callers can stop polling meanwhile.
+ */
+export function useRuntimeEventStream(enabled = true) {
+  const q = useQueryClient();
+  const [connected, setConnected] = useState(false);
+  useEffect(() => {
+    if (!enabled || typeof EventSource === "undefined") return;
+    return openEventStream({
+      topics: ["control", "traffic"],
+      onOpenChange: setConnected,
+      onEvent: (event) => {
+        switch (event.type) {
+          case "traffic.sample":
+            q.setQueryData(["runtime-traffic"], event.data as unknown as RuntimeTrafficData);
+            break;
+          case "config.applied":
+          case "reload.failed":
+          case "rollback.performed":
+          case "forwarding.started":
+          case "forwarding.stopped":
+            q.invalidateQueries({ queryKey: ["runtime-status"] });
+            q.invalidateQueries({ queryKey: ["runtime-connections"] });
+            break;
+          case "group.selection_changed":
+            q.invalidateQueries({ queryKey: ["runtime-groups"] });
+            break;
+          case "subscription.refreshed":
+            q.invalidateQueries({ queryKey: ["subscriptions"] });
+            break;
+          case "nodes.changed":
+            q.invalidateQueries({ queryKey: ["nodes"] });
+            break;
+        }
+      },
+    });
+  }, [enabled, q]);
+  return connected;
+}
+
+export function useRuntimeStatus(streaming = false) {
   return useQuery({
     queryKey: ["runtime-status"],
     queryFn: async () => {
@@ -24,7 +70,7 @@ export function useRuntimeStatus() {
     staleTime: 0,
     refetchOnMount: "always",
     refetchOnWindowFocus: true,
-    refetchInterval: 8_000,
+    refetchInterval: streaming ? 60_000 : 8_000,
     refetchIntervalInBackground: true,
   });
 }
@@ -57,7 +103,7 @@ export function useRuntimeReload() {
   });
 }
 
-export function useRuntimeTraffic() {
+export function useRuntimeTraffic(streaming = false) {
   return useQuery({
     queryKey: ["runtime-traffic"],
     queryFn: async () => {
@@ -66,8 +112,8 @@ export function useRuntimeTraffic() {
     },
     staleTime: 0,
     refetchOnMount: "always",
-    refetchOnWindowFocus: true,
-    refetchInterval: 4000,
+    refetchOnWindowFocus: !streaming,
+    refetchInterval: streaming ? false : 4000,
     refetchIntervalInBackground: true,
   });
 }
@@ -95,13 +141,23 @@ export function useRuntimeProxyCheck() {
   });
 }
 
-export function useRuntimeConnections(query?: string) {
+export type RuntimeConnectionFilters = {
+  outbound?: string;
+  group?: string;
+  rule?: string;
+  q?: string;
+};
+
+export function useRuntimeConnections(filters?: RuntimeConnectionFilters) {
+  const params = Object.fromEntries(
+    Object.entries(filters ?? {})
+      .map(([key, value]) => [key, (value ?? "").trim()])
+      .filter(([, value]) => value),
+  );
   return useQuery({
-    queryKey: ["runtime-connections", query ?? ""],
+    queryKey: ["runtime-connections", params],
     queryFn: async () => {
-      const { data } = await api.get<{ data: RuntimeConnectionsData }>("/runtime/connections", {
-        params: query && query.trim() ? { q: query.trim() } : undefined,
-      });
+      const { data } = await api.get<{ data: RuntimeConnectionsData }>("/runtime/connections", { params });
       return data.data;
     },
     staleTime: 0,
@@ -112,6 +168,32 @@ export function useRuntimeConnections(query?: string) {
   });
 }
 
+/** Closes one live connection by id, or all of them with { all: true }. */
+export function useCloseRuntimeConnection() {
+  const { tr } = useI18n();
+  const q = useQueryClient();
+  const { addToast } = useToast();
+  return useMutation({
+    mutationFn: async (body: { id?: string; all?: boolean }) => {
+      const { data } = await api.post<{ data: { closed: number } }>("/runtime/connections/close", body);
+      return data.data;
+    },
+    onSuccess: (data) => {
+      q.invalidateQueries({ queryKey: ["runtime-connections"] });
+      addToast("success", tr("toast.runtime.connections_closed", "Closed {count} connection(s)", { count: data.closed }));
+    },
+    onError: (error: unknown) => {
+      const anyErr = error as any;
+      const message =
+        anyErr?.appError?.message ||
+        anyErr?.response?.data?.error?.message ||
+        anyErr?.message ||
+        tr("toast.unknown", "Unknown error");
+      addToast("error", tr("toast.runtime.connection_close_failed", "Close connection failed: {message}", { message }));
+    },
+  });
+}
+
 export function useRuntimeGroups() {
   return useQuery({
     queryKey: ["runtime-groups"],
@@ -211,6 +293,10 @@ function isSoftAutoProbeError(message?: string): boolean {
   );
 }
 
+/**
+ * Loads buffered sing-box logs and, while the log stream is connected,
+ * prepends new lines to the cached page instead of polling.
+ */
 export function useRuntimeLogs(params?: {
   level?: string;
   q?: string;
@@ -223,9 +309,33 @@ export function useRuntimeLogs(params?: {
   const limit = params?.limit ?? 80;
   const enabled = params?.enabled ?? true;
   const refetchIntervalMs = params?.refetchIntervalMs ?? 5000;
+  const queryKey = ["runtime-logs", level, q, limit];
+  const client = useQueryClient();
+  const [streaming, setStreaming] = useState(false);
+
+  useEffect(() => {
+    if (!enabled || typeof EventSource === "undefined") return;
+    return openRuntimeLogStream({
+      level,
+      q,
+      tail: 0,
+      onOpenChange: setStreaming,
+      onLog: (item) => {
+        client.setQueryData<RuntimeLogsData>(["runtime-logs", level, q, limit], (prev) => {
+          if (!prev || prev.items.some((existing) => existing.seq === item.seq)) return prev;
+          return {
+            ...prev,
+            items: [item, ...prev.items].slice(0, limit),
+            last_seq: Math.max(prev.last_seq, item.seq),
+            counts: { ...prev.counts, [item.level]: (prev.counts[item.level] ?? 0) + 1 },
+          };
+        });
+      },
+    });
+  }, [enabled, level, q, limit, client]);
 
   return useQuery({
-    queryKey: ["runtime-logs", level, q, limit],
+    queryKey,
     queryFn: async () => {
       const { data } = await api.get<{ data: RuntimeLogsData }>("/runtime/logs", {
         params: {
@@ -240,7 +350,16 @@ export function useRuntimeLogs(params?: {
     staleTime: 0,
     refetchOnMount: "always",
     refetchOnWindowFocus: true,
-    refetchInterval: refetchIntervalMs,
+    // Resync now and then in case the stream dropped lines.
+    refetchInterval: streaming ? 60000 : refetchIntervalMs,
     refetchIntervalInBackground: true,
   });
 }
+
+export function useTrafficStats(by: TrafficStatsDimension, range: string) {
+  return useQuery({
+    queryKey: ["traffic-stats", by, range],
+    queryFn: () => getTrafficStats({ by, range }),
+    refetchInterval: 60_000,
+  });
+}
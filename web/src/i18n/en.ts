@@ -8,6 +8,28 @@ const en = {
   "nav.language": "Language",
   "nav.language.zh": "中文",
   "nav.language.en": "English",
+  "nav.logout": "Sign out",
+  "nav.logout.user": "Sign out {user}",
+  "auth.status_error": "Failed to reach BoxPilot: {message}",
+  "auth.login.title": "Sign in",
+  "auth.login.submit": "Sign in",
+  "auth.setup.title": "Create the admin user",
+  "auth.setup.desc": "No admin user exists yet. Paste the setup token printed in the server log on start (or BOXPILOT_SETUP_TOKEN).",
+  "auth.setup.token": "Setup token",
+  "auth.setup.token.required": "Enter the setup token",
+  "auth.setup.submit": "Create and sign in",
+  "auth.username": "Username",
+  "auth.username.required": "Enter the username",
+  "auth.password": "Password",
+  "auth.password.current": "Current password",
+  "auth.password.new": "New password",
+  "auth.password.confirm": "Confirm password",
+  "auth.password.required": "Enter the password",
+  "auth.password.min": "At least 8 characters",
+  "auth.password.mismatch": "Passwords do not match",
+  "auth.scope.read-only": "Read-only",
+  "auth.scope.operator": "Operator",
+  "auth.scope.admin": "Admin",
   "app.proxy.status": "Status",
   "app.proxy.selected": "Selected",
   "app.proxy.empty": "No forwarding nodes selected.",
@@ -72,6 +94,27 @@ const en = {
   "settings.section.access": "Access",
   "settings.section.routing": "Routing",
   "settings.section.runtime": "Runtime",
+  "settings.section.security": "Security",
+  "settings.security.kicker": "Security",
+  "settings.security.password.title": "Admin Password",
+  "settings.security.password.desc": "Changing the password signs out every other browser session.",
+  "settings.security.password.submit": "Change password",
+  "settings.security.tokens.title": "API Tokens",
+  "settings.security.tokens.desc": "For scripts and automation: send as Authorization: Bearer <token>. Read-only can view, operator can also refresh, test and reload, admin can change everything.",
+  "settings.security.tokens.created": "Copy the token now; it will not be shown again.",
+  "settings.security.tokens.name": "Name, e.g. grafana",
+  "settings.security.tokens.name.required": "Enter a name",
+  "settings.security.tokens.expires": "Days (empty = never)",
+  "settings.security.tokens.create": "Create token",
+  "settings.security.tokens.empty": "No API tokens.",
+  "settings.security.tokens.col.name": "Name",
+  "settings.security.tokens.col.scope": "Scope",
+  "settings.security.tokens.col.prefix": "Token",
+  "settings.security.tokens.col.expires": "Expires",
+  "settings.security.tokens.col.last_used": "Last used",
+  "settings.security.tokens.never": "Never",
+  "settings.security.tokens.revoke": "Revoke",
+  "settings.security.tokens.revoke.confirm": "Revoke this token?",
   "settings.copy.url": "Copy URL",
   "settings.copy.done": "Copied",
   "settings.copy.success": "Connection string copied ({host}:{port})",
@@ -335,6 +378,7 @@ const en = {
   "dashboard.logs.loading": "Loading runtime logs...",
   "dashboard.logs.empty": "No runtime logs yet.",
   "dashboard.logs.level.all": "All Levels",
+  "dashboard.logs.level.debug": "Debug",
   "dashboard.logs.level.info": "Info",
   "dashboard.logs.level.warn": "Warn",
   "dashboard.logs.level.error": "Error",
@@ -343,18 +387,29 @@ const en = {
   "dashboard.logs.col.level": "Level",
   "dashboard.logs.col.source": "Source",
   "dashboard.logs.col.message": "Message",
+  "dashboard.kicker.usage": "Usage",
+  "dashboard.stats.title": "Traffic Share",
+  "dashboard.stats.by.node": "By Node",
+  "dashboard.stats.by.group": "By Group",
+  "dashboard.stats.by.inbound": "By Inbound",
+  "dashboard.stats.empty": "No traffic recorded in this range yet.",
   "dashboard.connections.title": "Connections",
   "dashboard.connections.active": "Active {count}",
-  "dashboard.connections.total": "Total {count}",
-  "dashboard.connections.errors": "Errors {count}",
+  "dashboard.connections.totals": "Up {up} · Down {down}",
   "dashboard.connections.more": "View Details",
   "dashboard.connections.empty": "No active connections.",
-  "dashboard.connections.filter": "Filter connections by node/target/status",
-  "dashboard.connections.node": "Node",
-  "dashboard.connections.target": "Target",
-  "dashboard.connections.status": "Status",
-  "dashboard.connections.latency": "Latency",
-  "dashboard.connections.last_test": "Last Test",
+  "dashboard.connections.disabled": "Clash API is disabled; live connections are unavailable.",
+  "dashboard.connections.unavailable": "Clash API is unreachable.",
+  "dashboard.connections.filter": "Filter by host, address, rule or chain",
+  "dashboard.connections.outbound": "Outbound",
+  "dashboard.connections.group": "Group",
+  "dashboard.connections.destination": "Destination",
+  "dashboard.connections.chain": "Chain",
+  "dashboard.connections.rule": "Rule",
+  "dashboard.connections.traffic": "Up / Down",
+  "dashboard.connections.duration": "Duration",
+  "dashboard.connections.close": "Close",
+  "dashboard.connections.close_all": "Close All",
   "dashboard.sync": "Sync",
   "toast.unknown": "Unknown error",
   "toast.node.updated": "Node updated",
@@ -393,6 +448,8 @@ const en = {
   "toast.runtime.reload_failed": "Runtime reload failed: {message}",
   "toast.runtime.proxy_check_ok": "Proxy chain check completed",
   "toast.runtime.proxy_check_failed": "Proxy chain check failed: {message}",
+  "toast.runtime.connections_closed": "Closed {count} connection(s)",
+  "toast.runtime.connection_close_failed": "Close connection failed: {message}",
   "toast.runtime.group_selected": "Routing group selection applied",
   "toast.runtime.group_selected_auto": "Auto tested candidates. Current best node: {outbound}",
   "toast.runtime.group_selected_auto_probe_deferred":
@@ -402,6 +459,15 @@ const en = {
   "toast.runtime.group_selected_auto_pending":
     "Auto mode enabled and saved. Candidate test was triggered; best node will update shortly.",
   "toast.runtime.group_select_failed": "Failed to update routing group: {message}",
+  "toast.auth.setup_done": "Admin user created",
+  "toast.auth.setup_failed": "Setup failed: {message}",
+  "toast.auth.login_failed": "Sign in failed: {message}",
+  "toast.auth.logout_failed": "Sign out failed: {message}",
+  "toast.auth.password_changed": "Password changed; other sessions were signed out",
+  "toast.auth.password_failed": "Change password failed: {message}",
+  "toast.auth.token_create_failed": "Create token failed: {message}",
+  "toast.auth.token_deleted": "Token revoked",
+  "toast.auth.token_delete_failed": "Revoke token failed: {message}",
 };
 
 export default en;
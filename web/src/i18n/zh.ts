@@ -8,6 +8,28 @@ const zh = {
   "nav.language": "语言",
   "nav.language.zh": "中文",
   "nav.language.en": "English",
+  "nav.logout": "退出登录",
+  "nav.logout.user": "退出 {user}",
+  "auth.status_error": "无法连接 BoxPilot：{message}",
+  "auth.login.title": "登录",
+  "auth.login.submit": "登录",
+  "auth.setup.title": "创建管理员",
+  "auth.setup.desc": "尚未创建管理员。请粘贴服务启动时日志中输出的初始化令牌（或 BOXPILOT_SETUP_TOKEN）。",
+  "auth.setup.token": "初始化令牌",
+  "auth.setup.token.required": "请输入初始化令牌",
+  "auth.setup.submit": "创建并登录",
+  "auth.username": "用户名",
+  "auth.username.required": "请输入用户名",
+  "auth.password": "密码",
+  "auth.password.current": "当前密码",
+  "auth.password.new": "新密码",
+  "auth.password.confirm": "确认密码",
+  "auth.password.required": "请输入密码",
+  "auth.password.min": "至少 8 个字符",
+  "auth.password.mismatch": "两次输入的密码不一致",
+  "auth.scope.read-only": "只读",
+  "auth.scope.operator": "运维",
+  "auth.scope.admin": "管理员",
   "app.proxy.status": "状态",
   "app.proxy.selected": "已选",
   "app.proxy.empty": "当前没有选中的转发节点。",
@@ -71,6 +93,27 @@ const zh = {
   "settings.section.access": "接入",
   "settings.section.routing": "路由",
   "settings.section.runtime": "运行时",
+  "settings.section.security": "安全",
+  "settings.security.kicker": "安全",
+  "settings.security.password.title": "管理员密码",
+  "settings.security.password.desc": "修改密码后，其他浏览器会话将全部退出登录。",
+  "settings.security.password.submit": "修改密码",
+  "settings.security.tokens.title": "API 令牌",
+  "settings.security.tokens.desc": "供脚本和自动化使用：通过 Authorization: Bearer <token> 发送。只读仅可查看，运维还可刷新、测速和重载，管理员可修改全部配置。",
+  "settings.security.tokens.created": "请立即复制令牌，之后将不再显示。",
+  "settings.security.tokens.name": "名称，例如 grafana",
+  "settings.security.tokens.name.required": "请输入名称",
+  "settings.security.tokens.expires": "有效天数（留空为永久）",
+  "settings.security.tokens.create": "创建令牌",
+  "settings.security.tokens.empty": "暂无 API 令牌。",
+  "settings.security.tokens.col.name": "名称",
+  "settings.security.tokens.col.scope": "权限",
+  "settings.security.tokens.col.prefix": "令牌",
+  "settings.security.tokens.col.expires": "过期时间",
+  "settings.security.tokens.col.last_used": "最近使用",
+  "settings.security.tokens.never": "永不",
+  "settings.security.tokens.revoke": "吊销",
+  "settings.security.tokens.revoke.confirm": "确定吊销此令牌？",
   "settings.copy.url": "复制链接",
   "settings.copy.done": "已复制",
   "settings.copy.success": "连接串已复制（{host}:{port}）",
@@ -325,6 +368,7 @@ const zh = {
   "dashboard.logs.loading": "正在加载运行日志...",
   "dashboard.logs.empty": "暂无运行日志。",
   "dashboard.logs.level.all": "全部级别",
+  "dashboard.logs.level.debug": "调试",
   "dashboard.logs.level.info": "信息",
   "dashboard.logs.level.warn": "警告",
   "dashboard.logs.level.error": "错误",
@@ -333,18 +377,29 @@ const zh = {
   "dashboard.logs.col.level": "级别",
   "dashboard.logs.col.source": "来源",
   "dashboard.logs.col.message": "消息",
+  "dashboard.kicker.usage": "用量",
+  "dashboard.stats.title": "流量分布",
+  "dashboard.stats.by.node": "按节点",
+  "dashboard.stats.by.group": "按分组",
+  "dashboard.stats.by.inbound": "按入站",
+  "dashboard.stats.empty": "该时间范围内暂无流量记录。",
   "dashboard.connections.title": "连接诊断",
   "dashboard.connections.active": "活跃 {count}",
-  "dashboard.connections.total": "总数 {count}",
-  "dashboard.connections.errors": "错误 {count}",
+  "dashboard.connections.totals": "上传 {up} · 下载 {down}",
   "dashboard.connections.more": "查看详情",
   "dashboard.connections.empty": "暂无活跃连接。",
-  "dashboard.connections.filter": "按节点/目标/状态筛选连接",
-  "dashboard.connections.node": "节点",
-  "dashboard.connections.target": "目标",
-  "dashboard.connections.status": "状态",
-  "dashboard.connections.latency": "延迟",
-  "dashboard.connections.last_test": "最后测试",
+  "dashboard.connections.disabled": "Clash API 已关闭，无法查看实时连接。",
+  "dashboard.connections.unavailable": "Clash API 无法访问。",
+  "dashboard.connections.filter": "按域名、地址、规则或链路筛选",
+  "dashboard.connections.outbound": "出站",
+  "dashboard.connections.group": "分组",
+  "dashboard.connections.destination": "目标",
+  "dashboard.connections.chain": "链路",
+  "dashboard.connections.rule": "规则",
+  "dashboard.connections.traffic": "上传 / 下载",
+  "dashboard.connections.duration": "时长",
+  "dashboard.connections.close": "关闭",
+  "dashboard.connections.close_all": "全部关闭",
   "dashboard.sync": "同步",
   "toast.unknown": "未知错误",
   "toast.node.updated": "节点已更新",
@@ -383,6 +438,8 @@ const zh = {
   "toast.runtime.reload_failed": "运行时重载失败：{message}",
   "toast.runtime.proxy_check_ok": "代理链路检查完成",
   "toast.runtime.proxy_check_failed": "代理链路检查失败：{message}",
+  "toast.runtime.connections_closed": "已关闭 {count} 个连接",
+  "toast.runtime.connection_close_failed": "关闭连接失败：{message}",
   "toast.runtime.group_selected": "分组选择已应用",
   "toast.runtime.group_selected_auto": "自动候选已测速，当前最优节点：{outbound}",
   "toast.runtime.group_selected_auto_probe_deferred":
@@ -392,6 +449,15 @@ const zh = {
   "toast.runtime.group_selected_auto_pending":
     "已开启自动并保存，且已触发测速，最优节点将很快更新。",
   "toast.runtime.group_select_failed": "更新分组失败：{message}",
+  "toast.auth.setup_done": "管理员已创建",
+  "toast.auth.setup_failed": "初始化失败：{message}",
+  "toast.auth.login_failed": "登录失败：{message}",
+  "toast.auth.logout_failed": "退出登录失败：{message}",
+  "toast.auth.password_changed": "密码已修改，其他会话已退出登录",
+  "toast.auth.password_failed": "修改密码失败：{message}",
+  "toast.auth.token_create_failed": "创建令牌失败：{message}",
+  "toast.auth.token_deleted": "令牌已吊销",
+  "toast.auth.token_delete_failed": "吊销令牌失败：{message}",
 };
 
 export default zh;
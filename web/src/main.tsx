@@ -2,6 +2,7 @@ import React from "react";
 import ReactDOM from "react-dom/client";
 import { QueryClient, QueryClientProvider } from "@tanstack/react-query";
 import App from "./App";
+import AuthGate from "./components/auth/AuthGate";
 import { ToastProvider } from "./components/common/ToastContext";
 import { I18nProvider } from "./i18n/context";
 import "antd/dist/reset.css";
@@ -22,7 +23,9 @@ ReactDOM.createRoot(document.getElementById("root")!).render(
     <QueryClientProvider client={queryClient}>
       <I18nProvider>
         <ToastProvider>
-          <App />
+          <AuthGate>
+            <App />
+          </AuthGate>
         </ToastProvider>
       </I18nProvider>
     </QueryClientProvider>
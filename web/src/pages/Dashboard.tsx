@@ -3,11 +3,14 @@ import { Link } from "react-router-dom";
 import { Button, Input, Modal, Select, Table, Tag, Tooltip } from "antd";
 import { SearchOutlined } from "@ant-design/icons";
 import {
+  useRuntimeEventStream,
   useRuntimeStatus,
   useRuntimeTraffic,
   useRuntimeConnections,
+  useCloseRuntimeConnection,
   useRuntimeLogs,
   useRuntimeProxyCheck,
+  useTrafficStats,
 } from "../hooks/useRuntime";
 import { useSubscriptions } from "../hooks/useSubscriptions";
 import { useNodes } from "../hooks/useNodes";
@@ -15,13 +18,19 @@ import { useForwardingSummary, useRoutingSummary } from "../hooks/useProxySettin
 import { ErrorState } from "../components/common/ErrorState";
 import { formatDateTime } from "../utils/datetime";
 import type { ColumnsType } from "antd/es/table";
-import type { RuntimeConnection, RuntimeLogItem, RuntimeProxyCheckItem } from "../api/types";
+import type {
+  RuntimeConnection,
+  RuntimeLogItem,
+  RuntimeProxyCheckItem,
+  TrafficStatsDimension,
+} from "../api/types";
 import { useI18n } from "../i18n/context";
 
 export default function Dashboard() {
   const { tr } = useI18n();
-  const { data: runtime, isLoading: runtimeLoading, error: runtimeError } = useRuntimeStatus();
-  const { data: traffic } = useRuntimeTraffic();
+  const streaming = useRuntimeEventStream();
+  const { data: runtime, isLoading: runtimeLoading, error: runtimeError } = useRuntimeStatus(streaming);
+  const { data: traffic } = useRuntimeTraffic(streaming);
   const { data: subs } = useSubscriptions();
   const { data: nodes } = useNodes({});
   const { data: forwardingSummary } = useForwardingSummary();
@@ -43,11 +52,17 @@ export default function Dashboard() {
   });
   const [connectionsModalOpen, setConnectionsModalOpen] = useState(false);
   const [connQuery, setConnQuery] = useState("");
+  const [connOutbound, setConnOutbound] = useState<string>();
+  const [connGroup, setConnGroup] = useState<string>();
   const {
     data: connectionsData,
     isLoading: connectionsLoading,
     isFetching: connectionsFetching,
-  } = useRuntimeConnections();
+  } = useRuntimeConnections({ outbound: connOutbound, group: connGroup });
+  const closeConnection = useCloseRuntimeConnection();
+  const [statsBy, setStatsBy] = useState<TrafficStatsDimension>("node");
+  const [statsRange, setStatsRange] = useState("24h");
+  const { data: trafficStats, isLoading: trafficStatsLoading } = useTrafficStats(statsBy, statsRange);
   const [proxyCheckTarget, setProxyCheckTarget] = useState("https://www.gstatic.com/generate_204");
   const proxyCheck = useRuntimeProxyCheck();
   const proxyCheckResult = proxyCheck.data;
@@ -93,75 +108,92 @@ export default function Dashboard() {
     if (!q) {
       return connections;
     }
-    return connections.filter((row) => {
-      const nodeName = row.node_name?.toLowerCase() ?? "";
-      const nodeType = row.node_type?.toLowerCase() ?? "";
-      const target = row.target?.toLowerCase() ?? "";
-      const status = row.status?.toLowerCase() ?? "";
-      return (
-        nodeName.includes(q) || nodeType.includes(q) || target.includes(q) || status.includes(q)
-      );
-    });
+    return connections.filter((row) =>
+      [row.destination, row.destination_ip, row.source, row.inbound, row.rule, row.node_name, ...row.chain]
+        .filter(Boolean)
+        .some((value) => value!.toLowerCase().includes(q)),
+    );
   }, [connQuery, connections]);
-  const connectionErrorCount = useMemo(
-    () => connections.filter((row) => (row.status || "").toLowerCase() === "error").length,
+  const connectionOutbounds = useMemo(
+    () => Array.from(new Set(connections.map((row) => row.outbound).filter(Boolean))).sort(),
+    [connections],
+  );
+  const connectionGroups = useMemo(
+    () => Array.from(new Set(connections.flatMap((row) => row.chain.slice(0, -1)))).sort(),
     [connections],
   );
   const connectionColumns: ColumnsType<RuntimeConnection> = useMemo(
     () => [
       {
-        title: tr("dashboard.connections.node", "Node"),
-        dataIndex: "node_name",
-        key: "node_name",
-        sorter: (a, b) => a.node_name.localeCompare(b.node_name),
-        render: (name: string, row) => (
+        title: tr("dashboard.connections.destination", "Destination"),
+        dataIndex: "destination",
+        key: "destination",
+        sorter: (a, b) => a.destination.localeCompare(b.destination),
+        render: (destination: string, row) => (
           <div>
-            <div>{name}</div>
-            <span className="bp-muted bp-table-mono">{row.node_type.toUpperCase()}</span>
+            <div className="bp-table-mono">{destination}</div>
+            <span className="bp-muted bp-table-mono">
+              {row.network.toUpperCase()} {row.source} · {row.inbound}
+            </span>
           </div>
         ),
       },
       {
-        title: tr("dashboard.connections.target", "Target"),
-        dataIndex: "target",
-        key: "target",
-        className: "bp-table-mono",
+        title: tr("dashboard.connections.chain", "Chain"),
+        dataIndex: "chain",
+        key: "chain",
+        render: (chain: string[], row) => (
+          <div>
+            <div>{row.node_name || row.outbound || "-"}</div>
+            <span className="bp-muted bp-table-mono">{chain.join(" → ")}</span>
+          </div>
+        ),
       },
       {
-        title: tr("dashboard.connections.status", "Status"),
-        dataIndex: "status",
-        key: "status",
-        width: 120,
-        sorter: (a, b) => a.status.localeCompare(b.status),
-        render: (value: string) => (
-          <Tag color={value === "ok" ? "success" : value === "error" ? "error" : "processing"}>
-            {value.toUpperCase()}
-          </Tag>
+        title: tr("dashboard.connections.rule", "Rule"),
+        dataIndex: "rule",
+        key: "rule",
+        className: "bp-table-mono",
+        render: (rule: string, row) => (
+          <Tooltip title={row.rule_payload || undefined}>
+            <span>{rule || "-"}</span>
+          </Tooltip>
         ),
       },
       {
-        title: tr("dashboard.connections.latency", "Latency"),
-        dataIndex: "latency_ms",
-        key: "latency_ms",
-        width: 120,
-        sorter: (a, b) =>
-          (a.latency_ms ?? Number.MAX_SAFE_INTEGER) - (b.latency_ms ?? Number.MAX_SAFE_INTEGER),
-        render: (v: number | null | undefined, row) => (
-          <span className={`bp-latency-badge bp-latency-badge-${latencyTone(v, row.status)}`}>
-            {formatLatency(v)}
-          </span>
-        ),
+        title: tr("dashboard.connections.traffic", "Up / Down"),
+        key: "traffic",
+        width: 160,
+        className: "bp-table-mono",
+        sorter: (a, b) => a.upload_bytes + a.download_bytes - (b.upload_bytes + b.download_bytes),
+        render: (_: unknown, row) => `${formatBytes(row.upload_bytes)} / ${formatBytes(row.download_bytes)}`,
       },
       {
-        title: tr("dashboard.connections.last_test", "Last Test"),
-        dataIndex: "last_test_at",
-        key: "last_test_at",
+        title: tr("dashboard.connections.duration", "Duration"),
+        dataIndex: "duration_ms",
+        key: "duration_ms",
+        width: 110,
         className: "bp-table-mono",
-        sorter: (a, b) => (a.last_test_at || "").localeCompare(b.last_test_at || ""),
-        render: (v: string | null | undefined) => (v ? formatDateTime(v) : "-"),
+        sorter: (a, b) => a.duration_ms - b.duration_ms,
+        render: (v: number) => formatDuration(v),
+      },
+      {
+        title: "",
+        key: "actions",
+        width: 90,
+        render: (_: unknown, row) => (
+          <Button
+            size="small"
+            danger
+            loading={closeConnection.isPending && closeConnection.variables?.id === row.id}
+            onClick={() => closeConnection.mutate({ id: row.id })}
+          >
+            {tr("dashboard.connections.close", "Close")}
+          </Button>
+        ),
       },
     ],
-    [tr],
+    [tr, closeConnection],
   );
 
   return (
@@ -600,6 +632,56 @@ export default function Dashboard() {
             )}
           </div>
 
+          <div className="bp-card bp-dashboard-card bp-dashboard-card--wide">
+            <div className="bp-card-header">
+              <div>
+                <p className="bp-card-kicker">{tr("dashboard.kicker.usage", "Usage")}</p>
+                <h2 className="bp-card-title">{tr("dashboard.stats.title", "Traffic Share")}</h2>
+              </div>
+              <div className="bp-card-header-meta">
+                <Select
+                  size="small"
+                  value={statsBy}
+                  onChange={setStatsBy}
+                  style={{ width: 120 }}
+                  options={[
+                    { value: "node", label: tr("dashboard.stats.by.node", "By Node") },
+                    { value: "group", label: tr("dashboard.stats.by.group", "By Group") },
+                    { value: "inbound", label: tr("dashboard.stats.by.inbound", "By Inbound") },
+                  ]}
+                />
+                <Select
+                  size="small"
+                  value={statsRange}
+                  onChange={setStatsRange}
+                  style={{ width: 100 }}
+                  options={["24h", "7d", "30d"].map((value) => ({ value, label: value }))}
+                />
+              </div>
+            </div>
+            {trafficStats?.items.length ? (
+              <div className="bp-connection-preview-list">
+                {trafficStats.items.slice(0, 5).map((item) => (
+                  <div key={item.key} className="bp-connection-preview-item">
+                    <span>
+                      {item.name || item.key}
+                      {item.sub_name ? <span className="bp-muted"> · {item.sub_name}</span> : null}
+                    </span>
+                    <span className="bp-table-mono">
+                      ↑ {formatBytes(item.upload_bytes)} ↓ {formatBytes(item.download_bytes)}
+                    </span>
+                  </div>
+                ))}
+              </div>
+            ) : (
+              <p className="bp-muted">
+                {trafficStatsLoading
+                  ? tr("common.loading", "Loading...")
+                  : tr("dashboard.stats.empty", "No traffic recorded in this range yet.")}
+              </p>
+            )}
+          </div>
+
           <div className="bp-card bp-dashboard-card bp-dashboard-card--wide">
             <div className="bp-card-header">
               <div>
@@ -617,13 +699,9 @@ export default function Dashboard() {
                   })}
                 </span>
                 <span className="bp-metric-pill bp-metric-pill-neutral">
-                  {tr("dashboard.connections.total", "Total {count}", {
-                    count: connections.length,
-                  })}
-                </span>
-                <span className="bp-metric-pill bp-metric-pill-neutral">
-                  {tr("dashboard.connections.errors", "Errors {count}", {
-                    count: connectionErrorCount,
+                  {tr("dashboard.connections.totals", "Up {up} · Down {down}", {
+                    up: formatBytes(connectionsData?.upload_total ?? 0),
+                    down: formatBytes(connectionsData?.download_total ?? 0),
                   })}
                 </span>
                 <span
@@ -642,11 +720,9 @@ export default function Dashboard() {
               <div className="bp-connection-preview-list">
                 {connections.slice(0, 4).map((item) => (
                   <div key={item.id} className="bp-connection-preview-item">
-                    <span className="bp-table-mono">{item.target}</span>
-                    <Tag
-                      color={(item.status || "").toLowerCase() === "error" ? "error" : "processing"}
-                    >
-                      {(item.status || "unknown").toUpperCase()}
+                    <span className="bp-table-mono">{item.destination}</span>
+                    <Tag color={item.node_id ? "processing" : "default"}>
+                      {item.node_name || item.outbound || "-"}
                     </Tag>
                   </div>
                 ))}
@@ -655,7 +731,11 @@ export default function Dashboard() {
               <p className="bp-muted">
                 {connectionsLoading
                   ? tr("common.loading", "Loading...")
-                  : tr("dashboard.connections.empty", "No active connections.")}
+                  : connectionsData?.source === "singbox_clash_api_disabled"
+                    ? tr("dashboard.connections.disabled", "Clash API is disabled; live connections are unavailable.")
+                    : connectionsData?.source === "singbox_clash_api_unavailable"
+                      ? tr("dashboard.connections.unavailable", "Clash API is unreachable.")
+                      : tr("dashboard.connections.empty", "No active connections.")}
               </p>
             )}
             <div className="bp-page-actions bp-settings-actions">
@@ -681,6 +761,7 @@ export default function Dashboard() {
             style={{ width: 140 }}
             options={[
               { value: "all", label: tr("dashboard.logs.level.all", "All Levels") },
+              { value: "debug", label: tr("dashboard.logs.level.debug", "Debug") },
               { value: "info", label: tr("dashboard.logs.level.info", "Info") },
               { value: "warn", label: tr("dashboard.logs.level.warn", "Warn") },
               { value: "error", label: tr("dashboard.logs.level.error", "Error") },
@@ -748,6 +829,8 @@ export default function Dashboard() {
         onCancel={() => {
           setConnectionsModalOpen(false);
           setConnQuery("");
+          setConnOutbound(undefined);
+          setConnGroup(undefined);
         }}
         footer={null}
         width={1100}
@@ -762,9 +845,33 @@ export default function Dashboard() {
             prefix={<SearchOutlined style={{ color: "#94a3b8" }} />}
             placeholder={tr(
               "dashboard.connections.filter",
-              "Filter connections by node/target/status",
+              "Filter by host, address, rule or chain",
             )}
           />
+          <Select
+            allowClear
+            value={connOutbound}
+            onChange={setConnOutbound}
+            placeholder={tr("dashboard.connections.outbound", "Outbound")}
+            options={connectionOutbounds.map((tag) => ({ value: tag, label: tag }))}
+            style={{ width: 160 }}
+          />
+          <Select
+            allowClear
+            value={connGroup}
+            onChange={setConnGroup}
+            placeholder={tr("dashboard.connections.group", "Group")}
+            options={connectionGroups.map((tag) => ({ value: tag, label: tag }))}
+            style={{ width: 160 }}
+          />
+          <Button
+            danger
+            disabled={!connections.length}
+            loading={closeConnection.isPending && closeConnection.variables?.all === true}
+            onClick={() => closeConnection.mutate({ all: true })}
+          >
+            {tr("dashboard.connections.close_all", "Close All")}
+          </Button>
         </div>
         <Table<RuntimeConnection>
           rowKey="id"
@@ -859,36 +966,16 @@ function formatBytes(value: number): string {
   return `${formatted} ${units[idx]}`;
 }
 
-type LatencyTone = "excellent" | "good" | "medium" | "slow" | "poor" | "error" | "warn" | "unknown";
-
-function latencyTone(latencyMs?: number | null, testStatus?: string | null): LatencyTone {
-  const status = (testStatus || "").toLowerCase();
-  if (status === "error") {
-    return "error";
-  }
-  if (latencyMs === null || latencyMs === undefined) {
-    return status === "warn" ? "warn" : "unknown";
+function formatDuration(ms: number): string {
+  const seconds = Math.max(0, Math.floor(ms / 1000));
+  if (seconds < 60) {
+    return `${seconds}s`;
   }
-  if (latencyMs <= 80) {
-    return "excellent";
-  }
-  if (latencyMs <= 150) {
-    return "good";
-  }
-  if (latencyMs <= 300) {
-    return "medium";
-  }
-  if (latencyMs <= 600) {
-    return "slow";
-  }
-  return "poor";
-}
-
-function formatLatency(latencyMs?: number | null): string {
-  if (latencyMs === null || latencyMs === undefined) {
-    return "-";
+  const minutes = Math.floor(seconds / 60);
+  if (minutes < 60) {
+    return `${minutes}m ${seconds % 60}s`;
   }
-  return `${latencyMs} ms`;
+  return `${Math.floor(minutes / 60)}h ${minutes % 60}m`;
 }
 
 function proxyCheckTone(result: RuntimeProxyCheckItem): "success" | "error" | undefined {
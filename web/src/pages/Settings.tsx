@@ -24,6 +24,8 @@ import {
   useUpdateForwardingPolicy,
 } from "../hooks/useProxySettings";
 import { useRuntimeGroups, useSelectRuntimeGroup } from "../hooks/useRuntime";
+import { useAuthStatus } from "../hooks/useAuth";
+import { ApiTokensCard, ChangePasswordCard } from "../components/auth/SecuritySettings";
 import { useToast } from "../components/common/ToastContext";
 import { useI18n } from "../i18n/context";
 
@@ -38,11 +40,13 @@ interface ProxyCardProps {
   onSaved?: () => void;
 }
 
-type SettingsSection = "access" | "routing" | "runtime";
+type SettingsSection = "access" | "routing" | "runtime" | "security";
 
 export default function Settings() {
   const { tr } = useI18n();
   const [section, setSection] = useState<SettingsSection>("access");
+  const { data: authStatus } = useAuthStatus();
+  const showSecurity = !!authStatus?.enabled && authStatus.principal?.kind === "session";
   const [pendingApply, setPendingApply] = useState(false);
   const {
     data,
@@ -151,6 +155,9 @@ export default function Settings() {
           { label: tr("settings.section.access", "Access"), value: "access" },
           { label: tr("settings.section.routing", "Routing"), value: "routing" },
           { label: tr("settings.section.runtime", "Runtime"), value: "runtime" },
+          ...(showSecurity
+            ? [{ label: tr("settings.section.security", "Security"), value: "security" }]
+            : []),
         ]}
       />
       {section === "access" ? (
@@ -191,6 +198,12 @@ export default function Settings() {
           />
         </div>
       ) : null}
+      {section === "security" && showSecurity ? (
+        <div className="bp-settings-grid bp-settings-grid-security">
+          <ChangePasswordCard />
+          <ApiTokensCard />
+        </div>
+      ) : null}
       {showSectionLoading && (
         <p className="bp-muted" style={{ marginTop: 12 }}>
           {tr("common.loading", "Loading...")}
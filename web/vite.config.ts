@@ -15,6 +15,7 @@ export default defineConfig(({ mode }) => {
       proxy: {
         "/api": apiOrigin,
         "/healthz": apiOrigin,
+        "/sub": apiOrigin,
       },
     },
   };